@@ -76,6 +76,7 @@ func (a *Api) Serve() {
 	r.HandleFunc("/gorepeat", a.goRepeatHandler).Methods("POST")
 	r.HandleFunc("/model", a.modelHandler)
 	r.HandleFunc("/modelat", a.modelAtHandler)
+	r.HandleFunc("/drawing", a.drawingHandler)
 
 	//frontend handlers
 	r.HandleFunc("/loadstats", a.loadStatsHandler)
@@ -269,6 +269,24 @@ func (a *Api) modelHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(model)
 }
 
+// returns the current drawing, it isn't stored with the steps so replayed steps show the current drawing
+func (a *Api) drawingHandler(w http.ResponseWriter, r *http.Request) {
+	a.funcMutext.Lock()
+	defer a.funcMutext.Unlock()
+
+	if a.currentModel == nil {
+		http.Error(w, "Model not instantiated", http.StatusNotFound)
+		return
+	}
+
+	drawing := convertDrawingToApiDrawing(a.currentModel.Model().Drawing())
+
+	//return the drawing as json
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(drawing)
+}
+
 func (a *Api) modelAtHandler(w http.ResponseWriter, r *http.Request) {
 
 	if a.currentModel == nil {
@@ -21,6 +21,8 @@ func convertModelToApiModel(model *model.Model) *Model {
 		MinPzCor:    model.MinPzCor(),
 		MaxPzCor:    model.MaxPzCor(),
 		Is3D:        model.Is3D(),
+
+		DrawingVersion: model.Drawing().Version(),
 	}
 	return &apiModel
 }
@@ -98,8 +100,47 @@ func convertLinkSetToApiLinkSet(links *model.LinkAgentSet) []Link {
 			LabelColor: convertColorToApiColor(link.LabelColor),
 			Size:       link.Size,
 			Hidden:     link.IsHidden(),
+			Properties: link.Properties(),
 		}
 		apiLinks = append(apiLinks, apiLink)
 	})
 	return apiLinks
 }
+
+func convertDrawingToApiDrawing(drawing *model.Drawing) Drawing {
+	segments := drawing.Segments()
+	stamps := drawing.Stamps()
+
+	apiDrawing := Drawing{
+		Version:  drawing.Version(),
+		Segments: make([]DrawingSegment, 0, len(segments)),
+		Stamps:   make([]DrawingStamp, 0, len(stamps)),
+	}
+
+	for _, segment := range segments {
+		apiDrawing.Segments = append(apiDrawing.Segments, DrawingSegment{
+			X1:    segment.X1,
+			Y1:    segment.Y1,
+			Z1:    segment.Z1,
+			X2:    segment.X2,
+			Y2:    segment.Y2,
+			Z2:    segment.Z2,
+			Color: convertColorToApiColor(segment.Color),
+			Size:  segment.Size,
+		})
+	}
+
+	for _, stamp := range stamps {
+		apiDrawing.Stamps = append(apiDrawing.Stamps, DrawingStamp{
+			X:       stamp.X,
+			Y:       stamp.Y,
+			Z:       stamp.Z,
+			Heading: stamp.Heading,
+			Size:    stamp.Size,
+			Shape:   stamp.Shape,
+			Color:   convertColorToApiColor(stamp.Color),
+		})
+	}
+
+	return apiDrawing
+}
@@ -14,6 +14,9 @@ type Model struct {
 	MinPzCor    int      `json:"minPzCor"`
 	MaxPzCor    int      `json:"maxPzCor"`
 	Is3D        bool     `json:"is3D"`
+
+	// the drawing can be large so it is served by its own endpoint, clients fetch it again when the version changes
+	DrawingVersion uint64 `json:"drawingVersion"`
 }
 
 type Patch struct {
@@ -60,4 +63,33 @@ type Link struct {
 	LabelColor Color       `json:"labelColor"`
 	Size       int         `json:"size"`
 	Hidden     bool        `json:"hidden"`
+
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type Drawing struct {
+	Version  uint64           `json:"version"`
+	Segments []DrawingSegment `json:"segments"`
+	Stamps   []DrawingStamp   `json:"stamps"`
+}
+
+type DrawingSegment struct {
+	X1    float64 `json:"x1"`
+	Y1    float64 `json:"y1"`
+	Z1    float64 `json:"z1"`
+	X2    float64 `json:"x2"`
+	Y2    float64 `json:"y2"`
+	Z2    float64 `json:"z2"`
+	Color Color   `json:"color"`
+	Size  float64 `json:"size"`
+}
+
+type DrawingStamp struct {
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Z       float64 `json:"z"`
+	Heading float64 `json:"heading"`
+	Size    float64 `json:"size"`
+	Shape   string  `json:"shape"`
+	Color   Color   `json:"color"`
 }
@@ -1,9 +0,0 @@
-package concurrency
-
-type Concurrency struct {
-}
-
-type PoolTurtle struct {
-	channelIn  chan interface{}
-	channelOut chan interface{}
-}
@@ -0,0 +1,16 @@
+package concurrency
+
+import (
+	"fmt"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// Errors
+var (
+	ErrExecutorClosed = fmt.Errorf("executor is closed")
+)
+
+// PanicError is returned by an Executor when the operation panics on an agent
+// it is the same type model.AgentSet.AskParallel panics with so both fail the same way
+type PanicError = model.PanicError
@@ -0,0 +1,196 @@
+package concurrency
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// number of chunks each worker gets when the chunk size isn't set, more chunks balance uneven work better
+const chunksPerWorker = 4
+
+// ExecutorSettings holds the settings for an Executor
+type ExecutorSettings struct {
+	Workers   int // number of goroutines in the pool, 0 or less means one per cpu
+	ChunkSize int // number of agents handed to a goroutine at a time, 0 or less splits the agents into a few chunks per worker
+}
+
+// Executor is a pool of goroutines that stay alive between asks
+// asking through an executor hands the agents out in chunks instead of starting new goroutines and a channel every time,
+// which is cheaper when agents are asked many times a tick
+// an executor can be used from several goroutines at once and should be closed when it is no longer needed
+type Executor struct {
+	workers   int
+	chunkSize int
+
+	jobs chan func()
+
+	closedMu sync.RWMutex
+	closed   bool
+	running  sync.WaitGroup
+}
+
+// NewExecutor creates an executor and starts its goroutines
+func NewExecutor(settings ExecutorSettings) *Executor {
+	workers := settings.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	e := &Executor{
+		workers:   workers,
+		chunkSize: settings.ChunkSize,
+		jobs:      make(chan func(), workers),
+	}
+
+	e.running.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer e.running.Done()
+			for job := range e.jobs {
+				job()
+			}
+		}()
+	}
+
+	return e
+}
+
+// returns the number of goroutines in the pool
+func (e *Executor) Workers() int {
+	return e.workers
+}
+
+// Close stops the goroutines of the executor once the asks already running are done
+// asking after the executor is closed returns ErrExecutorClosed
+func (e *Executor) Close() {
+	e.closedMu.Lock()
+	if e.closed {
+		e.closedMu.Unlock()
+		return
+	}
+	e.closed = true
+	close(e.jobs)
+	e.closedMu.Unlock()
+
+	e.running.Wait()
+}
+
+// returns the number of agents in each chunk
+func (e *Executor) chunkSizeFor(agents int) int {
+	if e.chunkSize > 0 {
+		return e.chunkSize
+	}
+	chunks := e.workers * chunksPerWorker
+	return max(1, (agents+chunks-1)/chunks)
+}
+
+// Ask runs the operation on every agent using the executor's goroutines and waits for all of them to finish.
+// The agents are split into chunks that the goroutines take one at a time, the goroutine asking takes chunks as well.
+// Agents in different chunks run at the same time in no particular order.
+// If the operation panics the chunks that haven't started are skipped and a *PanicError with the agent is returned.
+// If the context is cancelled the chunks that haven't started are skipped and the context's error is returned.
+// An operation can ask through the same executor, if every goroutine is busy the goroutine asking runs all the chunks itself.
+func Ask[T model.Agent](ctx context.Context, e *Executor, agents []T, operation func(T)) error {
+	if operation == nil || len(agents) == 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	chunkSize := e.chunkSizeFor(len(agents))
+	chunks := (len(agents) + chunkSize - 1) / chunkSize
+
+	var done sync.WaitGroup
+	var next atomic.Int64
+	var stopped atomic.Bool
+	var skipped atomic.Bool
+	var panicked *PanicError
+	var panicOnce sync.Once
+
+	runChunk := func(chunk []T) {
+		var current T
+		defer func() {
+			if r := recover(); r != nil {
+				stopped.Store(true)
+				panicOnce.Do(func() {
+					panicked = &PanicError{
+						AgentID: current.ID(),
+						Agent:   current,
+						Value:   r,
+						Stack:   debug.Stack(),
+					}
+				})
+			}
+		}()
+
+		for _, agent := range chunk {
+			current = agent
+			operation(agent)
+		}
+	}
+
+	// takes chunks until there are none left, chunks are only skipped after a panic or cancellation
+	work := func() {
+		for {
+			i := int(next.Add(1) - 1)
+			if i >= chunks {
+				return
+			}
+			if stopped.Load() || ctx.Err() != nil {
+				skipped.Store(true)
+			} else {
+				runChunk(agents[i*chunkSize : min((i+1)*chunkSize, len(agents))])
+			}
+			done.Done()
+		}
+	}
+
+	e.closedMu.RLock()
+	if e.closed {
+		e.closedMu.RUnlock()
+		return ErrExecutorClosed
+	}
+	done.Add(chunks)
+
+	// wake up as many goroutines as there are chunks for other than the one taken here
+	// goroutines that are busy are skipped, and a goroutine that gets to the work late finds no chunks left
+	for i := 0; i < min(e.workers, chunks-1); i++ {
+		select {
+		case e.jobs <- work:
+		default:
+		}
+	}
+	e.closedMu.RUnlock()
+
+	work()
+	done.Wait()
+
+	if panicked != nil {
+		return panicked
+	}
+	if skipped.Load() {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// AskTurtles runs the operation on the turtles using the executor, see Ask
+func (e *Executor) AskTurtles(ctx context.Context, turtleList []*model.Turtle, operation model.TurtleOperation) error {
+	return Ask(ctx, e, turtleList, operation)
+}
+
+// AskPatches runs the operation on the patches using the executor, see Ask
+func (e *Executor) AskPatches(ctx context.Context, patchList []*model.Patch, operation model.PatchOperation) error {
+	return Ask(ctx, e, patchList, operation)
+}
+
+// AskLinks runs the operation on the links using the executor, see Ask
+func (e *Executor) AskLinks(ctx context.Context, links []*model.Link, operation model.LinkOperation) error {
+	return Ask(ctx, e, links, operation)
+}
@@ -1,6 +1,7 @@
 package concurrency
 
 import (
+	"math/rand/v2"
 	"sync"
 
 	"github.com/nlatham1999/go-agent/pkg/model"
@@ -40,3 +41,9 @@ func loadLinkChannel(links []*model.Link) <-chan *model.Link {
 
 	return linkChannel
 }
+
+// AskLinksDeterministic runs the operation on the links using numGoRoutines goroutines with reproducible randomness
+// the links are split into fixed chunks that each get their own random generator, see model.AgentSet.AskParallel
+func AskLinksDeterministic(links []*model.Link, operation func(link *model.Link, r *rand.Rand), numGoRoutines int) {
+	model.NewLinkAgentSet(links).AskParallel(model.ParallelSettings{Workers: numGoRoutines}, operation)
+}
@@ -1,6 +1,7 @@
 package concurrency
 
 import (
+	"math/rand/v2"
 	"sync"
 
 	"github.com/nlatham1999/go-agent/pkg/model"
@@ -40,3 +41,9 @@ func loadPatchChannel(patchList []*model.Patch) <-chan *model.Patch {
 
 	return patchChannel
 }
+
+// AskPatchesDeterministic runs the operation on the patches using numGoRoutines goroutines with reproducible randomness
+// the patches are split into fixed chunks that each get their own random generator, see model.AgentSet.AskParallel
+func AskPatchesDeterministic(patchList []*model.Patch, operation func(patch *model.Patch, r *rand.Rand), numGoRoutines int) {
+	model.NewPatchAgentSet(patchList).AskParallel(model.ParallelSettings{Workers: numGoRoutines}, operation)
+}
@@ -1,6 +1,7 @@
 package concurrency
 
 import (
+	"math/rand/v2"
 	"sync"
 
 	"github.com/nlatham1999/go-agent/pkg/model"
@@ -42,3 +43,9 @@ func loadTurtleChannel(turtleList []*model.Turtle) <-chan *model.Turtle {
 
 	return channel
 }
+
+// AskTurtlesDeterministic runs the operation on the turtles using numGoRoutines goroutines with reproducible randomness
+// the turtles are split into fixed chunks that each get their own random generator, see model.AgentSet.AskParallel
+func AskTurtlesDeterministic(turtleList []*model.Turtle, operation func(turtle *model.Turtle, r *rand.Rand), numGoRoutines int) {
+	model.NewTurtleAgentSet(turtleList).AskParallel(model.ParallelSettings{Workers: numGoRoutines}, operation)
+}
@@ -0,0 +1,777 @@
+package loader
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// the binary format is a compact alternative to saving the model as json for big models and frequent checkpoints
+// the agents are written and read one at a time so the whole model is never held in memory as a Model
+//
+//	magic        the 8 bytes "GOAGENTB"
+//	version      uvarint, BinaryVersion
+//	compression  1 byte, the Compression everything after it is written with
+//	header       uvarint length then the json of the model without its agents and what has been drawn, see GetModel
+//	patches      a record for every patch
+//	columns      the values of every patch for each patch column, in the order of the header's PatchColumns
+//	turtles      a record for every turtle in the order they were created
+//	links        a record for every link
+//	segments     a record for every drawing segment
+//	stamps       a record for every drawing stamp
+//
+// every list of records has a 1 byte before each record and a 0 byte after the last one
+// ints are zig-zag varints, floats are 8 bytes little endian and strings are a uvarint length then the bytes
+// breeds, shapes and property names are written out the first time and then referred to by their position, see binaryWriter.name
+// property values and labels start with a byte for their type, see valueNil
+// the values of a float column are 8 bytes per patch, an int column a varint per patch and a bool column a bit per patch
+
+// BinaryVersion is the version of the binary format WriteBinary writes
+const BinaryVersion = 1
+
+const binaryMagic = "GOAGENTB"
+
+// Compression is what the binary format is compressed with
+type Compression byte
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZstd // needs BinarySettings.ZstdWriter or ZstdReader
+)
+
+// BinarySettings holds the settings for writing and reading the binary format
+// zstd isn't in the standard library so its writer and reader are passed in, with github.com/klauspost/compress/zstd for example
+//
+//	ZstdWriter: func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) }
+//	ZstdReader: func(r io.Reader) (io.ReadCloser, error) {
+//		d, err := zstd.NewReader(r)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return d.IOReadCloser(), nil
+//	}
+type BinarySettings struct {
+	Compression Compression                               // compression to write with, reading uses the compression the data was written with
+	ZstdWriter  func(w io.Writer) (io.WriteCloser, error) // compresses with zstd when writing with CompressionZstd
+	ZstdReader  func(r io.Reader) (io.ReadCloser, error)  // decompresses zstd when reading data written with CompressionZstd
+}
+
+// the types of property values
+const (
+	valueNil byte = iota
+	valueFloat
+	valueInt
+	valueString
+	valueFalse
+	valueTrue
+	valueJSON // any other value, read back the way the json format would read it
+)
+
+// link and turtle flags
+const (
+	flagHidden   byte = 1 << 0
+	flagPenDown  byte = 1 << 1
+	flagDirected byte = 1 << 2
+)
+
+// WriteBinary saves the state of the model to w in the binary format, see GetModel for what is saved
+// nothing is written if the compression is unknown or its writer wasn't given
+func WriteBinary(m *model.Model, w io.Writer, settings BinarySettings) error {
+	switch settings.Compression {
+	case CompressionNone, CompressionGzip:
+	case CompressionZstd:
+		if settings.ZstdWriter == nil {
+			return ErrBinaryCompression
+		}
+	default:
+		return ErrBinaryCompression
+	}
+
+	prefix := binary.AppendUvarint([]byte(binaryMagic), BinaryVersion)
+	prefix = append(prefix, byte(settings.Compression))
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+
+	var compressor io.WriteCloser
+	switch settings.Compression {
+	case CompressionGzip:
+		compressor = gzip.NewWriter(w)
+	case CompressionZstd:
+		var err error
+		if compressor, err = settings.ZstdWriter(w); err != nil {
+			return err
+		}
+	}
+
+	var enc *binaryWriter
+	if compressor != nil {
+		enc = newBinaryWriter(compressor)
+	} else {
+		enc = newBinaryWriter(w)
+	}
+
+	header := modelHeader(m)
+	headerJson, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	enc.uvarint(uint64(len(headerJson)))
+	enc.w.Write(headerJson)
+
+	columns := m.PatchColumns()
+	m.Patches.Ask(func(patch *model.Patch) {
+		enc.next()
+		enc.patch(convertPatch(patch, columns))
+	})
+	enc.end()
+
+	for _, column := range header.PatchColumns {
+		enc.column(m, column)
+	}
+
+	m.Turtles().Ask(func(turtle *model.Turtle) {
+		enc.next()
+		enc.turtle(convertTurtle(turtle))
+	})
+	enc.end()
+
+	m.Links().Ask(func(link *model.Link) {
+		if apiLink, ok := convertLink(link); ok {
+			enc.next()
+			enc.link(apiLink)
+		}
+	})
+	enc.end()
+
+	for _, segment := range m.Drawing().Segments() {
+		enc.next()
+		enc.segment(segment)
+	}
+	enc.end()
+
+	for _, stamp := range m.Drawing().Stamps() {
+		enc.next()
+		enc.stamp(stamp)
+	}
+	enc.end()
+
+	if err := enc.flush(); err != nil {
+		return err
+	}
+	if compressor != nil {
+		return compressor.Close()
+	}
+	return nil
+}
+
+// ReadBinary builds a model from data written by WriteBinary
+// returns ErrBinaryFormat if the data isn't in the binary format, is cut short or fails the checksum of its compression
+func ReadBinary(r io.Reader, settings BinarySettings) (*model.Model, error) {
+	src := bufio.NewReader(r)
+
+	magic := make([]byte, len(binaryMagic))
+	if _, err := io.ReadFull(src, magic); err != nil || string(magic) != binaryMagic {
+		return nil, ErrBinaryFormat
+	}
+	version, err := binary.ReadUvarint(src)
+	if err != nil {
+		return nil, ErrBinaryFormat
+	}
+	if version > BinaryVersion {
+		return nil, ErrUnsupportedVersion
+	}
+	compression, err := src.ReadByte()
+	if err != nil {
+		return nil, ErrBinaryFormat
+	}
+
+	var body io.Reader = src
+	switch Compression(compression) {
+	case CompressionNone:
+	case CompressionGzip:
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return nil, ErrBinaryFormat
+		}
+		defer gz.Close()
+		body = gz
+	case CompressionZstd:
+		if settings.ZstdReader == nil {
+			return nil, ErrBinaryCompression
+		}
+		zr, err := settings.ZstdReader(src)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		body = zr
+	default:
+		return nil, ErrBinaryCompression
+	}
+
+	dec := newBinaryReader(body)
+
+	header := &Model{}
+	headerJson := dec.bytes()
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	if err := json.Unmarshal(headerJson, header); err != nil {
+		return nil, ErrBinaryFormat
+	}
+	if err := Migrate(header); err != nil {
+		return nil, err
+	}
+
+	builtModel := buildModel(header)
+
+	for dec.next() {
+		setPatch(builtModel, dec.patch())
+	}
+
+	for _, column := range header.PatchColumns {
+		dec.column(builtModel, column)
+	}
+
+	for dec.next() {
+		createTurtle(builtModel, dec.turtle())
+	}
+	builtModel.SetNextWho(header.NextWho)
+
+	for dec.next() {
+		createLink(builtModel, dec.link())
+	}
+	builtModel.SetNextLinkID(header.NextLinkID)
+
+	for dec.next() {
+		addSegment(builtModel, dec.segment())
+	}
+	for dec.next() {
+		addStamp(builtModel, dec.stamp())
+	}
+
+	if dec.err != nil {
+		return nil, dec.err
+	}
+
+	// the checksum of compressed data is only checked once the end of the stream is read
+	if Compression(compression) != CompressionNone {
+		if _, err := io.Copy(io.Discard, dec.r); err != nil {
+			return nil, ErrBinaryFormat
+		}
+	}
+
+	finishModel(builtModel, header)
+
+	return builtModel, nil
+}
+
+// writes the parts of the binary format
+// bufio.Writer keeps the first error and returns it from flush so the writes don't need to be checked one by one
+type binaryWriter struct {
+	w     *bufio.Writer
+	names map[string]uint64
+	buf   []byte
+	err   error
+}
+
+func newBinaryWriter(w io.Writer) *binaryWriter {
+	return &binaryWriter{
+		w:     bufio.NewWriterSize(w, 64*1024),
+		names: make(map[string]uint64),
+		buf:   make([]byte, 0, binary.MaxVarintLen64),
+	}
+}
+
+func (e *binaryWriter) flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+func (e *binaryWriter) next() {
+	e.w.WriteByte(1)
+}
+
+func (e *binaryWriter) end() {
+	e.w.WriteByte(0)
+}
+
+func (e *binaryWriter) uvarint(v uint64) {
+	e.w.Write(binary.AppendUvarint(e.buf[:0], v))
+}
+
+func (e *binaryWriter) varint(v int) {
+	e.w.Write(binary.AppendVarint(e.buf[:0], int64(v)))
+}
+
+func (e *binaryWriter) float(v float64) {
+	e.w.Write(binary.LittleEndian.AppendUint64(e.buf[:0], math.Float64bits(v)))
+}
+
+func (e *binaryWriter) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.w.WriteString(s)
+}
+
+// strings that repeat a lot are written out once, after that they are written as their position plus one
+func (e *binaryWriter) name(s string) {
+	if position, ok := e.names[s]; ok {
+		e.uvarint(position + 1)
+		return
+	}
+	e.uvarint(0)
+	e.string(s)
+	e.names[s] = uint64(len(e.names))
+}
+
+func (e *binaryWriter) color(c Color) {
+	e.varint(c.Red)
+	e.varint(c.Green)
+	e.varint(c.Blue)
+	e.varint(c.Alpha)
+}
+
+func (e *binaryWriter) value(v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		e.w.WriteByte(valueNil)
+	case float64:
+		e.w.WriteByte(valueFloat)
+		e.float(v)
+	case int:
+		e.w.WriteByte(valueInt)
+		e.varint(v)
+	case string:
+		e.w.WriteByte(valueString)
+		e.string(v)
+	case bool:
+		if v {
+			e.w.WriteByte(valueTrue)
+		} else {
+			e.w.WriteByte(valueFalse)
+		}
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			e.err = err
+			return
+		}
+		e.w.WriteByte(valueJSON)
+		e.string(string(encoded))
+	}
+}
+
+// the properties are written sorted by name so the same model is always written the same way
+func (e *binaryWriter) properties(properties map[string]interface{}) {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	e.uvarint(uint64(len(names)))
+	for _, name := range names {
+		e.name(name)
+		e.value(properties[name])
+	}
+}
+
+func (e *binaryWriter) patch(patch Patch) {
+	e.varint(patch.X)
+	e.varint(patch.Y)
+	e.varint(patch.Z)
+	e.color(patch.Color)
+	e.value(patch.Label)
+	e.color(patch.LabelColor)
+	e.properties(patch.Properties)
+}
+
+// writes the value of every patch in the column in the order of the patch indexes
+func (e *binaryWriter) column(m *model.Model, column PatchColumn) {
+	switch column.Type {
+	case "float":
+		for _, value := range m.FloatColumn(column.Name).Values() {
+			e.float(value)
+		}
+	case "int":
+		for _, value := range m.IntColumn(column.Name).Values() {
+			e.varint(value)
+		}
+	case "bool":
+		values := m.BoolColumn(column.Name).Values()
+		for i := 0; i < len(values); i += 8 {
+			var packed byte
+			for bit := 0; bit < 8 && i+bit < len(values); bit++ {
+				if values[i+bit] {
+					packed |= 1 << bit
+				}
+			}
+			e.w.WriteByte(packed)
+		}
+	}
+}
+
+func (e *binaryWriter) turtle(turtle Turtle) {
+	var flags byte
+	if turtle.Hidden {
+		flags |= flagHidden
+	}
+	if turtle.PenDown {
+		flags |= flagPenDown
+	}
+
+	e.varint(turtle.Who)
+	e.name(turtle.Breed)
+	e.float(turtle.X)
+	e.float(turtle.Y)
+	e.float(turtle.Z)
+	e.float(turtle.Heading)
+	e.float(turtle.Pitch)
+	e.float(turtle.Size)
+	e.color(turtle.Color)
+	e.name(turtle.Shape)
+	e.w.WriteByte(flags)
+	e.float(turtle.PenSize)
+	e.value(turtle.Label)
+	e.color(turtle.LabelColor)
+	e.properties(turtle.Properties)
+}
+
+// the positions of the ends are left out since they come from the turtles
+func (e *binaryWriter) link(link Link) {
+	var flags byte
+	if link.Hidden {
+		flags |= flagHidden
+	}
+	if link.Directed {
+		flags |= flagDirected
+	}
+
+	e.varint(link.ID)
+	e.varint(link.End1)
+	e.varint(link.End2)
+	e.w.WriteByte(flags)
+	e.name(link.Breed)
+	e.color(link.Color)
+	e.value(link.Label)
+	e.color(link.LabelColor)
+	e.varint(link.Size)
+	e.name(link.Shape)
+	e.float(link.Thickness)
+	e.varint(link.TieMode)
+	e.properties(link.Properties)
+}
+
+func (e *binaryWriter) segment(segment model.DrawingSegment) {
+	e.float(segment.X1)
+	e.float(segment.Y1)
+	e.float(segment.Z1)
+	e.float(segment.X2)
+	e.float(segment.Y2)
+	e.float(segment.Z2)
+	e.color(convertColor(segment.Color))
+	e.float(segment.Size)
+}
+
+func (e *binaryWriter) stamp(stamp model.DrawingStamp) {
+	e.float(stamp.X)
+	e.float(stamp.Y)
+	e.float(stamp.Z)
+	e.float(stamp.Heading)
+	e.float(stamp.Size)
+	e.name(stamp.Shape)
+	e.color(convertColor(stamp.Color))
+}
+
+// reads the parts of the binary format
+// the first error is kept and everything read after it is the zero value, so the reads don't need to be checked one by one
+type binaryReader struct {
+	r     *bufio.Reader
+	names []string
+	err   error
+}
+
+// the longest string the reader will allocate, anything longer means the data is corrupt
+const maxBinaryString = 1 << 30
+
+func newBinaryReader(r io.Reader) *binaryReader {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReaderSize(r, 64*1024)
+	}
+	return &binaryReader{r: br}
+}
+
+func (d *binaryReader) fail(err error) {
+	if d.err != nil {
+		return
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = ErrBinaryFormat
+	}
+	d.err = err
+}
+
+func (d *binaryReader) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	b, err := d.r.ReadByte()
+	if err != nil {
+		d.fail(err)
+	}
+	return b
+}
+
+// returns true if there is another record in the list
+func (d *binaryReader) next() bool {
+	return d.byte() == 1 && d.err == nil
+}
+
+func (d *binaryReader) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+func (d *binaryReader) varint() int {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	if err != nil {
+		d.fail(err)
+	}
+	return int(v)
+}
+
+func (d *binaryReader) float() float64 {
+	if d.err != nil {
+		return 0
+	}
+	var buf [8]byte
+	if _, err := io.ReadFull(d.r, buf[:]); err != nil {
+		d.fail(err)
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(buf[:]))
+}
+
+func (d *binaryReader) bytes() []byte {
+	length := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if length > maxBinaryString {
+		d.fail(ErrBinaryFormat)
+		return nil
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		d.fail(err)
+		return nil
+	}
+	return buf
+}
+
+func (d *binaryReader) string() string {
+	return string(d.bytes())
+}
+
+func (d *binaryReader) name() string {
+	position := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if position == 0 {
+		s := d.string()
+		d.names = append(d.names, s)
+		return s
+	}
+	if position > uint64(len(d.names)) {
+		d.fail(ErrBinaryFormat)
+		return ""
+	}
+	return d.names[position-1]
+}
+
+func (d *binaryReader) color() Color {
+	return Color{
+		Red:   d.varint(),
+		Green: d.varint(),
+		Blue:  d.varint(),
+		Alpha: d.varint(),
+	}
+}
+
+func (d *binaryReader) value() interface{} {
+	switch d.byte() {
+	case valueNil:
+		return nil
+	case valueFloat:
+		return d.float()
+	case valueInt:
+		return d.varint()
+	case valueString:
+		return d.string()
+	case valueFalse:
+		return false
+	case valueTrue:
+		return true
+	case valueJSON:
+		var v interface{}
+		if err := json.Unmarshal(d.bytes(), &v); err != nil {
+			d.fail(ErrBinaryFormat)
+		}
+		return v
+	default:
+		d.fail(ErrBinaryFormat)
+		return nil
+	}
+}
+
+func (d *binaryReader) properties() map[string]interface{} {
+	count := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	properties := make(map[string]interface{}, min(count, 64))
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		name := d.name()
+		properties[name] = d.value()
+	}
+	return properties
+}
+
+func (d *binaryReader) patch() Patch {
+	return Patch{
+		X:          d.varint(),
+		Y:          d.varint(),
+		Z:          d.varint(),
+		Color:      d.color(),
+		Label:      d.value(),
+		LabelColor: d.color(),
+		Properties: d.properties(),
+	}
+}
+
+// reads the values of the column straight into the model's column
+func (d *binaryReader) column(m *model.Model, column PatchColumn) {
+	switch column.Type {
+	case "float":
+		if m.FloatColumn(column.Name) == nil {
+			d.fail(ErrBinaryFormat)
+			return
+		}
+		values := m.FloatColumn(column.Name).Values()
+		for i := range values {
+			values[i] = d.float()
+		}
+	case "int":
+		if m.IntColumn(column.Name) == nil {
+			d.fail(ErrBinaryFormat)
+			return
+		}
+		values := m.IntColumn(column.Name).Values()
+		for i := range values {
+			values[i] = d.varint()
+		}
+	case "bool":
+		if m.BoolColumn(column.Name) == nil {
+			d.fail(ErrBinaryFormat)
+			return
+		}
+		values := m.BoolColumn(column.Name).Values()
+		for i := 0; i < len(values); i += 8 {
+			packed := d.byte()
+			for bit := 0; bit < 8 && i+bit < len(values); bit++ {
+				values[i+bit] = packed&(1<<bit) != 0
+			}
+		}
+	}
+}
+
+func (d *binaryReader) turtle() Turtle {
+	turtle := Turtle{
+		Who:     d.varint(),
+		Breed:   d.name(),
+		X:       d.float(),
+		Y:       d.float(),
+		Z:       d.float(),
+		Heading: d.float(),
+		Pitch:   d.float(),
+		Size:    d.float(),
+		Color:   d.color(),
+		Shape:   d.name(),
+	}
+	flags := d.byte()
+	turtle.Hidden = flags&flagHidden != 0
+	turtle.PenDown = flags&flagPenDown != 0
+	turtle.PenSize = d.float()
+	turtle.Label = d.value()
+	turtle.LabelColor = d.color()
+	turtle.Properties = d.properties()
+	return turtle
+}
+
+func (d *binaryReader) link() Link {
+	link := Link{
+		ID:   d.varint(),
+		End1: d.varint(),
+		End2: d.varint(),
+	}
+	flags := d.byte()
+	link.Hidden = flags&flagHidden != 0
+	link.Directed = flags&flagDirected != 0
+	link.Breed = d.name()
+	link.Color = d.color()
+	link.Label = d.value()
+	link.LabelColor = d.color()
+	link.Size = d.varint()
+	link.Shape = d.name()
+	link.Thickness = d.float()
+	link.TieMode = d.varint()
+	link.Properties = d.properties()
+	return link
+}
+
+func (d *binaryReader) segment() DrawingSegment {
+	return DrawingSegment{
+		X1:    d.float(),
+		Y1:    d.float(),
+		Z1:    d.float(),
+		X2:    d.float(),
+		Y2:    d.float(),
+		Z2:    d.float(),
+		Color: d.color(),
+		Size:  d.float(),
+	}
+}
+
+func (d *binaryReader) stamp() DrawingStamp {
+	return DrawingStamp{
+		X:       d.float(),
+		Y:       d.float(),
+		Z:       d.float(),
+		Heading: d.float(),
+		Size:    d.float(),
+		Shape:   d.name(),
+		Color:   d.color(),
+	}
+}
@@ -0,0 +1,148 @@
+package loader
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// column names of an edge list
+const (
+	columnSource   = "source"
+	columnTarget   = "target"
+	columnDirected = "directed"
+)
+
+// ReadEdgeListCSV reads an edge list into the model
+// the first row names the columns, source and target are required and hold the node ids of each edge
+// a breed column sets the link breed and a directed column sets if the link is directed, other columns set link properties
+// a turtle is created for each node id the first time it is seen
+// edges that can't be made into links, like a link that already exists, are skipped and returned as a SkippedEdgesError once the rest is read
+// returns the turtles by the node ids in the file
+func ReadEdgeListCSV(m *model.Model, r io.Reader, settings NetworkImportSettings) (map[string]*model.Turtle, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	source, target, directedColumn := -1, -1, -1
+	for i, name := range header {
+		switch name {
+		case columnSource:
+			source = i
+		case columnTarget:
+			target = i
+		case columnDirected:
+			directedColumn = i
+		}
+	}
+	if source == -1 || target == -1 {
+		return nil, ErrMissingColumn
+	}
+
+	turtles := map[string]*model.Turtle{}
+	turtleFor := func(id string) (*model.Turtle, error) {
+		if turtle, ok := turtles[id]; ok {
+			return turtle, nil
+		}
+		turtle, err := createNodeTurtle(m, map[string]interface{}{}, settings)
+		if err != nil {
+			return nil, err
+		}
+		turtles[id] = turtle
+		return turtle, nil
+	}
+
+	skipped := &SkippedEdgesError{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return turtles, err
+		}
+
+		from, err := turtleFor(row[source])
+		if err != nil {
+			return turtles, err
+		}
+		to, err := turtleFor(row[target])
+		if err != nil {
+			return turtles, err
+		}
+
+		directed := settings.Directed
+		attributes := map[string]interface{}{}
+		for i, value := range row {
+			switch {
+			case i == source || i == target:
+			case i == directedColumn:
+				if value != "" {
+					d, err := strconv.ParseBool(value)
+					if err != nil {
+						return turtles, ErrInvalidDirected
+					}
+					directed = d
+				}
+			case header[i] == attributeBreed:
+				if value != "" {
+					attributes[attributeBreed] = value
+				}
+			case value != "":
+				attributes[header[i]] = guessAttribute(value)
+			}
+		}
+
+		_, err = createEdgeLink(m, from, to, directed, attributes, settings)
+		if err == ErrUnknownLinkBreed {
+			return turtles, err
+		}
+		if err != nil {
+			skipped.add(err)
+		}
+	}
+
+	return turtles, skipped.err()
+}
+
+// WriteEdgeListCSV writes the links of the model as an edge list
+// the columns are source, target, breed, directed and then the link properties by name
+// the source and target are the who numbers of the ends of each link
+func WriteEdgeListCSV(m *model.Model, w io.Writer, settings NetworkExportSettings) error {
+	_, links := exportedAgents(m, settings)
+	attributes := linkAttributeList(links)
+
+	header := []string{columnSource, columnTarget, columnDirected}
+	for _, attribute := range attributes {
+		header = append(header, attribute.name)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		values := linkAttributes(link)
+		row := []string{
+			strconv.Itoa(link.End1().Who()),
+			strconv.Itoa(link.End2().Who()),
+			strconv.FormatBool(link.Directed()),
+		}
+		for _, attribute := range attributes {
+			row = append(row, formatAttribute(values[attribute.name]))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
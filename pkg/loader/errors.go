@@ -0,0 +1,31 @@
+package loader
+
+import "fmt"
+
+// Errors
+var (
+	ErrUnknownTurtleBreed = fmt.Errorf("turtle breed is not in the model")
+	ErrUnknownLinkBreed   = fmt.Errorf("link breed is not in the model")
+	ErrUnknownNode        = fmt.Errorf("edge refers to a node that is not in the network")
+	ErrMissingColumn      = fmt.Errorf("edge list is missing the source or target column")
+	ErrInvalidDirected    = fmt.Errorf("edge has a directed value that is not true or false")
+	ErrUnsupportedVersion = fmt.Errorf("saved model is from a newer version of the format")
+	ErrBinaryFormat       = fmt.Errorf("data is not a model saved in the binary format or is cut short")
+	ErrBinaryCompression  = fmt.Errorf("compression is unknown or its reader or writer wasn't given")
+)
+
+// SkippedEdgesError is what reading a network returns when some edges couldn't be made into links, usually because the link already exists
+// the rest of the network is still read
+type SkippedEdgesError struct {
+	Skipped int   // number of edges that weren't made into links
+	First   error // why the first of them wasn't
+}
+
+func (e *SkippedEdgesError) Error() string {
+	return fmt.Sprintf("%d edges could not be made into links: %v", e.Skipped, e.First)
+}
+
+// returns why the first edge was skipped
+func (e *SkippedEdgesError) Unwrap() error {
+	return e.First
+}
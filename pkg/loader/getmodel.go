@@ -3,17 +3,33 @@ package loader
 import (
 	"encoding/base64"
 	"fmt"
+	"sort"
 
 	"github.com/nlatham1999/go-agent/pkg/model"
 )
 
+// GetModel saves the state of the model in the current version of the format
+// events that only have an operation and the event handlers, tick stages, scheduler and hooks are code so they aren't saved
 func GetModel(model *model.Model) *Model {
+	modelJson := modelHeader(model)
+	modelJson.Patches = convertPatchSet(model.Patches, model.PatchColumns())
+	modelJson.Turtles = convertTurtleSet(model.Turtles())
+	modelJson.Links = convertLinkSet(model.Links())
+	modelJson.Drawing = convertDrawing(model.Drawing())
+
+	return modelJson
+}
+
+// saves everything about the model except its agents and what has been drawn
+func modelHeader(model *model.Model) *Model {
 	modelJson := Model{
+		Version:              CurrentVersion,
 		TurtleBreeds:         convertTurtleBreeds(model),
 		DirectedLinkBreeds:   convertLinkBreeds(model.DirectedLinkBreeds()),
 		UndirectedLinkBreeds: convertLinkBreeds(model.UndirectedLinkBreeds()),
 		PatchProperties:      model.DefaultPatchProperties,
 		TurtleProperties:     model.TurtleBreed("").DefaultProperties(),
+		LinkProperties:       model.UndirectedLinkBreed("").DefaultProperties(),
 		WrappingX:            model.WrappingX(),
 		WrappingY:            model.WrappingY(),
 		WorldWidth:           model.WorldWidth(),
@@ -22,10 +38,18 @@ func GetModel(model *model.Model) *Model {
 		MaxPxCor:             model.MaxPxCor(),
 		MinPyCor:             model.MinPyCor(),
 		MaxPyCor:             model.MaxPyCor(),
-		Patches:              convertPatchSet(model.Patches),
-		Turtles:              convertTurtleSet(model.Turtles()),
-		Links:                convertLinkSet(model.Links()),
+		MinPzCor:             model.MinPzCor(),
+		MaxPzCor:             model.MaxPzCor(),
+		DefaultShapeTurtles:  model.DefaultShapeTurtles,
+		DefaultShapeLinks:    model.DefaultShapeLinks,
+		NextWho:              model.NextWho(),
+		NextLinkID:           model.NextLinkID(),
+		PatchColumns:         convertPatchColumns(model.PatchColumns()),
 		Ticks:                model.Ticks,
+		Clock:                model.Clock(),
+		Events:               convertEvents(model.Events()),
+		Drawing:              Drawing{MaxSegments: model.Drawing().MaxSegments()},
+		SpatialIndexCellSize: model.SpatialIndexCellSize(),
 	}
 
 	seed1, seed2, state := model.GetRandomState()
@@ -36,19 +60,64 @@ func GetModel(model *model.Model) *Model {
 	return &modelJson
 }
 
-func convertPatchSet(patches *model.PatchAgentSet) []Patch {
+func convertPatchColumns(columns map[string]interface{}) []PatchColumn {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	arr := []PatchColumn{}
+	for _, name := range names {
+		column := PatchColumn{
+			Name:    name,
+			Default: columns[name],
+		}
+		switch columns[name].(type) {
+		case float64:
+			column.Type = "float"
+		case int:
+			column.Type = "int"
+		case bool:
+			column.Type = "bool"
+		}
+		arr = append(arr, column)
+	}
+	return arr
+}
+
+func convertPatchSet(patches *model.PatchAgentSet, columns map[string]interface{}) []Patch {
 	apiPatches := make([]Patch, 0, patches.Count())
 	patches.Ask(func(patch *model.Patch) {
-		apiPatch := Patch{
-			X:     patch.XCor(),
-			Y:     patch.YCor(),
-			Color: convertColor(patch.Color),
+		apiPatch := convertPatch(patch, columns)
+		if len(columns) > 0 {
+			apiPatch.Columns = make(map[string]interface{})
+			for name := range columns {
+				apiPatch.Columns[name] = patch.GetProperty(name)
+			}
 		}
 		apiPatches = append(apiPatches, apiPatch)
 	})
 	return apiPatches
 }
 
+// the column values are left out of the properties so they can be saved separately and keep their type
+func convertPatch(patch *model.Patch, columns map[string]interface{}) Patch {
+	apiPatch := Patch{
+		X:          patch.XCor(),
+		Y:          patch.YCor(),
+		Z:          patch.ZCor(),
+		Color:      convertColor(patch.Color),
+		Label:      patch.Label,
+		LabelColor: convertColor(patch.PlabelColor),
+		Properties: patch.Properties(),
+	}
+	for name := range columns {
+		delete(apiPatch.Properties, name)
+	}
+	return apiPatch
+}
+
 func convertColor(color model.Color) Color {
 	apiColor := Color{
 		Red:   color.Red,
@@ -62,50 +131,72 @@ func convertColor(color model.Color) Color {
 func convertTurtleSet(turtles *model.TurtleAgentSet) []Turtle {
 	apiTurtles := make([]Turtle, 0, turtles.Count())
 	turtles.Ask(func(turtle *model.Turtle) {
-		apiTurtle := Turtle{
-			X:          turtle.XCor(),
-			Y:          turtle.YCor(),
-			Color:      convertColor(turtle.Color),
-			Size:       turtle.GetSize(),
-			Who:        turtle.Who(),
-			Shape:      turtle.Shape,
-			Heading:    turtle.GetHeading(),
-			Label:      turtle.GetLabel(),
-			LabelColor: convertColor(turtle.LabelColor),
-			Breed:      turtle.BreedName(),
-		}
-		apiTurtles = append(apiTurtles, apiTurtle)
+		apiTurtles = append(apiTurtles, convertTurtle(turtle))
 	})
 	return apiTurtles
 }
 
+func convertTurtle(turtle *model.Turtle) Turtle {
+	return Turtle{
+		X:          turtle.XCor(),
+		Y:          turtle.YCor(),
+		Z:          turtle.ZCor(),
+		Color:      convertColor(turtle.Color),
+		Size:       turtle.GetSize(),
+		Who:        turtle.Who(),
+		Shape:      turtle.Shape,
+		Heading:    turtle.GetHeading(),
+		Pitch:      turtle.GetPitch(),
+		Hidden:     turtle.Hidden,
+		Label:      turtle.GetLabel(),
+		LabelColor: convertColor(turtle.LabelColor),
+		Properties: turtle.Properties(),
+		Breed:      turtle.BreedName(),
+		PenDown:    turtle.IsPenDown(),
+		PenSize:    turtle.GetPenSize(),
+	}
+}
+
 func convertLinkSet(links *model.LinkAgentSet) []Link {
 	apiLinks := make([]Link, 0, links.Count())
 	links.Ask(func(link *model.Link) {
-		if link.End1() == nil || link.End2() == nil {
+		apiLink, ok := convertLink(link)
+		if !ok {
 			fmt.Println("Link has nil ends")
 			return
 		}
-		apiLink := Link{
-			End1:       link.End1().Who(),
-			End2:       link.End2().Who(),
-			Directed:   link.Directed(),
-			End1X:      link.End1().XCor(),
-			End1Y:      link.End1().YCor(),
-			End2X:      link.End2().XCor(),
-			End2Y:      link.End2().YCor(),
-			Color:      convertColor(link.Color),
-			Label:      link.Label,
-			LabelColor: convertColor(link.LabelColor),
-			Size:       link.Size,
-			Hidden:     link.IsHidden(),
-			Breed:      link.BreedName(),
-		}
 		apiLinks = append(apiLinks, apiLink)
 	})
 	return apiLinks
 }
 
+// returns false if the link is missing one of its ends
+func convertLink(link *model.Link) (Link, bool) {
+	if link.End1() == nil || link.End2() == nil {
+		return Link{}, false
+	}
+	return Link{
+		ID:         link.ID(),
+		End1:       link.End1().Who(),
+		End2:       link.End2().Who(),
+		Directed:   link.Directed(),
+		End1X:      link.End1().XCor(),
+		End1Y:      link.End1().YCor(),
+		End2X:      link.End2().XCor(),
+		End2Y:      link.End2().YCor(),
+		Color:      convertColor(link.Color),
+		Label:      link.Label,
+		LabelColor: convertColor(link.LabelColor),
+		Size:       link.Size,
+		Shape:      link.Shape,
+		Thickness:  link.Thickness,
+		Hidden:     link.IsHidden(),
+		Breed:      link.BreedName(),
+		TieMode:    int(link.TieMode()),
+		Properties: link.Properties(),
+	}, true
+}
+
 func convertTurtleBreeds(model *model.Model) []TurtleBreed {
 
 	arr := []TurtleBreed{}
@@ -133,6 +224,7 @@ func convertLinkBreeds(modelLinks []*model.LinkBreed) []LinkBreed {
 		if breed.Name() != "" {
 			b := LinkBreed{
 				Name:         breed.Name(),
+				Properties:   breed.DefaultProperties(),
 				DefaultShape: breed.GetDefaultShape(),
 				Directed:     breed.Directed(),
 			}
@@ -142,3 +234,81 @@ func convertLinkBreeds(modelLinks []*model.LinkBreed) []LinkBreed {
 
 	return arr
 }
+
+// events without a name can't be restored so they are left out
+func convertEvents(events []*model.Event) []Event {
+	arr := []Event{}
+
+	for _, event := range events {
+		if event.Name() == "" {
+			continue
+		}
+
+		e := Event{
+			Name:     event.Name(),
+			Time:     event.Time(),
+			Interval: event.Interval(),
+		}
+
+		if t := event.Turtle(); t != nil {
+			who := t.Who()
+			e.Turtle = &who
+		}
+
+		if p := event.Patch(); p != nil {
+			e.Patch = &EventPatch{
+				X: p.XCor(),
+				Y: p.YCor(),
+				Z: p.ZCor(),
+			}
+		}
+
+		if l := event.Link(); l != nil {
+			e.Link = &EventLink{
+				End1:     l.End1().Who(),
+				End2:     l.End2().Who(),
+				Directed: l.Directed(),
+				Breed:    l.BreedName(),
+			}
+		}
+
+		arr = append(arr, e)
+	}
+
+	return arr
+}
+
+func convertDrawing(drawing *model.Drawing) Drawing {
+	arr := Drawing{
+		MaxSegments: drawing.MaxSegments(),
+		Segments:    []DrawingSegment{},
+		Stamps:      []DrawingStamp{},
+	}
+
+	for _, segment := range drawing.Segments() {
+		arr.Segments = append(arr.Segments, DrawingSegment{
+			X1:    segment.X1,
+			Y1:    segment.Y1,
+			Z1:    segment.Z1,
+			X2:    segment.X2,
+			Y2:    segment.Y2,
+			Z2:    segment.Z2,
+			Color: convertColor(segment.Color),
+			Size:  segment.Size,
+		})
+	}
+
+	for _, stamp := range drawing.Stamps() {
+		arr.Stamps = append(arr.Stamps, DrawingStamp{
+			X:       stamp.X,
+			Y:       stamp.Y,
+			Z:       stamp.Z,
+			Heading: stamp.Heading,
+			Size:    stamp.Size,
+			Shape:   stamp.Shape,
+			Color:   convertColor(stamp.Color),
+		})
+	}
+
+	return arr
+}
@@ -0,0 +1,362 @@
+package loader
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+const (
+	gexfNamespace    = "http://gexf.net/1.3"
+	gexfVizNamespace = "http://gexf.net/1.3/viz"
+)
+
+type gexf struct {
+	XMLName xml.Name  `xml:"gexf"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Viz     string    `xml:"xmlns:viz,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	Mode            string           `xml:"mode,attr"`
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	TimeFormat      string           `xml:"timeformat,attr,omitempty"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Mode       string          `xml:"mode,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues *gexfAttValues `xml:"attvalues,omitempty"`
+	Spells    *gexfSpells    `xml:"spells,omitempty"`
+	Position  *gexfPosition  `xml:"viz:position,omitempty"`
+}
+
+type gexfEdge struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Type      string         `xml:"type,attr"`
+	AttValues *gexfAttValues `xml:"attvalues,omitempty"`
+	Spells    *gexfSpells    `xml:"spells,omitempty"`
+}
+
+type gexfAttValues struct {
+	Values []gexfAttValue `xml:"attvalue"`
+}
+
+type gexfSpells struct {
+	Spells []gexfSpell `xml:"spell"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+	Start string `xml:"start,attr,omitempty"`
+	End   string `xml:"end,attr,omitempty"`
+}
+
+type gexfSpell struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr,omitempty"`
+}
+
+type gexfPosition struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+	Z float64 `xml:"z,attr"`
+}
+
+// a span of recordings something was present or had a value
+// last is the recording it was last seen in so gaps start a new span
+type gexfSpan struct {
+	value string
+	start float64
+	end   float64
+	last  int
+}
+
+// everything recorded about a turtle or link
+type gexfHistory struct {
+	id         string
+	label      string
+	source     string
+	target     string
+	directed   bool
+	position   gexfPosition
+	spells     []gexfSpan
+	attributes map[string][]gexfSpan
+}
+
+// marks the history as seen in the recording, extending the last span if it was seen in the recording before
+func extendSpans(spans []gexfSpan, value string, time float64, recording int) []gexfSpan {
+	if len(spans) > 0 {
+		last := &spans[len(spans)-1]
+		if last.last == recording-1 && last.value == value {
+			last.end = time
+			last.last = recording
+			return spans
+		}
+	}
+	return append(spans, gexfSpan{value: value, start: time, end: time, last: recording})
+}
+
+func (h *gexfHistory) record(attributes map[string]interface{}, time float64, recording int) {
+	h.spells = extendSpans(h.spells, "", time, recording)
+	for name, value := range attributes {
+		h.attributes[name] = extendSpans(h.attributes[name], formatAttribute(value), time, recording)
+	}
+}
+
+// GEXFRecorder records the turtles and links of a model over ticks and writes them as a dynamic GEXF network
+// call Record every tick, turtles and links are written with the spans of ticks they were alive for
+// and their attributes with the values they had over time
+type GEXFRecorder struct {
+	settings NetworkExportSettings
+
+	recordings int
+
+	nodes    []*gexfHistory
+	nodeByID map[int]*gexfHistory
+	edges    []*gexfHistory
+	edgeByID map[*model.Link]*gexfHistory
+
+	nodeAttributes []networkAttribute
+	edgeAttributes []networkAttribute
+	attributeSeen  map[string]bool
+}
+
+// NewGEXFRecorder creates a recorder for the turtles and links picked by the settings
+// the settings are applied each recording, so turtles added to the model later are recorded when the Turtles setting is nil
+func NewGEXFRecorder(settings NetworkExportSettings) *GEXFRecorder {
+	return &GEXFRecorder{
+		settings:      settings,
+		nodeByID:      map[int]*gexfHistory{},
+		edgeByID:      map[*model.Link]*gexfHistory{},
+		attributeSeen: map[string]bool{},
+	}
+}
+
+// Record records the turtles and links of the model at the current tick
+func (r *GEXFRecorder) Record(m *model.Model) {
+	r.RecordAt(m, float64(m.Ticks))
+}
+
+// RecordAt records the turtles and links of the model at the time passed in, for models that use the clock instead of ticks
+func (r *GEXFRecorder) RecordAt(m *model.Model, time float64) {
+	turtles, links := exportedAgents(m, r.settings)
+	recording := r.recordings
+	r.recordings++
+
+	for _, attribute := range turtleAttributeList(m, turtles) {
+		if key := "node:" + attribute.name; !r.attributeSeen[key] {
+			r.attributeSeen[key] = true
+			r.nodeAttributes = append(r.nodeAttributes, attribute)
+		}
+	}
+	for _, attribute := range linkAttributeList(links) {
+		if key := "edge:" + attribute.name; !r.attributeSeen[key] {
+			r.attributeSeen[key] = true
+			r.edgeAttributes = append(r.edgeAttributes, attribute)
+		}
+	}
+
+	for _, turtle := range turtles {
+		node, ok := r.nodeByID[turtle.Who()]
+		if !ok {
+			node = &gexfHistory{
+				id:         strconv.Itoa(turtle.Who()),
+				attributes: map[string][]gexfSpan{},
+			}
+			r.nodeByID[turtle.Who()] = node
+			r.nodes = append(r.nodes, node)
+		}
+
+		attributes := turtleAttributes(m, turtle)
+		node.label = node.id
+		if label, ok := attributes[attributeLabel].(string); ok && label != "" {
+			node.label = label
+		}
+		delete(attributes, attributeLabel)
+		node.position = gexfPosition{X: turtle.XCor(), Y: turtle.YCor(), Z: turtle.ZCor()}
+		node.record(attributes, time, recording)
+	}
+
+	for _, link := range links {
+		edge, ok := r.edgeByID[link]
+		if !ok {
+			edge = &gexfHistory{
+				id:         strconv.Itoa(len(r.edges)),
+				source:     strconv.Itoa(link.End1().Who()),
+				target:     strconv.Itoa(link.End2().Who()),
+				directed:   link.Directed(),
+				attributes: map[string][]gexfSpan{},
+			}
+			r.edgeByID[link] = edge
+			r.edges = append(r.edges, edge)
+		}
+		edge.record(linkAttributes(link), time, recording)
+	}
+}
+
+// Write writes everything recorded as a GEXF network
+// with a single recording a static network is written, otherwise a dynamic one with the spans of each turtle, link and attribute value
+func (r *GEXFRecorder) Write(w io.Writer) error {
+	dynamic := r.recordings > 1
+
+	document := gexf{
+		Xmlns:   gexfNamespace,
+		Viz:     gexfVizNamespace,
+		Version: "1.3",
+		Graph: gexfGraph{
+			Mode:            "static",
+			DefaultEdgeType: "undirected",
+		},
+	}
+	if dynamic {
+		document.Graph.Mode = "dynamic"
+		document.Graph.TimeFormat = "double"
+	}
+
+	nodeIDs := gexfAttributeList(&document, "node", r.nodeAttributes, dynamic)
+	edgeIDs := gexfAttributeList(&document, "edge", r.edgeAttributes, dynamic)
+
+	for _, history := range r.nodes {
+		position := history.position
+		node := gexfNode{
+			ID:        history.id,
+			Label:     history.label,
+			AttValues: r.attValues(history, r.nodeAttributes, nodeIDs, dynamic),
+			Position:  &position,
+		}
+		if dynamic {
+			node.Spells = r.spells(history.spells)
+		}
+		document.Graph.Nodes = append(document.Graph.Nodes, node)
+	}
+
+	for _, history := range r.edges {
+		edge := gexfEdge{
+			ID:        history.id,
+			Source:    history.source,
+			Target:    history.target,
+			Type:      "undirected",
+			AttValues: r.attValues(history, r.edgeAttributes, edgeIDs, dynamic),
+		}
+		if history.directed {
+			edge.Type = "directed"
+		}
+		if dynamic {
+			edge.Spells = r.spells(history.spells)
+		}
+		document.Graph.Edges = append(document.Graph.Edges, edge)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// adds the attribute declarations of the class to the document and returns their ids by name
+func gexfAttributeList(document *gexf, class string, attributes []networkAttribute, dynamic bool) map[string]string {
+	declaration := gexfAttributes{Class: class, Mode: "static"}
+	if dynamic {
+		declaration.Mode = "dynamic"
+	}
+
+	ids := map[string]string{}
+	for _, attribute := range attributes {
+		// the label is written on the node itself
+		if attribute.name == attributeLabel && class == "node" {
+			continue
+		}
+		id := strconv.Itoa(len(ids))
+		ids[attribute.name] = id
+		attributeType := attributeType(attribute.value)
+		if attributeType == "int" {
+			attributeType = "integer"
+		}
+		declaration.Attributes = append(declaration.Attributes, gexfAttribute{ID: id, Title: attribute.name, Type: attributeType})
+	}
+
+	document.Graph.Attributes = append(document.Graph.Attributes, declaration)
+	return ids
+}
+
+// returns the attribute values of a turtle or link in the order of the attributes
+func (r *GEXFRecorder) attValues(history *gexfHistory, attributes []networkAttribute, ids map[string]string, dynamic bool) *gexfAttValues {
+	values := []gexfAttValue{}
+	for _, attribute := range attributes {
+		id, ok := ids[attribute.name]
+		if !ok {
+			continue
+		}
+		spans := history.attributes[attribute.name]
+		if !dynamic {
+			if len(spans) > 0 {
+				values = append(values, gexfAttValue{For: id, Value: spans[len(spans)-1].value})
+			}
+			continue
+		}
+		for _, span := range spans {
+			start, end := r.spanBounds(span)
+			values = append(values, gexfAttValue{For: id, Value: span.value, Start: start, End: end})
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return &gexfAttValues{Values: values}
+}
+
+// returns the spells a turtle or link was present for
+func (r *GEXFRecorder) spells(spans []gexfSpan) *gexfSpells {
+	spells := make([]gexfSpell, 0, len(spans))
+	for _, span := range spans {
+		start, end := r.spanBounds(span)
+		spells = append(spells, gexfSpell{Start: start, End: end})
+	}
+	return &gexfSpells{Spells: spells}
+}
+
+// returns the start and end of the span, spans still going at the last recording are left open
+func (r *GEXFRecorder) spanBounds(span gexfSpan) (string, string) {
+	start := strconv.FormatFloat(span.start, 'g', -1, 64)
+	if span.last == r.recordings-1 {
+		return start, ""
+	}
+	return start, strconv.FormatFloat(span.end, 'g', -1, 64)
+}
+
+// WriteGEXF writes the turtles and links of the model as a static GEXF network
+// use a GEXFRecorder to write how the network changes over ticks
+func WriteGEXF(m *model.Model, w io.Writer, settings NetworkExportSettings) error {
+	recorder := NewGEXFRecorder(settings)
+	recorder.Record(m)
+	return recorder.Write(w)
+}
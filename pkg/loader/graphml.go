@@ -0,0 +1,213 @@
+package loader
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr,omitempty"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID      string  `xml:"id,attr"`
+	For     string  `xml:"for,attr"`
+	Name    string  `xml:"attr.name,attr"`
+	Type    string  `xml:"attr.type,attr"`
+	Default *string `xml:"default"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr,omitempty"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID       string        `xml:"id,attr,omitempty"`
+	Source   string        `xml:"source,attr"`
+	Target   string        `xml:"target,attr"`
+	Directed string        `xml:"directed,attr,omitempty"`
+	Data     []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ReadGraphML reads a GraphML network into the model
+// every node becomes a turtle and every edge a link, nodes are created in the order they are in the file
+// the breed, x, y, z and label node attributes set the turtle's breed, position and label, other attributes set its properties
+// the breed edge attribute sets the link's breed, other attributes set its properties
+// edges that can't be made into links, like a link that already exists, are skipped and returned as a SkippedEdgesError once the rest is read
+// returns the turtles by the node ids in the file
+func ReadGraphML(m *model.Model, r io.Reader, settings NetworkImportSettings) (map[string]*model.Turtle, error) {
+	var document graphML
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	nodeKeys := map[string]graphMLKey{}
+	edgeKeys := map[string]graphMLKey{}
+	for _, key := range document.Keys {
+		if key.Name == "" {
+			key.Name = key.ID
+		}
+		switch key.For {
+		case "node":
+			nodeKeys[key.ID] = key
+		case "edge":
+			edgeKeys[key.ID] = key
+		case "all", "":
+			nodeKeys[key.ID] = key
+			edgeKeys[key.ID] = key
+		}
+	}
+
+	turtles := make(map[string]*model.Turtle, len(document.Graph.Nodes))
+	for _, node := range document.Graph.Nodes {
+		turtle, err := createNodeTurtle(m, graphMLAttributes(node.Data, nodeKeys), settings)
+		if err != nil {
+			return turtles, err
+		}
+		turtles[node.ID] = turtle
+	}
+
+	defaultDirected := settings.Directed
+	switch document.Graph.EdgeDefault {
+	case "directed":
+		defaultDirected = true
+	case "undirected":
+		defaultDirected = false
+	}
+
+	skipped := &SkippedEdgesError{}
+	for _, edge := range document.Graph.Edges {
+		source, ok1 := turtles[edge.Source]
+		target, ok2 := turtles[edge.Target]
+		if !ok1 || !ok2 {
+			return turtles, ErrUnknownNode
+		}
+
+		directed := defaultDirected
+		if edge.Directed != "" {
+			d, err := strconv.ParseBool(edge.Directed)
+			if err != nil {
+				return turtles, ErrInvalidDirected
+			}
+			directed = d
+		}
+
+		_, err := createEdgeLink(m, source, target, directed, graphMLAttributes(edge.Data, edgeKeys), settings)
+		if err == ErrUnknownLinkBreed {
+			return turtles, err
+		}
+		if err != nil {
+			skipped.add(err)
+		}
+	}
+
+	return turtles, skipped.err()
+}
+
+// returns the attributes of a node or edge by name, including the defaults of the keys
+func graphMLAttributes(data []graphMLData, keys map[string]graphMLKey) map[string]interface{} {
+	attributes := map[string]interface{}{}
+	for _, key := range keys {
+		if key.Default != nil {
+			attributes[key.Name] = parseAttribute(*key.Default, key.Type)
+		}
+	}
+	for _, d := range data {
+		key, ok := keys[d.Key]
+		if !ok {
+			continue
+		}
+		attributes[key.Name] = parseAttribute(d.Value, key.Type)
+	}
+	return attributes
+}
+
+// WriteGraphML writes the turtles and links of the model as a GraphML network
+// turtles are written as nodes with their who number as the id, links as edges marked directed or not
+// the breed, position and label of each turtle and the breed of each link are written along with their properties
+func WriteGraphML(m *model.Model, w io.Writer, settings NetworkExportSettings) error {
+	turtles, links := exportedAgents(m, settings)
+
+	document := graphML{
+		Xmlns: graphMLNamespace,
+		Graph: graphMLGraph{
+			ID:          "G",
+			EdgeDefault: "undirected",
+			Nodes:       make([]graphMLNode, 0, len(turtles)),
+			Edges:       make([]graphMLEdge, 0, len(links)),
+		},
+	}
+
+	for i, attribute := range turtleAttributeList(m, turtles) {
+		document.Keys = append(document.Keys, graphMLKey{ID: fmt.Sprintf("n%d", i), For: "node", Name: attribute.name, Type: attributeType(attribute.value)})
+	}
+	for i, attribute := range linkAttributeList(links) {
+		document.Keys = append(document.Keys, graphMLKey{ID: fmt.Sprintf("e%d", i), For: "edge", Name: attribute.name, Type: attributeType(attribute.value)})
+	}
+
+	for _, turtle := range turtles {
+		node := graphMLNode{ID: strconv.Itoa(turtle.Who())}
+		node.Data = graphMLDataList(turtleAttributes(m, turtle), document.Keys, "node")
+		document.Graph.Nodes = append(document.Graph.Nodes, node)
+	}
+
+	for i, link := range links {
+		edge := graphMLEdge{
+			ID:       strconv.Itoa(i),
+			Source:   strconv.Itoa(link.End1().Who()),
+			Target:   strconv.Itoa(link.End2().Who()),
+			Directed: strconv.FormatBool(link.Directed()),
+		}
+		edge.Data = graphMLDataList(linkAttributes(link), document.Keys, "edge")
+		document.Graph.Edges = append(document.Graph.Edges, edge)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// returns the data of an agent in the order of the keys
+func graphMLDataList(attributes map[string]interface{}, keys []graphMLKey, keyFor string) []graphMLData {
+	data := []graphMLData{}
+	for _, key := range keys {
+		if key.For != keyFor {
+			continue
+		}
+		value, ok := attributes[key.Name]
+		if !ok {
+			continue
+		}
+		data = append(data, graphMLData{Key: key.ID, Value: formatAttribute(value)})
+	}
+	return data
+}
@@ -0,0 +1,45 @@
+package loader
+
+// migrations upgrade a saved model from the version they are keyed by to the next version
+var migrations = map[int]func(modelJson *Model){
+	1: migrateVersion1,
+}
+
+// Migrate upgrades a saved model from an older version of the format to CurrentVersion
+// saves from before the format had a version are treated as version 1
+// returns ErrUnsupportedVersion and leaves the model alone if it was saved by a newer version of the format
+func Migrate(modelJson *Model) error {
+	if modelJson.Version > CurrentVersion {
+		return ErrUnsupportedVersion
+	}
+
+	if modelJson.Version < 1 {
+		modelJson.Version = 1
+	}
+
+	for modelJson.Version < CurrentVersion {
+		migrations[modelJson.Version](modelJson)
+		modelJson.Version++
+	}
+
+	return nil
+}
+
+// version 1 didn't save the next who number or the link ids
+// the turtles and links were created in the order they were saved so the ids go in that order
+func migrateVersion1(modelJson *Model) {
+	nextWho := 0
+	for _, turtle := range modelJson.Turtles {
+		nextWho = max(nextWho, turtle.Who+1)
+	}
+	modelJson.NextWho = nextWho
+
+	// a new slice so the links of the model being migrated from aren't changed
+	links := make([]Link, len(modelJson.Links))
+	copy(links, modelJson.Links)
+	for i := range links {
+		links[i].ID = i
+	}
+	modelJson.Links = links
+	modelJson.NextLinkID = len(links)
+}
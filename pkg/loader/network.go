@@ -0,0 +1,305 @@
+package loader
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// attributes of nodes and edges that are read into and written from the turtle and link themselves instead of their properties
+const (
+	attributeBreed = "breed"
+	attributeX     = "x"
+	attributeY     = "y"
+	attributeZ     = "z"
+	attributeLabel = "label"
+)
+
+// NetworkImportSettings holds the settings for reading a network into a model
+// nodes become turtles and edges become links, attributes named like turtle or link properties are set on them
+// attributes that aren't properties of the turtle or link are left out
+type NetworkImportSettings struct {
+	TurtleBreed string // breed of nodes without a breed attribute, empty means the general breed
+	LinkBreed   string // breed of edges without a breed attribute, empty means the general breed
+	Directed    bool   // if edges are directed when the file doesn't say
+}
+
+// NetworkExportSettings holds the settings for writing the turtles and links of a model as a network
+type NetworkExportSettings struct {
+	Turtles *model.TurtleAgentSet // turtles to write, nil means every turtle
+	Links   *model.LinkAgentSet   // links to write, nil means every link. Links whose ends aren't both written are left out
+}
+
+// returns the turtles and links to write
+func exportedAgents(m *model.Model, settings NetworkExportSettings) ([]*model.Turtle, []*model.Link) {
+	turtles := settings.Turtles
+	if turtles == nil {
+		turtles = m.Turtles()
+	}
+	links := settings.Links
+	if links == nil {
+		links = m.Links()
+	}
+
+	turtleList := turtles.List()
+	exported := make(map[*model.Turtle]bool, len(turtleList))
+	for _, turtle := range turtleList {
+		exported[turtle] = true
+	}
+
+	linkList := []*model.Link{}
+	links.Ask(func(link *model.Link) {
+		if exported[link.End1()] && exported[link.End2()] {
+			linkList = append(linkList, link)
+		}
+	})
+
+	return turtleList, linkList
+}
+
+// a named attribute and the type of its values
+type networkAttribute struct {
+	name  string
+	value interface{} // first value seen, decides the type
+}
+
+// returns the property names of the agents with the first value seen for each, sorted by name
+// properties with the same name as a built in attribute are left out
+func propertyAttributes(properties []map[string]interface{}) []networkAttribute {
+	seen := map[string]interface{}{}
+	for _, agentProperties := range properties {
+		for name, value := range agentProperties {
+			if _, ok := seen[name]; !ok || seen[name] == nil {
+				seen[name] = value
+			}
+		}
+	}
+
+	attributes := []networkAttribute{}
+	for name, value := range seen {
+		switch name {
+		case attributeBreed, attributeX, attributeY, attributeZ, attributeLabel:
+			continue
+		}
+		attributes = append(attributes, networkAttribute{name: name, value: value})
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].name < attributes[j].name
+	})
+	return attributes
+}
+
+// returns the graphml type of a value, gexf uses the same names except for integer
+func attributeType(value interface{}) string {
+	switch value.(type) {
+	case float64, float32:
+		return "double"
+	case int, int64, int32:
+		return "int"
+	case bool:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// formats a value so it can be read back with parseAttribute
+func formatAttribute(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// reads a value of the type, values that don't match their type are kept as strings
+func parseAttribute(value string, attributeType string) interface{} {
+	switch attributeType {
+	case "int", "long", "integer":
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	case "float", "double":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// reads a value without a declared type, trying int, float and bool before falling back to a string
+func guessAttribute(value string) interface{} {
+	if i, err := strconv.Atoi(value); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b
+	}
+	return value
+}
+
+// creates a turtle for a node and sets it from the attributes
+func createNodeTurtle(m *model.Model, attributes map[string]interface{}, settings NetworkImportSettings) (*model.Turtle, error) {
+	breedName := settings.TurtleBreed
+	if breed, ok := attributes[attributeBreed]; ok {
+		breedName = fmt.Sprint(breed)
+	}
+	breed := m.TurtleBreed(breedName)
+	if breed == nil {
+		return nil, ErrUnknownTurtleBreed
+	}
+
+	turtles, err := breed.CreateAgents(1, nil)
+	if err != nil {
+		return nil, err
+	}
+	turtle, err := turtles.First()
+	if err != nil {
+		return nil, err
+	}
+
+	x, hasX := numberAttribute(attributes, attributeX)
+	y, hasY := numberAttribute(attributes, attributeY)
+	z, hasZ := numberAttribute(attributes, attributeZ)
+	if hasZ && m.Is3D() {
+		turtle.SetXYZ(x, y, z)
+	} else if hasX || hasY {
+		turtle.SetXY(x, y)
+	}
+
+	if label, ok := attributes[attributeLabel]; ok {
+		turtle.SetLabel(label)
+	}
+
+	for name, value := range attributes {
+		switch name {
+		case attributeBreed, attributeX, attributeY, attributeZ, attributeLabel:
+			continue
+		}
+		turtle.SetProperty(name, value)
+	}
+
+	return turtle, nil
+}
+
+// creates a link for an edge and sets its properties from the attributes
+func createEdgeLink(m *model.Model, source *model.Turtle, target *model.Turtle, directed bool, attributes map[string]interface{}, settings NetworkImportSettings) (*model.Link, error) {
+	breedName := settings.LinkBreed
+	if breed, ok := attributes[attributeBreed]; ok {
+		breedName = fmt.Sprint(breed)
+	}
+
+	operation := func(link *model.Link) {
+		for name, value := range attributes {
+			if name == attributeBreed {
+				continue
+			}
+			link.SetProperty(name, value)
+		}
+	}
+
+	if directed {
+		breed := m.DirectedLinkBreed(breedName)
+		if breed == nil {
+			return nil, ErrUnknownLinkBreed
+		}
+		return source.CreateLinkToTurtle(breed, target, operation)
+	}
+
+	breed := m.UndirectedLinkBreed(breedName)
+	if breed == nil {
+		return nil, ErrUnknownLinkBreed
+	}
+	return source.CreateLinkWithTurtle(breed, target, operation)
+}
+
+// counts an edge that couldn't be made into a link
+func (e *SkippedEdgesError) add(err error) {
+	if e.Skipped == 0 {
+		e.First = err
+	}
+	e.Skipped++
+}
+
+// returns the skipped edges as an error, nil if none were skipped
+func (e *SkippedEdgesError) err() error {
+	if e.Skipped == 0 {
+		return nil
+	}
+	return e
+}
+
+func numberAttribute(attributes map[string]interface{}, name string) (float64, bool) {
+	switch v := attributes[name].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// returns the built in attributes of a turtle followed by its properties
+func turtleAttributes(m *model.Model, turtle *model.Turtle) map[string]interface{} {
+	attributes := turtle.Properties()
+	attributes[attributeBreed] = turtle.BreedName()
+	attributes[attributeX] = turtle.XCor()
+	attributes[attributeY] = turtle.YCor()
+	if m.Is3D() {
+		attributes[attributeZ] = turtle.ZCor()
+	}
+	if label := turtle.GetLabel(); label != nil {
+		attributes[attributeLabel] = formatAttribute(label)
+	}
+	return attributes
+}
+
+// returns the attributes written for every turtle, the built in ones first
+func turtleAttributeList(m *model.Model, turtles []*model.Turtle) []networkAttribute {
+	attributes := []networkAttribute{
+		{name: attributeBreed, value: ""},
+		{name: attributeX, value: 0.0},
+		{name: attributeY, value: 0.0},
+	}
+	if m.Is3D() {
+		attributes = append(attributes, networkAttribute{name: attributeZ, value: 0.0})
+	}
+	attributes = append(attributes, networkAttribute{name: attributeLabel, value: ""})
+
+	properties := make([]map[string]interface{}, len(turtles))
+	for i, turtle := range turtles {
+		properties[i] = turtle.Properties()
+	}
+	return append(attributes, propertyAttributes(properties)...)
+}
+
+// returns the attributes written for every link, the breed first
+func linkAttributeList(links []*model.Link) []networkAttribute {
+	properties := make([]map[string]interface{}, len(links))
+	for i, link := range links {
+		properties[i] = link.Properties()
+	}
+	return append([]networkAttribute{{name: attributeBreed, value: ""}}, propertyAttributes(properties)...)
+}
+
+// returns the breed and properties of a link
+func linkAttributes(link *model.Link) map[string]interface{} {
+	attributes := link.Properties()
+	attributes[attributeBreed] = link.BreedName()
+	return attributes
+}
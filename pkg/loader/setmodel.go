@@ -1,11 +1,53 @@
 package loader
 
 import (
+	"encoding/base64"
+
 	"github.com/nlatham1999/go-agent/pkg/model"
 )
 
+// SetModel builds a model from a saved model
+// saves from older versions of the format are migrated first, see Migrate, the saved model passed in isn't changed
+// saves from newer versions are loaded as far as this version of the format goes
 func SetModel(modelJson *Model) *model.Model {
 
+	migrated := *modelJson
+	if err := Migrate(&migrated); err == nil {
+		modelJson = &migrated
+	}
+
+	builtModel := buildModel(modelJson)
+
+	for _, patch := range modelJson.Patches {
+		setPatch(builtModel, patch)
+	}
+
+	// the turtles are created one at a time in the order they were saved so they keep their who numbers
+	for _, turtle := range modelJson.Turtles {
+		createTurtle(builtModel, turtle)
+	}
+	builtModel.SetNextWho(modelJson.NextWho)
+
+	for _, link := range modelJson.Links {
+		createLink(builtModel, link)
+	}
+	builtModel.SetNextLinkID(modelJson.NextLinkID)
+
+	for _, segment := range modelJson.Drawing.Segments {
+		addSegment(builtModel, segment)
+	}
+	for _, stamp := range modelJson.Drawing.Stamps {
+		addStamp(builtModel, stamp)
+	}
+
+	finishModel(builtModel, modelJson)
+
+	return builtModel
+}
+
+// builds an empty model with the breeds, columns and world of the saved model
+func buildModel(modelJson *Model) *model.Model {
+
 	// build the turtle breeds
 	turtleBreeds := []*model.TurtleBreed{}
 	for _, breed := range modelJson.TurtleBreeds {
@@ -16,21 +58,45 @@ func SetModel(modelJson *Model) *model.Model {
 	// build the directed link breeds
 	directedLinkBreeds := []*model.LinkBreed{}
 	for _, breed := range modelJson.DirectedLinkBreeds {
-		newBreed := model.NewLinkBreed(breed.Name)
+		newBreed := model.NewLinkBreedWithProperties(breed.Name, breed.Properties)
 		directedLinkBreeds = append(directedLinkBreeds, newBreed)
 	}
 
 	// build the undirected link breeds
 	undirectedLinkBreeds := []*model.LinkBreed{}
 	for _, breed := range modelJson.UndirectedLinkBreeds {
-		newBreed := model.NewLinkBreed(breed.Name)
+		newBreed := model.NewLinkBreedWithProperties(breed.Name, breed.Properties)
 		undirectedLinkBreeds = append(undirectedLinkBreeds, newBreed)
 	}
 
+	// build the patch columns, json turns every number into a float64 so the declared type is used
+	patchColumns := make(map[string]interface{})
+	for _, column := range modelJson.PatchColumns {
+		switch column.Type {
+		case "float":
+			value, _ := column.Default.(float64)
+			patchColumns[column.Name] = value
+		case "int":
+			switch value := column.Default.(type) {
+			case float64:
+				patchColumns[column.Name] = int(value)
+			case int:
+				patchColumns[column.Name] = value
+			default:
+				patchColumns[column.Name] = 0
+			}
+		case "bool":
+			value, _ := column.Default.(bool)
+			patchColumns[column.Name] = value
+		}
+	}
+
 	// build the model
 	modelSettings := model.ModelSettings{
 		PatchProperties:      modelJson.PatchProperties,
+		PatchColumns:         patchColumns,
 		TurtleProperties:     modelJson.TurtleProperties,
+		LinkProperties:       modelJson.LinkProperties,
 		TurtleBreeds:         turtleBreeds,
 		DirectedLinkBreeds:   directedLinkBreeds,
 		UndirectedLinkBreeds: undirectedLinkBreeds,
@@ -40,83 +106,185 @@ func SetModel(modelJson *Model) *model.Model {
 		MaxPxCor:             modelJson.MaxPxCor,
 		MinPyCor:             modelJson.MinPyCor,
 		MaxPyCor:             modelJson.MaxPyCor,
+		MinPzCor:             modelJson.MinPzCor,
+		MaxPzCor:             modelJson.MaxPzCor,
 		RandomSeed:           modelJson.RandomSeed1,
 		RandomSeed2:          modelJson.RandomSeed2,
+		MaxDrawingSegments:   modelJson.Drawing.MaxSegments,
+		SpatialIndexCellSize: modelJson.SpatialIndexCellSize,
 	}
 	builtModel := model.NewModel(modelSettings)
+	builtModel.DefaultShapeTurtles = modelJson.DefaultShapeTurtles
+	builtModel.DefaultShapeLinks = modelJson.DefaultShapeLinks
 
-	//load the random state
-	builtModel.SetRandomState(modelJson.RandomSeed1, modelJson.RandomSeed2, []byte(modelJson.RandomState))
+	return builtModel
+}
 
-	// set all the patches
-	for _, patch := range modelJson.Patches {
-		p := builtModel.Patch(float64(patch.X), float64(patch.Y))
-		p.Color.SetColorRGBA(patch.Color.Red, patch.Color.Green, patch.Color.Blue, patch.Color.Alpha)
-		for key, val := range patch.Properties {
-			p.SetProperty(key, val)
-		}
+func setPatch(builtModel *model.Model, patch Patch) {
+	p := builtModel.Patch3D(float64(patch.X), float64(patch.Y), float64(patch.Z))
+	if p == nil {
+		return
+	}
+	p.Color.SetColorRGBA(patch.Color.Red, patch.Color.Green, patch.Color.Blue, patch.Color.Alpha)
+	p.Label = patch.Label
+	p.PlabelColor.SetColorRGBA(patch.LabelColor.Red, patch.LabelColor.Green, patch.LabelColor.Blue, patch.LabelColor.Alpha)
+	for key, val := range patch.Properties {
+		p.SetProperty(key, val)
 	}
+	for key, val := range patch.Columns {
+		p.SetProperty(key, val)
+	}
+}
 
-	// create basic turtles
-	builtModel.CreateTurtles(len(modelJson.Turtles), nil)
+// creates the turtle with its saved who number
+func createTurtle(builtModel *model.Model, turtle Turtle) {
+	breed := builtModel.TurtleBreed(turtle.Breed)
+	if breed == nil {
+		breed = builtModel.TurtleBreed("")
+	}
+	builtModel.SetNextWho(turtle.Who)
+	breed.CreateAgents(1, nil)
 
-	// set all the turtles
-	for _, turtle := range modelJson.Turtles {
-		t := builtModel.Turtle(turtle.Who)
+	t := builtModel.Turtle(turtle.Who)
+	if builtModel.Is3D() {
+		t.SetXYZ(turtle.X, turtle.Y, turtle.Z)
+	} else {
 		t.SetXY(turtle.X, turtle.Y)
-		t.Color.SetColorRGBA(turtle.Color.Red, turtle.Color.Green, turtle.Color.Blue, turtle.Color.Alpha)
-		t.SetSize(turtle.Size)
-		t.Shape = turtle.Shape
-		t.SetHeading(turtle.Heading)
-		t.SetLabel(turtle.Label)
-		t.LabelColor = model.Color{
-			Red:   turtle.LabelColor.Red,
-			Green: turtle.LabelColor.Green,
-			Blue:  turtle.LabelColor.Blue,
-			Alpha: turtle.LabelColor.Alpha,
-		}
-		for key, val := range turtle.Properties {
-			t.SetProperty(key, val)
+	}
+	t.Color.SetColorRGBA(turtle.Color.Red, turtle.Color.Green, turtle.Color.Blue, turtle.Color.Alpha)
+	t.SetSize(turtle.Size)
+	t.Shape = turtle.Shape
+	t.SetHeading(turtle.Heading)
+	t.SetPitch(turtle.Pitch)
+	t.Hidden = turtle.Hidden
+	t.SetLabel(turtle.Label)
+	t.LabelColor = model.Color{
+		Red:   turtle.LabelColor.Red,
+		Green: turtle.LabelColor.Green,
+		Blue:  turtle.LabelColor.Blue,
+		Alpha: turtle.LabelColor.Alpha,
+	}
+	for key, val := range turtle.Properties {
+		t.SetProperty(key, val)
+	}
+	// the pen is set after moving the turtle so that loading doesn't leave a trail
+	if turtle.PenSize > 0 {
+		t.SetPenSize(turtle.PenSize)
+	}
+	if turtle.PenDown {
+		t.PenDown()
+	}
+}
+
+// creates the link with its saved id, links to turtles that weren't loaded are skipped
+func createLink(builtModel *model.Model, link Link) {
+	end1 := builtModel.Turtle(link.End1)
+	end2 := builtModel.Turtle(link.End2)
+	if end1 == nil || end2 == nil {
+		return
+	}
+	builtModel.SetNextLinkID(link.ID)
+
+	setLink := func(l *model.Link) {
+		l.Color.SetColorRGBA(link.Color.Red, link.Color.Green, link.Color.Blue, link.Color.Alpha)
+		l.Label = link.Label
+		l.LabelColor.SetColorRGBA(link.LabelColor.Red, link.LabelColor.Green, link.LabelColor.Blue, link.LabelColor.Alpha)
+		l.Size = link.Size
+		l.Shape = link.Shape
+		l.Thickness = link.Thickness
+		for key, val := range link.Properties {
+			l.SetProperty(key, val)
 		}
-		if turtle.Breed != "" {
-			breed := builtModel.TurtleBreed(turtle.Breed)
-			t.SetBreed(breed)
+		l.SetTieMode(model.TieMode(link.TieMode))
+		if link.Hidden {
+			l.Hide()
+		} else {
+			l.Show()
 		}
 	}
 
-	// set all the links
-	for _, link := range modelJson.Links {
-		end1 := builtModel.Turtle(link.End1)
-		end2 := builtModel.Turtle(link.End2)
-
-		if link.Directed {
-			breed := builtModel.DirectedLinkBreed(link.Breed)
-			end1.CreateLinkToTurtle(breed, end2, func(l *model.Link) {
-				l.Color.SetColorRGBA(link.Color.Red, link.Color.Green, link.Color.Blue, link.Color.Alpha)
-				l.Label = link.Label
-				l.LabelColor.SetColorRGBA(link.LabelColor.Red, link.LabelColor.Green, link.LabelColor.Blue, link.LabelColor.Alpha)
-				l.Size = link.Size
-				if link.Hidden {
-					l.Hide()
-				} else {
-					l.Show()
-				}
-			})
-		} else {
-			breed := builtModel.UndirectedLinkBreed(link.Breed)
-			end1.CreateLinkWithTurtle(breed, end2, func(l *model.Link) {
-				l.Color.SetColorRGBA(link.Color.Red, link.Color.Green, link.Color.Blue, link.Color.Alpha)
-				l.Label = link.Label
-				l.LabelColor.SetColorRGBA(link.LabelColor.Red, link.LabelColor.Green, link.LabelColor.Blue, link.LabelColor.Alpha)
-				l.Size = link.Size
-				if link.Hidden {
-					l.Hide()
-				} else {
-					l.Show()
-				}
-			})
+	if link.Directed {
+		end1.CreateLinkToTurtle(builtModel.DirectedLinkBreed(link.Breed), end2, setLink)
+	} else {
+		end1.CreateLinkWithTurtle(builtModel.UndirectedLinkBreed(link.Breed), end2, setLink)
+	}
+}
+
+func addSegment(builtModel *model.Model, segment DrawingSegment) {
+	builtModel.Drawing().AddSegment(model.DrawingSegment{
+		X1:    segment.X1,
+		Y1:    segment.Y1,
+		Z1:    segment.Z1,
+		X2:    segment.X2,
+		Y2:    segment.Y2,
+		Z2:    segment.Z2,
+		Color: model.Color{Red: segment.Color.Red, Green: segment.Color.Green, Blue: segment.Color.Blue, Alpha: segment.Color.Alpha},
+		Size:  segment.Size,
+	})
+}
+
+func addStamp(builtModel *model.Model, stamp DrawingStamp) {
+	builtModel.Drawing().AddStamp(model.DrawingStamp{
+		X:       stamp.X,
+		Y:       stamp.Y,
+		Z:       stamp.Z,
+		Heading: stamp.Heading,
+		Size:    stamp.Size,
+		Shape:   stamp.Shape,
+		Color:   model.Color{Red: stamp.Color.Red, Green: stamp.Color.Green, Blue: stamp.Color.Blue, Alpha: stamp.Color.Alpha},
+	})
+}
+
+// restores the clock, the scheduled events and the random state once all the agents are loaded
+func finishModel(builtModel *model.Model, modelJson *Model) {
+
+	// the events are added in the order they fire so events at the same time keep their order
+	builtModel.Ticks = modelJson.Ticks
+	builtModel.SetClock(modelJson.Clock)
+	for _, event := range modelJson.Events {
+		settings := model.EventSettings{
+			Name:     event.Name,
+			Time:     event.Time,
+			Interval: event.Interval,
 		}
+
+		if event.Turtle != nil {
+			settings.Turtle = builtModel.Turtle(*event.Turtle)
+			if settings.Turtle == nil {
+				continue
+			}
+		}
+
+		if event.Patch != nil {
+			settings.Patch = builtModel.Patch3D(float64(event.Patch.X), float64(event.Patch.Y), float64(event.Patch.Z))
+		}
+
+		if event.Link != nil {
+			settings.Link = findLink(builtModel, event.Link)
+			if settings.Link == nil {
+				continue
+			}
+		}
+
+		builtModel.ScheduleEvent(settings)
 	}
 
-	return builtModel
+	// the random state is loaded last since creating the agents draws random numbers
+	if state, err := base64.StdEncoding.DecodeString(modelJson.RandomState); err == nil {
+		builtModel.SetRandomState(modelJson.RandomSeed1, modelJson.RandomSeed2, state)
+	}
+}
+
+func findLink(m *model.Model, link *EventLink) *model.Link {
+	end1 := m.Turtle(link.End1)
+	end2 := m.Turtle(link.End2)
+	if end1 == nil || end2 == nil {
+		return nil
+	}
+
+	if link.Directed {
+		return end1.LinkTo(m.DirectedLinkBreed(link.Breed), end2)
+	}
+
+	return end1.LinkWith(m.UndirectedLinkBreed(link.Breed), end2)
 }
@@ -1,12 +1,20 @@
 package loader
 
+// CurrentVersion is the version of the format GetModel saves in
+// saves from before the format had a version are version 1, see Migrate
+const CurrentVersion = 2
+
 type Model struct {
+	Version int `json:"version"`
+
 	TurtleBreeds         []TurtleBreed `json:"turtleBreeds"`
 	DirectedLinkBreeds   []LinkBreed   `json:"directedLinkBreeds"`
 	UndirectedLinkBreeds []LinkBreed   `json:"undirectedLinkBreeds"`
 
 	PatchProperties  map[string]interface{} `json:"patchProperties"`
+	PatchColumns     []PatchColumn          `json:"patchColumns,omitempty"`
 	TurtleProperties map[string]interface{} `json:"turtleProperties"`
+	LinkProperties   map[string]interface{} `json:"linkProperties"`
 
 	WrappingX bool `json:"wrappingX"`
 	WrappingY bool `json:"wrappingY"`
@@ -18,6 +26,14 @@ type Model struct {
 	MaxPxCor int `json:"maxPxCor"`
 	MinPyCor int `json:"minPyCor"`
 	MaxPyCor int `json:"maxPyCor"`
+	MinPzCor int `json:"minPzCor"`
+	MaxPzCor int `json:"maxPzCor"`
+
+	DefaultShapeTurtles string `json:"defaultShapeTurtles"`
+	DefaultShapeLinks   string `json:"defaultShapeLinks"`
+
+	NextWho    int `json:"nextWho"`    // who number of the next turtle to be created
+	NextLinkID int `json:"nextLinkId"` // id of the next link to be created
 
 	RandomSeed1 uint64 `json:"randomSeed1"`
 	RandomSeed2 uint64 `json:"randomSeed2"`
@@ -27,27 +43,51 @@ type Model struct {
 	Turtles []Turtle `json:"turtles"`
 	Links   []Link   `json:"links"`
 	Ticks   int      `json:"ticks"`
+
+	Clock  float64 `json:"clock"`
+	Events []Event `json:"events"`
+
+	Drawing Drawing `json:"drawing"`
+
+	SpatialIndexCellSize float64 `json:"spatialIndexCellSize,omitempty"`
 }
 
 type Patch struct {
 	X          int                    `json:"x"`
 	Y          int                    `json:"y"`
+	Z          int                    `json:"z"`
 	Color      Color                  `json:"color"`
+	Label      interface{}            `json:"label"`
+	LabelColor Color                  `json:"labelColor"`
 	Properties map[string]interface{} `json:"properties"`
+	Columns    map[string]interface{} `json:"columns,omitempty"`
+}
+
+// PatchColumn is the declaration of a typed patch column
+// Type is one of "float", "int" or "bool"
+type PatchColumn struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Default interface{} `json:"default"`
 }
 
 type Turtle struct {
 	X          float64                `json:"x"`
 	Y          float64                `json:"y"`
+	Z          float64                `json:"z"`
 	Color      Color                  `json:"color"`
 	Size       float64                `json:"size"`
 	Who        int                    `json:"who"`
 	Shape      string                 `json:"shape"`
 	Heading    float64                `json:"heading"`
+	Pitch      float64                `json:"pitch"`
+	Hidden     bool                   `json:"hidden"`
 	Label      interface{}            `json:"label"`
 	LabelColor Color                  `json:"labelColor"`
 	Properties map[string]interface{} `json:"properties"`
 	Breed      string                 `json:"breed"`
+	PenDown    bool                   `json:"penDown"`
+	PenSize    float64                `json:"penSize"`
 }
 
 type Color struct {
@@ -58,6 +98,7 @@ type Color struct {
 }
 
 type Link struct {
+	ID         int         `json:"id"`
 	End1       int         `json:"end1"`
 	End2       int         `json:"end2"`
 	End1X      float64     `json:"end1X"`
@@ -69,8 +110,13 @@ type Link struct {
 	Label      interface{} `json:"label"`
 	LabelColor Color       `json:"labelColor"`
 	Size       int         `json:"size"`
+	Shape      string      `json:"shape"`
+	Thickness  float64     `json:"thickness"`
 	Hidden     bool        `json:"hidden"`
 	Breed      string      `json:"breed"`
+	TieMode    int         `json:"tieMode"`
+
+	Properties map[string]interface{} `json:"properties"`
 }
 
 type TurtleBreed struct {
@@ -80,7 +126,57 @@ type TurtleBreed struct {
 }
 
 type LinkBreed struct {
-	Name         string `json:"name"`
-	DefaultShape string `json:"defaultShape"`
-	Directed     bool   `json:"directed"`
+	Name         string                 `json:"name"`
+	Properties   map[string]interface{} `json:"properties"`
+	DefaultShape string                 `json:"defaultShape"`
+	Directed     bool                   `json:"directed"`
+}
+
+type Event struct {
+	Name     string      `json:"name"`
+	Time     float64     `json:"time"`
+	Interval float64     `json:"interval"`
+	Turtle   *int        `json:"turtle,omitempty"` // who number of the turtle the event is bound to
+	Patch    *EventPatch `json:"patch,omitempty"`
+	Link     *EventLink  `json:"link,omitempty"`
+}
+
+type EventPatch struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	Z int `json:"z"`
+}
+
+type EventLink struct {
+	End1     int    `json:"end1"`
+	End2     int    `json:"end2"`
+	Directed bool   `json:"directed"`
+	Breed    string `json:"breed"`
+}
+
+type Drawing struct {
+	MaxSegments int              `json:"maxSegments"`
+	Segments    []DrawingSegment `json:"segments"`
+	Stamps      []DrawingStamp   `json:"stamps"`
+}
+
+type DrawingSegment struct {
+	X1    float64 `json:"x1"`
+	Y1    float64 `json:"y1"`
+	Z1    float64 `json:"z1"`
+	X2    float64 `json:"x2"`
+	Y2    float64 `json:"y2"`
+	Z2    float64 `json:"z2"`
+	Color Color   `json:"color"`
+	Size  float64 `json:"size"`
+}
+
+type DrawingStamp struct {
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Z       float64 `json:"z"`
+	Heading float64 `json:"heading"`
+	Size    float64 `json:"size"`
+	Shape   string  `json:"shape"`
+	Color   Color   `json:"color"`
 }
@@ -0,0 +1,22 @@
+package model
+
+// Agent is what turtles, patches and links have in common
+// agent sets are written once against it so every agent set gets the same features
+type Agent interface {
+	ID() int // who number for turtles, index for patches and creation order for links
+
+	GetProperty(key string) interface{}
+	SetProperty(key string, value interface{})
+	Properties() map[string]interface{}
+
+	GetColor() Color
+	SetColor(color Color)
+
+	GetLabel() interface{}
+	SetLabel(label interface{})
+
+	IsHidden() bool
+
+	// model the agent belongs to, nil if it doesn't belong to one
+	agentModel() *Model
+}
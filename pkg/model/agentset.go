@@ -0,0 +1,490 @@
+package model
+
+import (
+	"iter"
+
+	"github.com/nlatham1999/sortedset"
+)
+
+// AgentSet is an ordered set of agents that can be sorted
+// TurtleAgentSet, PatchAgentSet and LinkAgentSet are built on it so anything added here works for all of them
+// implements github.com/nlatham1999/sortedset
+type AgentSet[T Agent] struct {
+	agents *sortedset.SortedSet
+}
+
+// create a new AgentSet with the agents in the order they are in the list
+func NewAgentSet[T Agent](agents []T) *AgentSet[T] {
+	set := newAgentSet[T](sortedset.NewSortedSet())
+	for _, agent := range agents {
+		set.agents.Add(agent)
+	}
+	return set
+}
+
+func newAgentSet[T Agent](agents *sortedset.SortedSet) *AgentSet[T] {
+	return &AgentSet[T]{
+		agents: agents,
+	}
+}
+
+// returns the error for an empty agent set of the agent type
+func emptyAgentSetError[T Agent]() error {
+	var agent T
+	switch any(agent).(type) {
+	case *Turtle:
+		return ErrNoTurtlesInAgentSet
+	case *Patch:
+		return ErrNoPatchesInAgentSet
+	case *Link:
+		return ErrNoLinksInAgentSet
+	}
+	return ErrNoAgentsInAgentSet
+}
+
+// add an agent to the agent set
+func (a *AgentSet[T]) Add(agent T) {
+	a.agents.Add(agent)
+}
+
+// returns true if all the agents in the agent set satisfy the operation
+func (a *AgentSet[T]) All(operation func(T) bool) bool {
+	if operation == nil {
+		return false
+	}
+
+	return a.agents.All(func(agent interface{}) bool {
+		return operation(agent.(T))
+	})
+}
+
+// returns true if any of the agents in the agent set satisfy the operation
+func (a *AgentSet[T]) Any(operation func(T) bool) bool {
+	if operation == nil {
+		return false
+	}
+
+	return a.agents.Any(func(agent interface{}) bool {
+		return operation(agent.(T))
+	})
+}
+
+// perform the operation for all agents in the agent set
+func (a *AgentSet[T]) Ask(operation func(T)) {
+	if operation == nil {
+		return
+	}
+
+	a.agents.Ask(func(agent interface{}) {
+		operation(agent.(T))
+	})
+}
+
+// AskScheduled performs the operation for all agents in the agent set in the order given by the scheduler.
+// If the scheduler is nil then the model's scheduler is used.
+func (a *AgentSet[T]) AskScheduled(scheduler Scheduler, operation func(T)) {
+	if operation == nil {
+		return
+	}
+
+	a.askStaged(scheduler, []func(T) func(){
+		func(agent T) func() {
+			operation(agent)
+			return nil
+		},
+	})
+}
+
+// runs each stage on all agents in the agent set before moving on to the next stage
+// nil stages are skipped
+func (a *AgentSet[T]) askStaged(scheduler Scheduler, stages []func(T) func()) {
+	agents := a.List()
+	if len(agents) == 0 {
+		return
+	}
+
+	m := agents[0].agentModel()
+	if scheduler == nil && m != nil {
+		scheduler = m.scheduler
+	}
+
+	for _, stage := range stages {
+		if stage == nil {
+			continue
+		}
+
+		runScheduled(m, scheduler, len(agents), func(i int) func() {
+			// skip agents that were removed from the agent set by an earlier activation
+			if !a.agents.Contains(agents[i]) {
+				return nil
+			}
+			return stage(agents[i])
+		})
+	}
+}
+
+// returns true if the agent is in the agent set
+func (a *AgentSet[T]) Contains(agent T) bool {
+	return a.agents.Contains(agent)
+}
+
+// returns a copy of the agent set
+func (a *AgentSet[T]) Copy() *AgentSet[T] {
+	return NewAgentSet(a.List())
+}
+
+// returns the number of agents in the agent set
+func (a *AgentSet[T]) Count() int {
+	return a.agents.Len()
+}
+
+// returns the agent set as a list
+func (a *AgentSet[T]) List() []T {
+	agents := make([]T, 0, a.agents.Len())
+	a.agents.Ask(func(agent interface{}) {
+		agents = append(agents, agent.(T))
+	})
+	return agents
+}
+
+// returns the first n agents in the agent set
+func (a *AgentSet[T]) FirstNOf(n int) *AgentSet[T] {
+	set := sortedset.NewSortedSet()
+	agent := a.agents.First()
+	for i := 0; i < n && agent != nil; i++ {
+		set.Add(agent)
+		agent, _ = a.agents.Next()
+	}
+	return newAgentSet[T](set)
+}
+
+// returns the first agent in the agent set
+func (a *AgentSet[T]) First() (T, error) {
+	agent := a.agents.First()
+	if agent == nil {
+		var none T
+		return none, emptyAgentSetError[T]()
+	}
+	return agent.(T), nil
+}
+
+// returns the last n agents in the agent set
+func (a *AgentSet[T]) LastNOf(n int) *AgentSet[T] {
+	set := sortedset.NewSortedSet()
+	agent := a.agents.Last()
+	for i := 0; i < n && agent != nil; i++ {
+		set.Add(agent)
+		agent, _ = a.agents.Previous()
+	}
+	return newAgentSet[T](set)
+}
+
+// returns the last agent in the agent set
+func (a *AgentSet[T]) Last() (T, error) {
+	agent := a.agents.Last()
+	if agent == nil {
+		var none T
+		return none, emptyAgentSetError[T]()
+	}
+	return agent.(T), nil
+}
+
+// returns the agent after the one last returned by First, Last or Next
+func (a *AgentSet[T]) Next() (T, error) {
+	agent, _ := a.agents.Next()
+	if agent == nil {
+		var none T
+		return none, emptyAgentSetError[T]()
+	}
+	return agent.(T), nil
+}
+
+// returns a random agent in the agent set that satisfies the operation, nil if there are none
+func (a *AgentSet[T]) RandomWhere(operation func(T) bool) T {
+	var none T
+	if operation == nil {
+		return none
+	}
+
+	matches := []T{}
+	a.agents.Ask(func(agent interface{}) {
+		if operation(agent.(T)) {
+			matches = append(matches, agent.(T))
+		}
+	})
+	if len(matches) == 0 {
+		return none
+	}
+	return matches[agentsRandom(matches[0].agentModel()).IntN(len(matches))]
+}
+
+// remove an agent from the agent set
+func (a *AgentSet[T]) Remove(agent T) {
+	a.agents.Remove(agent)
+}
+
+// sort the agents in the agent set in ascending order based on the float operation
+func (a *AgentSet[T]) SortAsc(operation func(T) float64) {
+	a.agents.SortAsc(func(agent interface{}) interface{} {
+		return operation(agent.(T))
+	})
+}
+
+// sort the agents in the agent set in descending order based on the float operation
+func (a *AgentSet[T]) SortDesc(operation func(T) float64) {
+	a.agents.SortDesc(func(agent interface{}) interface{} {
+		return operation(agent.(T))
+	})
+}
+
+// returns a new agent set with all the agents that are not the given agent
+func (a *AgentSet[T]) WhoAreNotAgent(agent T) *AgentSet[T] {
+	return newAgentSet[T](a.agents.Difference(sortedset.NewSortedSet(agent)))
+}
+
+// returns a new agent set that contains all the agents that satisfy the operation
+// returns nil if the operation is nil
+func (a *AgentSet[T]) With(operation func(T) bool) *AgentSet[T] {
+	if operation == nil {
+		return nil
+	}
+
+	set := sortedset.NewSortedSet()
+	a.agents.Ask(func(agent interface{}) {
+		if operation(agent.(T)) {
+			set.Add(agent)
+		}
+	})
+	return newAgentSet[T](set)
+}
+
+// returns a random agent from the agent set
+func (a *AgentSet[T]) OneOf() (T, error) {
+	agents := a.List()
+	if len(agents) == 0 {
+		var none T
+		return none, emptyAgentSetError[T]()
+	}
+	return agents[agentsRandom(agents[0].agentModel()).IntN(len(agents))], nil
+}
+
+// returns n random agents from the agent set, or all of them if there are fewer than n
+// the agents stay in the order they are in the agent set
+func (a *AgentSet[T]) NOf(n int) *AgentSet[T] {
+	agents := a.List()
+	picked := []T{}
+	if len(agents) == 0 {
+		return NewAgentSet(picked)
+	}
+	for _, i := range randomIndices(agentsRandom(agents[0].agentModel()), len(agents), n) {
+		picked = append(picked, agents[i])
+	}
+	return NewAgentSet(picked)
+}
+
+// puts the agents of the agent set in a random order
+func (a *AgentSet[T]) Shuffle() {
+	agents := a.List()
+	if len(agents) == 0 {
+		return
+	}
+	shuffled := sortedset.NewSortedSet()
+	for _, i := range agentsRandom(agents[0].agentModel()).Perm(len(agents)) {
+		shuffled.Add(agents[i])
+	}
+	a.agents = shuffled
+}
+
+// returns a random agent from the agent set picked with a chance proportional to its weight
+// agents with a weight that isn't positive are never picked
+func (a *AgentSet[T]) WeightedOneOf(weight func(T) float64) (T, error) {
+	agents, weights := a.values(weight)
+	var none T
+	if len(agents) == 0 {
+		return none, emptyAgentSetError[T]()
+	}
+	i := weightedIndex(agentsRandom(agents[0].agentModel()), weights)
+	if i == -1 {
+		return none, emptyAgentSetError[T]()
+	}
+	return agents[i], nil
+}
+
+// returns n different agents from the agent set picked with chances proportional to their weights
+// agents with a weight that isn't positive are never picked so fewer than n may be returned
+// the agents stay in the order they are in the agent set
+func (a *AgentSet[T]) WeightedNOf(n int, weight func(T) float64) *AgentSet[T] {
+	agents, weights := a.values(weight)
+	picked := []T{}
+	if len(agents) == 0 {
+		return NewAgentSet(picked)
+	}
+	for _, i := range weightedIndices(agentsRandom(agents[0].agentModel()), weights, n) {
+		picked = append(picked, agents[i])
+	}
+	return NewAgentSet(picked)
+}
+
+// returns the values of the operation for each agent in the order of the agent set
+func (a *AgentSet[T]) values(operation func(T) float64) ([]T, []float64) {
+	agents := a.List()
+	values := make([]float64, len(agents))
+	for i, agent := range agents {
+		values[i] = operation(agent)
+	}
+	return agents, values
+}
+
+// returns the agent with the largest value of the operation, ties are broken at random
+func (a *AgentSet[T]) MaxOneOf(operation func(T) float64) (T, error) {
+	return a.extremeOneOf(operation, true)
+}
+
+// returns the agent with the smallest value of the operation, ties are broken at random
+func (a *AgentSet[T]) MinOneOf(operation func(T) float64) (T, error) {
+	return a.extremeOneOf(operation, false)
+}
+
+func (a *AgentSet[T]) extremeOneOf(operation func(T) float64, max bool) (T, error) {
+	agents, values := a.values(operation)
+	var none T
+	if len(agents) == 0 {
+		return none, emptyAgentSetError[T]()
+	}
+	i := extremeIndex(agentsRandom(agents[0].agentModel()), values, max)
+	if i == -1 {
+		return none, emptyAgentSetError[T]()
+	}
+	return agents[i], nil
+}
+
+// returns the n agents with the largest values of the operation, ties at the cut off are broken at random
+// the agents stay in the order they are in the agent set
+func (a *AgentSet[T]) MaxNOf(n int, operation func(T) float64) *AgentSet[T] {
+	return a.extremeNOf(n, operation, true)
+}
+
+// returns the n agents with the smallest values of the operation, ties at the cut off are broken at random
+// the agents stay in the order they are in the agent set
+func (a *AgentSet[T]) MinNOf(n int, operation func(T) float64) *AgentSet[T] {
+	return a.extremeNOf(n, operation, false)
+}
+
+func (a *AgentSet[T]) extremeNOf(n int, operation func(T) float64, max bool) *AgentSet[T] {
+	agents, values := a.values(operation)
+	picked := []T{}
+	if len(agents) == 0 {
+		return NewAgentSet(picked)
+	}
+	for _, i := range extremeIndices(agentsRandom(agents[0].agentModel()), values, n, max) {
+		picked = append(picked, agents[i])
+	}
+	return NewAgentSet(picked)
+}
+
+// returns the sum of the operation over the agents, 0 if there are none
+func (a *AgentSet[T]) Sum(operation func(T) float64) float64 {
+	_, values := a.values(operation)
+	return sumValues(values)
+}
+
+// returns the mean of the operation over the agents
+func (a *AgentSet[T]) Mean(operation func(T) float64) (float64, error) {
+	_, values := a.values(operation)
+	if len(values) == 0 {
+		return 0, emptyAgentSetError[T]()
+	}
+	return sumValues(values) / float64(len(values)), nil
+}
+
+// returns the sample variance of the operation over the agents, there have to be at least two
+func (a *AgentSet[T]) Variance(operation func(T) float64) (float64, error) {
+	_, values := a.values(operation)
+	if len(values) < 2 {
+		return 0, ErrVarianceTooFewAgents
+	}
+	return varianceValues(values), nil
+}
+
+// counts the agents whose value of the operation falls in each of the bins evenly splitting min to max
+// a value equal to max goes in the last bin, values outside of min and max are left out
+func (a *AgentSet[T]) Histogram(operation func(T) float64, min float64, max float64, bins int) []int {
+	_, values := a.values(operation)
+	return histogramValues(values, min, max, bins)
+}
+
+// splits the agents into agent sets by the key the operation returns for each agent
+// the keys have to be comparable, the agents in each agent set stay in the order they are in the agent set
+func (a *AgentSet[T]) GroupBy(operation func(T) interface{}) map[interface{}]*AgentSet[T] {
+	groups := map[interface{}]*AgentSet[T]{}
+	for _, agent := range a.List() {
+		key := operation(agent)
+		group, ok := groups[key]
+		if !ok {
+			group = NewAgentSet[T](nil)
+			groups[key] = group
+		}
+		group.Add(agent)
+	}
+	return groups
+}
+
+// splits the agents into agent sets by the value of the property
+func (a *AgentSet[T]) GroupByProperty(name string) map[interface{}]*AgentSet[T] {
+	return a.GroupBy(func(agent T) interface{} {
+		return agent.GetProperty(name)
+	})
+}
+
+// returns the agents of the agent set so they can be ranged over without copying them into a list
+// agents added or removed while ranging may or may not be seen
+func (a *AgentSet[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		// Any stops at the first true so returning true stops when the caller breaks out
+		a.agents.Any(func(agent interface{}) bool {
+			return !yield(agent.(T))
+		})
+	}
+}
+
+// returns a lazy query over the agents of the agent set
+func (a *AgentSet[T]) Query() *Query[T] {
+	return NewQuery(a.Iter())
+}
+
+// returns a new agent set with the agents in either agent set
+// the agents of this agent set come first followed by the ones only in the other
+func (a *AgentSet[T]) Union(other *AgentSet[T]) *AgentSet[T] {
+	union := sortedset.NewSortedSet()
+	a.agents.Ask(func(agent interface{}) {
+		union.Add(agent)
+	})
+	other.agents.Ask(func(agent interface{}) {
+		if !union.Contains(agent) {
+			union.Add(agent)
+		}
+	})
+	return newAgentSet[T](union)
+}
+
+// returns a new agent set with the agents in both agent sets, in the order of this agent set
+func (a *AgentSet[T]) Intersect(other *AgentSet[T]) *AgentSet[T] {
+	intersection := sortedset.NewSortedSet()
+	a.agents.Ask(func(agent interface{}) {
+		if other.agents.Contains(agent) {
+			intersection.Add(agent)
+		}
+	})
+	return newAgentSet[T](intersection)
+}
+
+// returns a new agent set with the agents of this agent set that aren't in the other
+func (a *AgentSet[T]) Difference(other *AgentSet[T]) *AgentSet[T] {
+	difference := sortedset.NewSortedSet()
+	a.agents.Ask(func(agent interface{}) {
+		if !other.agents.Contains(agent) {
+			difference.Add(agent)
+		}
+	})
+	return newAgentSet[T](difference)
+}
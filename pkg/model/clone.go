@@ -0,0 +1,481 @@
+package model
+
+import (
+	"container/heap"
+	"math/rand/v2"
+
+	"github.com/nlatham1999/sortedset"
+)
+
+// Snapshot holds a copy of the state of a model at the time it was taken
+// the model can be rolled back to it with Restore, as many times as needed
+type Snapshot struct {
+	model *Model // copy of the model that is never run
+	ticks int
+}
+
+// returns the tick count of the model when the snapshot was taken
+func (s *Snapshot) Ticks() int {
+	return s.ticks
+}
+
+// Clone returns a deep copy of the model that shares no agents, agentsets or breeds with it.
+// The patches, turtles, links, breeds, link adjacency, columns, scheduled events, drawing, spatial index,
+// tick count, model clock and the exact state of the random generator are all copied,
+// so the clone and the model give the same results when they are run the same way.
+// The breeds of the clone are new breeds with the same names, get them with TurtleBreed, DirectedLinkBreed and UndirectedLinkBreed.
+// Event handlers, tick stages and the scheduler are shared with the model, hooks are not copied.
+// Property values are copied as they are, so a property holding a pointer, slice or map points to the same value in both models.
+// It should not be called while agents are being asked.
+func (m *Model) Clone() *Model {
+	clone := &Model{}
+
+	// new breeds with the same names and settings
+	turtleBreeds := make(map[string]*TurtleBreed, len(m.breeds))
+	for name, breed := range m.breeds {
+		turtleBreeds[name] = &TurtleBreed{
+			name:              breed.name,
+			model:             clone,
+			defaultShape:      breed.defaultShape,
+			defaultProperties: copyProperties(breed.defaultProperties),
+		}
+	}
+	directedLinkBreeds := cloneLinkBreeds(m.directedLinkBreeds, clone)
+	undirectedLinkBreeds := cloneLinkBreeds(m.undirectedLinkBreeds, clone)
+
+	clone.eventHandlers = make(map[string]EventOperation, len(m.eventHandlers))
+	for name, handler := range m.eventHandlers {
+		clone.eventHandlers[name] = handler
+	}
+	clone.scheduler = m.scheduler
+	clone.tickStages = append([]TickStage(nil), m.tickStages...)
+
+	m.copyStateTo(clone, turtleBreeds, directedLinkBreeds, undirectedLinkBreeds)
+
+	return clone
+}
+
+func cloneLinkBreeds(breeds map[string]*LinkBreed, clone *Model) map[string]*LinkBreed {
+	cloned := make(map[string]*LinkBreed, len(breeds))
+	for name, breed := range breeds {
+		cloned[name] = &LinkBreed{
+			name:              breed.name,
+			model:             clone,
+			directed:          breed.directed,
+			defaultShape:      breed.defaultShape,
+			defaultProperties: copyProperties(breed.defaultProperties),
+		}
+	}
+	return cloned
+}
+
+// Snapshot takes a copy of the state of the model that it can be restored to with Restore
+// it copies the same state as Clone
+func (m *Model) Snapshot() *Snapshot {
+	return &Snapshot{
+		model: m.Clone(),
+		ticks: m.Ticks,
+	}
+}
+
+// Restore rolls the model back to the state it was in when the snapshot was taken, see Clone for what is part of the state.
+// The breeds of the model are kept and get back the agents they had, so the snapshot can only be restored into a model that has all of its breeds.
+// Event handlers, tick stages, the scheduler and hooks are not touched.
+// Agents and patches from before the restore are no longer part of the model and act like dead agents, get them again from the model.
+// The snapshot isn't changed so it can be restored again.
+func (m *Model) Restore(snapshot *Snapshot) error {
+	if snapshot == nil || snapshot.model == nil {
+		return ErrSnapshotNil
+	}
+	from := snapshot.model
+
+	// the breeds of the model take the place of the breeds of the snapshot
+	for name := range from.breeds {
+		if _, ok := m.breeds[name]; !ok {
+			return ErrSnapshotBreedMissing
+		}
+	}
+	for name := range from.directedLinkBreeds {
+		if _, ok := m.directedLinkBreeds[name]; !ok {
+			return ErrSnapshotBreedMissing
+		}
+	}
+	for name := range from.undirectedLinkBreeds {
+		if _, ok := m.undirectedLinkBreeds[name]; !ok {
+			return ErrSnapshotBreedMissing
+		}
+	}
+
+	// the old agents are zeroed out the same way as killed agents so events and code still holding them see them as dead
+	m.ClearEvents()
+	m.turtles.Ask(func(turtle *Turtle) {
+		*turtle = Turtle{}
+	})
+	m.links.Ask(func(link *Link) {
+		*link = Link{}
+	})
+	m.Patches.Ask(func(patch *Patch) {
+		*patch = Patch{}
+	})
+
+	// breeds that aren't in the snapshot end up empty
+	for _, breed := range m.breeds {
+		breed.turtles = NewTurtleAgentSet([]*Turtle{})
+	}
+	for _, breed := range m.directedLinkBreeds {
+		breed.links = NewLinkAgentSet([]*Link{})
+	}
+	for _, breed := range m.undirectedLinkBreeds {
+		breed.links = NewLinkAgentSet([]*Link{})
+	}
+
+	from.copyStateTo(m, m.breeds, m.directedLinkBreeds, m.undirectedLinkBreeds)
+
+	return nil
+}
+
+// maps the agents and breeds of a model to their copies while the state is being copied
+type modelCopier struct {
+	to *Model
+
+	patches map[*Patch]*Patch
+	turtles map[*Turtle]*Turtle
+	links   map[*Link]*Link
+
+	turtleBreeds map[*TurtleBreed]*TurtleBreed
+	linkBreeds   map[*LinkBreed]*LinkBreed
+}
+
+// copies the state of the model into the other model
+// the breeds passed in are the breeds of the other model, keyed by name, that take the place of the breeds of this model
+// the event handlers, tick stages, scheduler and hooks of the other model are left alone
+func (m *Model) copyStateTo(to *Model, turtleBreeds map[string]*TurtleBreed, directedLinkBreeds map[string]*LinkBreed, undirectedLinkBreeds map[string]*LinkBreed) {
+	c := &modelCopier{
+		to:           to,
+		patches:      make(map[*Patch]*Patch, m.Patches.Count()),
+		turtles:      make(map[*Turtle]*Turtle, m.turtles.Count()),
+		links:        make(map[*Link]*Link, m.links.Count()),
+		turtleBreeds: make(map[*TurtleBreed]*TurtleBreed, len(m.breeds)),
+		linkBreeds:   make(map[*LinkBreed]*LinkBreed, len(m.directedLinkBreeds)+len(m.undirectedLinkBreeds)),
+	}
+	for name, breed := range m.breeds {
+		c.turtleBreeds[breed] = turtleBreeds[name]
+	}
+	for name, breed := range m.directedLinkBreeds {
+		c.linkBreeds[breed] = directedLinkBreeds[name]
+	}
+	for name, breed := range m.undirectedLinkBreeds {
+		c.linkBreeds[breed] = undirectedLinkBreeds[name]
+	}
+
+	to.breeds = turtleBreeds
+	to.directedLinkBreeds = directedLinkBreeds
+	to.undirectedLinkBreeds = undirectedLinkBreeds
+
+	to.Ticks = m.Ticks
+	to.DefaultPatchProperties = copyProperties(m.DefaultPatchProperties)
+	to.DefaultShapeTurtles = m.DefaultShapeTurtles
+	to.DefaultShapeLinks = m.DefaultShapeLinks
+
+	to.maxPxCor, to.maxPyCor, to.maxPzCor = m.maxPxCor, m.maxPyCor, m.maxPzCor
+	to.minPxCor, to.minPyCor, to.minPzCor = m.minPxCor, m.minPyCor, m.minPzCor
+	to.maxXCor, to.maxYCor, to.maxZCor = m.maxXCor, m.maxYCor, m.maxZCor
+	to.minXCor, to.minYCor, to.minZCor = m.minXCor, m.minYCor, m.minZCor
+	to.worldWidth, to.worldHeight, to.worldDepth = m.worldWidth, m.worldHeight, m.worldDepth
+	to.wrappingX, to.wrappingY = m.wrappingX, m.wrappingY
+
+	to.turtlesWhoNumber = m.turtlesWhoNumber
+	to.linksIDNumber = m.linksIDNumber
+	to.modelStart = m.modelStart
+
+	// the random generator continues from exactly where the model's is
+	to.seedValue, to.seedValue2 = m.seedValue, m.seedValue2
+	to.randomSrc = rand.NewPCG(m.seedValue, m.seedValue2)
+	if state, err := m.randomSrc.MarshalBinary(); err == nil {
+		_ = to.randomSrc.UnmarshalBinary(state)
+	}
+	to.randomGenerator = rand.New(to.randomSrc)
+
+	c.copyPatches(m)
+	c.copyTurtles(m)
+	c.copyLinks(m)
+	c.copyLinkedTurtles(m)
+
+	for breed, clone := range c.turtleBreeds {
+		clone.turtles = newTurtleAgentSet(copyAgentSet(breed.turtles.AgentSet, c.turtles))
+	}
+	for breed, clone := range c.linkBreeds {
+		clone.links = newLinkAgentSet(copyAgentSet(breed.links.AgentSet, c.links))
+	}
+	to.ShownLinks = newLinkAgentSet(copyAgentSet(m.ShownLinks.AgentSet, c.links))
+
+	c.copyEvents(m)
+
+	to.drawing = m.drawing.copy()
+	to.spatialIndex = nil
+	if m.spatialIndex != nil {
+		to.spatialIndex = m.spatialIndex.copy(c.turtles)
+	}
+
+	to.deferMovement = m.deferMovement
+	to.moved = nil
+	for _, t := range m.moved {
+		to.moved = append(to.moved, c.turtles[t])
+	}
+
+	to.floatColumns = make(map[string]*FloatColumn, len(m.floatColumns))
+	for name, column := range m.floatColumns {
+		to.floatColumns[name] = &FloatColumn{name: column.name, defaultValue: column.defaultValue, values: append([]float64(nil), column.values...)}
+	}
+	to.intColumns = make(map[string]*IntColumn, len(m.intColumns))
+	for name, column := range m.intColumns {
+		to.intColumns[name] = &IntColumn{name: column.name, defaultValue: column.defaultValue, values: append([]int(nil), column.values...)}
+	}
+	to.boolColumns = make(map[string]*BoolColumn, len(m.boolColumns))
+	for name, column := range m.boolColumns {
+		to.boolColumns[name] = &BoolColumn{name: column.name, defaultValue: column.defaultValue, values: append([]bool(nil), column.values...)}
+	}
+
+	// the cached kernel targets are only read once they are built so they can be shared
+	m.kernelTargetsMutex.Lock()
+	kernelTargets := make(map[string][]int, len(m.kernelTargetsCache))
+	for key, targets := range m.kernelTargetsCache {
+		kernelTargets[key] = targets
+	}
+	kernelTargetsOrder := append([]string(nil), m.kernelTargetsOrder...)
+	m.kernelTargetsMutex.Unlock()
+	to.kernelTargetsMutex.Lock()
+	to.kernelTargetsCache = kernelTargets
+	to.kernelTargetsOrder = kernelTargetsOrder
+	to.kernelTargetsMutex.Unlock()
+}
+
+func (c *modelCopier) copyPatches(m *Model) {
+	to := c.to
+	patches := m.Patches.List()
+	for _, p := range patches {
+		c.patches[p] = &Patch{
+			x:               p.x,
+			y:               p.y,
+			z:               p.z,
+			index:           p.index,
+			parent:          to,
+			xFloat64:        p.xFloat64,
+			yFloat64:        p.yFloat64,
+			zFloat64:        p.zFloat64,
+			Color:           p.Color,
+			patchProperties: copyProperties(p.patchProperties),
+			Label:           p.Label,
+			PlabelColor:     p.PlabelColor,
+			turtles:         make(map[*TurtleBreed]*TurtleAgentSet),
+		}
+	}
+
+	to.Patches = newPatchAgentSet(copyAgentSet(m.Patches.AgentSet, c.patches))
+	to.posOfPatches = make(map[int]*Patch, len(m.posOfPatches))
+	for index, p := range m.posOfPatches {
+		to.posOfPatches[index] = c.patches[p]
+	}
+
+	for _, p := range patches {
+		clone := c.patches[p]
+		clone.patchNeighborsMap = make(map[*Patch]string, len(p.patchNeighborsMap))
+		for neighbor, direction := range p.patchNeighborsMap {
+			clone.patchNeighborsMap[c.patches[neighbor]] = direction
+		}
+		clone.neighborsPatchMap = make(map[string]*Patch, len(p.neighborsPatchMap))
+		for direction, neighbor := range p.neighborsPatchMap {
+			clone.neighborsPatchMap[direction] = c.patches[neighbor]
+		}
+	}
+}
+
+func (c *modelCopier) copyTurtles(m *Model) {
+	to := c.to
+	for _, t := range m.turtles.List() {
+		c.turtles[t] = &Turtle{
+			xcor:                    t.xcor,
+			ycor:                    t.ycor,
+			zcor:                    t.zcor,
+			heading:                 t.heading,
+			pitch:                   t.pitch,
+			patch:                   c.patches[t.patch],
+			who:                     t.who,
+			size:                    t.size,
+			Color:                   t.Color,
+			Hidden:                  t.Hidden,
+			breed:                   c.turtleBreeds[t.breed],
+			Shape:                   t.Shape,
+			parent:                  to,
+			label:                   t.label,
+			LabelColor:              t.LabelColor,
+			penDown:                 t.penDown,
+			penSize:                 t.penSize,
+			moved:                   t.moved,
+			snapshotX:               t.snapshotX,
+			snapshotY:               t.snapshotY,
+			snapshotZ:               t.snapshotZ,
+			indexCell:               t.indexCell,
+			indexSlot:               t.indexSlot,
+			turtlePropertiesGeneral: copyProperties(t.turtlePropertiesGeneral),
+			turtlePropertiesBreed:   copyProperties(t.turtlePropertiesBreed),
+		}
+	}
+
+	to.turtles = newTurtleAgentSet(copyAgentSet(m.turtles.AgentSet, c.turtles))
+	to.whoToTurtles = make(map[int]*Turtle, len(m.whoToTurtles))
+	for who, t := range m.whoToTurtles {
+		if clone, ok := c.turtles[t]; ok {
+			to.whoToTurtles[who] = clone
+		}
+	}
+
+	for p, clone := range c.patches {
+		for breed, turtles := range p.turtles {
+			clone.turtles[c.turtleBreeds[breed]] = newTurtleAgentSet(copyAgentSet(turtles.AgentSet, c.turtles))
+		}
+	}
+}
+
+func (c *modelCopier) copyLinks(m *Model) {
+	to := c.to
+	for _, l := range m.links.List() {
+		c.links[l] = &Link{
+			id:                    l.id,
+			Color:                 l.Color,
+			end1:                  c.turtles[l.end1],
+			end2:                  c.turtles[l.end2],
+			hidden:                l.hidden,
+			directed:              l.directed,
+			breed:                 c.linkBreeds[l.breed],
+			Shape:                 l.Shape,
+			Thickness:             l.Thickness,
+			parent:                to,
+			Size:                  l.Size,
+			Label:                 l.Label,
+			LabelColor:            l.LabelColor,
+			tieMode:               l.tieMode,
+			linkPropertiesGeneral: copyProperties(l.linkPropertiesGeneral),
+			linkPropertiesBreed:   copyProperties(l.linkPropertiesBreed),
+		}
+	}
+
+	to.links = newLinkAgentSet(copyAgentSet(m.links.AgentSet, c.links))
+}
+
+// copies the links of the turtles that are alive, killed turtles keep their entry in the model so those are left behind
+func (c *modelCopier) copyLinkedTurtles(m *Model) {
+	c.to.linkedTurtles = make(map[*Turtle]*turtleLinks, len(c.turtles))
+	for t, clone := range c.turtles {
+		links, ok := m.linkedTurtles[t]
+		if !ok {
+			c.to.linkedTurtles[clone] = newTurtleLinks()
+			continue
+		}
+		c.to.linkedTurtles[clone] = &turtleLinks{
+			allLinksDirectedOut:     c.linkKeys(links.allLinksDirectedOut),
+			allLinksDirectedIn:      c.linkKeys(links.allLinksDirectedIn),
+			allLinksUndirected:      c.linkKeys(links.allLinksUndirected),
+			allTurtlesDirectedOut:   c.turtleLinkSets(links.allTurtlesDirectedOut),
+			allTurtlesDirectedIn:    c.turtleLinkSets(links.allTurtlesDirectedIn),
+			allTurtlesUndirected:    c.turtleLinkSets(links.allTurtlesUndirected),
+			turtlesDirectedOutBreed: c.breedTurtleLinks(links.turtlesDirectedOutBreed),
+			turtlesDirectedInBreed:  c.breedTurtleLinks(links.turtlesDirectedInBreed),
+			turtlesUndirectedBreed:  c.breedTurtleLinks(links.turtlesUndirectedBreed),
+			tiedLinks:               newLinkAgentSet(copyAgentSet(links.tiedLinks.AgentSet, c.links)),
+		}
+	}
+}
+
+func (c *modelCopier) linkKeys(links map[*Link]interface{}) map[*Link]interface{} {
+	copied := make(map[*Link]interface{}, len(links))
+	for l, value := range links {
+		copied[c.links[l]] = value
+	}
+	return copied
+}
+
+func (c *modelCopier) turtleLinkSets(sets map[*Turtle]*LinkAgentSet) map[*Turtle]*LinkAgentSet {
+	copied := make(map[*Turtle]*LinkAgentSet, len(sets))
+	for t, links := range sets {
+		copied[c.turtles[t]] = newLinkAgentSet(copyAgentSet(links.AgentSet, c.links))
+	}
+	return copied
+}
+
+func (c *modelCopier) breedTurtleLinks(breeds map[*LinkBreed]map[*Turtle]*Link) map[*LinkBreed]map[*Turtle]*Link {
+	copied := make(map[*LinkBreed]map[*Turtle]*Link, len(breeds))
+	for breed, turtles := range breeds {
+		copiedTurtles := make(map[*Turtle]*Link, len(turtles))
+		for t, l := range turtles {
+			copiedTurtles[c.turtles[t]] = c.links[l]
+		}
+		copied[c.linkBreeds[breed]] = copiedTurtles
+	}
+	return copied
+}
+
+// copies the events that are waiting to fire, events bound to agents that died are dropped since they would never fire
+// the events keep their times and sequence numbers so they fire in the same order
+func (c *modelCopier) copyEvents(m *Model) {
+	to := c.to
+	to.now = m.now
+	to.eventSequence = m.eventSequence
+	to.events = eventQueue{}
+
+	for _, e := range m.events {
+		if !e.agentAlive() {
+			continue
+		}
+
+		copied := &Event{
+			name:      e.name,
+			operation: e.operation,
+			time:      e.time,
+			interval:  e.interval,
+			sequence:  e.sequence,
+			parent:    to,
+			index:     len(to.events),
+			cancelled: e.cancelled,
+		}
+		if e.turtle != nil {
+			copied.turtle = c.turtles[e.turtle]
+		}
+		if e.patch != nil {
+			copied.patch = c.patches[e.patch]
+		}
+		if e.link != nil {
+			copied.link = c.links[e.link]
+		}
+		to.events = append(to.events, copied)
+	}
+	heap.Init(&to.events)
+}
+
+// returns a copy of the agentset with every agent swapped for its copy, the order of the agents is kept
+func copyAgentSet[T interface {
+	Agent
+	comparable
+}](a *AgentSet[T], copies map[T]T) *AgentSet[T] {
+	set := sortedset.NewSortedSet()
+	if a != nil {
+		for _, agent := range a.List() {
+			if copied, ok := copies[agent]; ok {
+				set.Add(copied)
+			}
+		}
+	}
+	return newAgentSet[T](set)
+}
+
+// returns a copy of the properties map, nil stays nil
+func copyProperties(properties map[string]interface{}) map[string]interface{} {
+	if properties == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(properties))
+	for key, value := range properties {
+		copied[key] = value
+	}
+	return copied
+}
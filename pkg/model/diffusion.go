@@ -0,0 +1,372 @@
+package model
+
+import "fmt"
+
+// DiffusionBoundary is what happens to a patch variable at the edges of the world when it is diffused or convolved
+type DiffusionBoundary int
+
+const (
+	BoundaryWorld   DiffusionBoundary = iota // wrap where the world wraps and reflect everywhere else
+	BoundaryReflect                          // nothing crosses the edge, what would have left the world stays on the patch (Neumann)
+	BoundaryWrap                             // the edges wrap around on every axis, even if the world does not
+	BoundaryFixed                            // the world is surrounded by patches that always hold BoundaryValue (Dirichlet)
+)
+
+// number of kernel target tables kept on the model, the least recently used is dropped once there are more
+// diffuse, convolve and their reverse tables with a few kernels fit, models that keep changing kernels don't grow
+const maxKernelTargetsCached = 8
+
+// KernelWeight is one entry of a diffusion or convolution kernel
+// the offsets are relative to the patch and the weight is how much goes to or comes from the patch at that offset
+type KernelWeight struct {
+	DX     int
+	DY     int
+	DZ     int
+	Weight float64
+}
+
+// DiffusionSettings holds the settings for DiffuseWithSettings
+type DiffusionSettings struct {
+	Rate          float64           // fraction of each patch's value that is shared with the kernel each iteration, between 0 and 1
+	Kernel        []KernelWeight    // where the shared amount goes, the weights are normalized to add up to 1, defaults to the 8 neighbors or the 26 neighbors in 3D
+	Boundary      DiffusionBoundary // what happens at the edges of the world
+	BoundaryValue float64           // value of the patches outside the world when the boundary is BoundaryFixed
+	Iterations    int               // number of times to diffuse, defaults to 1
+	Evaporation   float64           // fraction of each patch's value that is removed after each iteration, between 0 and 1
+}
+
+// ConvolutionSettings holds the settings for Convolve
+type ConvolutionSettings struct {
+	Kernel        []KernelWeight    // weights of the patches around each patch that are summed into the new value, include an offset of 0 0 0 for the patch itself
+	Boundary      DiffusionBoundary // what happens at the edges of the world, BoundaryReflect uses the value of the closest patch inside the world
+	BoundaryValue float64           // value of the patches outside the world when the boundary is BoundaryFixed
+	Iterations    int               // number of times to convolve, defaults to 1
+}
+
+// returns a kernel that shares evenly with the patches to the top, bottom, left, and right
+func DiffusionKernel4() []KernelWeight {
+	return AnisotropicKernel(1, 1, 0)
+}
+
+// returns a kernel that shares evenly with the 8 surrounding patches
+func DiffusionKernel8() []KernelWeight {
+	kernel := []KernelWeight{}
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			kernel = append(kernel, KernelWeight{DX: dx, DY: dy, Weight: 1})
+		}
+	}
+	return kernel
+}
+
+// returns a kernel that shares evenly with the 6 patches that share a face with the patch in a 3D world
+func DiffusionKernel6() []KernelWeight {
+	return AnisotropicKernel(1, 1, 1)
+}
+
+// returns a kernel that shares evenly with the 26 surrounding patches in a 3D world
+func DiffusionKernel26() []KernelWeight {
+	kernel := []KernelWeight{}
+	for dz := -1; dz <= 1; dz++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 && dz == 0 {
+					continue
+				}
+				kernel = append(kernel, KernelWeight{DX: dx, DY: dy, DZ: dz, Weight: 1})
+			}
+		}
+	}
+	return kernel
+}
+
+// returns a kernel that shares with the patches along each axis with a different weight per axis
+// a weight of 0 leaves that axis out
+func AnisotropicKernel(weightX float64, weightY float64, weightZ float64) []KernelWeight {
+	kernel := []KernelWeight{}
+	if weightX != 0 {
+		kernel = append(kernel, KernelWeight{DX: -1, Weight: weightX}, KernelWeight{DX: 1, Weight: weightX})
+	}
+	if weightY != 0 {
+		kernel = append(kernel, KernelWeight{DY: -1, Weight: weightY}, KernelWeight{DY: 1, Weight: weightY})
+	}
+	if weightZ != 0 {
+		kernel = append(kernel, KernelWeight{DZ: -1, Weight: weightZ}, KernelWeight{DZ: 1, Weight: weightZ})
+	}
+	return kernel
+}
+
+// diffuses the patch variable using the kernel, boundary, iterations and evaporation in the settings
+// each iteration every patch keeps 1 - Rate of its value and shares the rest with the patches in the kernel
+// with BoundaryWorld, BoundaryReflect and BoundaryWrap and no evaporation the total of the variable stays the same
+// the patch variable has to be a float64 patch property or a float column
+func (m *Model) DiffuseWithSettings(patchVariable string, settings DiffusionSettings) error {
+	if settings.Rate < 0 || settings.Rate > 1 {
+		return ErrDiffusionRate
+	}
+
+	if settings.Evaporation < 0 || settings.Evaporation > 1 {
+		return ErrDiffusionEvaporation
+	}
+
+	kernel := settings.Kernel
+	if kernel == nil {
+		kernel = DiffusionKernel8()
+		if m.Is3D() {
+			kernel = DiffusionKernel26()
+		}
+	}
+
+	weights, err := normalizeKernel(kernel)
+	if err != nil {
+		return err
+	}
+
+	values, write, err := m.readPatchFloats(patchVariable)
+	if err != nil {
+		return err
+	}
+
+	// where each patch sends its share, and where each patch gets its share from
+	// -1 means the patch on the other end is outside the world
+	targets := m.kernelTargets(kernel, settings.Boundary, 1, false)
+	sources := m.kernelTargets(kernel, settings.Boundary, -1, false)
+
+	iterations := settings.Iterations
+	if iterations < 1 {
+		iterations = 1
+	}
+
+	next := make([]float64, len(values))
+	k := len(kernel)
+
+	for iteration := 0; iteration < iterations; iteration++ {
+		for i, value := range values {
+			next[i] = value * (1 - settings.Rate)
+		}
+
+		for i, value := range values {
+			share := value * settings.Rate
+			for j := 0; j < k; j++ {
+				target := targets[i*k+j]
+				if target >= 0 {
+					next[target] += share * weights[j]
+				} else if settings.Boundary != BoundaryFixed {
+					// the share would have left the world so it stays on the patch
+					next[i] += share * weights[j]
+				}
+			}
+		}
+
+		// the patches outside the world share their fixed value with the patches along the edge
+		if settings.Boundary == BoundaryFixed && settings.BoundaryValue != 0 {
+			inflow := settings.BoundaryValue * settings.Rate
+			for i := range next {
+				for j := 0; j < k; j++ {
+					if sources[i*k+j] < 0 {
+						next[i] += inflow * weights[j]
+					}
+				}
+			}
+		}
+
+		if settings.Evaporation > 0 {
+			for i := range next {
+				next[i] *= 1 - settings.Evaporation
+			}
+		}
+
+		values, next = next, values
+	}
+
+	write(values)
+
+	return nil
+}
+
+// replaces each patch's value of the patch variable with the weighted sum of the values of the patches in the kernel
+// unlike diffusion the weights are not normalized so kernels can be used for blurs, gradients, edge detection etc
+// the patch variable has to be a float64 patch property or a float column
+func (m *Model) Convolve(patchVariable string, settings ConvolutionSettings) error {
+	if len(settings.Kernel) == 0 {
+		return ErrKernelEmpty
+	}
+
+	values, write, err := m.readPatchFloats(patchVariable)
+	if err != nil {
+		return err
+	}
+
+	// patches outside the world are replaced by the closest patch inside the world when reflecting
+	sources := m.kernelTargets(settings.Kernel, settings.Boundary, 1, settings.Boundary != BoundaryFixed)
+
+	iterations := settings.Iterations
+	if iterations < 1 {
+		iterations = 1
+	}
+
+	next := make([]float64, len(values))
+	k := len(settings.Kernel)
+
+	for iteration := 0; iteration < iterations; iteration++ {
+		for i := range values {
+			sum := 0.0
+			for j, entry := range settings.Kernel {
+				source := sources[i*k+j]
+				if source >= 0 {
+					sum += values[source] * entry.Weight
+				} else {
+					sum += settings.BoundaryValue * entry.Weight
+				}
+			}
+			next[i] = sum
+		}
+
+		values, next = next, values
+	}
+
+	write(values)
+
+	return nil
+}
+
+// returns the weights of the kernel scaled so they add up to 1
+func normalizeKernel(kernel []KernelWeight) ([]float64, error) {
+	if len(kernel) == 0 {
+		return nil, ErrKernelEmpty
+	}
+
+	total := 0.0
+	for _, entry := range kernel {
+		if entry.Weight < 0 {
+			return nil, ErrKernelNegativeWeight
+		}
+		total += entry.Weight
+	}
+
+	if total == 0 {
+		return nil, ErrKernelEmpty
+	}
+
+	weights := make([]float64, len(kernel))
+	for i, entry := range kernel {
+		weights[i] = entry.Weight / total
+	}
+	return weights, nil
+}
+
+// returns the values of the patch variable indexed by patch index and a function to write them back
+func (m *Model) readPatchFloats(patchVariable string) ([]float64, func(values []float64), error) {
+	if column, ok := m.floatColumns[patchVariable]; ok {
+		values := make([]float64, len(column.values))
+		copy(values, column.values)
+		return values, func(values []float64) {
+			copy(column.values, values)
+		}, nil
+	}
+
+	get, set, ok := m.patchFloatAccessors(patchVariable)
+	if !ok {
+		return nil, nil, ErrPatchVariableNotFloat
+	}
+
+	values := make([]float64, len(m.posOfPatches))
+	for i := range values {
+		values[i] = get(m.posOfPatches[i])
+	}
+
+	return values, func(values []float64) {
+		for i, value := range values {
+			set(m.posOfPatches[i], value)
+		}
+	}, nil
+}
+
+// returns the index of the patch at each kernel offset for every patch, laid out as patch index * len(kernel) + kernel entry
+// direction is 1 to follow the offsets and -1 to go the opposite way
+// offsets that leave the world are wrapped depending on the boundary, otherwise they are clamped to the edge if clamp is true or -1
+// the tables only depend on the offsets so the most recently used ones are cached on the model, see maxKernelTargetsCached
+func (m *Model) kernelTargets(kernel []KernelWeight, boundary DiffusionBoundary, direction int, clamp bool) []int {
+	key := fmt.Sprint(boundary, direction, clamp)
+	for _, entry := range kernel {
+		key += fmt.Sprint(" ", entry.DX, entry.DY, entry.DZ)
+	}
+
+	m.kernelTargetsMutex.Lock()
+	defer m.kernelTargetsMutex.Unlock()
+
+	if targets, ok := m.kernelTargetsCache[key]; ok {
+		m.useKernelTargets(key)
+		return targets
+	}
+
+	width := m.worldWidth
+	height := m.worldHeight
+	depth := m.worldDepth
+
+	wrapX := boundary == BoundaryWrap || (boundary == BoundaryWorld && m.wrappingX)
+	wrapY := boundary == BoundaryWrap || (boundary == BoundaryWorld && m.wrappingY)
+	wrapZ := boundary == BoundaryWrap
+
+	// moves the coordinate along an axis returning false if it left the world
+	move := func(cor int, offset int, size int, wrap bool) (int, bool) {
+		cor += offset
+		if cor >= 0 && cor < size {
+			return cor, true
+		}
+		if wrap {
+			return ((cor % size) + size) % size, true
+		}
+		if clamp {
+			return clampInt(cor, 0, size-1), true
+		}
+		return cor, false
+	}
+
+	targets := make([]int, width*height*depth*len(kernel))
+
+	for z := 0; z < depth; z++ {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				i := (z*height+y)*width + x
+				for j, entry := range kernel {
+					tx, okX := move(x, entry.DX*direction, width, wrapX)
+					ty, okY := move(y, entry.DY*direction, height, wrapY)
+					tz, okZ := move(z, entry.DZ*direction, depth, wrapZ)
+					if okX && okY && okZ {
+						targets[i*len(kernel)+j] = (tz*height+ty)*width + tx
+					} else {
+						targets[i*len(kernel)+j] = -1
+					}
+				}
+			}
+		}
+	}
+
+	if m.kernelTargetsCache == nil {
+		m.kernelTargetsCache = make(map[string][]int)
+	}
+	m.kernelTargetsCache[key] = targets
+	m.kernelTargetsOrder = append(m.kernelTargetsOrder, key)
+	if len(m.kernelTargetsOrder) > maxKernelTargetsCached {
+		delete(m.kernelTargetsCache, m.kernelTargetsOrder[0])
+		m.kernelTargetsOrder = m.kernelTargetsOrder[1:]
+	}
+
+	return targets
+}
+
+// moves the key to the most recently used end of the kernel target cache
+// has to be called with the kernel targets mutex held
+func (m *Model) useKernelTargets(key string) {
+	for i, k := range m.kernelTargetsOrder {
+		if k == key {
+			m.kernelTargetsOrder = append(m.kernelTargetsOrder[:i], m.kernelTargetsOrder[i+1:]...)
+			break
+		}
+	}
+	m.kernelTargetsOrder = append(m.kernelTargetsOrder, key)
+}
@@ -0,0 +1,333 @@
+package model
+
+import (
+	"math"
+	"sync"
+)
+
+// default number of segments and stamps the drawing layer holds before the oldest ones get dropped
+const DefaultMaxDrawingSegments = 100000
+
+// DrawingSegment is a line left on the drawing layer by a turtle with its pen down or by a stamped link
+type DrawingSegment struct {
+	X1    float64
+	Y1    float64
+	Z1    float64
+	X2    float64
+	Y2    float64
+	Z2    float64
+	Color Color
+	Size  float64
+}
+
+// DrawingStamp is an image of a turtle left on the drawing layer
+type DrawingStamp struct {
+	X       float64
+	Y       float64
+	Z       float64
+	Heading float64 // heading in degrees
+	Size    float64
+	Shape   string
+	Color   Color
+}
+
+// Drawing is a layer that sits on top of the patches and holds the trails and stamps left by turtles and links
+// it is kept separate from the patches so drawing doesn't change any patch state
+// the layer holds a bounded number of segments and stamps, once full the oldest ones are dropped
+type Drawing struct {
+	mu sync.RWMutex
+
+	segments      []DrawingSegment // ring buffer of segments
+	segmentsStart int              // index of the oldest segment in the ring buffer
+
+	stamps      []DrawingStamp // ring buffer of stamps
+	stampsStart int            // index of the oldest stamp in the ring buffer
+
+	maxSegments int
+	version     uint64 // incremented every time the drawing changes
+}
+
+func newDrawing(maxSegments int) *Drawing {
+	if maxSegments <= 0 {
+		maxSegments = DefaultMaxDrawingSegments
+	}
+	return &Drawing{
+		maxSegments: maxSegments,
+	}
+}
+
+// adds a segment to the drawing, dropping the oldest segment if the drawing is full
+func (d *Drawing) AddSegment(segment DrawingSegment) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.segments) < d.maxSegments {
+		d.segments = append(d.segments, segment)
+	} else {
+		d.segments[d.segmentsStart] = segment
+		d.segmentsStart = (d.segmentsStart + 1) % d.maxSegments
+	}
+	d.version++
+}
+
+// adds a stamp to the drawing, dropping the oldest stamp if the drawing is full
+func (d *Drawing) AddStamp(stamp DrawingStamp) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.stamps) < d.maxSegments {
+		d.stamps = append(d.stamps, stamp)
+	} else {
+		d.stamps[d.stampsStart] = stamp
+		d.stampsStart = (d.stampsStart + 1) % d.maxSegments
+	}
+	d.version++
+}
+
+// returns the segments in the order they were drawn
+func (d *Drawing) Segments() []DrawingSegment {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	segments := make([]DrawingSegment, 0, len(d.segments))
+	segments = append(segments, d.segments[d.segmentsStart:]...)
+	segments = append(segments, d.segments[:d.segmentsStart]...)
+	return segments
+}
+
+// returns the stamps in the order they were made
+func (d *Drawing) Stamps() []DrawingStamp {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	stamps := make([]DrawingStamp, 0, len(d.stamps))
+	stamps = append(stamps, d.stamps[d.stampsStart:]...)
+	stamps = append(stamps, d.stamps[:d.stampsStart]...)
+	return stamps
+}
+
+// returns the max number of segments and the max number of stamps the drawing holds
+func (d *Drawing) MaxSegments() int {
+	return d.maxSegments
+}
+
+// returns a number that changes every time the drawing changes
+// useful for frontends to know when the drawing needs to be redrawn
+func (d *Drawing) Version() uint64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.version
+}
+
+// removes all segments and stamps from the drawing
+func (d *Drawing) Clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.segments = nil
+	d.segmentsStart = 0
+	d.stamps = nil
+	d.stampsStart = 0
+	d.version++
+}
+
+// returns a copy of the drawing that shares nothing with it
+func (d *Drawing) copy() *Drawing {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return &Drawing{
+		segments:      append([]DrawingSegment(nil), d.segments...),
+		segmentsStart: d.segmentsStart,
+		stamps:        append([]DrawingStamp(nil), d.stamps...),
+		stampsStart:   d.stampsStart,
+		maxSegments:   d.maxSegments,
+		version:       d.version,
+	}
+}
+
+// returns the drawing layer of the model
+func (m *Model) Drawing() *Drawing {
+	return m.drawing
+}
+
+// removes all trails and stamps from the drawing layer
+func (m *Model) ClearDrawing() {
+	m.drawing.Clear()
+}
+
+// puts the pen down so the turtle leaves a trail when it moves
+func (t *Turtle) PenDown() {
+	t.penDown = true
+}
+
+// lifts the pen up so the turtle stops leaving a trail
+func (t *Turtle) PenUp() {
+	t.penDown = false
+}
+
+// returns if the turtle's pen is down
+func (t *Turtle) IsPenDown() bool {
+	return t.penDown
+}
+
+// sets the width of the trail the turtle leaves
+func (t *Turtle) SetPenSize(size float64) {
+	t.penSize = size
+}
+
+// returns the width of the trail the turtle leaves
+func (t *Turtle) GetPenSize() float64 {
+	return t.penSize
+}
+
+// leaves an image of the turtle on the drawing layer
+func (t *Turtle) Stamp() {
+	if t.parent == nil {
+		return
+	}
+	x, y, z := t.position()
+	t.parent.drawing.AddStamp(DrawingStamp{
+		X:       x,
+		Y:       y,
+		Z:       z,
+		Heading: t.GetHeading(),
+		Size:    t.size,
+		Shape:   t.Shape,
+		Color:   t.Color,
+	})
+}
+
+// leaves an image of the link on the drawing layer
+func (l *Link) Stamp() {
+	if l.parent == nil {
+		return
+	}
+	x1, y1, z1 := l.end1.position()
+	x2, y2, z2 := l.end2.position()
+	l.parent.drawing.AddSegment(DrawingSegment{
+		X1:    x1,
+		Y1:    y1,
+		Z1:    z1,
+		X2:    x2,
+		Y2:    y2,
+		Z2:    z2,
+		Color: l.Color,
+		Size:  float64(l.Size),
+	})
+}
+
+// draws the trail of a turtle that has moved by dx dy dz and is now at x y z
+// if the move wrapped around the world then the trail is split at every edge it crosses
+// has to be called with the position lock held
+func (t *Turtle) drawTrail(dx float64, dy float64, dz float64) {
+	if !t.penDown {
+		return
+	}
+
+	m := t.parent
+
+	segment := DrawingSegment{
+		X1:    t.xcor - dx,
+		Y1:    t.ycor - dy,
+		Z1:    t.zcor - dz,
+		X2:    t.xcor,
+		Y2:    t.ycor,
+		Z2:    t.zcor,
+		Color: t.Color,
+		Size:  t.penSize,
+	}
+
+	if m.insideWorldXY(segment.X1, segment.Y1) {
+		m.drawing.AddSegment(segment)
+		return
+	}
+
+	// the turtle wrapped, maybe more than once if it moved further than the world is wide
+	// so the trail is walked from the old position and cut each time it leaves the world, carrying on from the opposite edge
+	x, y, _ := m.convertXYToInBounds(segment.X1, segment.Y1)
+	z := segment.Z1
+	width := m.maxXCor - m.minXCor
+	height := m.maxYCor - m.minYCor
+	left := 1.0 // part of the move that is still to be drawn
+
+	// every piece but the last ends at an edge, so this is enough pieces for any move
+	pieces := int(math.Abs(dx)/width+math.Abs(dy)/height) + 3
+	for i := 0; i < pieces && left > 0; i++ {
+		piece := segment
+		piece.X1, piece.Y1, piece.Z1 = x, y, z
+		piece.X2, piece.Y2, piece.Z2 = x+dx*left, y+dy*left, z+dz*left
+
+		end, inside := m.clipSegmentToWorld(&piece)
+		if !inside {
+			return
+		}
+		m.drawing.AddSegment(piece)
+
+		used := end * left
+		left -= used
+		x, y, z = x+dx*used, y+dy*used, z+dz*used
+
+		if m.wrappingX && dx < 0 && x <= m.minXCor {
+			x += width
+		} else if m.wrappingX && dx > 0 && x >= m.maxXCor {
+			x -= width
+		}
+		if m.wrappingY && dy < 0 && y <= m.minYCor {
+			y += height
+		} else if m.wrappingY && dy > 0 && y >= m.maxYCor {
+			y -= height
+		}
+	}
+}
+
+// returns if the x y is inside the world without wrapping
+func (m *Model) insideWorldXY(x float64, y float64) bool {
+	return x >= m.minXCor && x < m.maxXCor && y >= m.minYCor && y < m.maxYCor
+}
+
+// clips the segment so it only covers the part inside the world
+// returns how far along the segment the clipped part ends, from 0 to 1, and false if no part of the segment is inside the world
+func (m *Model) clipSegmentToWorld(segment *DrawingSegment) (float64, bool) {
+	dx := segment.X2 - segment.X1
+	dy := segment.Y2 - segment.Y1
+	dz := segment.Z2 - segment.Z1
+
+	// liang-barsky clipping against the world bounds
+	tMin := 0.0
+	tMax := 1.0
+	edges := [][2]float64{
+		{-dx, segment.X1 - m.minXCor},
+		{dx, m.maxXCor - segment.X1},
+		{-dy, segment.Y1 - m.minYCor},
+		{dy, m.maxYCor - segment.Y1},
+	}
+	for _, edge := range edges {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, false
+			}
+			continue
+		}
+		r := q / p
+		if p < 0 {
+			tMin = math.Max(tMin, r)
+		} else {
+			tMax = math.Min(tMax, r)
+		}
+		if tMin > tMax {
+			return 0, false
+		}
+	}
+
+	x1, y1, z1 := segment.X1, segment.Y1, segment.Z1
+	segment.X1 = x1 + tMin*dx
+	segment.Y1 = y1 + tMin*dy
+	segment.Z1 = z1 + tMin*dz
+	segment.X2 = x1 + tMax*dx
+	segment.Y2 = y1 + tMax*dy
+	segment.Z2 = z1 + tMax*dz
+
+	return tMax, tMin < tMax
+}
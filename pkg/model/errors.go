@@ -4,7 +4,47 @@ import "fmt"
 
 // Errors
 var (
-	ErrNoLinksInAgentSet   = fmt.Errorf("no links in agent set")
-	ErrNoTurtlesInAgentSet = fmt.Errorf("no turtles in agent set")
-	ErrNoPatchesInAgentSet = fmt.Errorf("no patches in agent set")
+	ErrNoLinksInAgentSet    = fmt.Errorf("no links in agent set")
+	ErrNoTurtlesInAgentSet  = fmt.Errorf("no turtles in agent set")
+	ErrNoPatchesInAgentSet  = fmt.Errorf("no patches in agent set")
+	ErrNoAgentsInAgentSet   = fmt.Errorf("no agents in agent set")
+	ErrVarianceTooFewAgents = fmt.Errorf("variance needs at least 2 agents")
+	ErrNoAgentsInQuery      = fmt.Errorf("no agents in query")
+	ErrEventNoOperation     = fmt.Errorf("event has no name or operation")
+	ErrEventInPast          = fmt.Errorf("event time is before the current model time")
+	ErrEventBadInterval     = fmt.Errorf("event interval is negative")
+
+	ErrPatchVariableNotFloat = fmt.Errorf("patch variable is not a float64 property or float column")
+	ErrDiffusionRate         = fmt.Errorf("diffusion rate is outside of 0 and 1")
+	ErrDiffusionEvaporation  = fmt.Errorf("evaporation is outside of 0 and 1")
+	ErrKernelEmpty           = fmt.Errorf("kernel has no weights")
+	ErrKernelNegativeWeight  = fmt.Errorf("diffusion kernel has a negative weight")
+
+	ErrNoPath           = fmt.Errorf("no path between the patches")
+	ErrPathConnectivity = fmt.Errorf("path connectivity has to be 4 or 8")
+
+	ErrSnapshotNil          = fmt.Errorf("snapshot is nil")
+	ErrSnapshotBreedMissing = fmt.Errorf("snapshot has a breed the model doesn't have")
 )
+
+// PanicError is what an operation asked in parallel panicking on an agent turns into
+// AskParallel panics with it on the goroutine that called it, the concurrency package's Executor returns it
+// the rest of the agents that weren't started yet are skipped
+type PanicError struct {
+	AgentID int         // id of the agent the operation panicked on, see Agent
+	Agent   Agent       // agent the operation panicked on
+	Value   interface{} // value the operation panicked with
+	Stack   []byte      // stack of the goroutine when it panicked
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("operation panicked on agent %d: %v", e.AgentID, e.Value)
+}
+
+// returns the value the operation panicked with if it was an error
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
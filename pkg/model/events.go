@@ -0,0 +1,255 @@
+package model
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// EventOperation is run when a scheduled event fires
+// func(e *Event)
+type EventOperation func(e *Event)
+
+// EventSettings describes an event to put on the model's schedule
+type EventSettings struct {
+	Name      string         // name of a registered event handler, events that use a name are saved with the model
+	Operation EventOperation // run instead of the named handler if provided, events that only have an operation can't be saved
+	Time      float64        // model time the event first fires at, can be fractional
+	Interval  float64        // if greater than zero the event repeats at this interval
+	Turtle    *Turtle        // optional turtle the event is bound to, the event is dropped if the turtle dies first
+	Patch     *Patch         // optional patch the event is bound to
+	Link      *Link          // optional link the event is bound to, the event is dropped if the link dies first
+}
+
+// Event is an action scheduled to happen at a point in model time
+type Event struct {
+	name      string
+	operation EventOperation
+	time      float64
+	interval  float64
+	sequence  uint64 // order the event was scheduled in, breaks ties between events at the same time
+
+	turtle *Turtle
+	patch  *Patch
+	link   *Link
+
+	parent    *Model
+	index     int // position of the event in the queue, -1 if not in the queue
+	cancelled bool
+}
+
+// priority queue of events ordered by time and then by the order they were scheduled in
+type eventQueue []*Event
+
+func (q eventQueue) Len() int {
+	return len(q)
+}
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].time == q[j].time {
+		return q[i].sequence < q[j].sequence
+	}
+	return q[i].time < q[j].time
+}
+
+func (q eventQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *eventQueue) Push(x interface{}) {
+	e := x.(*Event)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*q = old[:n-1]
+	return e
+}
+
+// registers a named event handler
+// named handlers are looked up when the event fires, so they can be registered after a saved model is loaded
+func (m *Model) RegisterEventHandler(name string, operation EventOperation) {
+	m.eventHandlers[name] = operation
+}
+
+// ScheduleEvent puts an event on the model's schedule
+// events fire when the model clock reaches their time, events at the same time fire in the order they were scheduled
+func (m *Model) ScheduleEvent(settings EventSettings) (*Event, error) {
+	if settings.Name == "" && settings.Operation == nil {
+		return nil, ErrEventNoOperation
+	}
+
+	if settings.Time < m.now {
+		return nil, ErrEventInPast
+	}
+
+	if settings.Interval < 0 {
+		return nil, ErrEventBadInterval
+	}
+
+	e := &Event{
+		name:      settings.Name,
+		operation: settings.Operation,
+		time:      settings.Time,
+		interval:  settings.Interval,
+		turtle:    settings.Turtle,
+		patch:     settings.Patch,
+		link:      settings.Link,
+		parent:    m,
+		index:     -1,
+	}
+
+	m.pushEvent(e)
+
+	return e, nil
+}
+
+// ScheduleEventIn puts an event on the schedule delay time units from now, the settings time is ignored
+func (m *Model) ScheduleEventIn(delay float64, settings EventSettings) (*Event, error) {
+	settings.Time = m.now + delay
+	return m.ScheduleEvent(settings)
+}
+
+func (m *Model) pushEvent(e *Event) {
+	e.sequence = m.eventSequence
+	m.eventSequence++
+	heap.Push(&m.events, e)
+}
+
+// returns the current model time
+// this is the tick count unless events have been run past it with RunEvents
+func (m *Model) Clock() float64 {
+	return m.now
+}
+
+// sets the model time without firing any events
+// used when restoring a saved model
+func (m *Model) SetClock(time float64) {
+	m.now = time
+}
+
+// returns the events that are waiting to fire in the order that they will fire
+func (m *Model) Events() []*Event {
+	events := make([]*Event, len(m.events))
+	copy(events, m.events)
+	sort.Slice(events, func(i, j int) bool {
+		return eventQueue(events).Less(i, j)
+	})
+	return events
+}
+
+// removes all events from the schedule
+func (m *Model) ClearEvents() {
+	for _, e := range m.events {
+		e.index = -1
+		e.cancelled = true
+	}
+	m.events = eventQueue{}
+}
+
+// RunEvents fires all events with a time up to and including the time passed in
+// while an event is firing the model clock is set to the event's time, afterwards it is set to the time passed in
+func (m *Model) RunEvents(until float64) {
+	for len(m.events) > 0 && m.events[0].time <= until {
+		e := heap.Pop(&m.events).(*Event)
+
+		if e.time > m.now {
+			m.now = e.time
+		}
+
+		if !e.agentAlive() {
+			continue
+		}
+
+		if operation := e.resolveOperation(); operation != nil {
+			operation(e)
+		}
+
+		// put repeating events back on the schedule unless they were cancelled while firing
+		if e.interval > 0 && !e.cancelled && e.agentAlive() {
+			e.time += e.interval
+			m.pushEvent(e)
+		}
+	}
+
+	if until > m.now {
+		m.now = until
+	}
+}
+
+// Cancel takes the event off the schedule, cancelling a repeating event stops it from repeating
+func (e *Event) Cancel() {
+	if e.cancelled {
+		return
+	}
+	e.cancelled = true
+
+	if e.index >= 0 && e.parent != nil {
+		heap.Remove(&e.parent.events, e.index)
+	}
+}
+
+// returns if the event has been cancelled
+func (e *Event) Cancelled() bool {
+	return e.cancelled
+}
+
+// returns the name of the handler the event runs, empty if the event only has an operation
+func (e *Event) Name() string {
+	return e.name
+}
+
+// returns the time the event fires at next
+func (e *Event) Time() float64 {
+	return e.time
+}
+
+// returns the interval that the event repeats at, 0 if it only fires once
+func (e *Event) Interval() float64 {
+	return e.interval
+}
+
+// returns the turtle the event is bound to or nil
+func (e *Event) Turtle() *Turtle {
+	return e.turtle
+}
+
+// returns the patch the event is bound to or nil
+func (e *Event) Patch() *Patch {
+	return e.patch
+}
+
+// returns the link the event is bound to or nil
+func (e *Event) Link() *Link {
+	return e.link
+}
+
+// returns the model the event belongs to
+func (e *Event) Model() *Model {
+	return e.parent
+}
+
+// dead turtles and links are zeroed out so they no longer have a parent
+func (e *Event) agentAlive() bool {
+	if e.turtle != nil && e.turtle.parent == nil {
+		return false
+	}
+	if e.link != nil && e.link.parent == nil {
+		return false
+	}
+	return true
+}
+
+func (e *Event) resolveOperation() EventOperation {
+	if e.operation != nil {
+		return e.operation
+	}
+	return e.parent.eventHandlers[e.name]
+}
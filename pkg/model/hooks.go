@@ -0,0 +1,119 @@
+package model
+
+import "sync"
+
+// HookKind is a kind of lifecycle change that hooks can subscribe to
+type HookKind int
+
+const (
+	HookTurtleBorn         HookKind = iota // a turtle was created by CreateTurtles, a breed's CreateAgents, Hatch or Sprout, runs after the operation given to them so a turtle the operation killed gets none
+	HookTurtleDied                         // a turtle is about to be killed, it can still be read
+	HookTurtleBreedChanged                 // a turtle's breed was changed by SetBreed
+	HookLinkCreated                        // a link was created
+	HookLinkDied                           // a link is about to be killed, either directly or because one of its ends died
+	HookLinkBreedChanged                   // a link's breed was changed by SetBreed
+	HookTick                               // the tick counter went up through Tick or TickAdvance
+)
+
+// Hook describes the lifecycle change a hook is run for
+// only the fields that make sense for the kind are set
+type Hook struct {
+	Kind  HookKind
+	Model *Model
+
+	Turtle *Turtle // the turtle for turtle hooks
+	Link   *Link   // the link for link hooks
+
+	OldTurtleBreed *TurtleBreed // breed of the turtle before HookTurtleBreedChanged
+	NewTurtleBreed *TurtleBreed // breed of the turtle for turtle hooks
+	OldLinkBreed   *LinkBreed   // breed of the link before HookLinkBreedChanged
+	NewLinkBreed   *LinkBreed   // breed of the link for link hooks
+
+	Ticks int // the tick counter for HookTick
+}
+
+// HookOperation is run when a lifecycle change the hook is subscribed to happens
+// func(h *Hook)
+type HookOperation func(h *Hook)
+
+type hookSubscription struct {
+	id        int
+	kind      HookKind
+	operation HookOperation
+}
+
+// the hooks subscribed to a model
+type hooks struct {
+	mu            sync.Mutex
+	subscriptions []hookSubscription // in the order they were subscribed
+	nextID        int
+}
+
+// Subscribe runs the operation every time a lifecycle change of the kind happens and returns an id to unsubscribe with
+// Hooks of the same kind run in the order they were subscribed, right after the change on the goroutine that made it.
+// Hooks can subscribe and unsubscribe from inside hooks and asks, a hook subscribed while a change is being handled
+// is run from the next change on.
+func (m *Model) Subscribe(kind HookKind, operation HookOperation) int {
+	m.hooks.mu.Lock()
+	defer m.hooks.mu.Unlock()
+
+	id := m.hooks.nextID
+	m.hooks.nextID++
+	if operation != nil {
+		m.hooks.subscriptions = append(m.hooks.subscriptions, hookSubscription{id: id, kind: kind, operation: operation})
+	}
+	return id
+}
+
+// Unsubscribe stops the hook with the id from running, returns false if there was no hook with the id
+func (m *Model) Unsubscribe(id int) bool {
+	m.hooks.mu.Lock()
+	defer m.hooks.mu.Unlock()
+
+	for i, subscription := range m.hooks.subscriptions {
+		if subscription.id == id {
+			// a new slice so hooks already being run are left alone
+			subscriptions := make([]hookSubscription, 0, len(m.hooks.subscriptions)-1)
+			subscriptions = append(subscriptions, m.hooks.subscriptions[:i]...)
+			m.hooks.subscriptions = append(subscriptions, m.hooks.subscriptions[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// runs the hooks subscribed to the kind of the change
+func (m *Model) runHooks(hook Hook) {
+	m.hooks.mu.Lock()
+	subscriptions := m.hooks.subscriptions
+	m.hooks.mu.Unlock()
+
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	hook.Model = m
+	for _, subscription := range subscriptions {
+		if subscription.kind != hook.Kind {
+			continue
+		}
+		// each hook gets its own copy so changing it doesn't affect the next
+		h := hook
+		subscription.operation(&h)
+	}
+}
+
+// runs the birth hooks once the operation given when creating the turtles has run
+// turtles the operation killed are skipped
+func (m *Model) turtlesBorn(turtles []*Turtle) {
+	for _, t := range turtles {
+		if m.whoToTurtles[t.who] != t {
+			continue
+		}
+		m.runHooks(Hook{Kind: HookTurtleBorn, Turtle: t, NewTurtleBreed: t.breed})
+	}
+}
+
+func (m *Model) linkCreated(l *Link) {
+	m.runHooks(Hook{Kind: HookLinkCreated, Link: l, NewLinkBreed: l.breed})
+}
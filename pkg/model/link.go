@@ -3,11 +3,13 @@ package model
 import (
 	"fmt"
 	"math"
+	"sync"
 )
 
 // A Link represents a connection between two turtles
 // A Link can be be a one way directed link or a two way undirected link
 type Link struct {
+	id         int         // number of the link in the order links were created
 	Color      Color       // Color of the link
 	end1       *Turtle     // the two ends of the link
 	end2       *Turtle     // the two ends of the link
@@ -20,6 +22,11 @@ type Link struct {
 	Size       int         // Size of the link
 	Label      interface{} // Label of the link
 	LabelColor Color       // Color of the label
+	tieMode    TieMode     // whether the link is tied and how the tied turtle follows the root
+
+	propertiesMutex       sync.RWMutex
+	linkPropertiesGeneral map[string]interface{} // links own variables
+	linkPropertiesBreed   map[string]interface{} // link breed own variables
 }
 
 // newLink creates a new link between two turtles
@@ -35,6 +42,7 @@ func newLink(model *Model, breed *LinkBreed, end1 *Turtle, end2 *Turtle, directe
 	}
 
 	l := &Link{
+		id:       model.linksIDNumber,
 		breed:    breed,
 		end1:     end1,
 		end2:     end2,
@@ -45,6 +53,21 @@ func newLink(model *Model, breed *LinkBreed, end1 *Turtle, end2 *Turtle, directe
 		Color:    White,
 	}
 
+	//set the link properties variables
+	//breed specific variables can override general variables
+	l.linkPropertiesGeneral = make(map[string]interface{})
+	l.linkPropertiesBreed = make(map[string]interface{})
+	generalTemplate := model.generalLinkBreed(directed).defaultProperties
+	for key, value := range generalTemplate {
+		l.linkPropertiesGeneral[key] = value
+	}
+	if breed.name != BreedNone {
+		for key, value := range breed.defaultProperties {
+			l.linkPropertiesBreed[key] = value
+		}
+	}
+
+	model.linksIDNumber++
 	model.links.Add(l)
 
 	model.ShownLinks.Add(l)
@@ -66,6 +89,8 @@ func newLink(model *Model, breed *LinkBreed, end1 *Turtle, end2 *Turtle, directe
 		model.linkedTurtles[end2].addUndirectedBreed(breed, end1, l)
 	}
 
+	model.linkCreated(l)
+
 	return l, nil
 }
 
@@ -127,7 +152,7 @@ func (l *Link) SetBreed(breed *LinkBreed) {
 			breed = l.parent.undirectedLinkBreeds[l.breed.name]
 		}
 
-		breed.links.links.Remove(l)
+		breed.links.Remove(l)
 	}
 
 	l.breed = breed
@@ -140,6 +165,16 @@ func (l *Link) SetBreed(breed *LinkBreed) {
 		}
 	}
 
+	// switch the links properties variables to the new breed
+	l.propertiesMutex.Lock()
+	l.linkPropertiesBreed = make(map[string]interface{})
+	if l.breed.name != BreedNone {
+		for key, value := range l.breed.defaultProperties {
+			l.linkPropertiesBreed[key] = value
+		}
+	}
+	l.propertiesMutex.Unlock()
+
 	//change the breed on the turtles
 	if l.directed {
 		l.parent.linkedTurtles[l.end1].changeDirectedOutBreed(oldBreed, breed, l.end2, l)
@@ -148,6 +183,115 @@ func (l *Link) SetBreed(breed *LinkBreed) {
 		l.parent.linkedTurtles[l.end1].changeUndirectedBreed(oldBreed, breed, l.end2, l)
 		l.parent.linkedTurtles[l.end2].changeUndirectedBreed(oldBreed, breed, l.end1, l)
 	}
+
+	l.parent.runHooks(Hook{Kind: HookLinkBreedChanged, Link: l, OldLinkBreed: oldBreed, NewLinkBreed: l.breed})
+}
+
+// GetProperty returns the link property variable.
+// This method is thread-safe and can be called concurrently.
+func (l *Link) GetProperty(key string) interface{} {
+	l.propertiesMutex.RLock()
+	defer l.propertiesMutex.RUnlock()
+
+	if val, found := l.linkPropertiesBreed[key]; found {
+		return val
+	}
+	if val, found := l.linkPropertiesGeneral[key]; found {
+		return val
+	}
+	return nil
+}
+
+// returns the link property variable as an int
+func (l *Link) GetPropI(key string) (int, error) {
+	v := l.GetProperty(key)
+	if v == nil {
+		return 0, fmt.Errorf("key not found: %s", key)
+	}
+	switch v := v.(type) {
+	case int:
+		return v, nil
+	case float64:
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("not a number")
+	}
+}
+
+// returns the link property variable as a float
+func (l *Link) GetPropF(key string) (float64, error) {
+	v := l.GetProperty(key)
+	if v == nil {
+		return 0, fmt.Errorf("key not found: %s", key)
+	}
+	switch v := v.(type) {
+	case int:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("not a number")
+	}
+}
+
+// returns the link property variable as a string
+func (l *Link) GetPropS(key string) (string, error) {
+	v := l.GetProperty(key)
+	if v == nil {
+		return "", fmt.Errorf("key not found: %s", key)
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("not a string")
+	}
+}
+
+// returns the link property variable as a bool
+func (l *Link) GetPropB(key string) (bool, error) {
+	v := l.GetProperty(key)
+	if v == nil {
+		return false, fmt.Errorf("key not found: %s", key)
+	}
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("not a bool")
+	}
+}
+
+// SetProperty sets the link property variable.
+// Only properties that were declared for links or for the link's breed can be set.
+// This method is thread-safe and can be called concurrently.
+func (l *Link) SetProperty(key string, value interface{}) {
+	l.propertiesMutex.Lock()
+	defer l.propertiesMutex.Unlock()
+
+	if _, found := l.linkPropertiesBreed[key]; found {
+		l.linkPropertiesBreed[key] = value
+		return
+	}
+	if _, found := l.linkPropertiesGeneral[key]; found {
+		l.linkPropertiesGeneral[key] = value
+	}
+}
+
+// returns a copy of all the link's property variables
+// breed specific variables take precedence over general variables
+func (l *Link) Properties() map[string]interface{} {
+	l.propertiesMutex.RLock()
+	defer l.propertiesMutex.RUnlock()
+
+	properties := make(map[string]interface{}, len(l.linkPropertiesGeneral)+len(l.linkPropertiesBreed))
+	for key, value := range l.linkPropertiesGeneral {
+		properties[key] = value
+	}
+	for key, value := range l.linkPropertiesBreed {
+		properties[key] = value
+	}
+	return properties
 }
 
 // sets the link to be hidden
@@ -161,6 +305,35 @@ func (l *Link) IsHidden() bool {
 	return l.hidden
 }
 
+// returns the id of the link, links are numbered in the order they were created
+func (l *Link) ID() int {
+	return l.id
+}
+
+// returns the color of the link
+func (l *Link) GetColor() Color {
+	return l.Color
+}
+
+// sets the color of the link
+func (l *Link) SetColor(color Color) {
+	l.Color = color
+}
+
+// returns the label of the link
+func (l *Link) GetLabel() interface{} {
+	return l.Label
+}
+
+// sets the label of the link
+func (l *Link) SetLabel(label interface{}) {
+	l.Label = label
+}
+
+func (l *Link) agentModel() *Model {
+	return l.parent
+}
+
 // returns the heading in degrees from end1 to end2. Returns an error if the link has zero length
 func (l *Link) Heading() (float64, error) {
 	if l.end1.xcor == l.end2.xcor && l.end1.ycor == l.end2.ycor {
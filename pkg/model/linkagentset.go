@@ -1,194 +1,140 @@
 package model
 
-import (
-	"github.com/nlatham1999/sortedset"
-)
+import "github.com/nlatham1999/sortedset"
 
 // LinkAgentSet is an ordered set of links than can be sorted
-// implements github.com/nlatham1999/sortedset
+// it is built on AgentSet so the methods that don't return an agent set come from there
 type LinkAgentSet struct {
-	links *sortedset.SortedSet
+	*AgentSet[*Link]
 }
 
 // create a new LinkAgentSet
 func NewLinkAgentSet(links []*Link) *LinkAgentSet {
-
-	linkSet := &LinkAgentSet{
-		links: sortedset.NewSortedSet(),
-	}
-
-	for _, link := range links {
-		linkSet.Add(link)
+	return &LinkAgentSet{
+		AgentSet: NewAgentSet(links),
 	}
-
-	return linkSet
-}
-
-// add a link to the agent set
-func (l *LinkAgentSet) Add(link *Link) {
-	l.links.Add(link)
 }
 
-// returns true if all the links in the agent set satisfy the operation
-func (l *LinkAgentSet) All(operation LinkBoolOperation) bool {
-	if operation == nil {
-		return false
+func newLinkAgentSet(set *AgentSet[*Link]) *LinkAgentSet {
+	if set == nil {
+		return nil
+	}
+	return &LinkAgentSet{
+		AgentSet: set,
 	}
-
-	return l.links.All(func(a interface{}) bool {
-		return operation(a.(*Link))
-	})
 }
 
-// returns true if any of the links in the agent set satisfy the operation
-func (l *LinkAgentSet) Any(operation LinkBoolOperation) bool {
-	if operation == nil {
-		return false
+// AskStaged runs each stage on all links in the agent set before moving on to the next stage.
+// The links are visited in the order given by the scheduler, which is asked for a new order every stage.
+// If the scheduler is buffered the updates returned by the links are committed once the whole stage has been run.
+// If the scheduler is nil then the model's scheduler is used.
+func (l *LinkAgentSet) AskStaged(scheduler Scheduler, stages []LinkStage) {
+	operations := make([]func(*Link) func(), len(stages))
+	for i, stage := range stages {
+		operations[i] = stage.Operation
 	}
-
-	return l.links.Any(func(a interface{}) bool {
-		return operation(a.(*Link))
-	})
+	l.askStaged(scheduler, operations)
 }
 
-// perform the operation for all links in the agent set
-func (l *LinkAgentSet) Ask(operation LinkOperation) {
-	if operation == nil {
-		return
+// returns the links in the agent set that have both ends on the patches at the given coordinates
+func (l *LinkAgentSet) AtPoints(m *Model, points []Coordinate) *LinkAgentSet {
+	// convert the points to patches
+	patchesAtPoints := sortedset.NewSortedSet()
+	for _, point := range points {
+		patch := m.Patch(point.X, point.Y)
+		patchesAtPoints.Add(patch)
 	}
 
-	l.links.Ask(func(a interface{}) {
-		operation(a.(*Link))
+	return l.With(func(link *Link) bool {
+		return patchesAtPoints.Contains(link.end1.PatchHere()) && patchesAtPoints.Contains(link.end2.PatchHere())
 	})
 }
 
-// returns true if the link is in the agent set
-func (l *LinkAgentSet) Contains(link *Link) bool {
-	return l.links.Contains(link)
-}
-
 // returns a copy of the agent set
 func (l *LinkAgentSet) Copy() *LinkAgentSet {
-	return &LinkAgentSet{
-		// links: l.links.Copy(),
-	}
+	return newLinkAgentSet(l.AgentSet.Copy())
 }
 
-// returns the length of the agent set
-func (l *LinkAgentSet) Count() int {
-	return l.links.Len()
+// returns the first n links in the agent set
+func (l *LinkAgentSet) FirstNOf(n int) *LinkAgentSet {
+	return newLinkAgentSet(l.AgentSet.FirstNOf(n))
 }
 
-// returns the agent set as a list
-func (l *LinkAgentSet) List() []*Link {
-	links := make([]*Link, 0)
-	l.links.Ask(func(a interface{}) {
-		links = append(links, a.(*Link))
-	})
-	return links
+// returns the last n links in the agent set
+func (l *LinkAgentSet) LastNOf(n int) *LinkAgentSet {
+	return newLinkAgentSet(l.AgentSet.LastNOf(n))
 }
 
-// returns the top n links in the agent set
-func (l *LinkAgentSet) FirstNOf(n int) *LinkAgentSet {
-	linkSet := sortedset.NewSortedSet()
-	link := l.links.First()
-	for i := 0; i < n && link != nil; i++ {
-		linkSet.Add(link)
-		link, _ = l.links.Next()
-	}
-	return &LinkAgentSet{
-		links: linkSet,
-	}
+// returns a new LinkAgentSet with all the links that are not in the given LinkAgentSet
+func (l *LinkAgentSet) WhoAreNot(links *LinkAgentSet) *LinkAgentSet {
+	return newLinkAgentSet(l.AgentSet.Difference(links.AgentSet))
 }
 
-// returns the first link in the agent set
-func (l *LinkAgentSet) First() (*Link, error) {
-	link := l.links.First()
-	if link == nil {
-		return nil, ErrNoLinksInAgentSet
-	}
-	return link.(*Link), nil
+// returns a new LinkAgentSet with all the links that are not the given link
+func (l *LinkAgentSet) WhoAreNotLink(link *Link) *LinkAgentSet {
+	return newLinkAgentSet(l.WhoAreNotAgent(link))
 }
 
-// returns the last n links in the agent set
-func (l *LinkAgentSet) LastNOf(n int) *LinkAgentSet {
-	linkSet := sortedset.NewSortedSet()
-	link := l.links.Last()
-	for i := 0; i < n && link != nil; i++ {
-		linkSet.Add(link)
-		link, _ = l.links.Previous()
-	}
-	return &LinkAgentSet{
-		links: linkSet,
-	}
+// returns a new agent set that contains all the links that satisfy the operation
+// returns nil if the operation is nil
+func (l *LinkAgentSet) With(operation LinkBoolOperation) *LinkAgentSet {
+	return newLinkAgentSet(l.AgentSet.With(operation))
 }
 
-// returns the last link in the agent set
-func (l *LinkAgentSet) Last() (*Link, error) {
-	link := l.links.Last()
-	if link == nil {
-		return nil, ErrNoLinksInAgentSet
-	}
-	return link.(*Link), nil
+// returns n random links from the agent set, or all of them if there are fewer than n
+// the links stay in the order they are in the agent set
+func (l *LinkAgentSet) NOf(n int) *LinkAgentSet {
+	return newLinkAgentSet(l.AgentSet.NOf(n))
 }
 
-// returns the next link in the set
-// func (l *LinkAgentSet) Next() (*Link, error) {
-// 	v, err := l.links.Next()
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	return v.(*Link), err
-// }
-
-// remove a link from the agent set
-func (l *LinkAgentSet) Remove(link *Link) {
-	l.links.Remove(link)
+// returns n different links from the agent set picked with chances proportional to their weights
+// links with a weight that isn't positive are never picked so fewer than n may be returned
+// the links stay in the order they are in the agent set
+func (l *LinkAgentSet) WeightedNOf(n int, weight LinkFloatOperation) *LinkAgentSet {
+	return newLinkAgentSet(l.AgentSet.WeightedNOf(n, weight))
 }
 
-// sort the agent set based on the float operation in ascending order
-func (l *LinkAgentSet) SortAsc(operation LinkFloatOperation) {
-	l.links.SortAsc(func(a interface{}) interface{} {
-		return operation(a.(*Link))
-	})
+// returns the n links with the largest values of the operation, ties at the cut off are broken at random
+// the links stay in the order they are in the agent set
+func (l *LinkAgentSet) MaxNOf(n int, operation LinkFloatOperation) *LinkAgentSet {
+	return newLinkAgentSet(l.AgentSet.MaxNOf(n, operation))
 }
 
-// sort the agent set based on the float operation in descending order
-func (l *LinkAgentSet) SortDesc(operation LinkFloatOperation) {
-	l.links.SortDesc(func(a interface{}) interface{} {
-		return operation(a.(*Link))
-	})
+// returns the n links with the smallest values of the operation, ties at the cut off are broken at random
+// the links stay in the order they are in the agent set
+func (l *LinkAgentSet) MinNOf(n int, operation LinkFloatOperation) *LinkAgentSet {
+	return newLinkAgentSet(l.AgentSet.MinNOf(n, operation))
 }
 
-// returns a new agent set with all the links that are not in the given agents set
-func (l *LinkAgentSet) WhoAreNot(links *LinkAgentSet) *LinkAgentSet {
-	return &LinkAgentSet{
-		links: l.links.Difference(links.links),
+// splits the links into agent sets by the key the operation returns for each link
+// the keys have to be comparable, the links in each agent set stay in the order they are in the agent set
+func (l *LinkAgentSet) GroupBy(operation LinkKeyOperation) map[interface{}]*LinkAgentSet {
+	groups := map[interface{}]*LinkAgentSet{}
+	for key, group := range l.AgentSet.GroupBy(operation) {
+		groups[key] = newLinkAgentSet(group)
 	}
+	return groups
 }
 
-// returns a new agent set with all the links that are not the given link
-func (l *LinkAgentSet) WhoAreNotLink(link *Link) *LinkAgentSet {
-	return &LinkAgentSet{
-		links: l.links.Difference(sortedset.NewSortedSet(link)),
-	}
+// splits the links into agent sets by the value of the property
+func (l *LinkAgentSet) GroupByProperty(name string) map[interface{}]*LinkAgentSet {
+	return l.GroupBy(func(link *Link) interface{} {
+		return link.GetProperty(name)
+	})
 }
 
-// returns a new agent set that is a subset of the agent set where all satisfy the bool operation
-func (l *LinkAgentSet) With(operation LinkBoolOperation) *LinkAgentSet {
-	linkSet := sortedset.NewSortedSet()
-
-	if operation == nil {
-		return nil
-	}
+// returns a new agent set with the links in either agent set
+// the links of this agent set come first followed by the ones only in the other
+func (l *LinkAgentSet) Union(other *LinkAgentSet) *LinkAgentSet {
+	return newLinkAgentSet(l.AgentSet.Union(other.AgentSet))
+}
 
-	l.links.Ask(func(a interface{}) {
-		if operation(a.(*Link)) {
-			linkSet.Add(a)
-		}
-	})
+// returns a new agent set with the links in both agent sets, in the order of this agent set
+func (l *LinkAgentSet) Intersect(other *LinkAgentSet) *LinkAgentSet {
+	return newLinkAgentSet(l.AgentSet.Intersect(other.AgentSet))
+}
 
-	return &LinkAgentSet{
-		links: linkSet,
-	}
+// returns a new agent set with the links of this agent set that aren't in the other, same as WhoAreNot
+func (l *LinkAgentSet) Difference(other *LinkAgentSet) *LinkAgentSet {
+	return newLinkAgentSet(l.AgentSet.Difference(other.AgentSet))
 }
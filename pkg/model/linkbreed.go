@@ -8,15 +8,28 @@ type LinkBreed struct {
 
 	directed     bool
 	defaultShape string
+
+	defaultProperties map[string]interface{}
 }
 
 // NewLinkBreed creates a new link breed
 func NewLinkBreed(name string) *LinkBreed {
+	return NewLinkBreedWithProperties(name, nil)
+}
+
+// NewLinkBreedWithProperties creates a new link breed where every link of the breed gets the properties passed in
+// after creating, pass it in the model settings
+func NewLinkBreedWithProperties(name string, linkProperties map[string]interface{}) *LinkBreed {
+	if linkProperties == nil {
+		linkProperties = make(map[string]interface{})
+	}
+
 	return &LinkBreed{
-		name:     name,
-		links:    NewLinkAgentSet(nil),
-		directed: false, // should get set by the model after being passed in model settings
-		model:    nil,   // should get set by the model after being passed in model settings
+		name:              name,
+		links:             NewLinkAgentSet(nil),
+		directed:          false, // should get set by the model after being passed in model settings
+		model:             nil,   // should get set by the model after being passed in model settings
+		defaultProperties: linkProperties,
 	}
 }
 
@@ -38,6 +51,11 @@ func (lb *LinkBreed) GetDefaultShape() string {
 	return lb.defaultShape
 }
 
+// returns the default properties of the breed
+func (lb *LinkBreed) DefaultProperties() map[string]interface{} {
+	return lb.defaultProperties
+}
+
 func (lb *LinkBreed) Name() string {
 	return lb.name
 }
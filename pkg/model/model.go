@@ -4,6 +4,7 @@ import (
 	"errors"
 	"math"
 	"math/rand/v2"
+	"sync"
 	"time"
 
 	"github.com/nlatham1999/sortedset"
@@ -47,6 +48,7 @@ type Model struct {
 	DefaultShapeLinks   string //the default shape for links
 
 	turtlesWhoNumber int //who number of the next turtle to be created
+	linksIDNumber    int //id of the next link to be created
 
 	randomGenerator *rand.Rand
 	seedValue       uint64
@@ -61,6 +63,32 @@ type Model struct {
 	// set of shown links
 	// used for efficient rendering
 	ShownLinks *LinkAgentSet
+
+	scheduler  Scheduler   // scheduler used for tick stages and agentsets asked with a nil scheduler
+	tickStages []TickStage // stages run on all agents each tick
+
+	now           float64                   // current model time, can run ahead of the ticks when running events
+	events        eventQueue                // events waiting to fire ordered by time
+	eventSequence uint64                    // number of events that have been scheduled, used to order events at the same time
+	eventHandlers map[string]EventOperation // named event handlers
+
+	drawing *Drawing // layer holding the trails and stamps left by turtles and links
+
+	spatialIndex *spatialIndex // optional grid of turtles used to speed up radius and nearest queries, nil if disabled
+
+	deferMovement bool       // if turtles keep their old patch until CommitMovementPhase, see BeginMovementPhase
+	movedMu       sync.Mutex // protects moved
+	moved         []*Turtle  // turtles that moved during the movement phase
+
+	hooks hooks // lifecycle hooks, see Subscribe
+
+	floatColumns map[string]*FloatColumn // patch variables stored as typed columns
+	intColumns   map[string]*IntColumn
+	boolColumns  map[string]*BoolColumn
+
+	kernelTargetsMutex sync.Mutex       // guards the kernel target cache
+	kernelTargetsCache map[string][]int // patch indices each diffusion kernel reaches, keyed by the kernel offsets and boundary
+	kernelTargetsOrder []string         // keys of the kernel target cache from least to most recently used
 }
 
 // Create a new model
@@ -123,11 +151,16 @@ func NewModel(
 		seedValue2:             settings.RandomSeed2,
 		modelStart:             time.Now(),
 		linkedTurtles:          make(map[*Turtle]*turtleLinks),
+		events:                 eventQueue{},
+		eventHandlers:          make(map[string]EventOperation),
+		drawing:                newDrawing(settings.MaxDrawingSegments),
 	}
 
 	model.randomSrc = rand.NewPCG(model.seedValue, model.seedValue2)
 	model.randomGenerator = rand.New(model.randomSrc)
 
+	model.SetScheduler(settings.Scheduler)
+
 	//construct turtle breeds
 	turtleBreedsMap := make(map[string]*TurtleBreed)
 	for _, breed := range settings.TurtleBreeds {
@@ -154,6 +187,12 @@ func NewModel(
 		undirectedLink.directed = false
 	}
 
+	// the general link properties are held by the general directed and undirected breeds
+	for key, value := range settings.LinkProperties {
+		model.directedLinkBreeds[BreedNone].defaultProperties[key] = value
+		model.undirectedLinkBreeds[BreedNone].defaultProperties[key] = value
+	}
+
 	//construct general turtle set
 	model.turtles = NewTurtleAgentSet([]*Turtle{})
 
@@ -176,8 +215,13 @@ func NewModel(
 	model.ShownLinks = NewLinkAgentSet([]*Link{})
 
 	// build patches
+	model.buildPatchColumns(settings.PatchColumns)
 	model.buildPatches()
 
+	if settings.SpatialIndexCellSize > 0 {
+		model.EnableSpatialIndex(settings.SpatialIndexCellSize)
+	}
+
 	return model
 }
 
@@ -282,11 +326,13 @@ func (m *Model) TurtleBreeds() []*TurtleBreed {
 	return breeds
 }
 
-// clear all patches and turtles and set the ticks to zero
+// clear all patches, turtles, scheduled events and the drawing and set the ticks to zero
 func (m *Model) ClearAll() {
 	m.ClearTicks()
 	m.ClearPatches()
 	m.ClearTurtles()
+	m.ClearEvents()
+	m.ClearDrawing()
 }
 
 // clear all links
@@ -301,11 +347,13 @@ func (m *Model) ClearLinks() {
 	m.turtles.Ask(func(turtle *Turtle) {
 		m.linkedTurtles[turtle] = newTurtleLinks()
 	})
+	m.linksIDNumber = 0
 }
 
-// set the ticks to zero
+// set the ticks and the model clock to zero
 func (m *Model) ClearTicks() {
 	m.Ticks = 0
+	m.now = 0
 }
 
 // clear all patches
@@ -336,6 +384,11 @@ func (m *Model) ClearTurtles() {
 		*turtle = Turtle{}
 	})
 
+	// empty out the spatial index
+	if m.spatialIndex != nil {
+		m.spatialIndex = newSpatialIndex(m, m.spatialIndex.cellSize)
+	}
+
 	m.turtles = NewTurtleAgentSet([]*Turtle{})
 	for breed := range m.breeds {
 		m.breeds[breed].turtles = NewTurtleAgentSet([]*Turtle{})
@@ -344,6 +397,7 @@ func (m *Model) ClearTurtles() {
 	m.whoToTurtles = make(map[int]*Turtle)
 
 	m.turtlesWhoNumber = 0
+	m.linksIDNumber = 0
 }
 
 // CreateTurtles creates the specified amount of turtles with the specified operation.
@@ -377,6 +431,8 @@ func (m *Model) createTurtlesBreeded(amount int, breed *TurtleBreed, operation T
 
 	turtles.Ask(operation)
 
+	m.turtlesBorn(turtles.List())
+
 	return turtles, nil
 }
 
@@ -443,6 +499,8 @@ func (m *Model) convertXYZToInBounds(x float64, y float64, z float64) (float64,
 // kills a turtle
 func (m *Model) KillTurtle(turtle *Turtle) {
 
+	m.runHooks(Hook{Kind: HookTurtleDied, Turtle: turtle, NewTurtleBreed: turtle.breed})
+
 	m.turtles.Remove(turtle)
 	if turtle.breed != nil {
 		m.breeds[turtle.breed.name].turtles.Remove(turtle)
@@ -473,20 +531,26 @@ func (m *Model) KillTurtle(turtle *Turtle) {
 		m.KillLink(link)
 	}
 
+	if m.spatialIndex != nil {
+		m.spatialIndex.remove(turtle)
+	}
+
 	*turtle = Turtle{}
 }
 
 // kills a link
 func (m *Model) KillLink(link *Link) {
 
-	m.links.links.Remove(link)
+	m.runHooks(Hook{Kind: HookLinkDied, Link: link, NewLinkBreed: link.breed})
+
+	m.links.Remove(link)
 	m.ShownLinks.Remove(link)
 
 	if link.breed.name != BreedNone {
 		if link.directed {
-			m.directedLinkBreeds[link.breed.name].links.links.Remove(link)
+			m.directedLinkBreeds[link.breed.name].links.Remove(link)
 		} else {
-			m.undirectedLinkBreeds[link.breed.name].links.links.Remove(link)
+			m.undirectedLinkBreeds[link.breed.name].links.Remove(link)
 		}
 	}
 
@@ -499,79 +563,50 @@ func (m *Model) KillLink(link *Link) {
 		m.linkedTurtles[link.end2].removeUndirectedBreed(link.breed, link.end1, link)
 	}
 
+	// untie the link so the turtles stop moving together
+	link.Untie()
+
 	*link = Link{}
 }
 
 // diffuse the patch variable of each patch to its neighbors
+// in 3D worlds the variable is diffused to all 26 neighbors
+// the patch variable has to be a float64 patch property or a float column
 func (m *Model) Diffuse(patchVariable string, percent float64) error {
 
 	if percent > 1 || percent < 0 {
 		return errors.New("percent amount was outside bounds")
 	}
 
-	diffusions := make(map[*Patch]float64)
-
-	//go through each patch and calculate the diffusion amount
-	m.Patches.Ask(func(patch *Patch) {
-		patchAmount := patch.patchProperties[patchVariable].(float64)
-		amountToGive := patchAmount * percent / 8
-		diffusions[patch] = amountToGive
-	})
-
-	//go through each patch and get the new amount
-	m.Patches.Ask(func(patch *Patch) {
-		amountFromNeighbors := 0.0
-		neighbors := m.neighbors(patch)
-		if neighbors.Count() > 8 || neighbors.Count() < 3 {
-			return
-		}
-		neighbors.Ask(func(n *Patch) {
-			amountFromNeighbors += diffusions[n]
-		})
-
-		patchAmount := patch.patchProperties[patchVariable].(float64)
-		amountToKeep := (patchAmount * (1 - percent)) + (float64(8-neighbors.Count()) * (patchAmount * percent / 8))
+	kernel := DiffusionKernel8()
+	if m.Is3D() {
+		kernel = DiffusionKernel26()
+	}
 
-		patch.patchProperties[patchVariable] = amountToKeep + amountFromNeighbors
+	return m.DiffuseWithSettings(patchVariable, DiffusionSettings{
+		Rate:   percent,
+		Kernel: kernel,
 	})
-
-	return nil
 }
 
 // diffuse the patch variable of each patch to its neighbors at the top, bottom, left, and right
+// in 3D worlds the patches in front and behind are included as well
+// the patch variable has to be a float64 patch property or a float column
 func (m *Model) Diffuse4(patchVariable string, percent float64) error {
 
 	if percent > 1 || percent < 0 {
 		return errors.New("percent amount was outside bounds")
 	}
 
-	diffusions := make(map[*Patch]float64)
-
-	//go through each patch and calculate the diffusion amount
-	m.Patches.Ask(func(patch *Patch) {
-		patchAmount := patch.patchProperties[patchVariable].(float64)
-		amountToGive := patchAmount * percent / 4
-		diffusions[patch] = amountToGive
-	})
-
-	//go through each patch and get the new amount
-	m.Patches.Ask(func(patch *Patch) {
-		amountFromNeighbors := 0.0
-		neighbors := m.neighbors4(patch)
-		if neighbors.Count() > 4 || neighbors.Count() < 2 {
-			return
-		}
-		neighbors.Ask(func(n *Patch) {
-			amountFromNeighbors += diffusions[n]
-		})
-
-		patchAmount := patch.patchProperties[patchVariable].(float64)
-		amountToKeep := (patchAmount * (1 - percent)) + (float64(4-neighbors.Count()) * (patchAmount * percent / 4))
+	kernel := DiffusionKernel4()
+	if m.Is3D() {
+		kernel = DiffusionKernel6()
+	}
 
-		patch.patchProperties[patchVariable] = amountToKeep + amountFromNeighbors
+	return m.DiffuseWithSettings(patchVariable, DiffusionSettings{
+		Rate:   percent,
+		Kernel: kernel,
 	})
-
-	return nil
 }
 
 // returns the directed link breed associated with the name
@@ -595,6 +630,14 @@ func (m *Model) Is3D() bool {
 	return m.worldDepth > 1
 }
 
+// returns the general link breed for either directed or undirected links
+func (m *Model) generalLinkBreed(directed bool) *LinkBreed {
+	if directed {
+		return m.directedLinkBreeds[BreedNone]
+	}
+	return m.undirectedLinkBreeds[BreedNone]
+}
+
 // returns the undirected link breed associated with the name
 func (m *Model) UndirectedLinkBreed(name string) *LinkBreed {
 	return m.undirectedLinkBreeds[name]
@@ -658,6 +701,31 @@ func (m *Model) distanceBetweenPoints(x1 float64, y1 float64, z1 float64, x2 flo
 	return distance
 }
 
+// returns the x and y offsets from the first point to the second point
+// if the world wraps then the shortest way around is used
+func (m *Model) shortestOffsetXY(x1 float64, y1 float64, x2 float64, y2 float64) (float64, float64) {
+	dx := x2 - x1
+	dy := y2 - y1
+
+	if m.wrappingX && math.Abs(dx) > float64(m.worldWidth)/2 {
+		if dx > 0 {
+			dx -= float64(m.worldWidth)
+		} else {
+			dx += float64(m.worldWidth)
+		}
+	}
+
+	if m.wrappingY && math.Abs(dy) > float64(m.worldHeight)/2 {
+		if dy > 0 {
+			dy -= float64(m.worldHeight)
+		} else {
+			dy += float64(m.worldHeight)
+		}
+	}
+
+	return dx, dy
+}
+
 // returns the distance between two points
 // convertXYToInBounds should be called before this function
 func (m *Model) DistanceBetweenPointsXY(x1 float64, y1 float64, x2 float64, y2 float64) float64 {
@@ -681,6 +749,24 @@ func (m *Model) LayoutCircle(turtles []*Turtle, radius float64) {
 	}
 }
 
+// layout the turtles evenly over a sphere with the specified radius, the 3D version of LayoutCircle
+// each turtle faces away from the center in the x y plane
+func (m *Model) LayoutSphere(turtles []*Turtle, radius float64) {
+	amount := len(turtles)
+	goldenAngle := math.Pi * (3 - math.Sqrt(5))
+	for i := 0; i < amount; i++ {
+		// spiral from the top of the sphere to the bottom, turning by the golden angle each turtle
+		z := 1.0
+		if amount > 1 {
+			z = 1 - 2*float64(i)/float64(amount-1)
+		}
+		ring := math.Sqrt(1 - z*z)
+		heading := goldenAngle * float64(i)
+		turtles[i].SetXYZ(radius*ring*math.Cos(heading), radius*ring*math.Sin(heading), radius*z)
+		turtles[i].setHeadingRadians(math.Mod(heading, 2*math.Pi))
+	}
+}
+
 // returns a link between two turtles that connects from turtle1 to turtle2
 func (m *Model) Link(turtle1 int, turtle2 int) *Link {
 
@@ -987,9 +1073,7 @@ func (m *Model) neighbors(p *Patch) *PatchAgentSet {
 		}
 	}
 
-	return &PatchAgentSet{
-		patches: n,
-	}
+	return newPatchAgentSet(newAgentSet[*Patch](n))
 }
 
 func (m *Model) neighborsAtZOffset(p *Patch, zOffset int) *PatchAgentSet {
@@ -1046,9 +1130,7 @@ func (m *Model) neighbors4(p *Patch) *PatchAgentSet {
 		n.Add(bottom)
 	}
 
-	return &PatchAgentSet{
-		patches: n,
-	}
+	return newPatchAgentSet(newAgentSet[*Patch](n))
 }
 
 func (m *Model) neighborsFromList(p *Patch, neighborNames []string) *PatchAgentSet {
@@ -1061,9 +1143,7 @@ func (m *Model) neighborsFromList(p *Patch, neighborNames []string) *PatchAgentS
 		}
 	}
 
-	return &PatchAgentSet{
-		patches: n,
-	}
+	return newPatchAgentSet(newAgentSet[*Patch](n))
 }
 
 func (m *Model) getPatchAtPos(x int) *Patch {
@@ -1220,9 +1300,10 @@ func (m *Model) RandomZCor() float64 {
 	return m.RandomFloat(m.maxZCor-m.minZCor) + m.minZCor
 }
 
-// sets the tick counter to zero
+// sets the tick counter and the model clock to zero
 func (m *Model) ResetTicks() {
 	m.Ticks = 0
+	m.now = 0
 }
 
 // resets the timer
@@ -1254,6 +1335,28 @@ func (m *Model) SetRandomState(seed1 uint64, seed2 uint64, state []byte) error {
 	return nil
 }
 
+// returns the who number the next turtle created gets
+func (m *Model) NextWho() int {
+	return m.turtlesWhoNumber
+}
+
+// sets the who number the next turtle created gets
+// used when restoring a saved model, giving out a who number that a living turtle already has breaks Turtle lookups
+func (m *Model) SetNextWho(who int) {
+	m.turtlesWhoNumber = who
+}
+
+// returns the id the next link created gets
+func (m *Model) NextLinkID() int {
+	return m.linksIDNumber
+}
+
+// sets the id the next link created gets
+// used when restoring a saved model
+func (m *Model) SetNextLinkID(id int) {
+	m.linksIDNumber = id
+}
+
 // sets the default shape for links
 func (m *Model) SetDefaultShapeLinks(shape string) {
 	m.DefaultShapeLinks = shape
@@ -1264,14 +1367,21 @@ func (m *Model) SetDefaultShapeTurtles(shape string) {
 	m.DefaultShapeTurtles = shape
 }
 
-// increments the tick counter by one
+// runs the tick stages if there are any and then increments the tick counter by one
+// any events scheduled up to the new tick are fired and then the tick hooks are run
 func (m *Model) Tick() {
+	m.runTickStages()
 	m.Ticks++
+	m.RunEvents(float64(m.Ticks))
+	m.runHooks(Hook{Kind: HookTick, Ticks: m.Ticks})
 }
 
 // increments the tick counter by the provided amount
+// any events scheduled up to the new tick are fired and then the tick hooks are run
 func (m *Model) TickAdvance(amount int) {
 	m.Ticks += amount
+	m.RunEvents(float64(m.Ticks))
+	m.runHooks(Hook{Kind: HookTick, Ticks: m.Ticks})
 }
 
 // returns the time since the model was started in milliseconds
@@ -1387,23 +1497,22 @@ func (m *Model) turtlesAtCoordsBreeded(breed *TurtleBreed, pxcor float64, pycor
 	return patch.turtlesHereBreeded(breed)
 }
 
+// returns an agentset of turtles that are within the provided radius of the provided coordinates
+// if use3D is true then the z coordinate is used as well
+// the radius is inclusive so a radius of 0 gives the turtles right at the coordinates, a negative radius gives none
 func (m *Model) TurtlesInRadius(xCor float64, yCor float64, zCor float64, radius float64, use3D bool) *TurtleAgentSet {
-	xMin := int(math.Floor(xCor - radius))
-	xMax := int(math.Ceil(xCor + radius))
-	yMin := int(math.Floor(yCor - radius))
-	yMax := int(math.Ceil(yCor + radius))
-	zMin := int(math.Floor(zCor - radius))
-	zMax := int(math.Ceil(zCor + radius))
+	if radius < 0 {
+		return NewTurtleAgentSet(nil)
+	}
 
-	// Calculate number of patches to check
-	patchCount := (xMax - xMin + 1) * (yMax - yMin + 1)
-	if use3D {
-		patchCount *= (zMax - zMin + 1)
+	// the spatial index only looks at the cells around the radius so it is used whenever it is enabled
+	if m.spatialIndex != nil {
+		return m.turtlesInRadiusIndexed(xCor, yCor, zCor, radius, use3D)
 	}
 
 	// If we're going to be looping through more patches than there are turtles,
 	// just loop through all the turtles
-	if patchCount > m.turtles.Count() {
+	if m.patchCountInRadius(xCor, yCor, zCor, radius, use3D) > m.turtles.Count() {
 		return m.Turtles().With(func(t *Turtle) bool {
 			if use3D {
 				return m.DistanceBetweenPointsXYZ(xCor, yCor, zCor, t.XCor(), t.YCor(), t.ZCor()) <= radius
@@ -1412,90 +1521,10 @@ func (m *Model) TurtlesInRadius(xCor float64, yCor float64, zCor float64, radius
 		})
 	}
 
-	patchesFullyInsideRadius := make([]*Patch, 0)
-	patchesPartiallyInsideRadius := make([]*Patch, 0)
-
-	// Iterate through patches in 2D or 3D
-	zRange := []int{0} // Default for 2D
-	if use3D {
-		zRange = make([]int, zMax-zMin+1)
-		for i := range zRange {
-			zRange[i] = zMin + i
-		}
-	}
-
-	for x := xMin; x <= xMax; x++ {
-		for y := yMin; y <= yMax; y++ {
-			for _, z := range zRange {
-				// Get the patch
-				var patch *Patch
-				if use3D {
-					patch = m.Patch3D(float64(x), float64(y), float64(z))
-				} else {
-					patch = m.Patch(float64(x), float64(y))
-				}
-
-				if patch == nil {
-					continue
-				}
-
-				// If there are no turtles on the patch then we can skip it
-				if patch.TurtlesHere().Count() == 0 {
-					continue
-				}
-
-				// Get the center of the patch
-				px := float64(patch.XCor())
-				py := float64(patch.YCor())
-				pz := float64(patch.ZCor())
-
-				// Check patch corners
-				var insideCount int
-				var totalCorners int
-
-				if use3D {
-					// 8 corners of a cube
-					corners := [8][3]float64{
-						{px - 0.5, py - 0.5, pz - 0.5},
-						{px + 0.5, py - 0.5, pz - 0.5},
-						{px - 0.5, py + 0.5, pz - 0.5},
-						{px + 0.5, py + 0.5, pz - 0.5},
-						{px - 0.5, py - 0.5, pz + 0.5},
-						{px + 0.5, py - 0.5, pz + 0.5},
-						{px - 0.5, py + 0.5, pz + 0.5},
-						{px + 0.5, py + 0.5, pz + 0.5},
-					}
-					totalCorners = 8
-					for _, corner := range corners {
-						if m.DistanceBetweenPointsXYZ(xCor, yCor, zCor, corner[0], corner[1], corner[2]) <= radius {
-							insideCount++
-						}
-					}
-				} else {
-					// 4 corners of a square
-					corners := [4][2]float64{
-						{px - 0.5, py - 0.5},
-						{px + 0.5, py - 0.5},
-						{px - 0.5, py + 0.5},
-						{px + 0.5, py + 0.5},
-					}
-					totalCorners = 4
-					for _, corner := range corners {
-						if m.DistanceBetweenPointsXY(xCor, yCor, corner[0], corner[1]) <= radius {
-							insideCount++
-						}
-					}
-				}
-
-				// Classify patch
-				if insideCount == totalCorners {
-					patchesFullyInsideRadius = append(patchesFullyInsideRadius, patch)
-				} else if insideCount > 0 {
-					patchesPartiallyInsideRadius = append(patchesPartiallyInsideRadius, patch)
-				}
-			}
-		}
-	}
+	// If there are no turtles on the patch then we can skip it
+	patchesFullyInsideRadius, patchesPartiallyInsideRadius := m.classifyPatchesInRadius(xCor, yCor, zCor, radius, use3D, func(patch *Patch) bool {
+		return patch.TurtlesHere().Count() == 0
+	})
 
 	turtles := NewTurtleAgentSet(nil)
 
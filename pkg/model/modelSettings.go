@@ -3,7 +3,9 @@ package model
 // modelSettings holds the settings for the model
 type ModelSettings struct {
 	PatchProperties      map[string]interface{}
+	PatchColumns         map[string]interface{} // patch variables stored as typed columns, the type of the default value (float64, int or bool) is the type of the column
 	TurtleProperties     map[string]interface{}
+	LinkProperties       map[string]interface{}
 	TurtleBreeds         []*TurtleBreed
 	DirectedLinkBreeds   []*LinkBreed
 	UndirectedLinkBreeds []*LinkBreed
@@ -17,4 +19,7 @@ type ModelSettings struct {
 	MaxPzCor             int // in development
 	RandomSeed           uint64
 	RandomSeed2          uint64
+	Scheduler            Scheduler // scheduler used for tick stages and agentsets asked with a nil scheduler, defaults to sequential
+	MaxDrawingSegments   int       // max number of segments and stamps the drawing layer holds, defaults to DefaultMaxDrawingSegments
+	SpatialIndexCellSize float64   // size of the cells of the spatial index used for radius, nearest and collision queries, 0 leaves it disabled. The index is updated on every move so it only pays off when the model queries more than it moves
 }
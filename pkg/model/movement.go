@@ -0,0 +1,49 @@
+package model
+
+import "sort"
+
+// BeginMovementPhase starts the concurrent movement mode so turtles can move from several goroutines at once.
+// Until CommitMovementPhase is called a turtle that moves keeps its old patch, so TurtlesHere, TurtlesOnPatch,
+// PatchHere and the spatial index all stay as they were when the phase began.
+// XCor, YCor and ZCor return the position from before the phase as well, while the moving turtle's own moves
+// still build on each other, so a turtle can go forward twice in one phase.
+// Only moving is safe during the phase, turtles must not be created, killed, tied or have their breed changed.
+// Distances, radius, nearest and cone queries read positions from before the phase too.
+//
+// WARNING: Not thread-safe. Call it before the goroutines start, not from inside them.
+func (m *Model) BeginMovementPhase() {
+	m.deferMovement = true
+}
+
+// CommitMovementPhase ends the concurrent movement mode and moves every turtle that moved during the phase to the patch at its new position.
+// Turtles are committed in order of their who number so the patches end up the same no matter which goroutine moved which turtle.
+//
+// WARNING: Not thread-safe. Call it after all the goroutines of the phase are done.
+func (m *Model) CommitMovementPhase() {
+	if !m.deferMovement {
+		return
+	}
+	m.deferMovement = false
+
+	moved := m.moved
+	m.moved = nil
+	sort.Slice(moved, func(i, j int) bool {
+		return moved[i].who < moved[j].who
+	})
+
+	for _, turtle := range moved {
+		// turtles killed during the phase are zeroed out and have nothing to commit
+		if turtle.parent == nil {
+			continue
+		}
+		turtle.positionMu.Lock()
+		turtle.moved = false
+		turtle.transferPatchOwnership()
+		turtle.positionMu.Unlock()
+	}
+}
+
+// returns true between BeginMovementPhase and CommitMovementPhase
+func (m *Model) InMovementPhase() bool {
+	return m.deferMovement
+}
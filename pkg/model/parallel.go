@@ -0,0 +1,131 @@
+package model
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// number of agents in each chunk of a parallel ask when the settings don't say
+const DefaultParallelChunkSize = 64
+
+// ParallelSettings holds the settings for asking agents in parallel
+type ParallelSettings struct {
+	Workers   int // number of goroutines, 0 or less means one per cpu
+	ChunkSize int // number of agents in each chunk, 0 or less means DefaultParallelChunkSize. Results depend on this but not on the workers
+
+	// if turtles that move keep their old patch until every agent has been asked, see Model.BeginMovementPhase
+	// turn this on when the operation moves turtles
+	DeferMovement bool
+}
+
+// AskParallel runs the operation on every agent in the agent set using several goroutines.
+// The agents are split into chunks of ChunkSize in the order they are in the agent set, and each chunk is run in order by one goroutine.
+// Each chunk gets its own random generator derived from the model's random generator and the chunk's index,
+// so the operation should draw random numbers from the generator it is passed instead of the model's random functions.
+// With the same seed and chunk size the generators are the same at any number of workers, so runs are reproducible.
+// The model's random generator is advanced once per call so asking again gives different generators.
+// Operations on different agents run at the same time so they must not change shared state without locking.
+// If the operation panics the chunks that haven't started are skipped and AskParallel panics with a *PanicError holding the agent
+// on the goroutine that called it, once every goroutine has stopped.
+func (a *AgentSet[T]) AskParallel(settings ParallelSettings, operation func(agent T, r *rand.Rand)) {
+	if operation == nil {
+		return
+	}
+	agents := a.List()
+	if len(agents) == 0 {
+		return
+	}
+
+	chunkSize := settings.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultParallelChunkSize
+	}
+	chunks := (len(agents) + chunkSize - 1) / chunkSize
+
+	workers := settings.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > chunks {
+		workers = chunks
+	}
+
+	m := agents[0].agentModel()
+	if settings.DeferMovement && m != nil && !m.InMovementPhase() {
+		m.BeginMovementPhase()
+		defer m.CommitMovementPhase()
+	}
+
+	// the seeds are drawn before any goroutine starts so they only depend on the model's random state
+	random := agentsRandom(m)
+	seed1, seed2 := random.Uint64(), random.Uint64()
+
+	// the first panic is kept and stops the chunks that haven't started yet
+	var stopped atomic.Bool
+	var panicked *PanicError
+	var panicOnce sync.Once
+
+	runChunk := func(chunk int) {
+		var current T
+		defer func() {
+			if r := recover(); r != nil {
+				stopped.Store(true)
+				panicOnce.Do(func() {
+					panicked = &PanicError{
+						AgentID: current.ID(),
+						Agent:   current,
+						Value:   r,
+						Stack:   debug.Stack(),
+					}
+				})
+			}
+		}()
+
+		r := chunkRandom(seed1, seed2, chunk)
+		end := min((chunk+1)*chunkSize, len(agents))
+		for _, agent := range agents[chunk*chunkSize : end] {
+			current = agent
+			operation(agent, r)
+		}
+	}
+
+	// goroutines take the next chunk until there are none left, which chunk a goroutine gets doesn't matter
+	// since the random generator belongs to the chunk
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stopped.Load() {
+				chunk := int(next.Add(1) - 1)
+				if chunk >= chunks {
+					return
+				}
+				runChunk(chunk)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if panicked != nil {
+		panic(panicked)
+	}
+}
+
+// returns the random generator of a chunk, a substream of the two seeds
+func chunkRandom(seed1 uint64, seed2 uint64, chunk int) *rand.Rand {
+	index := uint64(chunk)
+	return rand.New(rand.NewPCG(splitMix64(seed1^index), splitMix64(seed2+index)))
+}
+
+// scrambles the bits of x so nearby inputs give unrelated outputs, from the splitmix64 generator
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
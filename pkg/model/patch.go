@@ -105,7 +105,7 @@ func (p *Patch) removeTurtle(t *Turtle) {
 
 // returns the distance of this patch to the provided turtle
 func (p *Patch) DistanceTurtle(t *Turtle) float64 {
-	return p.parent.DistanceBetweenPointsXY(p.xFloat64, p.yFloat64, t.xcor, t.ycor)
+	return p.parent.DistanceBetweenPointsXY(p.xFloat64, p.yFloat64, t.XCor(), t.YCor())
 }
 
 // returns the distance of this patch to the provided patch
@@ -176,6 +176,40 @@ func (p *Patch) ZCor() int {
 	return p.z
 }
 
+// returns the index of the patch, which is unique within the model
+func (p *Patch) ID() int {
+	return p.index
+}
+
+// returns the color of the patch
+func (p *Patch) GetColor() Color {
+	return p.Color
+}
+
+// sets the color of the patch
+func (p *Patch) SetColor(color Color) {
+	p.Color = color
+}
+
+// returns the label of the patch
+func (p *Patch) GetLabel() interface{} {
+	return p.Label
+}
+
+// sets the label of the patch
+func (p *Patch) SetLabel(label interface{}) {
+	p.Label = label
+}
+
+// patches can't be hidden so this is always false
+func (p *Patch) IsHidden() bool {
+	return false
+}
+
+func (p *Patch) agentModel() *Model {
+	return p.parent
+}
+
 // resest the patch to the default values
 func (p *Patch) Reset(patchProperties map[string]interface{}) {
 	p.Color.SetColor(Black)
@@ -183,6 +217,8 @@ func (p *Patch) Reset(patchProperties map[string]interface{}) {
 	for key, value := range patchProperties {
 		p.patchProperties[key] = value
 	}
+
+	p.parent.resetPatchColumns(p)
 }
 
 // creates new turtles on this patch
@@ -211,6 +247,8 @@ func (p *Patch) sproutBreeded(breed *TurtleBreed, number int, operation TurtleOp
 	}
 
 	turtlesAdded.Ask(operation)
+
+	p.parent.turtlesBorn(turtlesAdded.List())
 }
 
 // returns the heading that points towards the provided patch
@@ -222,7 +260,7 @@ func (p *Patch) TowardsPatch(patch *Patch) float64 {
 // returns the heading that points towards the provided turtle
 func (p *Patch) TowardsTurtle(t *Turtle) float64 {
 	//returns heading that points towards the turtle
-	return p.TowardsXY(t.xcor, t.ycor)
+	return p.TowardsXY(t.XCor(), t.YCor())
 }
 
 // returns the heading that points towards the provided x y coordinates
@@ -264,40 +302,28 @@ func (p *Patch) turtlesHereBreeded(breed *TurtleBreed) *TurtleAgentSet {
 // GetProperty returns the patch property variable.
 // This method is thread-safe and can be called concurrently.
 func (p *Patch) GetProperty(key string) interface{} {
+	if value, ok := p.parent.patchColumnValue(p, key); ok {
+		return value
+	}
+
 	p.propertiesMutex.RLock()
 	defer p.propertiesMutex.RUnlock()
 
 	return p.patchProperties[key]
 }
 
+// returns the patch variable as an int, converting numbers the same way as setting them in an int column does
+// variables that aren't numbers give 0
 func (t *Patch) GetPropI(key string) int {
-	v := t.GetProperty(key)
-	if v == nil {
-		return 0
-	}
-	switch v := v.(type) {
-	case int:
-		return v
-	case float64:
-		return int(v)
-	default:
-		return 0
-	}
+	v, _ := patchInt(t.GetProperty(key))
+	return v
 }
 
+// returns the patch variable as a float, converting numbers the same way as setting them in a float column does
+// variables that aren't numbers give 0
 func (t *Patch) GetPropF(key string) float64 {
-	v := t.GetProperty(key)
-	if v == nil {
-		return 0
-	}
-	switch v := v.(type) {
-	case int:
-		return float64(v)
-	case float64:
-		return v
-	default:
-		return 0
-	}
+	v, _ := patchFloat(t.GetProperty(key))
+	return v
 }
 
 func (t *Patch) GetPropS(key string) string {
@@ -329,6 +355,10 @@ func (t *Patch) GetPropB(key string) bool {
 // SetProperty sets the patch property variable.
 // This method is thread-safe and can be called concurrently.
 func (p *Patch) SetProperty(key string, value interface{}) {
+	if p.parent.setPatchColumnValue(p, key, value) {
+		return
+	}
+
 	p.propertiesMutex.Lock()
 	defer p.propertiesMutex.Unlock()
 
@@ -342,3 +372,16 @@ func (p *Patch) SetProperty(key string, value interface{}) {
 	}
 	p.patchProperties[key] = value
 }
+
+// returns a copy of all the patch's property variables, including its values in the patch columns
+func (p *Patch) Properties() map[string]interface{} {
+	p.propertiesMutex.RLock()
+	properties := make(map[string]interface{}, len(p.patchProperties))
+	for key, value := range p.patchProperties {
+		properties[key] = value
+	}
+	p.propertiesMutex.RUnlock()
+
+	p.parent.addPatchColumnValues(p, properties)
+	return properties
+}
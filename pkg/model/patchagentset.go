@@ -1,62 +1,39 @@
 package model
 
-import (
-	"github.com/nlatham1999/sortedset"
-)
+import "github.com/nlatham1999/sortedset"
 
 // PatchAgentSet is an ordered set of patches than can be sorted
-// implements github.com/nlatham1999/sortedset
+// it is built on AgentSet so the methods that don't return an agent set come from there
 type PatchAgentSet struct {
-	patches *sortedset.SortedSet
+	*AgentSet[*Patch]
 }
 
 // create a new PatchAgentSet
 func NewPatchAgentSet(patches []*Patch) *PatchAgentSet {
-	patchSet := &PatchAgentSet{
-		patches: sortedset.NewSortedSet(),
-	}
-	for _, patch := range patches {
-		patchSet.patches.Add(patch)
+	return &PatchAgentSet{
+		AgentSet: NewAgentSet(patches),
 	}
-	return patchSet
 }
 
-// add a patch to the agent set
-func (p *PatchAgentSet) Add(patch *Patch) {
-	p.patches.Add(patch)
-}
-
-// returns true if all the patches in the agent set satisfy the operation
-func (p *PatchAgentSet) All(operation PatchBoolOperation) bool {
-	if operation == nil {
-		return false
+func newPatchAgentSet(set *AgentSet[*Patch]) *PatchAgentSet {
+	if set == nil {
+		return nil
 	}
-
-	return p.patches.All(func(a interface{}) bool {
-		return operation(a.(*Patch))
-	})
-}
-
-// returns true if any of the patches in the agent set satisfy the operation
-func (p *PatchAgentSet) Any(operation PatchBoolOperation) bool {
-	if operation == nil {
-		return false
+	return &PatchAgentSet{
+		AgentSet: set,
 	}
-
-	return p.patches.Any(func(a interface{}) bool {
-		return operation(a.(*Patch))
-	})
 }
 
-// perform the operation for all patches in the agent set
-func (p *PatchAgentSet) Ask(operation PatchOperation) {
-	if operation == nil {
-		return
+// AskStaged runs each stage on all patches in the agent set before moving on to the next stage.
+// The patches are visited in the order given by the scheduler, which is asked for a new order every stage.
+// If the scheduler is buffered the updates returned by the patches are committed once the whole stage has been run.
+// If the scheduler is nil then the model's scheduler is used.
+func (p *PatchAgentSet) AskStaged(scheduler Scheduler, stages []PatchStage) {
+	operations := make([]func(*Patch) func(), len(stages))
+	for i, stage := range stages {
+		operations[i] = stage.Operation
 	}
-
-	p.patches.Ask(func(a interface{}) {
-		operation(a.(*Patch))
-	})
+	p.askStaged(scheduler, operations)
 }
 
 // returns a subset of patches that are at the given coordinates
@@ -66,162 +43,100 @@ func (p *PatchAgentSet) AtPoints(m *Model, points []Coordinate) *PatchAgentSet {
 	for _, point := range points {
 		patch := m.Patch(point.X, point.Y)
 		if patch != nil {
-			if p.patches.Contains(patch) {
+			if p.Contains(patch) {
 				patchesAtPoints.Add(patch)
 			}
 		}
 	}
 
-	return &PatchAgentSet{
-		patches: patchesAtPoints,
-	}
-
-}
-
-// returns true if the patch is in the agent set
-func (p *PatchAgentSet) Contains(patch *Patch) bool {
-	return p.patches.Contains(patch)
+	return newPatchAgentSet(newAgentSet[*Patch](patchesAtPoints))
 }
 
 // returns a copy of the agent set
 func (p *PatchAgentSet) Copy() *PatchAgentSet {
-	return &PatchAgentSet{
-		// patches: p.patches.Copy(),
-	}
-}
-
-// returns the number of patches in the agent set
-func (p *PatchAgentSet) Count() int {
-	return p.patches.Len()
-}
-
-// returns the agent set as a list
-func (p *PatchAgentSet) List() []*Patch {
-	patches := make([]*Patch, 0)
-	p.patches.Ask(func(a interface{}) {
-		patches = append(patches, a.(*Patch))
-	})
-	return patches
+	return newPatchAgentSet(p.AgentSet.Copy())
 }
 
 // returns the first n patches in the agent set
 func (p *PatchAgentSet) FirstNOf(n int) *PatchAgentSet {
-	patchSet := sortedset.NewSortedSet()
-	patch := p.patches.First()
-	for i := 0; i < n && patch != nil; i++ {
-		patchSet.Add(patch)
-		patch, _ = p.patches.Next()
-	}
-	return &PatchAgentSet{
-		patches: patchSet,
-	}
-}
-
-// returns the first patch in the agent set
-func (p *PatchAgentSet) First() (*Patch, error) {
-	patch := p.patches.First()
-	if patch == nil {
-		return nil, ErrNoLinksInAgentSet
-	}
-	return patch.(*Patch), nil
+	return newPatchAgentSet(p.AgentSet.FirstNOf(n))
 }
 
 // returns the last n patches in the agent set
 func (p *PatchAgentSet) LastNOf(n int) *PatchAgentSet {
-	patchSet := sortedset.NewSortedSet()
-	patch := p.patches.Last()
-	for i := 0; i < n && patch != nil; i++ {
-		patchSet.Add(patch)
-		patch, _ = p.patches.Previous()
-	}
-	return &PatchAgentSet{
-		patches: patchSet,
-	}
+	return newPatchAgentSet(p.AgentSet.LastNOf(n))
 }
 
-// returns the last patch in the agent set
-func (p *PatchAgentSet) Last() (*Patch, error) {
-	patch := p.patches.Last()
-	if patch == nil {
-		return nil, ErrNoLinksInAgentSet
-	}
-	return patch.(*Patch), nil
+// returns a new PatchAgentSet with all the patches that are not in the given PatchAgentSet
+func (p *PatchAgentSet) WhoAreNot(patches *PatchAgentSet) *PatchAgentSet {
+	return newPatchAgentSet(p.AgentSet.Difference(patches.AgentSet))
 }
 
-// returns a random patch in the agent set that satisfies the operation
-// @TODO - this is not using the rand value so it's not deterministic
-func (p *PatchAgentSet) RandomWhere(operation PatchBoolOperation) *Patch {
-	if operation == nil {
-		return nil
-	}
-
-	patch := p.patches.RandomValueWhere(func(a interface{}) bool {
-		return operation(a.(*Patch))
-	})
-
-	if patch == nil {
-		return nil
-	}
-
-	return patch.(*Patch)
+// returns a new PatchAgentSet with all the patches that are not the given patch
+func (p *PatchAgentSet) WhoAreNotPatch(patch *Patch) *PatchAgentSet {
+	return newPatchAgentSet(p.WhoAreNotAgent(patch))
 }
 
-// func (p *PatchAgentSet) Next() (*Patch, error) {
-// 	patch, err := p.patches.Next()
-// 	if err != nil {
-// 		return nil, ErrNoLinksInAgentSet
-// 	}
-// 	return patch.(*Patch), nil
-// }
+// returns a new agent set that contains all the patches that satisfy the operation
+// returns nil if the operation is nil
+func (p *PatchAgentSet) With(operation PatchBoolOperation) *PatchAgentSet {
+	return newPatchAgentSet(p.AgentSet.With(operation))
+}
 
-// remove a patch from the agent set
-func (p *PatchAgentSet) Remove(patch *Patch) {
-	p.patches.Remove(patch)
+// returns n random patches from the agent set, or all of them if there are fewer than n
+// the patches stay in the order they are in the agent set
+func (p *PatchAgentSet) NOf(n int) *PatchAgentSet {
+	return newPatchAgentSet(p.AgentSet.NOf(n))
 }
 
-// sort the patches in the agent set in ascending order based on the float operation
-func (p *PatchAgentSet) SortAsc(operation PatchFloatOperation) {
-	p.patches.SortAsc(func(a interface{}) interface{} {
-		return operation(a.(*Patch))
-	})
+// returns n different patches from the agent set picked with chances proportional to their weights
+// patches with a weight that isn't positive are never picked so fewer than n may be returned
+// the patches stay in the order they are in the agent set
+func (p *PatchAgentSet) WeightedNOf(n int, weight PatchFloatOperation) *PatchAgentSet {
+	return newPatchAgentSet(p.AgentSet.WeightedNOf(n, weight))
 }
 
-// sort the patches in the agent set in descending order based on the float operation
-func (p *PatchAgentSet) SortDesc(operation PatchFloatOperation) {
-	p.patches.SortDesc(func(a interface{}) interface{} {
-		return operation(a.(*Patch))
-	})
+// returns the n patches with the largest values of the operation, ties at the cut off are broken at random
+// the patches stay in the order they are in the agent set
+func (p *PatchAgentSet) MaxNOf(n int, operation PatchFloatOperation) *PatchAgentSet {
+	return newPatchAgentSet(p.AgentSet.MaxNOf(n, operation))
 }
 
-// returns a new PatchAgentSet with all the patches that are not in the given PatchAgentSet
-func (p *PatchAgentSet) WhoAreNot(patches *PatchAgentSet) *PatchAgentSet {
-	return &PatchAgentSet{
-		patches: p.patches.Difference(patches.patches),
-	}
+// returns the n patches with the smallest values of the operation, ties at the cut off are broken at random
+// the patches stay in the order they are in the agent set
+func (p *PatchAgentSet) MinNOf(n int, operation PatchFloatOperation) *PatchAgentSet {
+	return newPatchAgentSet(p.AgentSet.MinNOf(n, operation))
 }
 
-// returns a new PatchAgentSet with all the patches that are not the given patch
-func (p *PatchAgentSet) WhoAreNotPatch(patch *Patch) *PatchAgentSet {
-	return &PatchAgentSet{
-		patches: p.patches.Difference(sortedset.NewSortedSet(patch)),
+// splits the patches into agent sets by the key the operation returns for each patch
+// the keys have to be comparable, the patches in each agent set stay in the order they are in the agent set
+func (p *PatchAgentSet) GroupBy(operation PatchKeyOperation) map[interface{}]*PatchAgentSet {
+	groups := map[interface{}]*PatchAgentSet{}
+	for key, group := range p.AgentSet.GroupBy(operation) {
+		groups[key] = newPatchAgentSet(group)
 	}
+	return groups
 }
 
-// returns a new agent set that contains all the patches that satisfy the operation
-func (p *PatchAgentSet) With(operation PatchBoolOperation) *PatchAgentSet {
-	patchSet := sortedset.NewSortedSet()
+// splits the patches into agent sets by the value of the property
+func (p *PatchAgentSet) GroupByProperty(name string) map[interface{}]*PatchAgentSet {
+	return p.GroupBy(func(patch *Patch) interface{} {
+		return patch.GetProperty(name)
+	})
+}
 
-	if operation == nil {
-		return nil
-	}
+// returns a new agent set with the patches in either agent set
+// the patches of this agent set come first followed by the ones only in the other
+func (p *PatchAgentSet) Union(other *PatchAgentSet) *PatchAgentSet {
+	return newPatchAgentSet(p.AgentSet.Union(other.AgentSet))
+}
 
-	p.patches.Ask(func(a interface{}) {
-		if operation(a.(*Patch)) {
-			patchSet.Add(a)
-		}
-	})
+// returns a new agent set with the patches in both agent sets, in the order of this agent set
+func (p *PatchAgentSet) Intersect(other *PatchAgentSet) *PatchAgentSet {
+	return newPatchAgentSet(p.AgentSet.Intersect(other.AgentSet))
+}
 
-	return &PatchAgentSet{
-		patches: patchSet,
-	}
+// returns a new agent set with the patches of this agent set that aren't in the other, same as WhoAreNot
+func (p *PatchAgentSet) Difference(other *PatchAgentSet) *PatchAgentSet {
+	return newPatchAgentSet(p.AgentSet.Difference(other.AgentSet))
 }
@@ -0,0 +1,426 @@
+package model
+
+// patch variables can be declared as typed columns instead of entries in each patch's property map
+// a column holds the value for every patch in one slice indexed by the patch's index
+// so operations over the whole world don't need a map lookup or a type assertion per patch
+//
+// columns are declared with ModelSettings.PatchColumns, the type of the default value decides the type of the column
+// float64, int and bool are supported
+// GetProperty and SetProperty still work for columns so existing code doesn't need to change
+//
+// setting values of different patches at the same time is safe
+// the column wide operations should not be run while other goroutines are changing the column
+
+// FloatColumn is a patch variable stored as a float64 for every patch
+type FloatColumn struct {
+	name         string
+	defaultValue float64
+	values       []float64
+}
+
+// IntColumn is a patch variable stored as an int for every patch
+type IntColumn struct {
+	name         string
+	defaultValue int
+	values       []int
+}
+
+// BoolColumn is a patch variable stored as a bool for every patch
+type BoolColumn struct {
+	name         string
+	defaultValue bool
+	values       []bool
+}
+
+// builds the columns declared in the settings, values of unsupported types are left as regular patch properties
+func (m *Model) buildPatchColumns(columns map[string]interface{}) {
+	m.floatColumns = make(map[string]*FloatColumn)
+	m.intColumns = make(map[string]*IntColumn)
+	m.boolColumns = make(map[string]*BoolColumn)
+
+	size := m.worldWidth * m.worldHeight * m.worldDepth
+
+	for name, value := range columns {
+		switch v := value.(type) {
+		case float64:
+			column := &FloatColumn{name: name, defaultValue: v, values: make([]float64, size)}
+			column.Fill(v)
+			m.floatColumns[name] = column
+		case int:
+			column := &IntColumn{name: name, defaultValue: v, values: make([]int, size)}
+			column.Fill(v)
+			m.intColumns[name] = column
+		case bool:
+			column := &BoolColumn{name: name, defaultValue: v, values: make([]bool, size)}
+			column.Fill(v)
+			m.boolColumns[name] = column
+		}
+	}
+}
+
+// returns the float column with the name, or nil if there is no float column with that name
+func (m *Model) FloatColumn(name string) *FloatColumn {
+	return m.floatColumns[name]
+}
+
+// returns the int column with the name, or nil if there is no int column with that name
+func (m *Model) IntColumn(name string) *IntColumn {
+	return m.intColumns[name]
+}
+
+// returns the bool column with the name, or nil if there is no bool column with that name
+func (m *Model) BoolColumn(name string) *BoolColumn {
+	return m.boolColumns[name]
+}
+
+// returns the declared patch columns and their default values
+func (m *Model) PatchColumns() map[string]interface{} {
+	columns := make(map[string]interface{})
+	for name, column := range m.floatColumns {
+		columns[name] = column.defaultValue
+	}
+	for name, column := range m.intColumns {
+		columns[name] = column.defaultValue
+	}
+	for name, column := range m.boolColumns {
+		columns[name] = column.defaultValue
+	}
+	return columns
+}
+
+// returns the value of the patch in the column with the name
+// the second value is false if there is no column with that name
+func (m *Model) patchColumnValue(p *Patch, name string) (interface{}, bool) {
+	// patches from before a restore have no model
+	if m == nil {
+		return nil, false
+	}
+	if column, ok := m.floatColumns[name]; ok {
+		return column.values[p.index], true
+	}
+	if column, ok := m.intColumns[name]; ok {
+		return column.values[p.index], true
+	}
+	if column, ok := m.boolColumns[name]; ok {
+		return column.values[p.index], true
+	}
+	return nil, false
+}
+
+// sets the value of the patch in the column with the name
+// numbers are converted to the type of the column, values of the wrong type are ignored
+// returns false if there is no column with that name
+func (m *Model) setPatchColumnValue(p *Patch, name string, value interface{}) bool {
+	if m == nil {
+		return false
+	}
+	if column, ok := m.floatColumns[name]; ok {
+		if v, ok := patchFloat(value); ok {
+			column.values[p.index] = v
+		}
+		return true
+	}
+	if column, ok := m.intColumns[name]; ok {
+		if v, ok := patchInt(value); ok {
+			column.values[p.index] = v
+		}
+		return true
+	}
+	if column, ok := m.boolColumns[name]; ok {
+		if v, ok := value.(bool); ok {
+			column.values[p.index] = v
+		}
+		return true
+	}
+	return false
+}
+
+// adds the patch's value in every column to the properties
+func (m *Model) addPatchColumnValues(p *Patch, properties map[string]interface{}) {
+	if m == nil {
+		return
+	}
+	for name, column := range m.floatColumns {
+		properties[name] = column.values[p.index]
+	}
+	for name, column := range m.intColumns {
+		properties[name] = column.values[p.index]
+	}
+	for name, column := range m.boolColumns {
+		properties[name] = column.values[p.index]
+	}
+}
+
+// converts a number to a float64 the same way for columns and untyped patch variables
+// returns false if the value isn't a number
+func patchFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// converts a number to an int the same way for columns and untyped patch variables, floats are truncated
+// returns false if the value isn't a number
+func patchInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+// resets the patch's value in every column to the column's default
+func (m *Model) resetPatchColumns(p *Patch) {
+	for _, column := range m.floatColumns {
+		column.values[p.index] = column.defaultValue
+	}
+	for _, column := range m.intColumns {
+		column.values[p.index] = column.defaultValue
+	}
+	for _, column := range m.boolColumns {
+		column.values[p.index] = column.defaultValue
+	}
+}
+
+// returns functions that read and write a numeric patch variable, either from a float column or the patch properties
+// ok is false if the variable is neither a float column nor a float64 patch property
+func (m *Model) patchFloatAccessors(name string) (get func(p *Patch) float64, set func(p *Patch, value float64), ok bool) {
+	if column, ok := m.floatColumns[name]; ok {
+		get = func(p *Patch) float64 {
+			return column.values[p.index]
+		}
+		set = func(p *Patch, value float64) {
+			column.values[p.index] = value
+		}
+		return get, set, true
+	}
+
+	if _, ok := m.DefaultPatchProperties[name].(float64); !ok {
+		return nil, nil, false
+	}
+
+	get = func(p *Patch) float64 {
+		return p.patchProperties[name].(float64)
+	}
+	set = func(p *Patch, value float64) {
+		p.patchProperties[name] = value
+	}
+	return get, set, true
+}
+
+// returns the name of the column
+func (c *FloatColumn) Name() string {
+	return c.name
+}
+
+// returns the value the column is reset to
+func (c *FloatColumn) Default() float64 {
+	return c.defaultValue
+}
+
+// returns the value for the patch
+func (c *FloatColumn) Get(p *Patch) float64 {
+	return c.values[p.index]
+}
+
+// sets the value for the patch
+func (c *FloatColumn) Set(p *Patch, value float64) {
+	c.values[p.index] = value
+}
+
+// returns the values of the column indexed by patch index
+// the slice is the column itself so changing it changes the patches
+func (c *FloatColumn) Values() []float64 {
+	return c.values
+}
+
+// sets every patch to the value
+func (c *FloatColumn) Fill(value float64) {
+	for i := range c.values {
+		c.values[i] = value
+	}
+}
+
+// multiplies every patch by the factor
+func (c *FloatColumn) Scale(factor float64) {
+	for i := range c.values {
+		c.values[i] *= factor
+	}
+}
+
+// adds the values of the other column to this column patch by patch
+func (c *FloatColumn) Add(other *FloatColumn) {
+	c.AddScaled(other, 1)
+}
+
+// adds the values of the other column multiplied by the factor to this column patch by patch
+func (c *FloatColumn) AddScaled(other *FloatColumn, factor float64) {
+	if other == nil {
+		return
+	}
+	for i := range c.values {
+		c.values[i] += other.values[i] * factor
+	}
+}
+
+// returns the smallest value in the column
+func (c *FloatColumn) Min() float64 {
+	min := c.values[0]
+	for _, value := range c.values[1:] {
+		if value < min {
+			min = value
+		}
+	}
+	return min
+}
+
+// returns the largest value in the column
+func (c *FloatColumn) Max() float64 {
+	max := c.values[0]
+	for _, value := range c.values[1:] {
+		if value > max {
+			max = value
+		}
+	}
+	return max
+}
+
+// returns the sum of all the values in the column
+func (c *FloatColumn) Sum() float64 {
+	sum := 0.0
+	for _, value := range c.values {
+		sum += value
+	}
+	return sum
+}
+
+// returns the name of the column
+func (c *IntColumn) Name() string {
+	return c.name
+}
+
+// returns the value the column is reset to
+func (c *IntColumn) Default() int {
+	return c.defaultValue
+}
+
+// returns the value for the patch
+func (c *IntColumn) Get(p *Patch) int {
+	return c.values[p.index]
+}
+
+// sets the value for the patch
+func (c *IntColumn) Set(p *Patch, value int) {
+	c.values[p.index] = value
+}
+
+// returns the values of the column indexed by patch index
+// the slice is the column itself so changing it changes the patches
+func (c *IntColumn) Values() []int {
+	return c.values
+}
+
+// sets every patch to the value
+func (c *IntColumn) Fill(value int) {
+	for i := range c.values {
+		c.values[i] = value
+	}
+}
+
+// multiplies every patch by the factor
+func (c *IntColumn) Scale(factor int) {
+	for i := range c.values {
+		c.values[i] *= factor
+	}
+}
+
+// adds the values of the other column to this column patch by patch
+func (c *IntColumn) Add(other *IntColumn) {
+	if other == nil {
+		return
+	}
+	for i := range c.values {
+		c.values[i] += other.values[i]
+	}
+}
+
+// returns the smallest value in the column
+func (c *IntColumn) Min() int {
+	min := c.values[0]
+	for _, value := range c.values[1:] {
+		if value < min {
+			min = value
+		}
+	}
+	return min
+}
+
+// returns the largest value in the column
+func (c *IntColumn) Max() int {
+	max := c.values[0]
+	for _, value := range c.values[1:] {
+		if value > max {
+			max = value
+		}
+	}
+	return max
+}
+
+// returns the sum of all the values in the column
+func (c *IntColumn) Sum() int {
+	sum := 0
+	for _, value := range c.values {
+		sum += value
+	}
+	return sum
+}
+
+// returns the name of the column
+func (c *BoolColumn) Name() string {
+	return c.name
+}
+
+// returns the value the column is reset to
+func (c *BoolColumn) Default() bool {
+	return c.defaultValue
+}
+
+// returns the value for the patch
+func (c *BoolColumn) Get(p *Patch) bool {
+	return c.values[p.index]
+}
+
+// sets the value for the patch
+func (c *BoolColumn) Set(p *Patch, value bool) {
+	c.values[p.index] = value
+}
+
+// returns the values of the column indexed by patch index
+// the slice is the column itself so changing it changes the patches
+func (c *BoolColumn) Values() []bool {
+	return c.values
+}
+
+// sets every patch to the value
+func (c *BoolColumn) Fill(value bool) {
+	for i := range c.values {
+		c.values[i] = value
+	}
+}
+
+// returns the number of patches that are true
+func (c *BoolColumn) Count() int {
+	count := 0
+	for _, value := range c.values {
+		if value {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,338 @@
+package model
+
+import (
+	"container/heap"
+	"math"
+)
+
+// PathSettings holds the settings for finding a path between patches
+type PathSettings struct {
+	Walkable           PatchBoolOperation  // returns true if the path can go through the patch, nil means every patch is walkable
+	Cost               PatchFloatOperation // cost of entering the patch, nil means every patch costs 1, patches with a negative cost are not walkable
+	Connectivity       int                 // 4 or 8, in 3D 4 moves along the axes (6 neighbors) and 8 moves diagonally too (26 neighbors), defaults to 8
+	AllowCornerCutting bool                // if true diagonal moves can squeeze between two patches that are not walkable
+	MinCost            float64             // lowest cost of entering any patch, used by AStar to estimate the remaining cost, defaults to 1
+}
+
+// Path is an ordered list of patches from the start to the goal, including both
+// the cost of a diagonal step is the cost of the patch times the length of the step
+type Path struct {
+	Patches []*Patch
+	Cost    float64
+}
+
+// offset to a neighboring patch and the length of the step
+type pathStep struct {
+	dx     int
+	dy     int
+	dz     int
+	length float64
+}
+
+// returns the steps a path can take from a patch
+func (m *Model) pathSteps(connectivity int) ([]pathStep, error) {
+	if connectivity == 0 {
+		connectivity = 8
+	}
+	if connectivity != 4 && connectivity != 8 {
+		return nil, ErrPathConnectivity
+	}
+
+	zRange := 0
+	if m.Is3D() {
+		zRange = 1
+	}
+
+	steps := []pathStep{}
+	for dz := -zRange; dz <= zRange; dz++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				axes := absInt(dx) + absInt(dy) + absInt(dz)
+				if axes == 0 || (connectivity == 4 && axes > 1) {
+					continue
+				}
+				steps = append(steps, pathStep{dx: dx, dy: dy, dz: dz, length: math.Sqrt(float64(axes))})
+			}
+		}
+	}
+	return steps, nil
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// returns the patch at the offset from the patch, wrapping if the world wraps
+// returns nil if the offset leaves the world
+func (m *Model) patchAtOffset(p *Patch, dx int, dy int, dz int) *Patch {
+	x := p.x + dx
+	y := p.y + dy
+	z := p.z + dz
+
+	if m.wrappingX {
+		x = ((x-m.minPxCor)%m.worldWidth+m.worldWidth)%m.worldWidth + m.minPxCor
+	}
+	if m.wrappingY {
+		y = ((y-m.minPyCor)%m.worldHeight+m.worldHeight)%m.worldHeight + m.minPyCor
+	}
+
+	return m.getPatchAtCoords(x, y, z)
+}
+
+// returns the shortest number of patches between two patches along each axis, going around if the world wraps
+func (m *Model) patchAxisDistances(a *Patch, b *Patch) (float64, float64, float64) {
+	dx := absInt(a.x - b.x)
+	dy := absInt(a.y - b.y)
+	dz := absInt(a.z - b.z)
+	if m.wrappingX && m.worldWidth-dx < dx {
+		dx = m.worldWidth - dx
+	}
+	if m.wrappingY && m.worldHeight-dy < dy {
+		dy = m.worldHeight - dy
+	}
+	return float64(dx), float64(dy), float64(dz)
+}
+
+// estimates the length of the shortest path between two patches ignoring anything that isn't walkable
+func (m *Model) pathHeuristic(a *Patch, b *Patch, connectivity int) float64 {
+	dx, dy, dz := m.patchAxisDistances(a, b)
+
+	if connectivity == 4 {
+		return dx + dy + dz
+	}
+
+	// move diagonally along all three axes first, then along two, then along one
+	small := math.Min(dx, math.Min(dy, dz))
+	large := math.Max(dx, math.Max(dy, dz))
+	middle := dx + dy + dz - small - large
+
+	return small*math.Sqrt(3) + (middle-small)*math.Sqrt(2) + (large - middle)
+}
+
+// item in the queue of patches waiting to be explored
+type pathNode struct {
+	patch    *Patch
+	priority float64
+	sequence int // order the node was added in, keeps the search deterministic when priorities are equal
+}
+
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int {
+	return len(q)
+}
+
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].priority == q[j].priority {
+		return q[i].sequence < q[j].sequence
+	}
+	return q[i].priority < q[j].priority
+}
+
+func (q pathQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *pathQueue) Push(x interface{}) {
+	*q = append(*q, x.(pathNode))
+}
+
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	node := old[n-1]
+	*q = old[:n-1]
+	return node
+}
+
+// runs a best first search from the start
+// if goal is nil every reachable patch is explored
+// useHeuristic turns the search from Dijkstra into A*
+func (m *Model) searchPaths(start *Patch, goal *Patch, settings PathSettings, useHeuristic bool) (map[*Patch]float64, map[*Patch]*Patch, error) {
+	steps, err := m.pathSteps(settings.Connectivity)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	connectivity := settings.Connectivity
+	if connectivity == 0 {
+		connectivity = 8
+	}
+
+	minCost := settings.MinCost
+	if minCost <= 0 {
+		minCost = 1
+	}
+
+	walkable := func(p *Patch) bool {
+		if p == nil {
+			return false
+		}
+		if settings.Walkable != nil && !settings.Walkable(p) {
+			return false
+		}
+		return settings.Cost == nil || settings.Cost(p) >= 0
+	}
+
+	cost := func(p *Patch) float64 {
+		if settings.Cost == nil {
+			return 1
+		}
+		return settings.Cost(p)
+	}
+
+	costs := map[*Patch]float64{start: 0}
+	previous := map[*Patch]*Patch{}
+	done := map[*Patch]bool{}
+
+	queue := &pathQueue{}
+	sequence := 0
+	heap.Push(queue, pathNode{patch: start})
+
+	for queue.Len() > 0 {
+		node := heap.Pop(queue).(pathNode)
+		current := node.patch
+		if done[current] {
+			continue
+		}
+		done[current] = true
+
+		if current == goal {
+			break
+		}
+
+		for _, step := range steps {
+			next := m.patchAtOffset(current, step.dx, step.dy, step.dz)
+			if next == nil || done[next] || !walkable(next) {
+				continue
+			}
+
+			// a diagonal move can't squeeze past patches that are not walkable along the way
+			if !settings.AllowCornerCutting && step.length > 1 {
+				blocked := (step.dx != 0 && !walkable(m.patchAtOffset(current, step.dx, 0, 0))) ||
+					(step.dy != 0 && !walkable(m.patchAtOffset(current, 0, step.dy, 0))) ||
+					(step.dz != 0 && !walkable(m.patchAtOffset(current, 0, 0, step.dz)))
+				if blocked {
+					continue
+				}
+			}
+
+			newCost := costs[current] + cost(next)*step.length
+			if oldCost, ok := costs[next]; ok && oldCost <= newCost {
+				continue
+			}
+			costs[next] = newCost
+			previous[next] = current
+
+			priority := newCost
+			if useHeuristic && goal != nil {
+				priority += m.pathHeuristic(next, goal, connectivity) * minCost
+			}
+
+			sequence++
+			heap.Push(queue, pathNode{patch: next, priority: priority, sequence: sequence})
+		}
+	}
+
+	return costs, previous, nil
+}
+
+// builds the path to the goal from the patches each patch was reached from
+func buildPath(start *Patch, goal *Patch, costs map[*Patch]float64, previous map[*Patch]*Patch) (*Path, error) {
+	cost, ok := costs[goal]
+	if !ok {
+		return nil, ErrNoPath
+	}
+
+	patches := []*Patch{goal}
+	for current := goal; current != start; {
+		current = previous[current]
+		patches = append(patches, current)
+	}
+
+	// the path was built from the goal back so reverse it
+	for i, j := 0, len(patches)-1; i < j; i, j = i+1, j-1 {
+		patches[i], patches[j] = patches[j], patches[i]
+	}
+
+	return &Path{
+		Patches: patches,
+		Cost:    cost,
+	}, nil
+}
+
+// finds the cheapest path from the start patch to the goal patch using A*
+// A* only looks at patches that can lead to the goal so it is usually faster than Dijkstra
+// the path is only guaranteed to be the cheapest if no patch costs less than settings.MinCost
+// returns ErrNoPath if the goal can't be reached
+func (m *Model) AStar(start *Patch, goal *Patch, settings PathSettings) (*Path, error) {
+	if start == nil || goal == nil {
+		return nil, ErrNoPath
+	}
+
+	costs, previous, err := m.searchPaths(start, goal, settings, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildPath(start, goal, costs, previous)
+}
+
+// finds the cheapest path from the start patch to the goal patch using Dijkstra's algorithm
+// returns ErrNoPath if the goal can't be reached
+func (m *Model) Dijkstra(start *Patch, goal *Patch, settings PathSettings) (*Path, error) {
+	if start == nil || goal == nil {
+		return nil, ErrNoPath
+	}
+
+	costs, previous, err := m.searchPaths(start, goal, settings, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildPath(start, goal, costs, previous)
+}
+
+// returns the cost of the cheapest path from the start patch to every patch that can be reached from it
+// useful for flow fields where many turtles head towards the same patch
+func (m *Model) PathCostsFrom(start *Patch, settings PathSettings) (map[*Patch]float64, error) {
+	if start == nil {
+		return nil, ErrNoPath
+	}
+
+	costs, _, err := m.searchPaths(start, nil, settings, false)
+	return costs, err
+}
+
+// moves the turtle one patch along the path
+// if the turtle is on a patch of the path it faces and moves to the next patch of the path
+// if it isn't on the path it moves to the start of the path
+// returns true once the turtle is on the last patch of the path
+func (t *Turtle) FollowPath(path *Path) bool {
+	if path == nil || len(path.Patches) == 0 {
+		return true
+	}
+
+	here := t.PatchHere()
+
+	next := path.Patches[0]
+	for i, patch := range path.Patches {
+		if patch == here {
+			if i == len(path.Patches)-1 {
+				return true
+			}
+			next = path.Patches[i+1]
+			break
+		}
+	}
+
+	if next != here {
+		t.FacePatch(next)
+	}
+	t.MoveToPatch(next)
+
+	return next == path.Patches[len(path.Patches)-1]
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"iter"
+	"sort"
+)
+
+// Query is a lazy pipeline over the agents of an agentset
+// each step returns a new query and nothing is run until the agents are asked for with Seq, Ask, Count, List or an aggregate
+// the agentset is read when the query is run, not when it is built, so a query can be built once and run every tick
+type Query[T any] struct {
+	seq iter.Seq[T]
+}
+
+// returns a query over the agents of the sequence
+func NewQuery[T any](seq iter.Seq[T]) *Query[T] {
+	return &Query[T]{seq: seq}
+}
+
+// keeps only the agents the operation returns true for
+func (q *Query[T]) Where(operation func(T) bool) *Query[T] {
+	seq := q.seq
+	return &Query[T]{seq: func(yield func(T) bool) {
+		for agent := range seq {
+			if operation(agent) && !yield(agent) {
+				return
+			}
+		}
+	}}
+}
+
+// orders the agents by the operation from smallest to largest, agents with the same value keep their order
+// sorting has to see every agent before the first one is passed on
+func (q *Query[T]) SortAsc(operation func(T) float64) *Query[T] {
+	return q.sortBy(operation, false)
+}
+
+// orders the agents by the operation from largest to smallest, agents with the same value keep their order
+// sorting has to see every agent before the first one is passed on
+func (q *Query[T]) SortDesc(operation func(T) float64) *Query[T] {
+	return q.sortBy(operation, true)
+}
+
+func (q *Query[T]) sortBy(operation func(T) float64, descending bool) *Query[T] {
+	seq := q.seq
+	return &Query[T]{seq: func(yield func(T) bool) {
+		agents := []T{}
+		keys := []float64{}
+		for agent := range seq {
+			agents = append(agents, agent)
+			keys = append(keys, operation(agent))
+		}
+
+		order := make([]int, len(agents))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			if descending {
+				return keys[order[i]] > keys[order[j]]
+			}
+			return keys[order[i]] < keys[order[j]]
+		})
+
+		for _, i := range order {
+			if !yield(agents[i]) {
+				return
+			}
+		}
+	}}
+}
+
+// keeps only the first n agents, the agents after them are never looked at
+func (q *Query[T]) Limit(n int) *Query[T] {
+	seq := q.seq
+	return &Query[T]{seq: func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		count := 0
+		for agent := range seq {
+			if !yield(agent) {
+				return
+			}
+			count++
+			if count >= n {
+				return
+			}
+		}
+	}}
+}
+
+// returns the agents of the query so they can be ranged over
+func (q *Query[T]) Seq() iter.Seq[T] {
+	return q.seq
+}
+
+// returns the value of the operation for each agent of the query
+func (q *Query[T]) Floats(operation func(T) float64) iter.Seq[float64] {
+	seq := q.seq
+	return func(yield func(float64) bool) {
+		for agent := range seq {
+			if !yield(operation(agent)) {
+				return
+			}
+		}
+	}
+}
+
+// runs the operation on each agent of the query
+func (q *Query[T]) Ask(operation func(T)) {
+	for agent := range q.seq {
+		operation(agent)
+	}
+}
+
+// returns the number of agents in the query
+func (q *Query[T]) Count() int {
+	count := 0
+	for range q.seq {
+		count++
+	}
+	return count
+}
+
+// returns the agents of the query
+func (q *Query[T]) List() []T {
+	agents := []T{}
+	for agent := range q.seq {
+		agents = append(agents, agent)
+	}
+	return agents
+}
+
+// returns the first agent of the query, only looking at as many agents as needed
+func (q *Query[T]) First() (T, error) {
+	for agent := range q.seq {
+		return agent, nil
+	}
+	var none T
+	return none, ErrNoAgentsInQuery
+}
+
+// returns true if the operation is true for any agent of the query, stopping at the first one
+func (q *Query[T]) Any(operation func(T) bool) bool {
+	for agent := range q.seq {
+		if operation(agent) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the sum of the operation over the agents of the query, 0 if there are none
+func (q *Query[T]) Sum(operation func(T) float64) float64 {
+	sum := 0.0
+	for value := range q.Floats(operation) {
+		sum += value
+	}
+	return sum
+}
+
+// returns the mean of the operation over the agents of the query
+func (q *Query[T]) Mean(operation func(T) float64) (float64, error) {
+	sum := 0.0
+	count := 0
+	for value := range q.Floats(operation) {
+		sum += value
+		count++
+	}
+	if count == 0 {
+		return 0, ErrNoAgentsInQuery
+	}
+	return sum / float64(count), nil
+}
@@ -0,0 +1,281 @@
+package model
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// all the random draws use the model's random generator so runs are reproducible with the same seed
+
+// returns a random number from a normal distribution with the mean and standard deviation
+func (m *Model) RandomNormal(mean float64, stdDev float64) float64 {
+	return m.randomGenerator.NormFloat64()*stdDev + mean
+}
+
+// returns a random number whose natural log is normally distributed with the mean mu and standard deviation sigma
+func (m *Model) RandomLogNormal(mu float64, sigma float64) float64 {
+	return math.Exp(m.RandomNormal(mu, sigma))
+}
+
+// returns a random number from an exponential distribution with the mean, like random-exponential in netlogo
+func (m *Model) RandomExponential(mean float64) float64 {
+	return m.randomGenerator.ExpFloat64() * mean
+}
+
+// returns a random count from a poisson distribution with the mean, like random-poisson in netlogo
+// returns 0 if the mean is not positive
+func (m *Model) RandomPoisson(mean float64) int {
+	return randomPoisson(m.randomGenerator, mean)
+}
+
+func randomPoisson(r *rand.Rand, mean float64) int {
+	if mean <= 0 {
+		return 0
+	}
+
+	// knuth's method multiplies uniform numbers until they drop below e^-mean, fine for small means
+	if mean < 10 {
+		limit := math.Exp(-mean)
+		count := 0
+		product := r.Float64()
+		for product > limit {
+			count++
+			product *= r.Float64()
+		}
+		return count
+	}
+
+	// transformed rejection (PTRS) from Hörmann, the time taken doesn't grow with the mean
+	sqrtMean := math.Sqrt(mean)
+	logMean := math.Log(mean)
+	b := 0.931 + 2.53*sqrtMean
+	a := -0.059 + 0.02483*b
+	invAlpha := 1.1239 + 1.1328/(b-3.4)
+	vr := 0.9277 - 3.6224/(b-2)
+	for {
+		u := r.Float64() - .5
+		v := r.Float64()
+		us := .5 - math.Abs(u)
+		k := math.Floor((2*a/us+b)*u + mean + .43)
+		if us >= .07 && v <= vr {
+			return int(k)
+		}
+		if k < 0 || (us < .013 && v > us) {
+			continue
+		}
+		logFactorial, _ := math.Lgamma(k + 1)
+		if math.Log(v)+math.Log(invAlpha)-math.Log(a/(us*us)+b) <= -mean+k*logMean-logFactorial {
+			return int(k)
+		}
+	}
+}
+
+// returns a random number from a gamma distribution with the shape alpha and rate lambda, like random-gamma in netlogo
+// the mean is alpha / lambda
+// returns 0 if alpha or lambda is not positive
+func (m *Model) RandomGamma(alpha float64, lambda float64) float64 {
+	if lambda <= 0 {
+		return 0
+	}
+	return randomGamma(m.randomGenerator, alpha) / lambda
+}
+
+// gamma with a rate of 1 using marsaglia and tsang's method
+func randomGamma(r *rand.Rand, alpha float64) float64 {
+	if alpha <= 0 {
+		return 0
+	}
+
+	// shapes below 1 are drawn with a shape one higher then scaled back down
+	if alpha < 1 {
+		return randomGamma(r, alpha+1) * math.Pow(r.Float64(), 1/alpha)
+	}
+
+	d := alpha - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := r.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := r.Float64()
+		if u < 1-.0331*x*x*x*x || math.Log(u) < .5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
+
+// returns a random number between 0 and 1 from a beta distribution with the shapes alpha and beta
+// returns 0 if either shape is not positive
+func (m *Model) RandomBeta(alpha float64, beta float64) float64 {
+	return randomBeta(m.randomGenerator, alpha, beta)
+}
+
+func randomBeta(r *rand.Rand, alpha float64, beta float64) float64 {
+	if alpha <= 0 || beta <= 0 {
+		return 0
+	}
+	x := randomGamma(r, alpha)
+	y := randomGamma(r, beta)
+	if x+y == 0 {
+		return 0
+	}
+	return x / (x + y)
+}
+
+// returns the number of successes out of the trials when each succeeds with the probability
+// the probability is kept between 0 and 1, returns 0 if there are no trials
+func (m *Model) RandomBinomial(trials int, probability float64) int {
+	r := m.randomGenerator
+	probability = math.Max(0, math.Min(1, probability))
+
+	// knuth's method, split the trials at the median of a beta draw until only a few are left
+	// so the time taken grows with the log of the number of trials
+	successes := 0
+	for trials > 16 {
+		a := 1 + trials/2
+		b := 1 + trials - a
+		x := randomBeta(r, float64(a), float64(b))
+		if x >= probability {
+			trials = a - 1
+			probability /= x
+		} else {
+			successes += a
+			trials = b - 1
+			probability = (probability - x) / (1 - x)
+		}
+	}
+	for i := 0; i < trials; i++ {
+		if r.Float64() < probability {
+			successes++
+		}
+	}
+	return successes
+}
+
+// returns a random index into the weights, each index is picked with a chance proportional to its weight
+// negative weights count as 0, returns -1 if no weight is positive
+func (m *Model) RandomWeighted(weights []float64) int {
+	return weightedIndex(m.randomGenerator, weights)
+}
+
+func weightedIndex(r *rand.Rand, weights []float64) int {
+	total := 0.0
+	for _, weight := range weights {
+		if weight > 0 {
+			total += weight
+		}
+	}
+	if total <= 0 {
+		return -1
+	}
+
+	target := r.Float64() * total
+	last := -1
+	for i, weight := range weights {
+		if weight <= 0 {
+			continue
+		}
+		last = i
+		target -= weight
+		if target < 0 {
+			return i
+		}
+	}
+
+	// rounding can leave a tiny bit of the target, which belongs to the last positive weight
+	return last
+}
+
+// returns a random rank from 1 to n from a zipf distribution with the exponent
+// rank k is picked with a chance proportional to 1 / k^exponent, the time taken grows with n
+// returns 0 if n is less than 1
+func (m *Model) RandomZipf(exponent float64, n int) int {
+	if n < 1 {
+		return 0
+	}
+
+	total := 0.0
+	for k := 1; k <= n; k++ {
+		total += math.Pow(float64(k), -exponent)
+	}
+
+	target := m.randomGenerator.Float64() * total
+	for k := 1; k <= n; k++ {
+		target -= math.Pow(float64(k), -exponent)
+		if target < 0 {
+			return k
+		}
+	}
+	return n
+}
+
+// returns the random generator of the model the agents belong to
+// agents that don't belong to a model get a generator that isn't seeded
+func agentsRandom(m *Model) *rand.Rand {
+	if m != nil && m.randomGenerator != nil {
+		return m.randomGenerator
+	}
+	return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+}
+
+// returns n different random indices below size in increasing order
+func randomIndices(r *rand.Rand, size int, n int) []int {
+	if n > size {
+		n = size
+	}
+	if n <= 0 {
+		return []int{}
+	}
+
+	// partial fisher-yates shuffle, only the first n spots are shuffled
+	indices := make([]int, size)
+	for i := range indices {
+		indices[i] = i
+	}
+	for i := 0; i < n; i++ {
+		j := i + r.IntN(size-i)
+		indices[i], indices[j] = indices[j], indices[i]
+	}
+
+	picked := indices[:n]
+	sort.Ints(picked)
+	return picked
+}
+
+// returns n different indices picked with chances proportional to their weights, in increasing order
+// indices with a weight that isn't positive are never picked so fewer than n may be returned
+func weightedIndices(r *rand.Rand, weights []float64, n int) []int {
+	// efraimidis and spirakis, give each index a key of u^(1/weight) and keep the largest keys
+	type keyed struct {
+		index int
+		key   float64
+	}
+	keys := make([]keyed, 0, len(weights))
+	for i, weight := range weights {
+		if weight <= 0 {
+			continue
+		}
+		keys = append(keys, keyed{index: i, key: math.Pow(r.Float64(), 1/weight)})
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].key > keys[j].key
+	})
+	if n > len(keys) {
+		n = len(keys)
+	}
+	if n < 0 {
+		n = 0
+	}
+
+	picked := make([]int, n)
+	for i := 0; i < n; i++ {
+		picked[i] = keys[i].index
+	}
+	sort.Ints(picked)
+	return picked
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// helpers for the agentset reporters, the values are in the order of the agents in the agentset
+
+// returns the index of the largest value, or the smallest if max is false
+// ties are broken at random so no agent is favored by its place in the agentset
+// returns -1 if there are no values that are numbers
+func extremeIndex(r *rand.Rand, values []float64, max bool) int {
+	best := -1
+	ties := 0
+	for i, value := range values {
+		if math.IsNaN(value) {
+			continue
+		}
+		if best == -1 || (max && value > values[best]) || (!max && value < values[best]) {
+			best = i
+			ties = 1
+			continue
+		}
+		if value == values[best] {
+			// keep each tied value with an equal chance
+			ties++
+			if r.IntN(ties) == 0 {
+				best = i
+			}
+		}
+	}
+	return best
+}
+
+// returns the indices of the n largest values, or the smallest if max is false, in increasing order
+// ties at the cut off are broken at random, values that aren't numbers are never picked
+func extremeIndices(r *rand.Rand, values []float64, n int, max bool) []int {
+	indices := []int{}
+	for i, value := range values {
+		if !math.IsNaN(value) {
+			indices = append(indices, i)
+		}
+	}
+
+	// shuffle first so the stable sort leaves tied values in a random order
+	r.Shuffle(len(indices), func(i, j int) {
+		indices[i], indices[j] = indices[j], indices[i]
+	})
+	sort.SliceStable(indices, func(i, j int) bool {
+		if max {
+			return values[indices[i]] > values[indices[j]]
+		}
+		return values[indices[i]] < values[indices[j]]
+	})
+
+	if n > len(indices) {
+		n = len(indices)
+	}
+	if n < 0 {
+		n = 0
+	}
+	picked := indices[:n]
+	sort.Ints(picked)
+	return picked
+}
+
+func sumValues(values []float64) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum
+}
+
+// sample variance, divides by one less than the number of values like variance in netlogo
+func varianceValues(values []float64) float64 {
+	mean := sumValues(values) / float64(len(values))
+	sum := 0.0
+	for _, value := range values {
+		sum += (value - mean) * (value - mean)
+	}
+	return sum / float64(len(values)-1)
+}
+
+// counts the values falling in each of the bins evenly splitting min to max
+// a value equal to max goes in the last bin, values outside of min and max are left out
+func histogramValues(values []float64, min float64, max float64, bins int) []int {
+	if bins <= 0 {
+		return []int{}
+	}
+	counts := make([]int, bins)
+	if max <= min {
+		return counts
+	}
+
+	width := (max - min) / float64(bins)
+	for _, value := range values {
+		if value < min || value > max || math.IsNaN(value) {
+			continue
+		}
+		bin := int((value - min) / width)
+		if bin >= bins {
+			bin = bins - 1
+		}
+		counts[bin]++
+	}
+	return counts
+}
@@ -0,0 +1,173 @@
+package model
+
+// Scheduler decides the order that agents are activated in when they are asked through a scheduler
+// and whether the changes they make are applied right away or only after every agent has gone
+type Scheduler interface {
+	// Order returns the order that n agents belonging to the model should be activated in as indexes into the list of agents
+	// the model is nil when the agents don't belong to a model
+	Order(m *Model, n int) []int
+
+	// Buffered returns true if the updates returned by the agents should only be committed after all agents have been activated
+	Buffered() bool
+}
+
+// activates the agents in the order they are in the agentset
+type sequentialScheduler struct{}
+
+// NewSequentialScheduler creates a scheduler that activates agents in the order they are in the agentset
+func NewSequentialScheduler() Scheduler {
+	return &sequentialScheduler{}
+}
+
+func (s *sequentialScheduler) Order(m *Model, n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+func (s *sequentialScheduler) Buffered() bool {
+	return false
+}
+
+// activates the agents in a random order that is reshuffled every time the agents are asked
+type randomScheduler struct{}
+
+// NewRandomScheduler creates a scheduler that shuffles the agents each time they are asked, like ask in netlogo
+// the shuffle uses the model's random generator so runs are reproducible with the same seed
+// agents that don't belong to a model are activated in the order they are in the agentset
+func NewRandomScheduler() Scheduler {
+	return &randomScheduler{}
+}
+
+func (s *randomScheduler) Order(m *Model, n int) []int {
+	if m == nil {
+		return NewSequentialScheduler().Order(m, n)
+	}
+	return m.randomGenerator.Perm(n)
+}
+
+func (s *randomScheduler) Buffered() bool {
+	return false
+}
+
+// activates all agents against the old state and commits the new state once every agent has been activated
+type simultaneousScheduler struct {
+	order Scheduler
+}
+
+// NewSimultaneousScheduler creates a scheduler where every agent reads the state from before the ask
+// and the updates returned by the agents are only committed once all agents have been activated
+// order decides the order the agents are visited and committed in, if nil then the agents are visited sequentially
+func NewSimultaneousScheduler(order Scheduler) Scheduler {
+	if order == nil {
+		order = NewSequentialScheduler()
+	}
+	return &simultaneousScheduler{
+		order: order,
+	}
+}
+
+func (s *simultaneousScheduler) Order(m *Model, n int) []int {
+	return s.order.Order(m, n)
+}
+
+func (s *simultaneousScheduler) Buffered() bool {
+	return true
+}
+
+// TurtleStage is a named phase of a turtle's activation
+// all turtles finish a stage before any turtle starts the next one
+type TurtleStage struct {
+	Name      string
+	Operation TurtleUpdateOperation
+}
+
+// PatchStage is a named phase of a patch's activation
+// all patches finish a stage before any patch starts the next one
+type PatchStage struct {
+	Name      string
+	Operation PatchUpdateOperation
+}
+
+// LinkStage is a named phase of a link's activation
+// all links finish a stage before any link starts the next one
+type LinkStage struct {
+	Name      string
+	Operation LinkUpdateOperation
+}
+
+// TickStage is a named phase that the model runs on all of its agents when Tick is called
+// turtles go first, then patches, then links. Nil operations are skipped
+type TickStage struct {
+	Name    string
+	Turtles TurtleUpdateOperation
+	Patches PatchUpdateOperation
+	Links   LinkUpdateOperation
+}
+
+// runs the activate function for each of the n agents in the order given by the scheduler
+// if the scheduler is buffered the commits are held back until every agent has been activated
+func runScheduled(m *Model, scheduler Scheduler, n int, activate func(i int) func()) {
+	if scheduler == nil {
+		scheduler = NewSequentialScheduler()
+	}
+
+	order := scheduler.Order(m, n)
+
+	if !scheduler.Buffered() {
+		for _, i := range order {
+			if commit := activate(i); commit != nil {
+				commit()
+			}
+		}
+		return
+	}
+
+	commits := make([]func(), 0, len(order))
+	for _, i := range order {
+		if commit := activate(i); commit != nil {
+			commits = append(commits, commit)
+		}
+	}
+
+	for _, commit := range commits {
+		commit()
+	}
+}
+
+// returns the scheduler the model uses for tick stages and for agentsets asked with a nil scheduler
+func (m *Model) Scheduler() Scheduler {
+	return m.scheduler
+}
+
+// sets the scheduler the model uses for tick stages and for agentsets asked with a nil scheduler
+// if the scheduler is nil then the model goes back to activating agents sequentially
+func (m *Model) SetScheduler(scheduler Scheduler) {
+	if scheduler == nil {
+		scheduler = NewSequentialScheduler()
+	}
+	m.scheduler = scheduler
+}
+
+// sets the stages that are run on all the agents each time Tick is called
+// the stages are run with the model's scheduler before the tick counter is incremented
+func (m *Model) SetTickStages(stages []TickStage) {
+	m.tickStages = stages
+}
+
+// runs the tick stages on all the turtles, patches and links
+func (m *Model) runTickStages() {
+	for _, stage := range m.tickStages {
+		if stage.Turtles != nil {
+			m.turtles.AskStaged(m.scheduler, []TurtleStage{{Name: stage.Name, Operation: stage.Turtles}})
+		}
+		if stage.Patches != nil {
+			m.Patches.AskStaged(m.scheduler, []PatchStage{{Name: stage.Name, Operation: stage.Patches}})
+		}
+		if stage.Links != nil {
+			m.links.AskStaged(m.scheduler, []LinkStage{{Name: stage.Name, Operation: stage.Links}})
+		}
+	}
+}
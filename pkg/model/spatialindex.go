@@ -0,0 +1,339 @@
+package model
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// spatialIndex is a uniform grid over the world that buckets turtles by their position
+// the cells are independent of the patches so they can be sized to the query radius the model uses the most
+// turtles are kept in slices instead of maps so that queries return turtles in a deterministic order
+type spatialIndex struct {
+	mu sync.RWMutex
+
+	cellSize float64 // cell size that was asked for
+
+	cols   int
+	rows   int
+	layers int
+
+	// size of a cell along each axis, the cells always divide the world evenly so wrapping lines up with the cells
+	cellWidth  float64
+	cellHeight float64
+	cellDepth  float64
+
+	cells [][]*Turtle
+}
+
+func newSpatialIndex(m *Model, cellSize float64) *spatialIndex {
+	cols := int(math.Max(1, math.Ceil(float64(m.worldWidth)/cellSize)))
+	rows := int(math.Max(1, math.Ceil(float64(m.worldHeight)/cellSize)))
+	layers := 1
+	if m.Is3D() {
+		layers = int(math.Max(1, math.Ceil(float64(m.worldDepth)/cellSize)))
+	}
+
+	return &spatialIndex{
+		cellSize:   cellSize,
+		cols:       cols,
+		rows:       rows,
+		layers:     layers,
+		cellWidth:  float64(m.worldWidth) / float64(cols),
+		cellHeight: float64(m.worldHeight) / float64(rows),
+		cellDepth:  float64(m.worldDepth) / float64(layers),
+		cells:      make([][]*Turtle, cols*rows*layers),
+	}
+}
+
+// returns a copy of the index with every turtle swapped for its copy
+// the turtles stay in the same order in their cells so queries on the copy return turtles in the same order
+func (s *spatialIndex) copy(turtles map[*Turtle]*Turtle) *spatialIndex {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cells := make([][]*Turtle, len(s.cells))
+	for i, cell := range s.cells {
+		if len(cell) == 0 {
+			continue
+		}
+		cells[i] = make([]*Turtle, len(cell))
+		for j, t := range cell {
+			cells[i][j] = turtles[t]
+		}
+	}
+
+	return &spatialIndex{
+		cellSize:   s.cellSize,
+		cols:       s.cols,
+		rows:       s.rows,
+		layers:     s.layers,
+		cellWidth:  s.cellWidth,
+		cellHeight: s.cellHeight,
+		cellDepth:  s.cellDepth,
+		cells:      cells,
+	}
+}
+
+// returns the column, row and layer of the cell the coordinates fall in, clamped to the grid
+func (s *spatialIndex) cellCoords(m *Model, x float64, y float64, z float64) (int, int, int) {
+	col := clampInt(int(math.Floor((x-m.minXCor)/s.cellWidth)), 0, s.cols-1)
+	row := clampInt(int(math.Floor((y-m.minYCor)/s.cellHeight)), 0, s.rows-1)
+	layer := 0
+	if s.layers > 1 {
+		layer = clampInt(int(math.Floor((z-m.minZCor)/s.cellDepth)), 0, s.layers-1)
+	}
+	return col, row, layer
+}
+
+func (s *spatialIndex) cellIndex(col int, row int, layer int) int {
+	return (layer*s.rows+row)*s.cols + col
+}
+
+// adds the turtle to the cell at its position
+func (s *spatialIndex) insert(m *Model, t *Turtle) {
+	cell := s.cellIndex(s.cellCoords(m, t.xcor, t.ycor, t.zcor))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t.indexCell = cell
+	t.indexSlot = len(s.cells[cell])
+	s.cells[cell] = append(s.cells[cell], t)
+}
+
+// removes the turtle from the cell it is in
+func (s *spatialIndex) remove(t *Turtle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeLocked(t)
+}
+
+func (s *spatialIndex) removeLocked(t *Turtle) {
+	if t.indexCell < 0 {
+		return
+	}
+
+	// swap the last turtle of the cell into the slot being removed
+	cell := s.cells[t.indexCell]
+	last := len(cell) - 1
+	cell[t.indexSlot] = cell[last]
+	cell[t.indexSlot].indexSlot = t.indexSlot
+	cell[last] = nil
+	s.cells[t.indexCell] = cell[:last]
+
+	t.indexCell = -1
+	t.indexSlot = -1
+}
+
+// moves the turtle to the cell at its new position if it has changed cells
+func (s *spatialIndex) update(m *Model, t *Turtle) {
+	cell := s.cellIndex(s.cellCoords(m, t.xcor, t.ycor, t.zcor))
+	if cell == t.indexCell {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeLocked(t)
+	t.indexCell = cell
+	t.indexSlot = len(s.cells[cell])
+	s.cells[cell] = append(s.cells[cell], t)
+}
+
+// returns the range of cells along an axis that could hold turtles within the radius of the coordinate
+// if the range wraps around the world then the start can be negative or the end can be past the last cell
+func cellRange(cor float64, radius float64, min float64, size float64, count int, wrapping bool) (int, int) {
+	start := int(math.Floor((cor - radius - min) / size))
+	end := int(math.Floor((cor + radius - min) / size))
+
+	if wrapping {
+		if end-start+1 >= count {
+			return 0, count - 1
+		}
+		return start, end
+	}
+
+	return clampInt(start, 0, count-1), clampInt(end, 0, count-1)
+}
+
+// calls visit with every turtle in the cells that could hold turtles within the radius
+func (s *spatialIndex) visitInRadius(m *Model, xCor float64, yCor float64, zCor float64, radius float64, use3D bool, visit func(t *Turtle)) {
+	colStart, colEnd := cellRange(xCor, radius, m.minXCor, s.cellWidth, s.cols, m.wrappingX)
+	rowStart, rowEnd := cellRange(yCor, radius, m.minYCor, s.cellHeight, s.rows, m.wrappingY)
+	layerStart, layerEnd := 0, s.layers-1
+	if use3D && s.layers > 1 {
+		layerStart, layerEnd = cellRange(zCor, radius, m.minZCor, s.cellDepth, s.layers, false)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for layer := layerStart; layer <= layerEnd; layer++ {
+		for r := rowStart; r <= rowEnd; r++ {
+			row := ((r % s.rows) + s.rows) % s.rows
+			for c := colStart; c <= colEnd; c++ {
+				col := ((c % s.cols) + s.cols) % s.cols
+				for _, t := range s.cells[s.cellIndex(col, row, layer)] {
+					visit(t)
+				}
+			}
+		}
+	}
+}
+
+func clampInt(n int, min int, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// EnableSpatialIndex builds a grid of cells of roughly cellSize that keeps track of which turtles are where
+// radius, nearest neighbor and collision queries use the grid instead of scanning patches or every turtle
+// the grid is kept up to date as turtles move, are created and die
+// a good cell size is around the radius that the model queries the most
+func (m *Model) EnableSpatialIndex(cellSize float64) {
+	if cellSize <= 0 {
+		m.DisableSpatialIndex()
+		return
+	}
+
+	index := newSpatialIndex(m, cellSize)
+	m.turtles.Ask(func(t *Turtle) {
+		index.insert(m, t)
+	})
+	m.spatialIndex = index
+}
+
+// DisableSpatialIndex removes the spatial index, queries go back to using the patches
+func (m *Model) DisableSpatialIndex() {
+	m.spatialIndex = nil
+	m.turtles.Ask(func(t *Turtle) {
+		t.indexCell = -1
+		t.indexSlot = -1
+	})
+}
+
+// returns true if the model is using a spatial index
+func (m *Model) SpatialIndexEnabled() bool {
+	return m.spatialIndex != nil
+}
+
+// returns the cell size the spatial index was enabled with, 0 if it is disabled
+func (m *Model) SpatialIndexCellSize() float64 {
+	if m.spatialIndex == nil {
+		return 0
+	}
+	return m.spatialIndex.cellSize
+}
+
+// returns the turtles within the radius using the spatial index
+func (m *Model) turtlesInRadiusIndexed(xCor float64, yCor float64, zCor float64, radius float64, use3D bool) *TurtleAgentSet {
+	turtles := NewTurtleAgentSet(nil)
+	m.spatialIndex.visitInRadius(m, xCor, yCor, zCor, radius, use3D, func(t *Turtle) {
+		x, y, z := t.position()
+		if use3D {
+			if m.DistanceBetweenPointsXYZ(xCor, yCor, zCor, x, y, z) <= radius {
+				turtles.Add(t)
+			}
+		} else {
+			if m.DistanceBetweenPointsXY(xCor, yCor, x, y) <= radius {
+				turtles.Add(t)
+			}
+		}
+	})
+	return turtles
+}
+
+// returns the k turtles closest to the provided coordinates, closest first
+// turtles at the same distance are ordered by who number
+// if use3D is true then the z coordinate is used as well
+func (m *Model) NearestTurtles(xCor float64, yCor float64, zCor float64, k int, use3D bool) *TurtleAgentSet {
+	if k <= 0 || m.turtles.Count() == 0 {
+		return NewTurtleAgentSet(nil)
+	}
+
+	distance := func(t *Turtle) float64 {
+		x, y, z := t.position()
+		if use3D {
+			return m.DistanceBetweenPointsXYZ(xCor, yCor, zCor, x, y, z)
+		}
+		return m.DistanceBetweenPointsXY(xCor, yCor, x, y)
+	}
+
+	candidates := []*Turtle{}
+	if m.spatialIndex != nil && k < m.turtles.Count() {
+		// grow the search radius until there are at least k turtles within it
+		// the k turtles closest are then guaranteed to be inside the radius
+		maxRadius := math.Sqrt(float64(m.worldWidth*m.worldWidth + m.worldHeight*m.worldHeight + m.worldDepth*m.worldDepth))
+		radius := math.Min(m.spatialIndex.cellWidth, m.spatialIndex.cellHeight)
+		for {
+			candidates = candidates[:0]
+			m.spatialIndex.visitInRadius(m, xCor, yCor, zCor, radius, use3D, func(t *Turtle) {
+				if distance(t) <= radius {
+					candidates = append(candidates, t)
+				}
+			})
+			if len(candidates) >= k || radius > maxRadius {
+				break
+			}
+			radius *= 2
+		}
+	} else {
+		candidates = m.turtles.List()
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		di := distance(candidates[i])
+		dj := distance(candidates[j])
+		if di == dj {
+			return candidates[i].who < candidates[j].who
+		}
+		return di < dj
+	})
+
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+
+	return NewTurtleAgentSet(candidates)
+}
+
+// returns the k turtles closest to the provided x y coordinates, closest first
+func (m *Model) NearestTurtlesXY(xCor float64, yCor float64, k int) *TurtleAgentSet {
+	return m.NearestTurtles(xCor, yCor, 0, k, false)
+}
+
+// returns the k turtles closest to the provided x y z coordinates, closest first
+func (m *Model) NearestTurtlesXYZ(xCor float64, yCor float64, zCor float64, k int) *TurtleAgentSet {
+	return m.NearestTurtles(xCor, yCor, zCor, k, true)
+}
+
+// returns the k other turtles closest to the turtle, closest first
+func (t *Turtle) NearestTurtles(k int) *TurtleAgentSet {
+	x, y, z := t.position()
+	nearest := t.parent.NearestTurtles(x, y, z, k+1, t.parent.Is3D())
+	nearest.Remove(t)
+	if nearest.Count() > k {
+		return nearest.FirstNOf(k)
+	}
+	return nearest
+}
+
+// returns the turtles that the turtle overlaps with, using the size of the turtles as their diameter
+// only works for 2D worlds like TurtlesCollide
+// biggestSize is the size of the biggest turtle in the model and is used to limit how far out to look
+func (m *Model) TurtlesCollidingWith(turtle *Turtle, biggestSize float64) *TurtleAgentSet {
+	potentialTurtles := m.TurtlesInRadiusXY(turtle.XCor(), turtle.YCor(), (turtle.size+biggestSize)/2)
+	potentialTurtles.Remove(turtle)
+
+	return potentialTurtles.With(func(t *Turtle) bool {
+		return m.TurtlesCollide(turtle, t, 0, 0, 0)
+	})
+}
@@ -0,0 +1,228 @@
+package model
+
+import "math"
+
+// TieMode describes how the turtle at the end of a tied link follows the root turtle
+type TieMode int
+
+const (
+	TieNone  TieMode = iota // the link is not tied
+	TieFixed                // the tied turtle moves with the root, and when the root turns or in 3D pitches the tied turtle turns with it and swings around the root
+	TieFree                 // the tied turtle moves with the root but is not affected when the root turns
+)
+
+// ties the link in fixed mode
+// for a directed link end1 is the root and end2 moves with it, for an undirected link both ends move with each other
+// a tied turtle stops at the edges of the world that don't wrap while the root keeps going, so near those edges
+// the tied turtles don't keep their distance and angle to the root
+func (l *Link) Tie() {
+	l.SetTieMode(TieFixed)
+}
+
+// unties the link so the ends move independently again
+func (l *Link) Untie() {
+	l.SetTieMode(TieNone)
+}
+
+// sets how the turtles at the ends of the link follow each other
+func (l *Link) SetTieMode(mode TieMode) {
+	if l.parent == nil || l.tieMode == mode {
+		return
+	}
+
+	wasTied := l.tieMode != TieNone
+	l.tieMode = mode
+
+	if wasTied && mode == TieNone {
+		l.parent.linkedTurtles[l.end1].tiedLinks.Remove(l)
+		if !l.directed {
+			l.parent.linkedTurtles[l.end2].tiedLinks.Remove(l)
+		}
+	}
+
+	if !wasTied && mode != TieNone {
+		l.parent.linkedTurtles[l.end1].tiedLinks.Add(l)
+		if !l.directed {
+			l.parent.linkedTurtles[l.end2].tiedLinks.Add(l)
+		}
+	}
+}
+
+// returns the tie mode of the link
+func (l *Link) TieMode() TieMode {
+	return l.tieMode
+}
+
+// returns if the link is tied
+func (l *Link) Tied() bool {
+	return l.tieMode != TieNone
+}
+
+// returns the turtles that move when this turtle moves, including turtles tied through other tied turtles
+func (t *Turtle) TiedTurtles() *TurtleAgentSet {
+	tied := NewTurtleAgentSet([]*Turtle{})
+	t.walkTies(func(leaf *Turtle, link *Link, rotate bool) bool {
+		tied.Add(leaf)
+		return true
+	})
+	return tied
+}
+
+// moves the turtles tied to t after t has moved by dx dy dz, turned by turn radians and pitched up by tilt radians
+// the whole tree of tied turtles follows, with each turtle only being moved once so cycles of ties are fine
+// it must be called without t's position lock held, each tied turtle only takes its own lock
+func (t *Turtle) moveTiedTurtles(dx float64, dy float64, dz float64, turn float64, tilt float64) {
+	if t.parent == nil {
+		return
+	}
+
+	if links, ok := t.parent.linkedTurtles[t]; !ok || links.tiedLinks.Count() == 0 {
+		return
+	}
+
+	moved := dx != 0 || dy != 0 || dz != 0
+	turned := turn != 0 || tilt != 0
+
+	t.positionMu.RLock()
+	root := tieRoot{x: t.xcor, y: t.ycor, z: t.zcor, heading: t.heading}
+	t.positionMu.RUnlock()
+
+	t.walkTies(func(leaf *Turtle, link *Link, rotate bool) bool {
+		rotate = rotate && turned
+
+		// nothing below a free tie changes if the root only turned
+		if !moved && !rotate {
+			return false
+		}
+
+		leaf.followTie(root, dx, dy, dz, turn, tilt, rotate)
+		return true
+	})
+}
+
+// walks the tied turtles breadth first starting from t
+// visit is called with each turtle, the link it was reached through and if every tie on the way was fixed
+// if visit returns false then the turtles tied to that turtle are not walked
+func (t *Turtle) walkTies(visit func(leaf *Turtle, link *Link, rotate bool) bool) {
+	type tiedTurtle struct {
+		turtle *Turtle
+		rotate bool
+	}
+
+	visited := map[*Turtle]bool{t: true}
+	queue := []tiedTurtle{{turtle: t, rotate: true}}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, link := range t.parent.linkedTurtles[current.turtle].tiedLinks.List() {
+			leaf := link.OtherEnd(current.turtle)
+			if leaf == nil || visited[leaf] {
+				continue
+			}
+			visited[leaf] = true
+
+			rotate := current.rotate && link.tieMode == TieFixed
+			if visit(leaf, link, rotate) {
+				queue = append(queue, tiedTurtle{turtle: leaf, rotate: rotate})
+			}
+		}
+	}
+}
+
+// where the root of a tie is after it moved, read once so the tied turtles don't need the root's lock
+type tieRoot struct {
+	x, y, z float64
+	heading float64
+}
+
+// moves the turtle along with the root of its tie
+// if rotate is true the turtle also turns and pitches with the root and swings around it
+// a turtle that would be moved past an edge of the world that doesn't wrap stops at the edge, it still turns with the root
+// the position and heading are set directly so that the tie doesn't get walked again from this turtle
+func (t *Turtle) followTie(root tieRoot, dx float64, dy float64, dz float64, turn float64, tilt float64, rotate bool) {
+	t.positionMu.Lock()
+	defer t.positionMu.Unlock()
+
+	x := t.xcor + dx
+	y := t.ycor + dy
+	z := t.zcor + dz
+
+	if rotate {
+		// swing around the root using the shortest offset in case the tie crosses a wrapped edge
+		offsetX, offsetY := t.parent.shortestOffsetXY(root.x, root.y, x, y)
+		offsetZ := z - root.z
+
+		// turning swings around the vertical axis
+		cos := math.Cos(turn)
+		sin := math.Sin(turn)
+		offsetX, offsetY = offsetX*cos-offsetY*sin, offsetX*sin+offsetY*cos
+
+		// pitching swings around the axis pointing to the right of the root
+		if tilt != 0 {
+			offsetX, offsetY, offsetZ = rotateAroundAxis(offsetX, offsetY, offsetZ, math.Sin(root.heading), -math.Cos(root.heading), 0, tilt)
+		}
+
+		x = root.x + offsetX
+		y = root.y + offsetY
+		z = root.z + offsetZ
+	}
+
+	x, y, z = t.parent.clampToEdges(x, y, z)
+
+	allowed := false
+	if t.parent.Is3D() {
+		x, y, z, allowed = t.parent.convertXYZToInBounds(x, y, z)
+	} else {
+		x, y, allowed = t.parent.convertXYToInBounds(x, y)
+	}
+	if !allowed {
+		return
+	}
+
+	if rotate {
+		t.heading = math.Mod(t.heading+turn, 2*math.Pi)
+		t.pitch += tilt
+	}
+
+	oldX, oldY, oldZ := t.xcor, t.ycor, t.zcor
+
+	t.positionChanging()
+
+	t.xcor = x
+	t.ycor = y
+	t.zcor = z
+
+	t.transferPatchOwnership()
+
+	trailX, trailY := t.parent.shortestOffsetXY(oldX, oldY, x, y)
+	t.drawTrail(trailX, trailY, z-oldZ)
+}
+
+// rotates the vector x y z by angle radians around the unit axis ax ay az
+func rotateAroundAxis(x float64, y float64, z float64, ax float64, ay float64, az float64, angle float64) (float64, float64, float64) {
+	cos := math.Cos(angle)
+	sin := math.Sin(angle)
+	dot := (ax*x + ay*y + az*z) * (1 - cos)
+
+	// rodrigues' rotation formula
+	crossX := ay*z - az*y
+	crossY := az*x - ax*z
+	crossZ := ax*y - ay*x
+	return x*cos + crossX*sin + ax*dot, y*cos + crossY*sin + ay*dot, z*cos + crossZ*sin + az*dot
+}
+
+// moves the position onto the world along the edges that don't wrap, the max edges are just inside since they aren't part of the world
+func (m *Model) clampToEdges(x float64, y float64, z float64) (float64, float64, float64) {
+	if !m.wrappingX {
+		x = math.Max(m.minXCor, math.Min(x, math.Nextafter(m.maxXCor, m.minXCor)))
+	}
+	if !m.wrappingY {
+		y = math.Max(m.minYCor, math.Min(y, math.Nextafter(m.maxYCor, m.minYCor)))
+	}
+	if m.Is3D() {
+		z = math.Max(m.minZCor, math.Min(z, math.Nextafter(m.maxZCor, m.minZCor)))
+	}
+	return x, y, z
+}
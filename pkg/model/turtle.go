@@ -30,6 +30,16 @@ type Turtle struct {
 	label      interface{}
 	LabelColor Color
 
+	penDown bool    // if the turtle leaves a trail on the drawing layer when it moves
+	penSize float64 // width of the trail
+
+	// position from before the movement phase, only used between BeginMovementPhase and CommitMovementPhase
+	moved                           bool
+	snapshotX, snapshotY, snapshotZ float64
+
+	indexCell int // cell of the spatial index the turtle is in, -1 if it isn't in the index
+	indexSlot int // position of the turtle within its cell of the spatial index
+
 	propertiesMutex         sync.RWMutex
 	turtlePropertiesGeneral map[string]interface{} // turtles own variables
 	turtlePropertiesBreed   map[string]interface{} // turtle properties variables
@@ -51,6 +61,9 @@ func newTurtle(m *Model, who int, breed *TurtleBreed, x float64, y float64) *Tur
 		label:      "",
 		LabelColor: Black,
 		Shape:      "circle",
+		penSize:    1,
+		indexCell:  -1,
+		indexSlot:  -1,
 	}
 
 	// add in the linked turtles
@@ -71,6 +84,10 @@ func newTurtle(m *Model, who int, breed *TurtleBreed, x float64, y float64) *Tur
 	}
 	t.patch.addTurtle(t)
 
+	if m.spatialIndex != nil {
+		m.spatialIndex.insert(m, t)
+	}
+
 	//set the turtle properties variables
 	//breed specific variables can override general variables
 	t.turtlePropertiesGeneral = make(map[string]interface{})
@@ -111,9 +128,10 @@ func (t *Turtle) SetBreed(breed *TurtleBreed) {
 		return
 	}
 
+	oldBreed := t.breed
+
 	// remove the turtle from the patch and add it back in at the end
 	t.patch.removeTurtle(t)
-	defer t.patch.addTurtle(t)
 
 	if t.breed != nil {
 		t.parent.breeds[t.breed.name].turtles.Remove(t)
@@ -135,7 +153,9 @@ func (t *Turtle) SetBreed(breed *TurtleBreed) {
 	}
 
 	// add the turtle to the patch
+	t.patch.addTurtle(t)
 
+	t.parent.runHooks(Hook{Kind: HookTurtleBreedChanged, Turtle: t, OldTurtleBreed: oldBreed, NewTurtleBreed: t.breed})
 }
 
 // returns if the turtle can move foward by the distance passed in
@@ -178,30 +198,34 @@ func (t *Turtle) Die() {
 
 // returns the distance between the two turtles
 func (t *Turtle) DistanceTurtle(turtle *Turtle) float64 {
+	x, y, z := t.position()
+	otherX, otherY, otherZ := turtle.position()
 	if t.parent.Is3D() {
-		return t.parent.DistanceBetweenPointsXYZ(t.xcor, t.ycor, t.zcor, turtle.xcor, turtle.ycor, turtle.zcor)
+		return t.parent.DistanceBetweenPointsXYZ(x, y, z, otherX, otherY, otherZ)
 	} else {
-		return t.parent.DistanceBetweenPointsXY(t.xcor, t.ycor, turtle.xcor, turtle.ycor)
+		return t.parent.DistanceBetweenPointsXY(x, y, otherX, otherY)
 	}
 }
 
 // returns the distance between the turtle and the middle of the patch
 func (t *Turtle) DistancePatch(patch *Patch) float64 {
+	x, y, z := t.position()
 	if t.parent.Is3D() {
-		return t.parent.DistanceBetweenPointsXYZ(t.xcor, t.ycor, t.zcor, patch.xFloat64, patch.yFloat64, patch.zFloat64)
+		return t.parent.DistanceBetweenPointsXYZ(x, y, z, patch.xFloat64, patch.yFloat64, patch.zFloat64)
 	} else {
-		return t.parent.DistanceBetweenPointsXY(t.xcor, t.ycor, patch.xFloat64, patch.yFloat64)
+		return t.parent.DistanceBetweenPointsXY(x, y, patch.xFloat64, patch.yFloat64)
 	}
 }
 
 // returns the distance between the turtle and the x y coordinates
 func (t *Turtle) DistanceXY(x float64, y float64) float64 {
-	return t.parent.DistanceBetweenPointsXY(t.xcor, t.ycor, x, y)
+	return t.parent.DistanceBetweenPointsXY(t.XCor(), t.YCor(), x, y)
 }
 
 // returns the distance between the turtle and the x y z coordinates
 func (t *Turtle) DistanceXYZ(x float64, y float64, z float64) float64 {
-	return t.parent.DistanceBetweenPointsXYZ(t.xcor, t.ycor, t.zcor, x, y, z)
+	tx, ty, tz := t.position()
+	return t.parent.DistanceBetweenPointsXYZ(tx, ty, tz, x, y, z)
 }
 
 // moves the turtle to the neighboring patch that has the lowest value of the patch variable
@@ -211,20 +235,21 @@ func (t *Turtle) Downhill(patchVariable string) {
 	p := t.PatchHere()
 
 	// if the patch variable is not a number then return
-	if _, ok := t.parent.DefaultPatchProperties[patchVariable].(float64); !ok {
+	get, _, ok := t.parent.patchFloatAccessors(patchVariable)
+	if !ok {
 		return
 	}
 
 	minPatch := p
-	minValue := minPatch.patchProperties[patchVariable].(float64)
+	minValue := get(minPatch)
 
 	for patch := range p.patchNeighborsMap {
 		if patch == nil {
 			continue
 		}
-		if patch.patchProperties[patchVariable].(float64) < minValue {
+		if get(patch) < minValue {
 			minPatch = patch
-			minValue = patch.patchProperties[patchVariable].(float64)
+			minValue = get(patch)
 		}
 	}
 
@@ -298,17 +323,18 @@ func (t *Turtle) Downhill4(patchVariable string) {
 	}
 
 	// if the patch variable is not a number then return
-	if _, ok := t.parent.DefaultPatchProperties[patchVariable].(float64); !ok {
+	get, _, ok := t.parent.patchFloatAccessors(patchVariable)
+	if !ok {
 		return
 	}
 
 	minPatch := t.PatchHere()
-	minValue := minPatch.patchProperties[patchVariable].(float64)
+	minValue := get(minPatch)
 
 	for patch := range neighborsMap {
-		if patch.patchProperties[patchVariable].(float64) < minValue {
+		if get(patch) < minValue {
 			minPatch = patch
-			minValue = patch.patchProperties[patchVariable].(float64)
+			minValue = get(patch)
 		}
 	}
 
@@ -468,6 +494,8 @@ func (t *Turtle) Hatch(amount int, operation TurtleOperation) {
 		turtles[i].size = t.size
 		turtles[i].label = t.label
 		turtles[i].LabelColor = t.LabelColor
+		turtles[i].penDown = t.penDown
+		turtles[i].penSize = t.penSize
 
 		// copy the property variables
 		for key, value := range t.turtlePropertiesGeneral {
@@ -485,6 +513,8 @@ func (t *Turtle) Hatch(amount int, operation TurtleOperation) {
 			operation(turtle)
 		}
 	}
+
+	t.parent.turtlesBorn(turtles)
 }
 
 // returns the heading of the turtle in degrees
@@ -507,9 +537,6 @@ func (t *Turtle) GetPitch() float64 {
 // SetHeading sets the turtle's heading in degrees.
 // This method is thread-safe and can be called concurrently.
 func (t *Turtle) SetHeading(heading float64) {
-	t.positionMu.Lock()
-	defer t.positionMu.Unlock()
-
 	//make sure the heading is between -360 and 360
 	if heading > 360 || heading < -360 {
 		heading = math.Mod(heading, 360)
@@ -524,12 +551,31 @@ func (t *Turtle) SetHeading(heading float64) {
 	t.setHeadingRadians(heading * (math.Pi / 180))
 }
 
+// the position lock is let go before the tied turtles turn so two turtles' locks are never held at once
 func (t *Turtle) setHeadingRadians(heading float64) {
+	t.positionMu.Lock()
+	turn := heading - t.heading
 	t.heading = heading
+	t.positionMu.Unlock()
+
+	t.moveTiedTurtles(0, 0, 0, turn, 0)
+}
+
+// SetPitch sets the turtle's pitch in degrees (3D models only).
+// This method is thread-safe and can be called concurrently.
+func (t *Turtle) SetPitch(pitch float64) {
+	t.setPitchRadians(pitch * (math.Pi / 180))
 }
 
 func (t *Turtle) setPitchRadians(pitch float64) {
+	t.positionMu.Lock()
+	tilt := pitch - t.pitch
 	t.pitch = pitch
+	t.positionMu.Unlock()
+
+	if t.parent != nil && t.parent.Is3D() {
+		t.moveTiedTurtles(0, 0, 0, 0, tilt)
+	}
 }
 
 // Hide the turtle
@@ -712,6 +758,22 @@ func (t *Turtle) SetProperty(key string, value interface{}) {
 	}
 }
 
+// returns a copy of all the turtle's property variables
+// breed specific variables take precedence over general variables
+func (t *Turtle) Properties() map[string]interface{} {
+	t.propertiesMutex.RLock()
+	defer t.propertiesMutex.RUnlock()
+
+	properties := make(map[string]interface{}, len(t.turtlePropertiesGeneral)+len(t.turtlePropertiesBreed))
+	for key, value := range t.turtlePropertiesGeneral {
+		properties[key] = value
+	}
+	for key, value := range t.turtlePropertiesBreed {
+		properties[key] = value
+	}
+	return properties
+}
+
 // returns the patch that is ahead of the turtle by the distance passed in in relation to its heading
 func (t *Turtle) PatchAhead(distance float64) *Patch {
 	distX := t.xcor + distance*math.Cos(t.heading)
@@ -776,39 +838,99 @@ func (t *Turtle) Right(number float64) {
 // SetXY sets the turtle's position to the specified coordinates.
 // This method is thread-safe and can be called concurrently.
 func (t *Turtle) SetXY(x float64, y float64) {
+	// the tied turtles are moved after the position lock is let go so two turtles' locks are never held at once,
+	// otherwise turtles tied both ways and moved from two goroutines could deadlock
+	dx, dy, moved := t.setXY(x, y)
+	if moved {
+		t.moveTiedTurtles(dx, dy, 0, 0, 0)
+	}
+}
+
+// moves the turtle without moving the turtles tied to it
+// returns how far it moved before wrapping and false if the position is outside the world
+func (t *Turtle) setXY(x float64, y float64) (float64, float64, bool) {
 	t.positionMu.Lock()
 	defer t.positionMu.Unlock()
 
+	// use the distance before wrapping so that tied turtles move the same way
+	dx := x - t.xcor
+	dy := y - t.ycor
+
 	x, y, allowed := t.parent.convertXYToInBounds(x, y)
 	if !allowed {
-		return
+		return 0, 0, false
 	}
 
+	t.positionChanging()
+
 	t.xcor = x
 	t.ycor = y
 
 	t.transferPatchOwnership()
+
+	t.drawTrail(dx, dy, 0)
+
+	return dx, dy, true
 }
 
 // SetXYZ sets the turtle's position to the specified coordinates.
 // This method is thread-safe and can be called concurrently.
 func (t *Turtle) SetXYZ(x float64, y float64, z float64) {
+	// the tied turtles are moved after the position lock is let go, see SetXY
+	dx, dy, dz, moved := t.setXYZ(x, y, z)
+	if moved {
+		t.moveTiedTurtles(dx, dy, dz, 0, 0)
+	}
+}
+
+// moves the turtle without moving the turtles tied to it, see setXY
+func (t *Turtle) setXYZ(x float64, y float64, z float64) (float64, float64, float64, bool) {
 	t.positionMu.Lock()
 	defer t.positionMu.Unlock()
 
+	// use the distance before wrapping so that tied turtles move the same way
+	dx := x - t.xcor
+	dy := y - t.ycor
+	dz := z - t.zcor
+
 	x, y, z, allowed := t.parent.convertXYZToInBounds(x, y, z)
 	if !allowed {
-		return
+		return 0, 0, 0, false
 	}
 
+	t.positionChanging()
+
 	t.xcor = x
 	t.ycor = y
 	t.zcor = z
 
 	t.transferPatchOwnership()
+
+	t.drawTrail(dx, dy, dz)
+
+	return dx, dy, dz, true
 }
 
+// keeps the position from before the movement phase the first time the turtle moves in it
+// has to be called with the position lock held before the position changes
+func (t *Turtle) positionChanging() {
+	if !t.parent.deferMovement || t.moved {
+		return
+	}
+	t.moved = true
+	t.snapshotX, t.snapshotY, t.snapshotZ = t.xcor, t.ycor, t.zcor
+
+	t.parent.movedMu.Lock()
+	t.parent.moved = append(t.parent.moved, t)
+	t.parent.movedMu.Unlock()
+}
+
+// moves the turtle to the patch at its position, during the movement phase this waits for CommitMovementPhase
 func (t *Turtle) transferPatchOwnership() {
+	if t.moved {
+		return
+	}
+
 	oldPatch := t.patch
 	t.patch = nil
 	t.patch = t.PatchHere()
@@ -816,6 +938,10 @@ func (t *Turtle) transferPatchOwnership() {
 		oldPatch.removeTurtle(t)
 		t.patch.addTurtle(t)
 	}
+
+	if t.parent.spatialIndex != nil {
+		t.parent.spatialIndex.update(t.parent, t)
+	}
 }
 
 func (t *Turtle) Show() {
@@ -859,20 +985,21 @@ func (t *Turtle) Uphill(patchVariable string) {
 	p := t.PatchHere()
 
 	// if the patch variable is not a number then return
-	if _, ok := t.parent.DefaultPatchProperties[patchVariable].(float64); !ok {
+	get, _, ok := t.parent.patchFloatAccessors(patchVariable)
+	if !ok {
 		return
 	}
 
 	minPatch := p
-	minValue := minPatch.patchProperties[patchVariable].(float64)
+	minValue := get(minPatch)
 
 	for patch := range p.patchNeighborsMap {
 		if patch == nil {
 			continue
 		}
-		if patch.patchProperties[patchVariable].(float64) > minValue {
+		if get(patch) > minValue {
 			minPatch = patch
-			minValue = patch.patchProperties[patchVariable].(float64)
+			minValue = get(patch)
 		}
 	}
 
@@ -931,17 +1058,18 @@ func (t *Turtle) Uphill4(patchVariable string) {
 	}
 
 	// if the patch variable is not a number then return
-	if _, ok := t.parent.DefaultPatchProperties[patchVariable].(float64); !ok {
+	get, _, ok := t.parent.patchFloatAccessors(patchVariable)
+	if !ok {
 		return
 	}
 
 	minPatch := t.PatchHere()
-	minValue := minPatch.patchProperties[patchVariable].(float64)
+	minValue := get(minPatch)
 
 	for patch := range neighborsMap {
-		if patch.patchProperties[patchVariable].(float64) > minValue {
+		if get(patch) > minValue {
 			minPatch = patch
-			minValue = patch.patchProperties[patchVariable].(float64)
+			minValue = get(patch)
 		}
 	}
 
@@ -968,26 +1096,73 @@ func (t *Turtle) Who() int {
 	return t.who
 }
 
+// returns the who number of the turtle
+func (t *Turtle) ID() int {
+	return t.who
+}
+
+// returns the color of the turtle
+func (t *Turtle) GetColor() Color {
+	return t.Color
+}
+
+// sets the color of the turtle
+func (t *Turtle) SetColor(color Color) {
+	t.Color = color
+}
+
+// returns whether the turtle is hidden
+func (t *Turtle) IsHidden() bool {
+	return t.Hidden
+}
+
+func (t *Turtle) agentModel() *Model {
+	return t.parent
+}
+
 // XCor returns the turtle's x coordinate.
 // This method is thread-safe and can be called concurrently.
+// During the movement phase it returns the x coordinate from before the phase.
 func (t *Turtle) XCor() float64 {
 	t.positionMu.RLock()
 	defer t.positionMu.RUnlock()
+	if t.moved {
+		return t.snapshotX
+	}
 	return t.xcor
 }
 
 // YCor returns the turtle's y coordinate.
 // This method is thread-safe and can be called concurrently.
+// During the movement phase it returns the y coordinate from before the phase.
 func (t *Turtle) YCor() float64 {
 	t.positionMu.RLock()
 	defer t.positionMu.RUnlock()
+	if t.moved {
+		return t.snapshotY
+	}
 	return t.ycor
 }
 
 // ZCor returns the turtle's z coordinate.
 // This method is thread-safe and can be called concurrently.
+// During the movement phase it returns the z coordinate from before the phase.
 func (t *Turtle) ZCor() float64 {
 	t.positionMu.RLock()
 	defer t.positionMu.RUnlock()
+	if t.moved {
+		return t.snapshotZ
+	}
 	return t.zcor
 }
+
+// returns the turtle's coordinates the same way XCor, YCor and ZCor do, so from before the movement phase during it
+// queries that read the position of other turtles go through this so they are safe while turtles move
+func (t *Turtle) position() (float64, float64, float64) {
+	t.positionMu.RLock()
+	defer t.positionMu.RUnlock()
+	if t.moved {
+		return t.snapshotX, t.snapshotY, t.snapshotZ
+	}
+	return t.xcor, t.ycor, t.zcor
+}
@@ -21,6 +21,9 @@ type turtleLinks struct {
 	turtlesDirectedOutBreed map[*LinkBreed]map[*Turtle]*Link
 	turtlesDirectedInBreed  map[*LinkBreed]map[*Turtle]*Link
 	turtlesUndirectedBreed  map[*LinkBreed]map[*Turtle]*Link
+
+	// tied links where the turtle is a root, the turtles on the other end move with it
+	tiedLinks *LinkAgentSet
 }
 
 // links to connected turtles are stored in the turtleLinks struct
@@ -42,6 +45,8 @@ func newTurtleLinks() *turtleLinks {
 		turtlesDirectedOutBreed: make(map[*LinkBreed]map[*Turtle]*Link),
 		turtlesDirectedInBreed:  make(map[*LinkBreed]map[*Turtle]*Link),
 		turtlesUndirectedBreed:  make(map[*LinkBreed]map[*Turtle]*Link),
+
+		tiedLinks: NewLinkAgentSet([]*Link{}),
 	}
 }
 
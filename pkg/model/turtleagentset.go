@@ -1,59 +1,43 @@
 package model
 
-import (
-	"github.com/nlatham1999/sortedset"
-)
+import "github.com/nlatham1999/sortedset"
 
+// TurtleAgentSet is an ordered set of turtles than can be sorted
+// it is built on AgentSet so the methods that don't return an agent set come from there
 type TurtleAgentSet struct {
-	turtles *sortedset.SortedSet //map of turtles so we can quickly check if a turtle is in the set
+	*AgentSet[*Turtle]
 }
 
+// create a new TurtleAgentSet
 func NewTurtleAgentSet(turtles []*Turtle) *TurtleAgentSet {
-	turtleSet := &TurtleAgentSet{
-		turtles: sortedset.NewSortedSet(),
-	}
-	for _, turtle := range turtles {
-		turtleSet.turtles.Add(turtle)
+	return &TurtleAgentSet{
+		AgentSet: NewAgentSet(turtles),
 	}
-	return turtleSet
-}
-
-func (t *TurtleAgentSet) Add(turtle *Turtle) {
-	t.turtles.Add(turtle)
 }
 
-func (t *TurtleAgentSet) All(operation TurtleBoolOperation) bool {
-	if operation == nil {
-		return false
+func newTurtleAgentSet(set *AgentSet[*Turtle]) *TurtleAgentSet {
+	if set == nil {
+		return nil
 	}
-
-	return t.turtles.All(func(a interface{}) bool {
-		return operation(a.(*Turtle))
-	})
-}
-
-func (t *TurtleAgentSet) Any(operation TurtleBoolOperation) bool {
-	if operation == nil {
-		return false
+	return &TurtleAgentSet{
+		AgentSet: set,
 	}
-
-	return t.turtles.Any(func(a interface{}) bool {
-		return operation(a.(*Turtle))
-	})
 }
 
-func (t *TurtleAgentSet) Ask(operation TurtleOperation) {
-	if operation == nil {
-		return
+// AskStaged runs each stage on all turtles in the agent set before moving on to the next stage.
+// The turtles are visited in the order given by the scheduler, which is asked for a new order every stage.
+// If the scheduler is buffered the updates returned by the turtles are committed once the whole stage has been run.
+// If the scheduler is nil then the model's scheduler is used.
+func (t *TurtleAgentSet) AskStaged(scheduler Scheduler, stages []TurtleStage) {
+	operations := make([]func(*Turtle) func(), len(stages))
+	for i, stage := range stages {
+		operations[i] = stage.Operation
 	}
-
-	t.turtles.Ask(func(a interface{}) {
-		operation(a.(*Turtle))
-	})
+	t.askStaged(scheduler, operations)
 }
 
+// returns the turtles in the agent set that are on the patches at the given coordinates
 func (t *TurtleAgentSet) AtPoints(m *Model, points []Coordinate) *TurtleAgentSet {
-
 	// convert the points to patches
 	patchesAtPoints := sortedset.NewSortedSet()
 	for _, point := range points {
@@ -64,123 +48,93 @@ func (t *TurtleAgentSet) AtPoints(m *Model, points []Coordinate) *TurtleAgentSet
 	return t.With(func(turtle *Turtle) bool {
 		return patchesAtPoints.Contains(turtle.PatchHere())
 	})
-
 }
 
-func (t *TurtleAgentSet) Contains(turtle *Turtle) bool {
-	return t.turtles.Contains(turtle)
+// returns a copy of the agent set
+func (t *TurtleAgentSet) Copy() *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.Copy())
 }
 
-func (t *TurtleAgentSet) Copy() *TurtleAgentSet {
-	return &TurtleAgentSet{
-		// 	turtles: t.turtles.Copy(),
-	}
+// returns the first n turtles in the agent set
+func (t *TurtleAgentSet) FirstNOf(n int) *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.FirstNOf(n))
 }
 
-func (t *TurtleAgentSet) Count() int {
-	return t.turtles.Len()
+// returns the last n turtles in the agent set
+func (t *TurtleAgentSet) LastNOf(n int) *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.LastNOf(n))
 }
 
-func (t *TurtleAgentSet) List() []*Turtle {
-	turtles := make([]*Turtle, 0)
-	t.turtles.Ask(func(turtle interface{}) {
-		turtles = append(turtles, turtle.(*Turtle))
-	})
-	return turtles
+// returns a new TurtleAgentSet with all the turtles that are not in the given TurtleAgentSet
+func (t *TurtleAgentSet) WhoAreNot(turtles *TurtleAgentSet) *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.Difference(turtles.AgentSet))
 }
 
-func (t *TurtleAgentSet) FirstNOf(n int) *TurtleAgentSet {
-	turtleSet := sortedset.NewSortedSet()
-	turtle := t.turtles.First()
-	for i := 0; i < n && turtle != nil; i++ {
-		turtleSet.Add(turtle)
-		turtle, _ = t.turtles.Next()
-	}
-	return &TurtleAgentSet{
-		turtles: turtleSet,
-	}
+// returns a new TurtleAgentSet with all the turtles that are not the given turtle
+func (t *TurtleAgentSet) WhoAreNotTurtle(turtle *Turtle) *TurtleAgentSet {
+	return newTurtleAgentSet(t.WhoAreNotAgent(turtle))
 }
 
-func (t *TurtleAgentSet) First() (*Turtle, error) {
-	turtle := t.turtles.First()
-	if turtle == nil {
-		return nil, ErrNoTurtlesInAgentSet
-	}
-	return turtle.(*Turtle), nil
+// returns a new agent set that contains all the turtles that satisfy the operation
+// returns nil if the operation is nil
+func (t *TurtleAgentSet) With(operation TurtleBoolOperation) *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.With(operation))
 }
 
-func (t *TurtleAgentSet) LastNOf(n int) *TurtleAgentSet {
-	turtleSet := sortedset.NewSortedSet()
-	turtle := t.turtles.Last()
-	for i := 0; i < n && turtle != nil; i++ {
-		turtleSet.Add(turtle)
-		turtle, _ = t.turtles.Previous()
-	}
-	return &TurtleAgentSet{
-		turtles: turtleSet,
-	}
+// returns n random turtles from the agent set, or all of them if there are fewer than n
+// the turtles stay in the order they are in the agent set
+func (t *TurtleAgentSet) NOf(n int) *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.NOf(n))
 }
 
-func (t *TurtleAgentSet) Last() (*Turtle, error) {
-	turtle := t.turtles.Last()
-	if turtle == nil {
-		return nil, ErrNoTurtlesInAgentSet
-	}
-	return turtle.(*Turtle), nil
+// returns n different turtles from the agent set picked with chances proportional to their weights
+// turtles with a weight that isn't positive are never picked so fewer than n may be returned
+// the turtles stay in the order they are in the agent set
+func (t *TurtleAgentSet) WeightedNOf(n int, weight TurtleFloatOperation) *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.WeightedNOf(n, weight))
 }
 
-func (t *TurtleAgentSet) Next() (*Turtle, error) {
-	turtle, _ := t.turtles.Next()
-	if turtle == nil {
-		return nil, ErrNoTurtlesInAgentSet
-	}
-	return turtle.(*Turtle), nil
+// returns the n turtles with the largest values of the operation, ties at the cut off are broken at random
+// the turtles stay in the order they are in the agent set
+func (t *TurtleAgentSet) MaxNOf(n int, operation TurtleFloatOperation) *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.MaxNOf(n, operation))
 }
 
-func (t *TurtleAgentSet) Remove(turtle *Turtle) {
-	t.turtles.Remove(turtle)
+// returns the n turtles with the smallest values of the operation, ties at the cut off are broken at random
+// the turtles stay in the order they are in the agent set
+func (t *TurtleAgentSet) MinNOf(n int, operation TurtleFloatOperation) *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.MinNOf(n, operation))
 }
 
-func (t *TurtleAgentSet) SortAsc(operation TurtleFloatOperation) {
-	t.turtles.SortAsc(func(a interface{}) interface{} {
-		return operation(a.(*Turtle))
-	})
+// splits the turtles into agent sets by the key the operation returns for each turtle
+// the keys have to be comparable, the turtles in each agent set stay in the order they are in the agent set
+func (t *TurtleAgentSet) GroupBy(operation TurtleKeyOperation) map[interface{}]*TurtleAgentSet {
+	groups := map[interface{}]*TurtleAgentSet{}
+	for key, group := range t.AgentSet.GroupBy(operation) {
+		groups[key] = newTurtleAgentSet(group)
+	}
+	return groups
 }
 
-func (t *TurtleAgentSet) SortDesc(operation TurtleFloatOperation) {
-	t.turtles.SortDesc(func(a interface{}) interface{} {
-		return operation(a.(*Turtle))
+// splits the turtles into agent sets by the value of the property
+func (t *TurtleAgentSet) GroupByProperty(name string) map[interface{}]*TurtleAgentSet {
+	return t.GroupBy(func(turtle *Turtle) interface{} {
+		return turtle.GetProperty(name)
 	})
 }
 
-// returns a new TurtleAgentSet with all the turtles that are not in the given TurtleAgentSet
-func (t *TurtleAgentSet) WhoAreNot(turtles *TurtleAgentSet) *TurtleAgentSet {
-	return &TurtleAgentSet{
-		turtles: t.turtles.Difference(turtles.turtles),
-	}
+// returns a new agent set with the turtles in either agent set
+// the turtles of this agent set come first followed by the ones only in the other
+func (t *TurtleAgentSet) Union(other *TurtleAgentSet) *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.Union(other.AgentSet))
 }
 
-// returns a new TurtleAgentSet with all the turtles that are not the given turtle
-func (t *TurtleAgentSet) WhoAreNotTurtle(turtle *Turtle) *TurtleAgentSet {
-	return &TurtleAgentSet{
-		turtles: t.turtles.Difference(sortedset.NewSortedSet(turtle)),
-	}
+// returns a new agent set with the turtles in both agent sets, in the order of this agent set
+func (t *TurtleAgentSet) Intersect(other *TurtleAgentSet) *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.Intersect(other.AgentSet))
 }
 
-func (t *TurtleAgentSet) With(operation TurtleBoolOperation) *TurtleAgentSet {
-	turtleSet := sortedset.NewSortedSet()
-
-	if operation == nil {
-		return nil
-	}
-
-	t.turtles.Ask(func(a interface{}) {
-		if operation(a.(*Turtle)) {
-			turtleSet.Add(a)
-		}
-	})
-
-	return &TurtleAgentSet{
-		turtles: turtleSet,
-	}
+// returns a new agent set with the turtles of this agent set that aren't in the other, same as WhoAreNot
+func (t *TurtleAgentSet) Difference(other *TurtleAgentSet) *TurtleAgentSet {
+	return newTurtleAgentSet(t.AgentSet.Difference(other.AgentSet))
 }
@@ -14,6 +14,10 @@ type LinkBoolOperation func(l *Link) bool
 // func(l *Link) float64
 type LinkFloatOperation func(l *Link) float64
 
+// general function that takes in a link and returns a key to group it by
+// func(l *Link) interface{}
+type LinkKeyOperation func(l *Link) interface{}
+
 // general function for acting on a patch
 // func(p *Patch)
 type PatchOperation func(p *Patch)
@@ -26,6 +30,10 @@ type PatchBoolOperation func(p *Patch) bool
 // func(p *Patch) float64
 type PatchFloatOperation func(p *Patch) float64
 
+// general function that takes in a patch and returns a key to group it by
+// func(p *Patch) interface{}
+type PatchKeyOperation func(p *Patch) interface{}
+
 // general function for acting on a turtle
 // func(t *Turtle)
 type TurtleOperation func(t *Turtle)
@@ -37,3 +45,22 @@ type TurtleBoolOperation func(t *Turtle) bool
 // general function that takes in a turtle and returns a float
 // func(t *Turtle) float64
 type TurtleFloatOperation func(t *Turtle) float64
+
+// general function that takes in a turtle and returns a key to group it by
+// func(t *Turtle) interface{}
+type TurtleKeyOperation func(t *Turtle) interface{}
+
+// function that acts on a link and returns an optional update to commit
+// when asked through a buffered scheduler the update is only run once every link has been activated
+// func(l *Link) func()
+type LinkUpdateOperation func(l *Link) func()
+
+// function that acts on a patch and returns an optional update to commit
+// when asked through a buffered scheduler the update is only run once every patch has been activated
+// func(p *Patch) func()
+type PatchUpdateOperation func(p *Patch) func()
+
+// function that acts on a turtle and returns an optional update to commit
+// when asked through a buffered scheduler the update is only run once every turtle has been activated
+// func(t *Turtle) func()
+type TurtleUpdateOperation func(t *Turtle) func()
@@ -14,3 +14,7 @@ func radiansToDegrees(radians float64) float64 {
 	}
 	return degrees
 }
+
+func degreesToRadians(degrees float64) float64 {
+	return degrees * (math.Pi / 180)
+}
@@ -0,0 +1,302 @@
+package model
+
+import "math"
+
+// returns the range of patch coordinates that could be within the radius of the coordinate
+// the range is clamped to the world, or to one world length if the world wraps, so no patch is looked at twice
+func (m *Model) patchRangeInRadius(cor float64, radius float64, minPCor int, maxPCor int, wrapping bool) (int, int) {
+	low := int(math.Floor(cor - radius))
+	high := int(math.Ceil(cor + radius))
+
+	if wrapping {
+		if high-low+1 > maxPCor-minPCor+1 {
+			high = low + maxPCor - minPCor
+		}
+		return low, high
+	}
+
+	if low < minPCor {
+		low = minPCor
+	}
+	if high > maxPCor {
+		high = maxPCor
+	}
+	return low, high
+}
+
+// returns the number of patches that have to be looked at to find the agents within the radius
+func (m *Model) patchCountInRadius(xCor float64, yCor float64, zCor float64, radius float64, use3D bool) int {
+	xMin, xMax := m.patchRangeInRadius(xCor, radius, m.minPxCor, m.maxPxCor, m.wrappingX)
+	yMin, yMax := m.patchRangeInRadius(yCor, radius, m.minPyCor, m.maxPyCor, m.wrappingY)
+
+	count := (xMax - xMin + 1) * (yMax - yMin + 1)
+	if use3D {
+		zMin, zMax := m.patchRangeInRadius(zCor, radius, m.minPzCor, m.maxPzCor, false)
+		count *= zMax - zMin + 1
+	}
+	if count < 0 {
+		return 0
+	}
+	return count
+}
+
+// classifies the patches around the coordinates by how much of them is within the radius
+// full patches are completely inside the radius so everything on them is within the radius
+// partial patches cross the edge of the radius so whatever is on them has to be checked
+// patches that skip returns true for are left out, skip can be nil
+func (m *Model) classifyPatchesInRadius(xCor float64, yCor float64, zCor float64, radius float64, use3D bool, skip func(patch *Patch) bool) ([]*Patch, []*Patch) {
+	xMin, xMax := m.patchRangeInRadius(xCor, radius, m.minPxCor, m.maxPxCor, m.wrappingX)
+	yMin, yMax := m.patchRangeInRadius(yCor, radius, m.minPyCor, m.maxPyCor, m.wrappingY)
+
+	// Iterate through patches in 2D or 3D
+	zMin, zMax := 0, 0
+	if use3D {
+		zMin, zMax = m.patchRangeInRadius(zCor, radius, m.minPzCor, m.maxPzCor, false)
+	}
+
+	full := make([]*Patch, 0)
+	partial := make([]*Patch, 0)
+
+	for x := xMin; x <= xMax; x++ {
+		for y := yMin; y <= yMax; y++ {
+			for z := zMin; z <= zMax; z++ {
+				var patch *Patch
+				if use3D {
+					patch = m.Patch3D(float64(x), float64(y), float64(z))
+				} else {
+					patch = m.Patch(float64(x), float64(y))
+				}
+
+				if patch == nil {
+					continue
+				}
+
+				if skip != nil && skip(patch) {
+					continue
+				}
+
+				// offset from the coordinates to the center of the patch, going the short way around if the world wraps
+				dx, dy := m.shortestOffsetXY(xCor, yCor, patch.xFloat64, patch.yFloat64)
+				dx = math.Abs(dx)
+				dy = math.Abs(dy)
+				dz := 0.0
+				if use3D {
+					dz = math.Abs(patch.zFloat64 - zCor)
+				}
+
+				// the closest and furthest points of the patch from the coordinates
+				nearest := math.Sqrt(square(math.Max(dx-.5, 0)) + square(math.Max(dy-.5, 0)) + square(math.Max(dz-.5, 0)))
+				if nearest > radius {
+					continue
+				}
+
+				furthest := math.Sqrt(square(dx+.5) + square(dy+.5))
+				if use3D {
+					furthest = math.Sqrt(square(dx+.5) + square(dy+.5) + square(dz+.5))
+				}
+
+				if furthest <= radius {
+					full = append(full, patch)
+				} else {
+					partial = append(partial, patch)
+				}
+			}
+		}
+	}
+
+	return full, partial
+}
+
+func square(n float64) float64 {
+	return n * n
+}
+
+// returns an agentset of patches whose centers are within the provided radius of the provided coordinates
+// if use3D is true then the z coordinate is used as well
+// like TurtlesInRadius the radius is inclusive and a negative radius gives none
+func (m *Model) PatchesInRadius(xCor float64, yCor float64, zCor float64, radius float64, use3D bool) *PatchAgentSet {
+	if radius < 0 {
+		return NewPatchAgentSet(nil)
+	}
+
+	full, partial := m.classifyPatchesInRadius(xCor, yCor, zCor, radius, use3D, nil)
+
+	patches := NewPatchAgentSet(full)
+
+	// patches that are partially inside the radius are included if their center is
+	for _, patch := range partial {
+		if use3D {
+			if m.DistanceBetweenPointsXYZ(xCor, yCor, zCor, patch.xFloat64, patch.yFloat64, patch.zFloat64) <= radius {
+				patches.Add(patch)
+			}
+		} else {
+			if m.DistanceBetweenPointsXY(xCor, yCor, patch.xFloat64, patch.yFloat64) <= radius {
+				patches.Add(patch)
+			}
+		}
+	}
+
+	return patches
+}
+
+// returns an agentset of patches whose centers are within the provided radius of the provided x y coordinates
+func (m *Model) PatchesInRadiusXY(xCor float64, yCor float64, radius float64) *PatchAgentSet {
+	return m.PatchesInRadius(xCor, yCor, 0, radius, false)
+}
+
+// returns an agentset of patches whose centers are within the provided radius of the provided x y z coordinates
+func (m *Model) PatchesInRadiusXYZ(xCor float64, yCor float64, zCor float64, radius float64) *PatchAgentSet {
+	return m.PatchesInRadius(xCor, yCor, zCor, radius, true)
+}
+
+// returns the turtles in the agentset that are within the provided radius of the provided coordinates
+// if use3D is true then the z coordinate is used as well
+func (t *TurtleAgentSet) InRadius(xCor float64, yCor float64, zCor float64, radius float64, use3D bool) *TurtleAgentSet {
+	if radius < 0 {
+		return NewTurtleAgentSet(nil)
+	}
+
+	var m *Model
+	t.Any(func(turtle *Turtle) bool {
+		m = turtle.parent
+		return m != nil
+	})
+	if m == nil {
+		return NewTurtleAgentSet(nil)
+	}
+
+	// for small agentsets it is quicker to check each turtle than to look through the patches
+	if t.Count() < m.patchCountInRadius(xCor, yCor, zCor, radius, use3D) {
+		return t.With(func(turtle *Turtle) bool {
+			if turtle.parent == nil {
+				return false
+			}
+			x, y, z := turtle.position()
+			if use3D {
+				return m.DistanceBetweenPointsXYZ(xCor, yCor, zCor, x, y, z) <= radius
+			}
+			return m.DistanceBetweenPointsXY(xCor, yCor, x, y) <= radius
+		})
+	}
+
+	inRadius := m.TurtlesInRadius(xCor, yCor, zCor, radius, use3D)
+	return t.With(inRadius.Contains)
+}
+
+// returns the turtles in the agentset that are within the provided radius of the provided x y coordinates
+func (t *TurtleAgentSet) InRadiusXY(xCor float64, yCor float64, radius float64) *TurtleAgentSet {
+	return t.InRadius(xCor, yCor, 0, radius, false)
+}
+
+// returns the turtles in the agentset that are within the provided radius of the provided x y z coordinates
+func (t *TurtleAgentSet) InRadiusXYZ(xCor float64, yCor float64, zCor float64, radius float64) *TurtleAgentSet {
+	return t.InRadius(xCor, yCor, zCor, radius, true)
+}
+
+// returns the patches in the agentset whose centers are within the provided radius of the provided coordinates
+// if use3D is true then the z coordinate is used as well
+func (p *PatchAgentSet) InRadius(xCor float64, yCor float64, zCor float64, radius float64, use3D bool) *PatchAgentSet {
+	first, err := p.First()
+	if err != nil || radius < 0 {
+		return NewPatchAgentSet(nil)
+	}
+	m := first.parent
+
+	// for small agentsets it is quicker to check each patch than to look through the patches around the coordinates
+	if p.Count() < m.patchCountInRadius(xCor, yCor, zCor, radius, use3D) {
+		return p.With(func(patch *Patch) bool {
+			if use3D {
+				return m.DistanceBetweenPointsXYZ(xCor, yCor, zCor, patch.xFloat64, patch.yFloat64, patch.zFloat64) <= radius
+			}
+			return m.DistanceBetweenPointsXY(xCor, yCor, patch.xFloat64, patch.yFloat64) <= radius
+		})
+	}
+
+	inRadius := m.PatchesInRadius(xCor, yCor, zCor, radius, use3D)
+	return p.With(inRadius.Contains)
+}
+
+// returns the patches in the agentset whose centers are within the provided radius of the provided x y coordinates
+func (p *PatchAgentSet) InRadiusXY(xCor float64, yCor float64, radius float64) *PatchAgentSet {
+	return p.InRadius(xCor, yCor, 0, radius, false)
+}
+
+// returns the patches in the agentset whose centers are within the provided radius of the provided x y z coordinates
+func (p *PatchAgentSet) InRadiusXYZ(xCor float64, yCor float64, zCor float64, radius float64) *PatchAgentSet {
+	return p.InRadius(xCor, yCor, zCor, radius, true)
+}
+
+// returns true if the point is within the cone in front of the turtle
+// the cone is centered on the turtle's heading, and in 3D models on its pitch as well
+// angle is the full width of the cone in degrees
+func (t *Turtle) pointInCone(x float64, y float64, z float64, distance float64, angle float64) bool {
+	tx, ty, tz := t.position()
+	dx, dy := t.parent.shortestOffsetXY(tx, ty, x, y)
+	dz := 0.0
+	if t.parent.Is3D() {
+		dz = z - tz
+	}
+
+	d := math.Sqrt(dx*dx + dy*dy + dz*dz)
+	if d > distance {
+		return false
+	}
+
+	// the turtle's own position and cones that go all the way around always count
+	if d == 0 || angle >= 360 {
+		return true
+	}
+
+	// direction the turtle is facing
+	hx := math.Cos(t.heading)
+	hy := math.Sin(t.heading)
+	hz := 0.0
+	if t.parent.Is3D() {
+		hx *= math.Cos(t.pitch)
+		hy *= math.Cos(t.pitch)
+		hz = math.Sin(t.pitch)
+	}
+
+	cos := (dx*hx + dy*hy + dz*hz) / d
+	cos = math.Max(-1, math.Min(1, cos))
+
+	// small tolerance so points right on the edge of the cone aren't lost to rounding
+	return math.Acos(cos) <= degreesToRadians(angle)/2+1e-9
+}
+
+// returns the turtles that are within the cone in front of the turtle, including the turtle itself
+// the cone reaches out distance and is angle degrees wide centered on the turtle's heading
+// in 3D models the cone is centered on the turtle's heading and pitch
+func (t *Turtle) InCone(distance float64, angle float64) *TurtleAgentSet {
+	x, y, z := t.position()
+	return t.parent.TurtlesInRadius(x, y, z, distance, t.parent.Is3D()).With(func(turtle *Turtle) bool {
+		otherX, otherY, otherZ := turtle.position()
+		return t.pointInCone(otherX, otherY, otherZ, distance, angle)
+	})
+}
+
+// returns the patches whose centers are within the cone in front of the turtle
+// the cone reaches out distance and is angle degrees wide centered on the turtle's heading
+func (t *Turtle) PatchesInCone(distance float64, angle float64) *PatchAgentSet {
+	x, y, z := t.position()
+	return t.parent.PatchesInRadius(x, y, z, distance, t.parent.Is3D()).With(func(patch *Patch) bool {
+		return t.pointInCone(patch.xFloat64, patch.yFloat64, patch.zFloat64, distance, angle)
+	})
+}
+
+// returns the turtles in the agentset that are within the cone in front of the turtle passed in
+func (t *TurtleAgentSet) InCone(turtle *Turtle, distance float64, angle float64) *TurtleAgentSet {
+	x, y, z := turtle.position()
+	return t.InRadius(x, y, z, distance, turtle.parent.Is3D()).With(func(other *Turtle) bool {
+		otherX, otherY, otherZ := other.position()
+		return turtle.pointInCone(otherX, otherY, otherZ, distance, angle)
+	})
+}
+
+// returns the patches in the agentset whose centers are within the cone in front of the turtle passed in
+func (p *PatchAgentSet) InCone(turtle *Turtle, distance float64, angle float64) *PatchAgentSet {
+	x, y, z := turtle.position()
+	return p.InRadius(x, y, z, distance, turtle.parent.Is3D()).With(func(patch *Patch) bool {
+		return turtle.pointInCone(patch.xFloat64, patch.yFloat64, patch.zFloat64, distance, angle)
+	})
+}
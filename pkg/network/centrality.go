@@ -0,0 +1,274 @@
+package network
+
+import (
+	"math"
+	"sort"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+const (
+	centralityTolerance     = 1e-10 // iterative centralities stop once no value changes by more than this
+	centralityMaxIterations = 10000
+)
+
+// returns the shortest path search from the source, weighted if the network has a weight property
+func (n *Network) search(source int) *searchResult {
+	if n.weighted {
+		return n.searchWeighted(source)
+	}
+	return n.searchUnweighted(source)
+}
+
+// returns the betweenness centrality of every turtle
+// the betweenness of a turtle is the number of shortest paths between other turtles that go through it
+// when there are several shortest paths between two turtles each counts as a fraction
+// for networks without directed links each pair of turtles is only counted once
+func (n *Network) BetweennessCentrality() map[*model.Turtle]float64 {
+	size := len(n.turtles)
+	betweenness := make([]float64, size)
+
+	for source := 0; source < size; source++ {
+		// brandes' algorithm, find every shortest path from the source then walk back from the furthest turtles
+		distance := make([]float64, size)
+		paths := make([]float64, size) // number of shortest paths from the source
+		previous := make([][]int, size)
+		for i := range distance {
+			distance[i] = math.Inf(1)
+		}
+		distance[source] = 0
+		paths[source] = 1
+
+		order := n.shortestPathOrder(source, distance, paths, previous)
+
+		dependency := make([]float64, size)
+		for i := len(order) - 1; i >= 0; i-- {
+			w := order[i]
+			for _, v := range previous[w] {
+				dependency[v] += paths[v] / paths[w] * (1 + dependency[w])
+			}
+			if w != source {
+				betweenness[w] += dependency[w]
+			}
+		}
+	}
+
+	if !n.directed {
+		for i := range betweenness {
+			betweenness[i] /= 2
+		}
+	}
+
+	return n.toMap(betweenness)
+}
+
+// finds every shortest path from the source filling in the distances, number of paths and the turtles each turtle is reached from
+// returns the turtles in the order they were reached, closest first
+func (n *Network) shortestPathOrder(source int, distance []float64, paths []float64, previous [][]int) []int {
+	order := []int{}
+
+	if !n.weighted {
+		queue := []int{source}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			order = append(order, current)
+			for _, e := range n.out[current] {
+				if math.IsInf(distance[e.to], 1) {
+					distance[e.to] = distance[current] + 1
+					queue = append(queue, e.to)
+				}
+				if distance[e.to] == distance[current]+1 {
+					paths[e.to] += paths[current]
+					previous[e.to] = append(previous[e.to], current)
+				}
+			}
+		}
+		return order
+	}
+
+	copy(distance, n.searchWeighted(source).distance)
+
+	reached := []int{source}
+	for turtle := range distance {
+		if turtle != source && !math.IsInf(distance[turtle], 1) {
+			reached = append(reached, turtle)
+		}
+	}
+	sort.SliceStable(reached, func(i, j int) bool {
+		return distance[reached[i]] < distance[reached[j]]
+	})
+
+	// links that weigh 0 join turtles at the same distance, so within a distance each turtle has to come after the turtles it is reached from
+	// a loop of links that weigh 0 is cut where the search first comes back around it
+	position := make([]int, len(n.turtles))
+	visited := make([]bool, len(n.turtles))
+	finished := []int{}
+	var visit func(turtle int)
+	visit = func(turtle int) {
+		visited[turtle] = true
+		for _, e := range n.out[turtle] {
+			if !visited[e.to] && distance[turtle]+e.weight == distance[e.to] && distance[e.to] == distance[turtle] {
+				visit(e.to)
+			}
+		}
+		finished = append(finished, turtle)
+	}
+	for start := 0; start < len(reached); {
+		end := start
+		for end < len(reached) && distance[reached[end]] == distance[reached[start]] {
+			end++
+		}
+		finished = finished[:0]
+		for _, turtle := range reached[start:end] {
+			if !visited[turtle] {
+				visit(turtle)
+			}
+		}
+		for i := len(finished) - 1; i >= 0; i-- {
+			position[finished[i]] = len(order)
+			order = append(order, finished[i])
+		}
+		start = end
+	}
+
+	for _, current := range order {
+		for _, e := range n.out[current] {
+			if distance[current]+e.weight == distance[e.to] && position[e.to] > position[current] {
+				paths[e.to] += paths[current]
+				previous[e.to] = append(previous[e.to], current)
+			}
+		}
+	}
+
+	return order
+}
+
+// returns the closeness centrality of every turtle
+// the closeness of a turtle is one over the average distance to the other turtles it can reach
+// turtles that can't reach any other turtle have a closeness of 0
+func (n *Network) ClosenessCentrality() map[*model.Turtle]float64 {
+	closeness := make([]float64, len(n.turtles))
+
+	for source := range n.turtles {
+		result := n.search(source)
+
+		total := 0.0
+		reached := 0
+		for i, distance := range result.distance {
+			if i == source || math.IsInf(distance, 1) {
+				continue
+			}
+			total += distance
+			reached++
+		}
+
+		if total > 0 {
+			closeness[source] = float64(reached) / total
+		}
+	}
+
+	return n.toMap(closeness)
+}
+
+// returns the eigenvector centrality of every turtle, scaled so the most central turtle has a centrality of 1
+// a turtle is central if the turtles linking to it are central
+// links are followed in their direction and weighted if the network has a weight property
+func (n *Network) EigenvectorCentrality() map[*model.Turtle]float64 {
+	size := len(n.turtles)
+	if size == 0 {
+		return map[*model.Turtle]float64{}
+	}
+
+	centrality := make([]float64, size)
+	for i := range centrality {
+		centrality[i] = 1
+	}
+	next := make([]float64, size)
+
+	for iteration := 0; iteration < centralityMaxIterations; iteration++ {
+		// adding the current value to itself keeps the iteration from bouncing back and forth on bipartite networks
+		max := 0.0
+		for v := range next {
+			next[v] = centrality[v]
+			for _, e := range n.in[v] {
+				next[v] += centrality[e.to] * e.weight
+			}
+			if next[v] > max {
+				max = next[v]
+			}
+		}
+
+		change := 0.0
+		for v := range next {
+			if max > 0 {
+				next[v] /= max
+			}
+			change = math.Max(change, math.Abs(next[v]-centrality[v]))
+		}
+
+		centrality, next = next, centrality
+		if change < centralityTolerance {
+			break
+		}
+	}
+
+	return n.toMap(centrality)
+}
+
+// returns the page rank of every turtle, the page ranks add up to 1
+// damping is the chance of following a link instead of jumping to a random turtle, 0.85 is common
+// links are followed in their direction and weighted if the network has a weight property
+func (n *Network) PageRank(damping float64) map[*model.Turtle]float64 {
+	size := len(n.turtles)
+	if size == 0 {
+		return map[*model.Turtle]float64{}
+	}
+
+	outWeight := make([]float64, size)
+	for u := range n.turtles {
+		for _, e := range n.out[u] {
+			outWeight[u] += e.weight
+		}
+	}
+
+	rank := make([]float64, size)
+	for i := range rank {
+		rank[i] = 1 / float64(size)
+	}
+	next := make([]float64, size)
+
+	for iteration := 0; iteration < centralityMaxIterations; iteration++ {
+		// turtles without links out share their rank with everyone
+		dangling := 0.0
+		for u, weight := range outWeight {
+			if weight == 0 {
+				dangling += rank[u]
+			}
+		}
+
+		for v := range next {
+			next[v] = (1-damping)/float64(size) + damping*dangling/float64(size)
+		}
+		for u := range n.turtles {
+			if outWeight[u] == 0 {
+				continue
+			}
+			for _, e := range n.out[u] {
+				next[e.to] += damping * rank[u] * e.weight / outWeight[u]
+			}
+		}
+
+		change := 0.0
+		for v := range next {
+			change = math.Max(change, math.Abs(next[v]-rank[v]))
+		}
+
+		rank, next = next, rank
+		if change < centralityTolerance {
+			break
+		}
+	}
+
+	return n.toMap(rank)
+}
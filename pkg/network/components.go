@@ -0,0 +1,123 @@
+package network
+
+import (
+	"sort"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// returns the groups of turtles that are connected to each other, ignoring the direction of links
+// the components are ordered by their first turtle in the network
+func (n *Network) Components() []*model.TurtleAgentSet {
+	seen := make([]bool, len(n.turtles))
+	components := []*model.TurtleAgentSet{}
+
+	for start := range n.turtles {
+		if seen[start] {
+			continue
+		}
+
+		seen[start] = true
+		members := []int{start}
+		for i := 0; i < len(members); i++ {
+			current := members[i]
+			for _, edges := range [][]edge{n.out[current], n.in[current]} {
+				for _, e := range edges {
+					if !seen[e.to] {
+						seen[e.to] = true
+						members = append(members, e.to)
+					}
+				}
+			}
+		}
+
+		sort.Ints(members)
+		components = append(components, model.NewTurtleAgentSet(n.toTurtles(members)))
+	}
+
+	return components
+}
+
+// returns the groups of turtles where every turtle can reach every other turtle following the direction of links
+// for networks without directed links this is the same as Components
+func (n *Network) StronglyConnectedComponents() []*model.TurtleAgentSet {
+	// tarjan's algorithm, done with an explicit stack so large networks don't need deep recursion
+	index := make([]int, len(n.turtles))
+	lowLink := make([]int, len(n.turtles))
+	onStack := make([]bool, len(n.turtles))
+	for i := range index {
+		index[i] = -1
+	}
+
+	stack := []int{}
+	nextIndex := 0
+	components := []*model.TurtleAgentSet{}
+
+	type frame struct {
+		turtle int
+		edge   int // next edge of the turtle to look at
+	}
+
+	for start := range n.turtles {
+		if index[start] != -1 {
+			continue
+		}
+
+		callStack := []frame{{turtle: start}}
+		index[start] = nextIndex
+		lowLink[start] = nextIndex
+		nextIndex++
+		stack = append(stack, start)
+		onStack[start] = true
+
+		for len(callStack) > 0 {
+			top := &callStack[len(callStack)-1]
+			current := top.turtle
+
+			if top.edge < len(n.out[current]) {
+				next := n.out[current][top.edge].to
+				top.edge++
+
+				if index[next] == -1 {
+					index[next] = nextIndex
+					lowLink[next] = nextIndex
+					nextIndex++
+					stack = append(stack, next)
+					onStack[next] = true
+					callStack = append(callStack, frame{turtle: next})
+				} else if onStack[next] && index[next] < lowLink[current] {
+					lowLink[current] = index[next]
+				}
+				continue
+			}
+
+			// every edge has been looked at so the turtle is done
+			callStack = callStack[:len(callStack)-1]
+			if len(callStack) > 0 {
+				parent := callStack[len(callStack)-1].turtle
+				if lowLink[current] < lowLink[parent] {
+					lowLink[parent] = lowLink[current]
+				}
+			}
+
+			if lowLink[current] == index[current] {
+				members := []int{}
+				for {
+					last := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[last] = false
+					members = append(members, last)
+					if last == current {
+						break
+					}
+				}
+
+				// keep the turtles in the order they are in the network
+				sort.Ints(members)
+				components = append(components, model.NewTurtleAgentSet(n.toTurtles(members)))
+			}
+		}
+	}
+
+	return components
+}
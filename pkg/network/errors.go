@@ -0,0 +1,12 @@
+package network
+
+import "fmt"
+
+// Errors
+var (
+	ErrTurtleNotInNetwork   = fmt.Errorf("turtle is not in the network")
+	ErrNoPath               = fmt.Errorf("no path between the turtles")
+	ErrGeneratorAmount      = fmt.Errorf("amount of turtles is not valid for the generator")
+	ErrGeneratorProbability = fmt.Errorf("probability must be between 0 and 1")
+	ErrGeneratorNeighbors   = fmt.Errorf("amount of neighbors is not valid for the amount of turtles")
+)
@@ -0,0 +1,273 @@
+package network
+
+import (
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// GeneratorSettings holds the settings for the turtles and links a generator creates
+type GeneratorSettings struct {
+	TurtleBreed     *model.TurtleBreed    // breed of the new turtles, nil means the general breed
+	LinkBreed       *model.LinkBreed      // breed of the new links, nil means general undirected links. See the generators for the way directed links go
+	TurtleOperation model.TurtleOperation // run on each new turtle after it is created
+	LinkOperation   model.LinkOperation   // run on each new link after it is created
+}
+
+// creates the turtles for a generator
+func createTurtles(m *model.Model, amount int, settings GeneratorSettings) ([]*model.Turtle, error) {
+	var turtles *model.TurtleAgentSet
+	var err error
+	if settings.TurtleBreed != nil {
+		turtles, err = settings.TurtleBreed.CreateAgents(amount, settings.TurtleOperation)
+	} else {
+		turtles, err = m.CreateTurtles(amount, settings.TurtleOperation)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return turtles.List(), nil
+}
+
+// creates a link of the generator's breed between two turtles
+func createLink(from *model.Turtle, to *model.Turtle, settings GeneratorSettings) {
+	if settings.LinkBreed != nil && settings.LinkBreed.Directed() {
+		from.CreateLinkToTurtle(settings.LinkBreed, to, settings.LinkOperation)
+		return
+	}
+	from.CreateLinkWithTurtle(settings.LinkBreed, to, settings.LinkOperation)
+}
+
+// creates the turtles then links them by the pairs of indices, directed links go from the first of the pair to the second
+func generate(m *model.Model, amount int, pairs [][2]int, settings GeneratorSettings) (*model.TurtleAgentSet, error) {
+	turtles, err := createTurtles(m, amount, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pair := range pairs {
+		createLink(turtles[pair[0]], turtles[pair[1]], settings)
+	}
+
+	return model.NewTurtleAgentSet(turtles), nil
+}
+
+// puts the lower index first so each pair of turtles has one key
+func pairKey(a int, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// ErdosRenyi creates the amount of turtles and links each pair of them with the probability
+// directed links go from the earlier turtle to the later one
+// returns the new turtles
+func ErdosRenyi(m *model.Model, amount int, probability float64, settings GeneratorSettings) (*model.TurtleAgentSet, error) {
+	if amount < 0 {
+		return nil, ErrGeneratorAmount
+	}
+	if probability < 0 || probability > 1 {
+		return nil, ErrGeneratorProbability
+	}
+
+	pairs := [][2]int{}
+	for i := 0; i < amount; i++ {
+		for j := i + 1; j < amount; j++ {
+			if m.RandomFloat(1) < probability {
+				pairs = append(pairs, [2]int{i, j})
+			}
+		}
+	}
+
+	return generate(m, amount, pairs, settings)
+}
+
+// WattsStrogatz creates a small world network
+// the amount of turtles are put in a ring with each linked to its neighbors closer than the neighbors amount on each side
+// then each link is rewired to a random turtle with the probability
+// directed links go forward around the ring, a rewired link still goes from the turtle it started at
+// returns the new turtles in the order of the ring
+func WattsStrogatz(m *model.Model, amount int, neighbors int, probability float64, settings GeneratorSettings) (*model.TurtleAgentSet, error) {
+	if amount < 0 {
+		return nil, ErrGeneratorAmount
+	}
+	if neighbors < 0 || (amount > 0 && 2*neighbors >= amount) {
+		return nil, ErrGeneratorNeighbors
+	}
+	if probability < 0 || probability > 1 {
+		return nil, ErrGeneratorProbability
+	}
+
+	linked := map[[2]int]bool{}
+	pairs := [][2]int{}
+	for i := 0; i < amount; i++ {
+		for j := 1; j <= neighbors; j++ {
+			pair := [2]int{i, (i + j) % amount}
+			linked[pairKey(pair[0], pair[1])] = true
+			pairs = append(pairs, pair)
+		}
+	}
+
+	for p, pair := range pairs {
+		if m.RandomFloat(1) >= probability {
+			continue
+		}
+
+		// a turtle linked to every other turtle has nowhere to rewire to
+		degree := 0
+		for other := 0; other < amount; other++ {
+			if other != pair[0] && linked[pairKey(pair[0], other)] {
+				degree++
+			}
+		}
+		if degree >= amount-1 {
+			continue
+		}
+
+		target := m.RandomAmount(amount)
+		for target == pair[0] || linked[pairKey(pair[0], target)] {
+			target = m.RandomAmount(amount)
+		}
+
+		delete(linked, pairKey(pair[0], pair[1]))
+		linked[pairKey(pair[0], target)] = true
+		pairs[p] = [2]int{pair[0], target}
+	}
+
+	return generate(m, amount, pairs, settings)
+}
+
+// BarabasiAlbert creates a scale free network by preferential attachment
+// starts with links+1 turtles all linked to each other, then each new turtle links to that many existing turtles
+// picked with a chance proportional to how many links they have
+// directed links go from the earlier turtle to the later one
+// returns the new turtles in the order they were added
+func BarabasiAlbert(m *model.Model, amount int, links int, settings GeneratorSettings) (*model.TurtleAgentSet, error) {
+	if links < 1 {
+		return nil, ErrGeneratorNeighbors
+	}
+	if amount < links+1 {
+		return nil, ErrGeneratorAmount
+	}
+
+	pairs := [][2]int{}
+
+	// each turtle is in the list once for every link it has, so picking from it favors turtles with more links
+	ends := []int{}
+	for i := 0; i <= links; i++ {
+		for j := i + 1; j <= links; j++ {
+			pairs = append(pairs, [2]int{i, j})
+			ends = append(ends, i, j)
+		}
+	}
+
+	for i := links + 1; i < amount; i++ {
+		picked := map[int]bool{}
+		targets := []int{}
+		for len(targets) < links {
+			target := ends[m.RandomAmount(len(ends))]
+			if picked[target] {
+				continue
+			}
+			picked[target] = true
+			targets = append(targets, target)
+		}
+
+		for _, target := range targets {
+			pairs = append(pairs, [2]int{target, i})
+			ends = append(ends, target, i)
+		}
+	}
+
+	return generate(m, amount, pairs, settings)
+}
+
+// Lattice2D creates a grid of turtles with each linked to the turtles next to it up, down, left and right
+// if wrap is true the edges of the grid are linked to the opposite edges
+// directed links go from the earlier turtle to the later one
+// returns the new turtles row by row
+func Lattice2D(m *model.Model, rows int, columns int, wrap bool, settings GeneratorSettings) (*model.TurtleAgentSet, error) {
+	if rows < 0 || columns < 0 {
+		return nil, ErrGeneratorAmount
+	}
+
+	linked := map[[2]int]bool{}
+	pairs := [][2]int{}
+	add := func(a int, b int) {
+		if a == b || linked[pairKey(a, b)] {
+			return
+		}
+		linked[pairKey(a, b)] = true
+		pairs = append(pairs, pairKey(a, b))
+	}
+
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			current := row*columns + column
+			if column+1 < columns {
+				add(current, current+1)
+			} else if wrap {
+				add(current, row*columns)
+			}
+			if row+1 < rows {
+				add(current, current+columns)
+			} else if wrap {
+				add(current, column)
+			}
+		}
+	}
+
+	return generate(m, rows*columns, pairs, settings)
+}
+
+// Ring creates the amount of turtles with each linked to the next, and the last linked to the first
+// directed links go forward around the ring so they form a cycle
+// returns the new turtles in the order of the ring
+func Ring(m *model.Model, amount int, settings GeneratorSettings) (*model.TurtleAgentSet, error) {
+	if amount < 0 {
+		return nil, ErrGeneratorAmount
+	}
+
+	pairs := [][2]int{}
+	for i := 0; i+1 < amount; i++ {
+		pairs = append(pairs, [2]int{i, i + 1})
+	}
+	if amount > 2 {
+		pairs = append(pairs, [2]int{amount - 1, 0})
+	}
+
+	return generate(m, amount, pairs, settings)
+}
+
+// Star creates the amount of turtles with the first linked to all of the others
+// directed links go out from the center
+// returns the new turtles with the center first
+func Star(m *model.Model, amount int, settings GeneratorSettings) (*model.TurtleAgentSet, error) {
+	if amount < 0 {
+		return nil, ErrGeneratorAmount
+	}
+
+	pairs := [][2]int{}
+	for i := 1; i < amount; i++ {
+		pairs = append(pairs, [2]int{0, i})
+	}
+
+	return generate(m, amount, pairs, settings)
+}
+
+// Complete creates the amount of turtles with every turtle linked to every other turtle
+// directed links go from the earlier turtle to the later one
+// returns the new turtles
+func Complete(m *model.Model, amount int, settings GeneratorSettings) (*model.TurtleAgentSet, error) {
+	if amount < 0 {
+		return nil, ErrGeneratorAmount
+	}
+
+	pairs := [][2]int{}
+	for i := 0; i < amount; i++ {
+		for j := i + 1; j < amount; j++ {
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+
+	return generate(m, amount, pairs, settings)
+}
@@ -0,0 +1,419 @@
+package network
+
+import (
+	"math"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+const (
+	layoutTolerance   = 1e-3 // a layout has settled once no turtle moves further than this in a step
+	layoutMinDistance = 1e-2 // turtles closer than this are treated as this far apart so forces stay finite
+)
+
+// Layout moves the turtles of a network a little each step so it can be run every tick and watched
+type Layout interface {
+	// moves the turtles one step, returns true once the layout has settled
+	Step() bool
+}
+
+// RunLayout steps the layout until it settles or the max steps are reached
+// returns the number of steps taken
+func RunLayout(layout Layout, maxSteps int) int {
+	for step := 1; step <= maxSteps; step++ {
+		if layout.Step() {
+			return step
+		}
+	}
+	return maxSteps
+}
+
+// position of a turtle while a layout is working on it
+type point struct {
+	x, y, z float64
+}
+
+func (p point) sub(o point) point {
+	return point{p.x - o.x, p.y - o.y, p.z - o.z}
+}
+
+func (p point) add(o point) point {
+	return point{p.x + o.x, p.y + o.y, p.z + o.z}
+}
+
+func (p point) scale(s float64) point {
+	return point{p.x * s, p.y * s, p.z * s}
+}
+
+func (p point) length() float64 {
+	return math.Sqrt(p.x*p.x + p.y*p.y + p.z*p.z)
+}
+
+// state shared by all the layouts
+type layoutBase struct {
+	model   *model.Model
+	network *Network
+	pinned  []bool
+	use3D   bool
+}
+
+func newLayoutBase(m *model.Model, n *Network, pinned *model.TurtleAgentSet, use3D bool) layoutBase {
+	base := layoutBase{
+		model:   m,
+		network: n,
+		pinned:  make([]bool, len(n.turtles)),
+		use3D:   use3D && m.Is3D(),
+	}
+	if pinned != nil {
+		for i, turtle := range n.turtles {
+			base.pinned[i] = pinned.Contains(turtle)
+		}
+	}
+	return base
+}
+
+// reads where the turtles are now so turtles moved between steps are picked up
+func (l *layoutBase) positions() []point {
+	positions := make([]point, len(l.network.turtles))
+	for i, turtle := range l.network.turtles {
+		positions[i] = point{turtle.XCor(), turtle.YCor(), 0}
+		if l.use3D {
+			positions[i].z = turtle.ZCor()
+		}
+	}
+	return positions
+}
+
+// keeps the point inside the edges of the world, the max edges belong to the other side of the world so the point stays just short of them
+func (l *layoutBase) clamp(p point) point {
+	m := l.model
+	p.x = math.Max(m.MinXCor(), math.Min(math.Nextafter(m.MaxXCor(), m.MinXCor()), p.x))
+	p.y = math.Max(m.MinYCor(), math.Min(math.Nextafter(m.MaxYCor(), m.MinYCor()), p.y))
+	if l.use3D {
+		p.z = math.Max(m.MinZCor(), math.Min(math.Nextafter(m.MaxZCor(), m.MinZCor()), p.z))
+	}
+	return p
+}
+
+// moves the turtle unless it is pinned, returns how far it moved
+func (l *layoutBase) moveTo(i int, from point, to point) float64 {
+	if l.pinned[i] {
+		return 0
+	}
+
+	to = l.clamp(to)
+	turtle := l.network.turtles[i]
+	if l.use3D {
+		turtle.SetXYZ(to.x, to.y, to.z)
+	} else {
+		turtle.SetXY(to.x, to.y)
+	}
+	return to.sub(from).length()
+}
+
+// returns the turtles next to each turtle ignoring the direction of links, each neighbor listed once
+func (n *Network) undirectedNeighbors() [][]int {
+	neighbors := make([][]int, len(n.turtles))
+	for i := range n.turtles {
+		seen := map[int]bool{i: true}
+		for _, edges := range [][]edge{n.out[i], n.in[i]} {
+			for _, e := range edges {
+				if !seen[e.to] {
+					seen[e.to] = true
+					neighbors[i] = append(neighbors[i], e.to)
+				}
+			}
+		}
+	}
+	return neighbors
+}
+
+// SpringLayoutSettings holds the settings for a spring layout
+type SpringLayoutSettings struct {
+	Pinned       *model.TurtleAgentSet // turtles that are never moved
+	Use3D        bool                  // if true and the world is 3D the turtles are also laid out along z
+	SpringLength float64               // distance linked turtles settle at, 0 picks one from the size of the world and the number of turtles
+	Temperature  float64               // furthest a turtle can move in the first step, 0 means a tenth of the world width
+	Cooling      float64               // the temperature is multiplied by this after each step, 0 means .95
+}
+
+// SpringLayout is a Fruchterman-Reingold force directed layout
+// every pair of turtles pushes apart and linked turtles pull together, with moves getting smaller each step
+type SpringLayout struct {
+	layoutBase
+	neighbors    [][]int
+	springLength float64
+	temperature  float64
+	cooling      float64
+}
+
+// NewSpringLayout creates a spring layout of the turtles in the network
+func NewSpringLayout(m *model.Model, n *Network, settings SpringLayoutSettings) *SpringLayout {
+	l := &SpringLayout{
+		layoutBase:   newLayoutBase(m, n, settings.Pinned, settings.Use3D),
+		neighbors:    n.undirectedNeighbors(),
+		springLength: settings.SpringLength,
+		temperature:  settings.Temperature,
+		cooling:      settings.Cooling,
+	}
+
+	width := float64(m.MaxPxCor() - m.MinPxCor())
+	if l.springLength <= 0 && len(n.turtles) > 0 {
+		volume := width * float64(m.MaxPyCor()-m.MinPyCor())
+		power := .5
+		if l.use3D {
+			volume *= float64(m.MaxPzCor() - m.MinPzCor())
+			power = 1.0 / 3
+		}
+		l.springLength = math.Pow(volume/float64(len(n.turtles)), power)
+	}
+	if l.springLength <= 0 {
+		l.springLength = 1
+	}
+	if l.temperature <= 0 {
+		l.temperature = width / 10
+	}
+	if l.cooling <= 0 || l.cooling >= 1 {
+		l.cooling = .95
+	}
+
+	return l
+}
+
+// Step moves every turtle along the forces on it by at most the current temperature
+func (l *SpringLayout) Step() bool {
+	positions := l.positions()
+	size := len(positions)
+	displacement := make([]point, size)
+	k := l.springLength
+
+	for i := 0; i < size; i++ {
+		for j := i + 1; j < size; j++ {
+			delta := positions[i].sub(positions[j])
+			distance := delta.length()
+			if distance == 0 {
+				// turtles on top of each other are pushed apart in a direction picked from their indices
+				angle := float64(i+j) * 2.399963 // golden angle
+				delta = point{math.Cos(angle), math.Sin(angle), 0}
+				distance = 1
+			}
+			force := k * k / math.Max(distance, layoutMinDistance)
+			push := delta.scale(force / distance)
+			displacement[i] = displacement[i].add(push)
+			displacement[j] = displacement[j].sub(push)
+		}
+	}
+
+	for i, neighbors := range l.neighbors {
+		for _, j := range neighbors {
+			// each link is seen from both ends so only pull from one of them
+			if j < i {
+				continue
+			}
+			delta := positions[i].sub(positions[j])
+			distance := delta.length()
+			if distance == 0 {
+				continue
+			}
+			pull := delta.scale(distance / k)
+			displacement[i] = displacement[i].sub(pull)
+			displacement[j] = displacement[j].add(pull)
+		}
+	}
+
+	moved := 0.0
+	for i, d := range displacement {
+		length := d.length()
+		if length == 0 {
+			continue
+		}
+		step := d.scale(math.Min(length, l.temperature) / length)
+		moved = math.Max(moved, l.moveTo(i, positions[i], positions[i].add(step)))
+	}
+
+	l.temperature *= l.cooling
+	return moved < layoutTolerance || l.temperature < layoutTolerance
+}
+
+// RadialLayoutSettings holds the settings for a radial layout
+type RadialLayoutSettings struct {
+	Pinned      *model.TurtleAgentSet // turtles that are never moved
+	Root        *model.Turtle         // turtle at the center, nil means the first turtle in the network
+	CenterX     float64               // where the root is placed
+	CenterY     float64
+	CenterZ     float64 // only used with Use3D
+	RingSpacing float64 // distance between the rings of turtles, 0 fits the furthest ring in the world
+	Speed       float64 // part of the way to its spot each turtle moves in a step, 0 or 1 moves them there in one step
+	Use3D       bool    // if true and the world is 3D the rings lie flat at CenterZ, otherwise the turtles keep their z
+}
+
+// RadialLayout puts the root at the center and the other turtles in rings around it by how many links away they are
+// each turtle gets a slice of its parent's angle sized by how many turtles are below it
+// turtles that can't be reached from the root are left where they are
+type RadialLayout struct {
+	layoutBase
+	targets []point
+	placed  []bool
+	speed   float64
+}
+
+// NewRadialLayout creates a radial layout of the turtles in the network
+func NewRadialLayout(m *model.Model, n *Network, settings RadialLayoutSettings) *RadialLayout {
+	l := &RadialLayout{
+		layoutBase: newLayoutBase(m, n, settings.Pinned, settings.Use3D),
+		targets:    make([]point, len(n.turtles)),
+		placed:     make([]bool, len(n.turtles)),
+		speed:      settings.Speed,
+	}
+	if l.speed <= 0 || l.speed > 1 {
+		l.speed = 1
+	}
+	if len(n.turtles) == 0 {
+		return l
+	}
+
+	root := 0
+	if settings.Root != nil {
+		index, ok := n.index[settings.Root]
+		if !ok {
+			return l
+		}
+		root = index
+	}
+
+	// breadth first tree from the root
+	neighbors := n.undirectedNeighbors()
+	depth := make([]int, len(n.turtles))
+	children := make([][]int, len(n.turtles))
+	order := []int{root}
+	l.placed[root] = true
+	for i := 0; i < len(order); i++ {
+		current := order[i]
+		for _, next := range neighbors[current] {
+			if !l.placed[next] {
+				l.placed[next] = true
+				depth[next] = depth[current] + 1
+				children[current] = append(children[current], next)
+				order = append(order, next)
+			}
+		}
+	}
+
+	// leaves under each turtle, counted from the deepest turtles back up
+	weight := make([]float64, len(n.turtles))
+	for i := len(order) - 1; i >= 0; i-- {
+		current := order[i]
+		if len(children[current]) == 0 {
+			weight[current] = 1
+		}
+		for _, child := range children[current] {
+			weight[current] += weight[child]
+		}
+	}
+
+	spacing := settings.RingSpacing
+	maxDepth := depth[order[len(order)-1]]
+	if spacing <= 0 && maxDepth > 0 {
+		room := math.Min(
+			math.Min(settings.CenterX-float64(m.MinPxCor()), float64(m.MaxPxCor())-settings.CenterX),
+			math.Min(settings.CenterY-float64(m.MinPyCor()), float64(m.MaxPyCor())-settings.CenterY),
+		)
+		spacing = math.Max(room, 0) / float64(maxDepth)
+	}
+
+	// each turtle's children split its slice of the circle
+	start := make([]float64, len(n.turtles))
+	sweep := make([]float64, len(n.turtles))
+	sweep[root] = 2 * math.Pi
+	for _, current := range order {
+		middle := start[current] + sweep[current]/2
+		radius := float64(depth[current]) * spacing
+		l.targets[current] = point{settings.CenterX + radius*math.Cos(middle), settings.CenterY + radius*math.Sin(middle), settings.CenterZ}
+
+		angle := start[current]
+		for _, child := range children[current] {
+			start[child] = angle
+			sweep[child] = sweep[current] * weight[child] / weight[current]
+			angle += sweep[child]
+		}
+	}
+
+	return l
+}
+
+// Step moves every placed turtle toward its spot
+func (l *RadialLayout) Step() bool {
+	positions := l.positions()
+	moved := 0.0
+	for i, position := range positions {
+		if !l.placed[i] {
+			continue
+		}
+		to := l.targets[i]
+		if to.sub(position).length()*(1-l.speed) >= layoutTolerance {
+			to = position.add(to.sub(position).scale(l.speed))
+		}
+		moved = math.Max(moved, l.moveTo(i, position, to))
+	}
+	return moved < layoutTolerance
+}
+
+// TutteLayoutSettings holds the settings for a tutte layout
+type TutteLayoutSettings struct {
+	Pinned        *model.TurtleAgentSet // turtles that are never moved by the steps, the others settle between them
+	Use3D         bool                  // if true and the world is 3D the turtles are also laid out along z
+	ArrangePinned bool                  // if true the pinned turtles are first put evenly on a circle of Radius around the center of the world
+	Radius        float64
+}
+
+// TutteLayout moves every turtle that isn't pinned to the average position of the turtles linked to it
+// the pinned turtles hold the network open, without any the turtles pull together into a point
+type TutteLayout struct {
+	layoutBase
+	neighbors [][]int
+}
+
+// NewTutteLayout creates a tutte layout of the turtles in the network
+func NewTutteLayout(m *model.Model, n *Network, settings TutteLayoutSettings) *TutteLayout {
+	l := &TutteLayout{
+		layoutBase: newLayoutBase(m, n, settings.Pinned, settings.Use3D),
+		neighbors:  n.undirectedNeighbors(),
+	}
+
+	if settings.ArrangePinned {
+		pinned := []*model.Turtle{}
+		for i, turtle := range n.turtles {
+			if l.pinned[i] {
+				pinned = append(pinned, turtle)
+			}
+		}
+		centerX := float64(m.MinPxCor()+m.MaxPxCor()) / 2
+		centerY := float64(m.MinPyCor()+m.MaxPyCor()) / 2
+		for i, turtle := range pinned {
+			angle := 2 * math.Pi * float64(i) / float64(len(pinned))
+			to := l.clamp(point{centerX + settings.Radius*math.Cos(angle), centerY + settings.Radius*math.Sin(angle), 0})
+			turtle.SetXY(to.x, to.y)
+		}
+	}
+
+	return l
+}
+
+// Step moves every turtle that isn't pinned to the average of its neighbors
+// all turtles move from where they were at the start of the step so the order of the turtles doesn't matter
+func (l *TutteLayout) Step() bool {
+	positions := l.positions()
+	moved := 0.0
+	for i, neighbors := range l.neighbors {
+		if len(neighbors) == 0 {
+			continue
+		}
+		average := point{}
+		for _, j := range neighbors {
+			average = average.add(positions[j])
+		}
+		average = average.scale(1 / float64(len(neighbors)))
+		moved = math.Max(moved, l.moveTo(i, positions[i], average))
+	}
+	return moved < layoutTolerance
+}
@@ -0,0 +1,151 @@
+// Package network provides graph algorithms over the turtles and links of a model
+// similar to the nw extension in NetLogo
+//
+// a Network is a snapshot of the turtles and links when it is built
+// if turtles or links are added or removed after, build a new Network
+package network
+
+import (
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// NetworkSettings holds the settings for building a network
+type NetworkSettings struct {
+	Turtles        *model.TurtleAgentSet // turtles in the network, nil means every turtle in the model
+	LinkBreeds     []*model.LinkBreed    // link breeds that make up the network, empty means every link in the model
+	WeightProperty string                // link property holding the weight of each link, empty means every link weighs 1. Negative weights count as 0
+	Undirected     bool                  // if true directed links can be followed both ways
+}
+
+// Network is the graph formed by a set of turtles and the links between them
+type Network struct {
+	turtles []*model.Turtle
+	index   map[*model.Turtle]int
+
+	out [][]edge // edges that can be followed out of each turtle
+	in  [][]edge // edges that can be followed into each turtle
+
+	directed bool // true if any edge can only be followed one way
+	weighted bool
+}
+
+// an edge of the network from one turtle to another
+type edge struct {
+	to     int
+	link   *model.Link
+	weight float64
+}
+
+// NewNetwork builds a network out of the turtles and links of the model
+// links whose ends are not both in the network are left out
+// links whose weight property is missing or not a number weigh 1
+// links with a negative weight weigh 0 since the shortest paths and the centralities that use them don't work with negative weights
+func NewNetwork(m *model.Model, settings NetworkSettings) *Network {
+	turtles := settings.Turtles
+	if turtles == nil {
+		turtles = m.Turtles()
+	}
+
+	n := &Network{
+		turtles:  turtles.List(),
+		index:    make(map[*model.Turtle]int),
+		weighted: settings.WeightProperty != "",
+	}
+	for i, turtle := range n.turtles {
+		n.index[turtle] = i
+	}
+	n.out = make([][]edge, len(n.turtles))
+	n.in = make([][]edge, len(n.turtles))
+
+	inBreeds := func(link *model.Link) bool {
+		if len(settings.LinkBreeds) == 0 {
+			return true
+		}
+		for _, breed := range settings.LinkBreeds {
+			if breed != nil && breed.Name() == link.BreedName() && breed.Directed() == link.Directed() {
+				return true
+			}
+		}
+		return false
+	}
+
+	m.Links().Ask(func(link *model.Link) {
+		if !inBreeds(link) {
+			return
+		}
+
+		from, ok1 := n.index[link.End1()]
+		to, ok2 := n.index[link.End2()]
+		if !ok1 || !ok2 {
+			return
+		}
+
+		weight := 1.0
+		if settings.WeightProperty != "" {
+			if value, err := link.GetPropF(settings.WeightProperty); err == nil {
+				weight = max(value, 0)
+			}
+		}
+
+		n.out[from] = append(n.out[from], edge{to: to, link: link, weight: weight})
+		n.in[to] = append(n.in[to], edge{to: from, link: link, weight: weight})
+
+		if link.Directed() && !settings.Undirected {
+			n.directed = true
+			return
+		}
+
+		if from != to {
+			n.out[to] = append(n.out[to], edge{to: from, link: link, weight: weight})
+			n.in[from] = append(n.in[from], edge{to: to, link: link, weight: weight})
+		}
+	})
+
+	return n
+}
+
+// returns the turtles in the network
+func (n *Network) Turtles() *model.TurtleAgentSet {
+	return model.NewTurtleAgentSet(n.turtles)
+}
+
+// returns true if the network has links that can only be followed one way
+func (n *Network) Directed() bool {
+	return n.directed
+}
+
+// returns the number of turtles in the network
+func (n *Network) Count() int {
+	return len(n.turtles)
+}
+
+// returns the turtles that can be reached from the turtle by following one link
+func (n *Network) Neighbors(turtle *model.Turtle) *model.TurtleAgentSet {
+	neighbors := model.NewTurtleAgentSet(nil)
+	i, ok := n.index[turtle]
+	if !ok {
+		return neighbors
+	}
+	for _, e := range n.out[i] {
+		neighbors.Add(n.turtles[e.to])
+	}
+	return neighbors
+}
+
+// converts a list of turtle indices to turtles
+func (n *Network) toTurtles(indices []int) []*model.Turtle {
+	turtles := make([]*model.Turtle, len(indices))
+	for i, index := range indices {
+		turtles[i] = n.turtles[index]
+	}
+	return turtles
+}
+
+// converts values indexed by turtle index to a map keyed by turtle
+func (n *Network) toMap(values []float64) map[*model.Turtle]float64 {
+	result := make(map[*model.Turtle]float64, len(values))
+	for i, value := range values {
+		result[n.turtles[i]] = value
+	}
+	return result
+}
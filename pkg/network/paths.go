@@ -0,0 +1,267 @@
+package network
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// results of a single source search, indexed by turtle index
+type searchResult struct {
+	distance []float64 // distance from the source, +Inf if it can't be reached
+	previous []int     // turtle each turtle was reached from, -1 for the source and unreached turtles
+	link     []*model.Link
+}
+
+func newSearchResult(size int) *searchResult {
+	result := &searchResult{
+		distance: make([]float64, size),
+		previous: make([]int, size),
+		link:     make([]*model.Link, size),
+	}
+	for i := range result.distance {
+		result.distance[i] = math.Inf(1)
+		result.previous[i] = -1
+	}
+	return result
+}
+
+// breadth first search counting each link as 1
+func (n *Network) searchUnweighted(source int) *searchResult {
+	result := newSearchResult(len(n.turtles))
+	result.distance[source] = 0
+
+	queue := []int{source}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, e := range n.out[current] {
+			if !math.IsInf(result.distance[e.to], 1) {
+				continue
+			}
+			result.distance[e.to] = result.distance[current] + 1
+			result.previous[e.to] = current
+			result.link[e.to] = e.link
+			queue = append(queue, e.to)
+		}
+	}
+
+	return result
+}
+
+// item in the queue of turtles waiting to be explored
+type searchNode struct {
+	turtle   int
+	distance float64
+	sequence int // order the node was added in, keeps the search deterministic when distances are equal
+}
+
+type searchQueue []searchNode
+
+func (q searchQueue) Len() int {
+	return len(q)
+}
+
+func (q searchQueue) Less(i, j int) bool {
+	if q[i].distance == q[j].distance {
+		return q[i].sequence < q[j].sequence
+	}
+	return q[i].distance < q[j].distance
+}
+
+func (q searchQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *searchQueue) Push(x interface{}) {
+	*q = append(*q, x.(searchNode))
+}
+
+func (q *searchQueue) Pop() interface{} {
+	old := *q
+	last := len(old) - 1
+	node := old[last]
+	*q = old[:last]
+	return node
+}
+
+// dijkstra's algorithm using the link weights
+func (n *Network) searchWeighted(source int) *searchResult {
+	result := newSearchResult(len(n.turtles))
+	result.distance[source] = 0
+
+	done := make([]bool, len(n.turtles))
+	queue := &searchQueue{}
+	sequence := 0
+	heap.Push(queue, searchNode{turtle: source})
+
+	for queue.Len() > 0 {
+		node := heap.Pop(queue).(searchNode)
+		if done[node.turtle] {
+			continue
+		}
+		done[node.turtle] = true
+
+		for _, e := range n.out[node.turtle] {
+			distance := node.distance + e.weight
+			if done[e.to] || distance >= result.distance[e.to] {
+				continue
+			}
+			result.distance[e.to] = distance
+			result.previous[e.to] = node.turtle
+			result.link[e.to] = e.link
+			sequence++
+			heap.Push(queue, searchNode{turtle: e.to, distance: distance, sequence: sequence})
+		}
+	}
+
+	return result
+}
+
+// returns the indices of the two turtles, or an error if either is not in the network
+func (n *Network) indices(from *model.Turtle, to *model.Turtle) (int, int, error) {
+	source, ok := n.index[from]
+	if !ok {
+		return 0, 0, ErrTurtleNotInNetwork
+	}
+	target, ok := n.index[to]
+	if !ok {
+		return 0, 0, ErrTurtleNotInNetwork
+	}
+	return source, target, nil
+}
+
+// returns the turtles and links on the path to the target from the search results
+func (n *Network) pathTo(result *searchResult, target int) ([]*model.Turtle, []*model.Link, error) {
+	if math.IsInf(result.distance[target], 1) {
+		return nil, nil, ErrNoPath
+	}
+
+	turtles := []*model.Turtle{n.turtles[target]}
+	links := []*model.Link{}
+	for current := target; result.previous[current] != -1; current = result.previous[current] {
+		turtles = append(turtles, n.turtles[result.previous[current]])
+		links = append(links, result.link[current])
+	}
+
+	// the path was built from the target back so reverse it
+	for i, j := 0, len(turtles)-1; i < j; i, j = i+1, j-1 {
+		turtles[i], turtles[j] = turtles[j], turtles[i]
+	}
+	for i, j := 0, len(links)-1; i < j; i, j = i+1, j-1 {
+		links[i], links[j] = links[j], links[i]
+	}
+
+	return turtles, links, nil
+}
+
+// returns the turtles on the path with the fewest links between the two turtles, including both ends
+func (n *Network) ShortestPath(from *model.Turtle, to *model.Turtle) ([]*model.Turtle, error) {
+	source, target, err := n.indices(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	turtles, _, err := n.pathTo(n.searchUnweighted(source), target)
+	return turtles, err
+}
+
+// returns the links on the path with the fewest links between the two turtles
+func (n *Network) ShortestPathLinks(from *model.Turtle, to *model.Turtle) ([]*model.Link, error) {
+	source, target, err := n.indices(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	_, links, err := n.pathTo(n.searchUnweighted(source), target)
+	return links, err
+}
+
+// returns the turtles on the path with the lowest total weight between the two turtles, including both ends, and the total weight
+func (n *Network) WeightedShortestPath(from *model.Turtle, to *model.Turtle) ([]*model.Turtle, float64, error) {
+	source, target, err := n.indices(from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := n.searchWeighted(source)
+	turtles, _, err := n.pathTo(result, target)
+	if err != nil {
+		return nil, 0, err
+	}
+	return turtles, result.distance[target], nil
+}
+
+// returns the links on the path with the lowest total weight between the two turtles
+func (n *Network) WeightedShortestPathLinks(from *model.Turtle, to *model.Turtle) ([]*model.Link, error) {
+	source, target, err := n.indices(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	_, links, err := n.pathTo(n.searchWeighted(source), target)
+	return links, err
+}
+
+// returns the number of links on the shortest path between the two turtles
+func (n *Network) DistanceTo(from *model.Turtle, to *model.Turtle) (int, error) {
+	source, target, err := n.indices(from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	distance := n.searchUnweighted(source).distance[target]
+	if math.IsInf(distance, 1) {
+		return 0, ErrNoPath
+	}
+	return int(distance), nil
+}
+
+// returns the total weight of the lightest path between the two turtles
+func (n *Network) WeightedDistanceTo(from *model.Turtle, to *model.Turtle) (float64, error) {
+	source, target, err := n.indices(from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	distance := n.searchWeighted(source).distance[target]
+	if math.IsInf(distance, 1) {
+		return 0, ErrNoPath
+	}
+	return distance, nil
+}
+
+// returns the turtles that can be reached from the turtle within the radius, counting each link as 1
+// the turtle itself is included
+func (n *Network) TurtlesInRadius(turtle *model.Turtle, radius int) *model.TurtleAgentSet {
+	return n.turtlesWithin(turtle, float64(radius), false)
+}
+
+// returns the turtles that can be reached from the turtle with a total weight within the radius
+// the turtle itself is included
+func (n *Network) TurtlesInWeightedRadius(turtle *model.Turtle, radius float64) *model.TurtleAgentSet {
+	return n.turtlesWithin(turtle, radius, true)
+}
+
+func (n *Network) turtlesWithin(turtle *model.Turtle, radius float64, weighted bool) *model.TurtleAgentSet {
+	turtles := model.NewTurtleAgentSet(nil)
+	source, ok := n.index[turtle]
+	if !ok {
+		return turtles
+	}
+
+	var result *searchResult
+	if weighted {
+		result = n.searchWeighted(source)
+	} else {
+		result = n.searchUnweighted(source)
+	}
+
+	for i, distance := range result.distance {
+		if distance <= radius {
+			turtles.Add(n.turtles[i])
+		}
+	}
+	return turtles
+}
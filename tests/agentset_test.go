@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// counts the agents of any agent set that are hidden, to check agent sets can be used generically
+func hiddenCount[T model.Agent](set *model.AgentSet[T]) int {
+	count := 0
+	for agent := range set.Iter() {
+		if agent.IsHidden() {
+			count++
+		}
+	}
+	return count
+}
+
+func TestAgentSetGeneric(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:      3,
+		PatchProperties: map[string]interface{}{"food": 1.0},
+	})
+	m.CreateTurtles(4, nil)
+	m.Turtle(1).Hide()
+	m.Turtle(2).Hide()
+
+	for i := 1; i < 4; i++ {
+		if _, err := m.Turtle(0).CreateLinkWithTurtle(nil, m.Turtle(i), nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	links := m.Links().List()
+	links[0].Hide()
+
+	if hiddenCount(m.Turtles().AgentSet) != 2 {
+		t.Errorf("Expected 2 hidden turtles")
+	}
+	if hiddenCount(m.Links().AgentSet) != 1 {
+		t.Errorf("Expected 1 hidden link")
+	}
+	if hiddenCount(m.Patches.AgentSet) != 0 {
+		t.Errorf("Expected patches to never be hidden")
+	}
+
+	// links are numbered in the order they were created
+	for i, link := range links {
+		if link.ID() != i {
+			t.Errorf("Expected link %d to have id %d, got %d", i, i, link.ID())
+		}
+	}
+
+	patch := m.Patch(0, 0)
+	patch.SetColor(model.Red)
+	patch.SetLabel("home")
+	if patch.GetColor() != model.Red || patch.GetLabel() != "home" || patch.Label != "home" {
+		t.Errorf("Expected the patch color and label to be set")
+	}
+	if patch.Properties()["food"] != 1.0 {
+		t.Errorf("Expected the patch properties to have food")
+	}
+}
+
+func TestAgentSetCopy(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+	m.CreateTurtles(6, nil)
+
+	copied := m.Turtles().Copy()
+	if copied.Count() != 6 {
+		t.Fatalf("Expected the copy to have 6 turtles, got %d", copied.Count())
+	}
+
+	copied.Remove(m.Turtle(0))
+	if m.Turtles().Count() != 6 || copied.Count() != 5 {
+		t.Errorf("Expected removing from the copy to leave the original alone")
+	}
+
+	if m.Patches.Copy().Count() != m.Patches.Count() {
+		t.Errorf("Expected the patch copy to have all the patches")
+	}
+}
+
+func TestAgentSetSameOnAllTypes(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+	m.CreateTurtles(6, nil)
+	for i := 1; i < 4; i++ {
+		m.Turtle(0).CreateLinkWithTurtle(nil, m.Turtle(i), nil)
+	}
+
+	// Next used to only exist on turtle agent sets
+	links := m.Links()
+	first, _ := links.First()
+	next, err := links.Next()
+	if err != nil || next == first {
+		t.Errorf("Expected Next to move to the second link")
+	}
+
+	// RandomWhere used to only exist on patch agent sets and wasn't seeded
+	later := func(turtle *model.Turtle) bool {
+		return turtle.Who() >= 2
+	}
+	picked := []*model.Turtle{}
+	for i := 0; i < 2; i++ {
+		seeded := model.NewModel(model.ModelSettings{
+			RandomSeed:  4,
+			RandomSeed2: 4,
+		})
+		seeded.CreateTurtles(6, nil)
+		picked = append(picked, seeded.Turtles().RandomWhere(later))
+	}
+	if picked[0] == nil || picked[0].Who() != picked[1].Who() || picked[0].Who() < 2 {
+		t.Errorf("Expected the same matching turtle from the same seed")
+	}
+	if m.Turtles().RandomWhere(func(turtle *model.Turtle) bool { return false }) != nil {
+		t.Errorf("Expected nil when no turtle matches")
+	}
+
+	// the empty errors are the ones for the agent type
+	if _, err := model.NewPatchAgentSet(nil).First(); err != model.ErrNoPatchesInAgentSet {
+		t.Errorf("Expected ErrNoPatchesInAgentSet, got %v", err)
+	}
+	if _, err := model.NewLinkAgentSet(nil).OneOf(); err != model.ErrNoLinksInAgentSet {
+		t.Errorf("Expected ErrNoLinksInAgentSet, got %v", err)
+	}
+
+	if m.Turtles().With(nil) != nil {
+		t.Errorf("Expected nil from With with no operation")
+	}
+}
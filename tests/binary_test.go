@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/loader"
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// saves the model in the binary format and loads it back
+func binarySaveAndLoad(t *testing.T, m *model.Model, settings loader.BinarySettings) *model.Model {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := loader.WriteBinary(m, &buf, settings); err != nil {
+		t.Fatalf("Unexpected error writing: %v", err)
+	}
+	loaded, err := loader.ReadBinary(&buf, settings)
+	if err != nil {
+		t.Fatalf("Unexpected error reading: %v", err)
+	}
+	return loaded
+}
+
+// stands in for zstd, which isn't in the standard library
+var flateSettings = loader.BinarySettings{
+	Compression: loader.CompressionZstd,
+	ZstdWriter: func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.BestSpeed)
+	},
+	ZstdReader: func(r io.Reader) (io.ReadCloser, error) {
+		return flate.NewReader(r), nil
+	},
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	for _, settings := range []loader.BinarySettings{{}, {Compression: loader.CompressionGzip}, flateSettings} {
+		for seed := uint64(0); seed < 30; seed++ {
+			m := randomWorld(seed)
+			expectSameModel(t, seed, m, binarySaveAndLoad(t, m, settings))
+		}
+	}
+}
+
+func TestBinaryKeepsPropertyTypes(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		TurtleProperties: map[string]interface{}{"count": 0, "tags": nil},
+		PatchColumns:     map[string]interface{}{"height": 0},
+	})
+	m.CreateTurtles(1, func(turtle *model.Turtle) {
+		turtle.SetProperty("count", 3)
+		turtle.SetProperty("tags", []interface{}{"a", "b"})
+	})
+	m.Patch(1, 1).SetProperty("height", 7)
+
+	loaded := binarySaveAndLoad(t, m, loader.BinarySettings{})
+
+	// json would read the int back as a float64
+	if count := loaded.Turtle(0).GetProperty("count"); count != 3 {
+		t.Errorf("Expected the int property to stay an int, got %v (%T)", count, count)
+	}
+	tags, _ := json.Marshal(loaded.Turtle(0).GetProperty("tags"))
+	if string(tags) != `["a","b"]` {
+		t.Errorf("Expected other values to be saved like json, got %s", tags)
+	}
+	if height := loaded.IntColumn("height").Get(loaded.Patch(1, 1)); height != 7 {
+		t.Errorf("Expected the column value 7, got %d", height)
+	}
+}
+
+func TestBinaryIsSmallerThanJSON(t *testing.T) {
+	m := benchmarkWorld(2000)
+
+	var binaryBuf bytes.Buffer
+	if err := loader.WriteBinary(m, &binaryBuf, loader.BinarySettings{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	jsonBytes, err := json.Marshal(loader.GetModel(m))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if binaryBuf.Len()*2 > len(jsonBytes) {
+		t.Errorf("Expected the binary format to be less than half the size of json, got %d and %d bytes", binaryBuf.Len(), len(jsonBytes))
+	}
+}
+
+func TestBinaryErrors(t *testing.T) {
+	m := randomWorld(1)
+
+	var buf bytes.Buffer
+	if err := loader.WriteBinary(m, &buf, loader.BinarySettings{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data := buf.Bytes()
+
+	if _, err := loader.ReadBinary(bytes.NewReader([]byte(`{"version": 2}`)), loader.BinarySettings{}); !errors.Is(err, loader.ErrBinaryFormat) {
+		t.Errorf("Expected ErrBinaryFormat for json, got %v", err)
+	}
+
+	// cutting the data anywhere is caught
+	for _, length := range []int{4, 12, len(data) / 2, len(data) - 1} {
+		if _, err := loader.ReadBinary(bytes.NewReader(data[:length]), loader.BinarySettings{}); !errors.Is(err, loader.ErrBinaryFormat) {
+			t.Errorf("Expected ErrBinaryFormat for data cut to %d bytes, got %v", length, err)
+		}
+	}
+
+	// the version comes right after the magic
+	newer := bytes.Clone(data)
+	newer[8] = loader.BinaryVersion + 1
+	if _, err := loader.ReadBinary(bytes.NewReader(newer), loader.BinarySettings{}); !errors.Is(err, loader.ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	// the settings are checked before anything is written
+	buf.Reset()
+	if err := loader.WriteBinary(m, &buf, loader.BinarySettings{Compression: loader.CompressionZstd}); !errors.Is(err, loader.ErrBinaryCompression) {
+		t.Errorf("Expected ErrBinaryCompression writing zstd without a writer, got %v", err)
+	}
+	if err := loader.WriteBinary(m, &buf, loader.BinarySettings{Compression: 9}); !errors.Is(err, loader.ErrBinaryCompression) {
+		t.Errorf("Expected ErrBinaryCompression writing an unknown compression, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing to be written with bad settings, got %d bytes", buf.Len())
+	}
+
+	// gzip ends with the checksum of the data then its length
+	buf.Reset()
+	if err := loader.WriteBinary(m, &buf, loader.BinarySettings{Compression: loader.CompressionGzip}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	corrupt := bytes.Clone(buf.Bytes())
+	corrupt[len(corrupt)-8] ^= 0xff
+	if _, err := loader.ReadBinary(bytes.NewReader(corrupt), loader.BinarySettings{}); !errors.Is(err, loader.ErrBinaryFormat) {
+		t.Errorf("Expected ErrBinaryFormat for a bad gzip checksum, got %v", err)
+	}
+	buf.Reset()
+	if err := loader.WriteBinary(m, &buf, flateSettings); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := loader.ReadBinary(&buf, loader.BinarySettings{}); !errors.Is(err, loader.ErrBinaryCompression) {
+		t.Errorf("Expected ErrBinaryCompression reading zstd without a reader, got %v", err)
+	}
+}
+
+// a world with enough agents for the formats to be compared
+func benchmarkWorld(turtles int) *model.Model {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:       1,
+		MinPxCor:         -50,
+		MaxPxCor:         50,
+		MinPyCor:         -50,
+		MaxPyCor:         50,
+		TurtleProperties: map[string]interface{}{"energy": 0.0, "age": 0},
+		PatchProperties:  map[string]interface{}{"owner": ""},
+		PatchColumns:     map[string]interface{}{"grass": 0.0, "visits": 0, "wet": false},
+	})
+	grass, visits, wet := m.FloatColumn("grass"), m.IntColumn("visits"), m.BoolColumn("wet")
+	m.Patches.Ask(func(p *model.Patch) {
+		grass.Set(p, m.RandomFloat(1))
+		visits.Set(p, m.RandomInt(100))
+		wet.Set(p, m.RandomInt(2) == 0)
+	})
+	m.CreateTurtles(turtles, func(t *model.Turtle) {
+		t.SetXY(m.RandomXCor(), m.RandomYCor())
+		t.SetHeading(m.RandomFloat(360))
+		t.SetProperty("energy", m.RandomFloat(10))
+		t.SetProperty("age", m.RandomInt(50))
+	})
+	list := m.Turtles().List()
+	for i := 0; i+1 < len(list); i += 4 {
+		list[i].CreateLinkWithTurtle(nil, list[i+1], nil)
+	}
+	return m
+}
+
+func benchmarkWrite(b *testing.B, write func(m *model.Model, w io.Writer) error) {
+	m := benchmarkWorld(20000)
+
+	var buf bytes.Buffer
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if err := write(m, &buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(buf.Len()), "bytes")
+}
+
+func benchmarkRead(b *testing.B, write func(m *model.Model, w io.Writer) error, read func(r io.Reader) error) {
+	var buf bytes.Buffer
+	if err := write(benchmarkWorld(20000), &buf); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := read(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func writeJSON(m *model.Model, w io.Writer) error {
+	return json.NewEncoder(w).Encode(loader.GetModel(m))
+}
+
+func readJSON(r io.Reader) error {
+	saved := &loader.Model{}
+	if err := json.NewDecoder(r).Decode(saved); err != nil {
+		return err
+	}
+	loader.SetModel(saved)
+	return nil
+}
+
+func writeBinary(settings loader.BinarySettings) func(m *model.Model, w io.Writer) error {
+	return func(m *model.Model, w io.Writer) error {
+		return loader.WriteBinary(m, w, settings)
+	}
+}
+
+func readBinary(r io.Reader) error {
+	_, err := loader.ReadBinary(r, loader.BinarySettings{})
+	return err
+}
+
+func BenchmarkWriteJSON(b *testing.B) {
+	benchmarkWrite(b, writeJSON)
+}
+
+func BenchmarkWriteBinary(b *testing.B) {
+	benchmarkWrite(b, writeBinary(loader.BinarySettings{}))
+}
+
+func BenchmarkWriteBinaryGzip(b *testing.B) {
+	benchmarkWrite(b, writeBinary(loader.BinarySettings{Compression: loader.CompressionGzip}))
+}
+
+func BenchmarkReadJSON(b *testing.B) {
+	benchmarkRead(b, writeJSON, readJSON)
+}
+
+func BenchmarkReadBinary(b *testing.B) {
+	benchmarkRead(b, writeBinary(loader.BinarySettings{}), readBinary)
+}
+
+func BenchmarkReadBinaryGzip(b *testing.B) {
+	benchmarkRead(b, writeBinary(loader.BinarySettings{Compression: loader.CompressionGzip}), readBinary)
+}
@@ -0,0 +1,385 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// runs one tick of a model that draws random numbers, moves turtles and creates and kills agents
+func cloneStep(m *model.Model) {
+	grass := m.FloatColumn("grass")
+	m.Turtles().Ask(func(t *model.Turtle) {
+		t.Right(m.RandomFloat(90) - 45)
+		t.Forward(m.RandomFloat(2))
+
+		p := t.PatchHere()
+		p.SetProperty("visits", p.GetProperty("visits").(float64)+1)
+		grass.Set(p, grass.Get(p)*0.5)
+
+		t.SetProperty("energy", t.GetProperty("energy").(int)-1+m.TurtlesInRadiusXY(t.XCor(), t.YCor(), 2).Count())
+	})
+
+	if m.RandomInt(3) == 0 {
+		parent, _ := m.Turtles().OneOf()
+		parent.Hatch(1, nil)
+	}
+	if m.RandomInt(4) == 0 && m.Turtles().Count() > 5 {
+		dead, _ := m.Turtles().OneOf()
+		m.KillTurtle(dead)
+	}
+	t1, _ := m.Turtles().OneOf()
+	t2, _ := m.Turtles().OneOf()
+	if t1 != t2 {
+		t1.CreateLinkWithTurtle(m.UndirectedLinkBreed("friends"), t2, nil)
+	}
+
+	m.Tick()
+}
+
+// describes everything about the model that a run can change
+func cloneDigest(m *model.Model) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ticks %d clock %v\n", m.Ticks, m.Clock())
+	m.Turtles().Ask(func(t *model.Turtle) {
+		fmt.Fprintf(&b, "turtle %d %s %v %v %v %v %v %v\n", t.Who(), t.BreedName(), t.XCor(), t.YCor(), t.GetHeading(), t.GetColor(), t.GetProperty("energy"), t.PatchHere().ID())
+	})
+	m.Links().Ask(func(l *model.Link) {
+		fmt.Fprintf(&b, "link %d %d %d %s %v\n", l.ID(), l.End1().Who(), l.End2().Who(), l.BreedName(), l.GetProperty("strength"))
+	})
+	m.Patches.Ask(func(p *model.Patch) {
+		fmt.Fprintf(&b, "patch %v %v %d\n", p.GetProperty("visits"), p.GetProperty("grass"), p.TurtlesHere().Count())
+	})
+	for _, e := range m.Events() {
+		fmt.Fprintf(&b, "event %s %v %d\n", e.Name(), e.Time(), e.Turtle().Who())
+	}
+	for _, breed := range []string{"wolves", "sheep"} {
+		fmt.Fprintf(&b, "breed %s %d\n", breed, m.TurtleBreed(breed).Agents().Count())
+	}
+	fmt.Fprintf(&b, "friends %d\n", m.UndirectedLinkBreed("friends").Links().Count())
+	fmt.Fprintf(&b, "random %v\n", m.RandomFloat(1))
+	return b.String()
+}
+
+func TestCloneRunsTheSameAsTheOriginal(t *testing.T) {
+	wolves := model.NewTurtleBreed("wolves", "", map[string]interface{}{"hunger": 0})
+	sheep := model.NewTurtleBreed("sheep", "", map[string]interface{}{"wool": 1})
+	friends := model.NewLinkBreedWithProperties("friends", map[string]interface{}{"strength": 1.0})
+
+	// a model that uses breeds, links, properties, columns, events and the spatial index
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:           11,
+		TurtleBreeds:         []*model.TurtleBreed{wolves, sheep},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+		TurtleProperties:     map[string]interface{}{"energy": 10},
+		PatchProperties:      map[string]interface{}{"visits": 0.0},
+		PatchColumns:         map[string]interface{}{"grass": 1.0},
+		SpatialIndexCellSize: 3,
+	})
+
+	wolves.CreateAgents(5, func(turtle *model.Turtle) {
+		turtle.SetXY(m.RandomXCor(), m.RandomYCor())
+	})
+	sheep.CreateAgents(10, func(turtle *model.Turtle) {
+		turtle.SetXY(m.RandomXCor(), m.RandomYCor())
+	})
+	m.Turtle(0).CreateLinkWithTurtle(friends, m.Turtle(1), nil)
+	m.Turtle(2).CreateLinkToTurtle(nil, m.Turtle(7), nil)
+
+	m.RegisterEventHandler("feed", func(e *model.Event) {
+		e.Turtle().SetProperty("energy", e.Turtle().GetProperty("energy").(int)+5)
+	})
+	m.ScheduleEvent(model.EventSettings{Name: "feed", Time: 2, Interval: 3, Turtle: m.Turtle(3)})
+	for i := 0; i < 3; i++ {
+		cloneStep(m)
+	}
+
+	clone := m.Clone()
+
+	for i := 0; i < 10; i++ {
+		cloneStep(m)
+		cloneStep(clone)
+	}
+
+	original, cloned := cloneDigest(m), cloneDigest(clone)
+	if original != cloned {
+		t.Errorf("Expected the clone to run the same as the original\noriginal:\n%s\nclone:\n%s", original, cloned)
+	}
+}
+
+func TestCloneSharesNothing(t *testing.T) {
+	wolves := model.NewTurtleBreed("wolves", "", map[string]interface{}{"hunger": 0})
+	sheep := model.NewTurtleBreed("sheep", "", map[string]interface{}{"wool": 1})
+	friends := model.NewLinkBreedWithProperties("friends", map[string]interface{}{"strength": 1.0})
+
+	// a model that uses breeds, links, properties, columns, events and the spatial index
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:           11,
+		TurtleBreeds:         []*model.TurtleBreed{wolves, sheep},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+		TurtleProperties:     map[string]interface{}{"energy": 10},
+		PatchProperties:      map[string]interface{}{"visits": 0.0},
+		PatchColumns:         map[string]interface{}{"grass": 1.0},
+		SpatialIndexCellSize: 3,
+	})
+
+	wolves.CreateAgents(5, func(turtle *model.Turtle) {
+		turtle.SetXY(m.RandomXCor(), m.RandomYCor())
+	})
+	sheep.CreateAgents(10, func(turtle *model.Turtle) {
+		turtle.SetXY(m.RandomXCor(), m.RandomYCor())
+	})
+	m.Turtle(0).CreateLinkWithTurtle(friends, m.Turtle(1), nil)
+	m.Turtle(2).CreateLinkToTurtle(nil, m.Turtle(7), nil)
+
+	m.RegisterEventHandler("feed", func(e *model.Event) {
+		e.Turtle().SetProperty("energy", e.Turtle().GetProperty("energy").(int)+5)
+	})
+	m.ScheduleEvent(model.EventSettings{Name: "feed", Time: 2, Interval: 3, Turtle: m.Turtle(3)})
+	before := cloneDigest(m.Clone())
+
+	clone := m.Clone()
+	if clone.TurtleBreed("wolves") == wolves {
+		t.Fatalf("Expected the clone to have its own breeds")
+	}
+	if clone.Turtle(0) == m.Turtle(0) || clone.Patch(0, 0) == m.Patch(0, 0) {
+		t.Fatalf("Expected the clone to have its own agents")
+	}
+	if clone.Turtle(0).PatchHere() != clone.Patch(clone.Turtle(0).XCor(), clone.Turtle(0).YCor()) {
+		t.Errorf("Expected the clone's turtles to be on the clone's patches")
+	}
+
+	// changing the clone leaves the original alone
+	for i := 0; i < 5; i++ {
+		cloneStep(clone)
+	}
+	clone.TurtleBreed("wolves").CreateAgents(3, nil)
+	turtles := clone.Turtles().List()
+	clone.KillTurtle(turtles[0])
+	turtles[1].SetBreed(clone.TurtleBreed("sheep"))
+
+	if after := cloneDigest(m.Clone()); after != before {
+		t.Errorf("Expected the original to be unchanged by the clone")
+	}
+	if wolves.Agents().Count() != 5 {
+		t.Errorf("Expected the original wolves to be unchanged, got %d", wolves.Agents().Count())
+	}
+	if m.Turtle(0).LinkedTurtles(friends).Count() != 1 {
+		t.Errorf("Expected the original turtle to keep its link")
+	}
+}
+
+func TestCloneKeepsEvents(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		TurtleProperties: map[string]interface{}{"energy": 10},
+	})
+	m.CreateTurtles(4, nil)
+	m.RegisterEventHandler("feed", func(e *model.Event) {
+		e.Turtle().SetProperty("energy", e.Turtle().GetProperty("energy").(int)+5)
+	})
+	m.ScheduleEvent(model.EventSettings{Name: "feed", Time: 2, Interval: 3, Turtle: m.Turtle(3)})
+
+	clone := m.Clone()
+
+	events := clone.Events()
+	if len(events) != 1 || events[0].Turtle() != clone.Turtle(3) || events[0].Model() != clone {
+		t.Fatalf("Expected the clone's event to be bound to the clone's turtle")
+	}
+
+	clone.TickAdvance(2)
+	if clone.Turtle(3).GetProperty("energy") != 15 {
+		t.Errorf("Expected the clone's event to feed the clone's turtle, got %v", clone.Turtle(3).GetProperty("energy"))
+	}
+	if m.Turtle(3).GetProperty("energy") != 10 {
+		t.Errorf("Expected the original turtle not to be fed, got %v", m.Turtle(3).GetProperty("energy"))
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	wolves := model.NewTurtleBreed("wolves", "", map[string]interface{}{"hunger": 0})
+	sheep := model.NewTurtleBreed("sheep", "", map[string]interface{}{"wool": 1})
+	friends := model.NewLinkBreedWithProperties("friends", map[string]interface{}{"strength": 1.0})
+
+	// a model that uses breeds, links, properties, columns, events and the spatial index
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:           11,
+		TurtleBreeds:         []*model.TurtleBreed{wolves, sheep},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+		TurtleProperties:     map[string]interface{}{"energy": 10},
+		PatchProperties:      map[string]interface{}{"visits": 0.0},
+		PatchColumns:         map[string]interface{}{"grass": 1.0},
+		SpatialIndexCellSize: 3,
+	})
+
+	wolves.CreateAgents(5, func(turtle *model.Turtle) {
+		turtle.SetXY(m.RandomXCor(), m.RandomYCor())
+	})
+	sheep.CreateAgents(10, func(turtle *model.Turtle) {
+		turtle.SetXY(m.RandomXCor(), m.RandomYCor())
+	})
+	m.Turtle(0).CreateLinkWithTurtle(friends, m.Turtle(1), nil)
+	m.Turtle(2).CreateLinkToTurtle(nil, m.Turtle(7), nil)
+
+	m.RegisterEventHandler("feed", func(e *model.Event) {
+		e.Turtle().SetProperty("energy", e.Turtle().GetProperty("energy").(int)+5)
+	})
+	m.ScheduleEvent(model.EventSettings{Name: "feed", Time: 2, Interval: 3, Turtle: m.Turtle(3)})
+	cloneStep(m)
+
+	snapshot := m.Snapshot()
+	if snapshot.Ticks() != 1 {
+		t.Errorf("Expected the snapshot to be taken at tick 1, got %d", snapshot.Ticks())
+	}
+
+	for i := 0; i < 5; i++ {
+		cloneStep(m)
+	}
+	first := cloneDigest(m)
+	oldTurtle := m.Turtles().List()[0]
+
+	// restoring twice gives the same run both times
+	for run := 0; run < 2; run++ {
+		if err := m.Restore(snapshot); err != nil {
+			t.Fatalf("Expected no error restoring, got %v", err)
+		}
+		if m.Ticks != 1 {
+			t.Errorf("Expected the ticks to be restored to 1, got %d", m.Ticks)
+		}
+		for i := 0; i < 5; i++ {
+			cloneStep(m)
+		}
+		if again := cloneDigest(m); again != first {
+			t.Errorf("Expected the run after restoring to be the same as the first run\nfirst:\n%s\nagain:\n%s", first, again)
+		}
+	}
+
+	// the breeds held from before the restore still work
+	if m.TurtleBreed("wolves") != wolves {
+		t.Errorf("Expected the model to keep its breeds")
+	}
+	wolves.Agents().Ask(func(turtle *model.Turtle) {
+		if m.Turtle(turtle.Who()) != turtle {
+			t.Errorf("Expected the wolves to be the restored turtles")
+		}
+	})
+
+	// turtles from before the restore are dead
+	if m.Turtles().Contains(oldTurtle) || m.Turtle(oldTurtle.Who()) == oldTurtle {
+		t.Errorf("Expected the turtle from before the restore to be dead")
+	}
+}
+
+func TestRestoreOldPatches(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		PatchProperties: map[string]interface{}{"visits": 0.0},
+		PatchColumns:    map[string]interface{}{"grass": 1.0},
+	})
+	snapshot := m.Snapshot()
+	oldPatch := m.Patch(2, 3)
+
+	if err := m.Restore(snapshot); err != nil {
+		t.Fatalf("Expected no error restoring, got %v", err)
+	}
+
+	// a patch held from before the restore no longer changes the model
+	oldPatch.SetProperty("grass", 0.25)
+	oldPatch.SetProperty("visits", 4.0)
+	if oldPatch.GetProperty("grass") != nil {
+		t.Errorf("Expected the patch from before the restore to have no column values, got %v", oldPatch.GetProperty("grass"))
+	}
+	patch := m.Patch(2, 3)
+	if patch == oldPatch {
+		t.Fatalf("Expected the model to have new patches after the restore")
+	}
+	if patch.GetProperty("grass") != 1.0 || patch.GetProperty("visits") != 0.0 {
+		t.Errorf("Expected the restored patch to be unchanged, got %v and %v", patch.GetProperty("grass"), patch.GetProperty("visits"))
+	}
+	m.Patches.Ask(func(p *model.Patch) {
+		if m.FloatColumn("grass").Get(p) != 1.0 {
+			t.Errorf("Expected every restored patch to keep its grass")
+		}
+	})
+}
+
+func TestRestoreKeepsHooks(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+	snapshot := m.Snapshot()
+
+	ticks := 0
+	m.Subscribe(model.HookTick, func(h *model.Hook) {
+		ticks++
+	})
+	m.Restore(snapshot)
+	m.Tick()
+
+	if ticks != 1 {
+		t.Errorf("Expected the hook to still run after restoring, got %d", ticks)
+	}
+
+	clone := m.Clone()
+	clone.Tick()
+	if ticks != 1 {
+		t.Errorf("Expected the hook not to be copied to the clone, got %d", ticks)
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	wolves := model.NewTurtleBreed("wolves", "", map[string]interface{}{"hunger": 0})
+	sheep := model.NewTurtleBreed("sheep", "", map[string]interface{}{"wool": 1})
+	friends := model.NewLinkBreedWithProperties("friends", map[string]interface{}{"strength": 1.0})
+
+	// a model that uses breeds, links, properties, columns, events and the spatial index
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:           11,
+		TurtleBreeds:         []*model.TurtleBreed{wolves, sheep},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+		TurtleProperties:     map[string]interface{}{"energy": 10},
+		PatchProperties:      map[string]interface{}{"visits": 0.0},
+		PatchColumns:         map[string]interface{}{"grass": 1.0},
+		SpatialIndexCellSize: 3,
+	})
+
+	wolves.CreateAgents(5, func(turtle *model.Turtle) {
+		turtle.SetXY(m.RandomXCor(), m.RandomYCor())
+	})
+	sheep.CreateAgents(10, func(turtle *model.Turtle) {
+		turtle.SetXY(m.RandomXCor(), m.RandomYCor())
+	})
+	m.Turtle(0).CreateLinkWithTurtle(friends, m.Turtle(1), nil)
+	m.Turtle(2).CreateLinkToTurtle(nil, m.Turtle(7), nil)
+
+	m.RegisterEventHandler("feed", func(e *model.Event) {
+		e.Turtle().SetProperty("energy", e.Turtle().GetProperty("energy").(int)+5)
+	})
+	m.ScheduleEvent(model.EventSettings{Name: "feed", Time: 2, Interval: 3, Turtle: m.Turtle(3)})
+
+	if err := m.Restore(nil); !errors.Is(err, model.ErrSnapshotNil) {
+		t.Errorf("Expected ErrSnapshotNil, got %v", err)
+	}
+
+	other := model.NewModel(model.ModelSettings{})
+	if err := other.Restore(m.Snapshot()); !errors.Is(err, model.ErrSnapshotBreedMissing) {
+		t.Errorf("Expected ErrSnapshotBreedMissing, got %v", err)
+	}
+
+	// a model with every breed of the snapshot can take it
+	copied := model.NewModel(model.ModelSettings{
+		TurtleBreeds: []*model.TurtleBreed{
+			model.NewTurtleBreed("wolves", "", map[string]interface{}{"hunger": 0}),
+			model.NewTurtleBreed("sheep", "", map[string]interface{}{"wool": 1}),
+		},
+		UndirectedLinkBreeds: []*model.LinkBreed{
+			model.NewLinkBreedWithProperties("friends", map[string]interface{}{"strength": 1.0}),
+		},
+	})
+	copied.Tick()
+	if err := copied.Restore(m.Snapshot()); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if cloneDigest(copied) != cloneDigest(m) {
+		t.Errorf("Expected the model to match the snapshot's model after restoring")
+	}
+}
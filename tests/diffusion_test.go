@@ -0,0 +1,314 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func totalHeat(m *model.Model) float64 {
+	total := 0.0
+	m.Patches.Ask(func(p *model.Patch) {
+		total += p.GetPropF("heat")
+	})
+	return total
+}
+
+func TestDiffusionConservesMass(t *testing.T) {
+	boundaries := []model.DiffusionBoundary{model.BoundaryWorld, model.BoundaryReflect, model.BoundaryWrap}
+	kernels := [][]model.KernelWeight{
+		nil,
+		model.DiffusionKernel4(),
+		model.AnisotropicKernel(3, 1, 0),
+		{{DX: 2, DY: 1, Weight: 1}, {DX: -1, Weight: 2}},
+	}
+
+	for _, boundary := range boundaries {
+		for _, kernel := range kernels {
+			m := model.NewModel(model.ModelSettings{
+				MinPxCor:        -4,
+				MaxPxCor:        4,
+				MinPyCor:        -3,
+				MaxPyCor:        3,
+				WrappingX:       true,
+				PatchProperties: map[string]interface{}{"heat": 0.0},
+			})
+
+			// put heat in the corners and middle so it reaches the edges
+			m.Patch(-4, -3).SetProperty("heat", 50.0)
+			m.Patch(4, 3).SetProperty("heat", 30.0)
+			m.Patch(0, 0).SetProperty("heat", 20.0)
+
+			err := m.DiffuseWithSettings("heat", model.DiffusionSettings{
+				Rate:       .6,
+				Kernel:     kernel,
+				Boundary:   boundary,
+				Iterations: 25,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !closeTo(totalHeat(m), 100) {
+				t.Errorf("Expected the total heat to stay 100 with boundary %d and kernel %v, got %f", boundary, kernel, totalHeat(m))
+			}
+		}
+	}
+}
+
+func TestDiffusion3DConservesMass(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor:     -2,
+		MaxPxCor:     2,
+		MinPyCor:     -2,
+		MaxPyCor:     2,
+		MinPzCor:     -2,
+		MaxPzCor:     2,
+		PatchColumns: map[string]interface{}{"pheromone": 0.0},
+	})
+
+	column := m.FloatColumn("pheromone")
+	column.Set(m.Patch3D(0, 0, 2), 100)
+
+	err := m.DiffuseWithSettings("pheromone", model.DiffusionSettings{
+		Rate:       .5,
+		Iterations: 10,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !closeTo(column.Sum(), 100) {
+		t.Errorf("Expected the total to stay 100 in 3D, got %f", column.Sum())
+	}
+
+	if column.Get(m.Patch3D(0, 0, -2)) <= 0 {
+		t.Errorf("Expected the pheromone to spread along the z axis")
+	}
+
+	// the 6 neighbor kernel should only reach along the axes after one step
+	m.FloatColumn("pheromone").Fill(0)
+	column.Set(m.Patch3D(0, 0, 0), 60)
+	m.DiffuseWithSettings("pheromone", model.DiffusionSettings{
+		Rate:   1,
+		Kernel: model.DiffusionKernel6(),
+	})
+	if column.Get(m.Patch3D(0, 0, 1)) != 10 || column.Get(m.Patch3D(1, 1, 0)) != 0 {
+		t.Errorf("Expected the 6 neighbor kernel to share evenly with the faces")
+	}
+
+	// Diffuse in 3D should now reach the neighbors above and below
+	column.Fill(0)
+	column.Set(m.Patch3D(0, 0, 0), 100)
+	m.Diffuse("pheromone", .5)
+	if column.Get(m.Patch3D(0, 0, 1)) == 0 || !closeTo(column.Sum(), 100) {
+		t.Errorf("Expected Diffuse to spread in 3D")
+	}
+}
+
+func TestDiffusionEvaporation(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		PatchProperties: map[string]interface{}{"heat": 0.0},
+	})
+
+	m.Patch(0, 0).SetProperty("heat", 100.0)
+
+	err := m.DiffuseWithSettings("heat", model.DiffusionSettings{
+		Rate:        .5,
+		Evaporation: .1,
+		Iterations:  3,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !closeTo(totalHeat(m), 100*.9*.9*.9) {
+		t.Errorf("Expected 10 percent to evaporate each iteration, got %f", totalHeat(m))
+	}
+}
+
+func TestDiffusionFixedBoundary(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor:        -2,
+		MaxPxCor:        2,
+		MinPyCor:        -2,
+		MaxPyCor:        2,
+		PatchProperties: map[string]interface{}{"heat": 0.0},
+	})
+
+	// an absorbing boundary loses what reaches the edge
+	m.Patch(2, 0).SetProperty("heat", 100.0)
+	m.DiffuseWithSettings("heat", model.DiffusionSettings{
+		Rate:     .8,
+		Kernel:   model.DiffusionKernel4(),
+		Boundary: model.BoundaryFixed,
+	})
+	if !closeTo(totalHeat(m), 100-100*.8/4) {
+		t.Errorf("Expected the share past the edge to be lost, got %f", totalHeat(m))
+	}
+
+	// a fixed value boundary heats up the edges until the world matches it
+	m.ClearPatches()
+	m.DiffuseWithSettings("heat", model.DiffusionSettings{
+		Rate:          .8,
+		Kernel:        model.DiffusionKernel4(),
+		Boundary:      model.BoundaryFixed,
+		BoundaryValue: 10,
+		Iterations:    500,
+	})
+	m.Patches.Ask(func(p *model.Patch) {
+		if !closeTo(p.GetPropF("heat"), 10) {
+			t.Errorf("Expected patch %d %d to reach the boundary value, got %f", p.XCor(), p.YCor(), p.GetPropF("heat"))
+		}
+	})
+}
+
+func TestDiffusionWrapBoundary(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor:        -2,
+		MaxPxCor:        2,
+		MinPyCor:        -2,
+		MaxPyCor:        2,
+		PatchProperties: map[string]interface{}{"heat": 0.0},
+	})
+
+	m.Patch(2, 0).SetProperty("heat", 100.0)
+
+	// the world doesn't wrap so by default nothing crosses the edge
+	m.DiffuseWithSettings("heat", model.DiffusionSettings{Rate: .4, Kernel: model.DiffusionKernel4()})
+	if m.Patch(-2, 0).GetPropF("heat") != 0 {
+		t.Errorf("Expected nothing to cross the edge of a world that doesn't wrap")
+	}
+
+	m.DiffuseWithSettings("heat", model.DiffusionSettings{Rate: .4, Kernel: model.DiffusionKernel4(), Boundary: model.BoundaryWrap})
+	if m.Patch(-2, 0).GetPropF("heat") == 0 {
+		t.Errorf("Expected the heat to wrap around with BoundaryWrap")
+	}
+}
+
+func TestDiffusionAnisotropic(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		PatchProperties: map[string]interface{}{"heat": 0.0},
+	})
+
+	m.Patch(0, 0).SetProperty("heat", 100.0)
+	m.DiffuseWithSettings("heat", model.DiffusionSettings{
+		Rate:   .5,
+		Kernel: model.AnisotropicKernel(3, 1, 0),
+	})
+
+	if !closeTo(m.Patch(1, 0).GetPropF("heat"), 50*3.0/8) || !closeTo(m.Patch(0, 1).GetPropF("heat"), 50*1.0/8) {
+		t.Errorf("Expected more heat to go along x than y, got %f and %f", m.Patch(1, 0).GetPropF("heat"), m.Patch(0, 1).GetPropF("heat"))
+	}
+}
+
+func TestDiffusionErrors(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		PatchProperties: map[string]interface{}{"heat": 0.0},
+	})
+
+	if m.DiffuseWithSettings("heat", model.DiffusionSettings{Rate: 1.5}) != model.ErrDiffusionRate {
+		t.Errorf("Expected an error for a rate above 1")
+	}
+	if m.DiffuseWithSettings("heat", model.DiffusionSettings{Rate: .5, Evaporation: -1}) != model.ErrDiffusionEvaporation {
+		t.Errorf("Expected an error for negative evaporation")
+	}
+	if m.DiffuseWithSettings("heat", model.DiffusionSettings{Rate: .5, Kernel: []model.KernelWeight{}}) != model.ErrKernelEmpty {
+		t.Errorf("Expected an error for an empty kernel")
+	}
+	if m.DiffuseWithSettings("heat", model.DiffusionSettings{Rate: .5, Kernel: []model.KernelWeight{{DX: 1, Weight: -1}}}) != model.ErrKernelNegativeWeight {
+		t.Errorf("Expected an error for a negative weight")
+	}
+	if m.DiffuseWithSettings("missing", model.DiffusionSettings{Rate: .5}) != model.ErrPatchVariableNotFloat {
+		t.Errorf("Expected an error for a variable that isn't a float")
+	}
+}
+
+func TestConvolve(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor:        -2,
+		MaxPxCor:        2,
+		MinPyCor:        -2,
+		MaxPyCor:        2,
+		PatchProperties: map[string]interface{}{"heat": 0.0},
+	})
+
+	m.Patches.Ask(func(p *model.Patch) {
+		p.SetProperty("heat", float64(p.XCor()))
+	})
+
+	// a gradient kernel along x
+	err := m.Convolve("heat", model.ConvolutionSettings{
+		Kernel: []model.KernelWeight{{DX: 1, Weight: .5}, {DX: -1, Weight: -.5}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if m.Patch(0, 0).GetPropF("heat") != 1 {
+		t.Errorf("Expected a gradient of 1 in the middle, got %f", m.Patch(0, 0).GetPropF("heat"))
+	}
+
+	// at the edge the patch outside is replaced by the edge patch
+	if m.Patch(2, 0).GetPropF("heat") != .5 {
+		t.Errorf("Expected a gradient of .5 at the edge, got %f", m.Patch(2, 0).GetPropF("heat"))
+	}
+
+	m.Patches.Ask(func(p *model.Patch) {
+		p.SetProperty("heat", 1.0)
+	})
+	m.Convolve("heat", model.ConvolutionSettings{
+		Kernel:        []model.KernelWeight{{Weight: .5}, {DX: 1, Weight: .5}},
+		Boundary:      model.BoundaryFixed,
+		BoundaryValue: 3,
+	})
+	if m.Patch(2, 0).GetPropF("heat") != 2 || m.Patch(1, 0).GetPropF("heat") != 1 {
+		t.Errorf("Expected the fixed boundary value to be used past the edge")
+	}
+
+	if m.Convolve("heat", model.ConvolutionSettings{}) != model.ErrKernelEmpty {
+		t.Errorf("Expected an error for an empty kernel")
+	}
+}
+
+func TestConvolveChangingKernels(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor:        -2,
+		MaxPxCor:        2,
+		MinPyCor:        -2,
+		MaxPyCor:        2,
+		PatchProperties: map[string]interface{}{"heat": 0.0},
+	})
+
+	// a shift by a different amount each time so only a few of the kernels stay cached
+	for shift := 1; shift <= 20; shift++ {
+		m.Patches.Ask(func(p *model.Patch) {
+			p.SetProperty("heat", p.XCor()*10+p.YCor())
+		})
+		m.Convolve("heat", model.ConvolutionSettings{
+			Kernel:   []model.KernelWeight{{DX: shift % 5, DY: shift / 5, Weight: 1}},
+			Boundary: model.BoundaryWrap,
+		})
+
+		// the patch ends up with the value of the patch at the offset, wrapped around the world
+		x := (0+shift%5+2)%5 - 2
+		y := (0+shift/5+2)%5 - 2
+		if m.Patch(0, 0).GetPropF("heat") != float64(x*10+y) {
+			t.Errorf("Expected shift %d to read the patch at %d %d, got %f", shift, x, y, m.Patch(0, 0).GetPropF("heat"))
+		}
+	}
+}
+
+func BenchmarkDiffuseWithSettings(b *testing.B) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor:     -50,
+		MaxPxCor:     50,
+		MinPyCor:     -50,
+		MaxPyCor:     50,
+		PatchColumns: map[string]interface{}{"pheromone": 0.0},
+	})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.DiffuseWithSettings("pheromone", model.DiffusionSettings{Rate: .5, Evaporation: .01})
+	}
+}
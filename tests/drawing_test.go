@@ -0,0 +1,218 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/loader"
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func TestPenDownLeavesTrail(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(1, nil)
+	turtle := m.Turtle(0)
+	turtle.SetHeading(0)
+
+	turtle.Forward(2)
+	if len(m.Drawing().Segments()) != 0 {
+		t.Errorf("Expected no trail with the pen up, got %d segments", len(m.Drawing().Segments()))
+	}
+
+	turtle.PenDown()
+	turtle.SetPenSize(3)
+	turtle.Color = model.Red
+	turtle.Forward(2)
+
+	segments := m.Drawing().Segments()
+	if len(segments) != 2 {
+		t.Fatalf("Expected 2 segments, got %d", len(segments))
+	}
+
+	if !closeTo(segments[0].X1, 2) || !closeTo(segments[1].X2, 4) {
+		t.Errorf("Expected the trail to go from 2 to 4, got %f to %f", segments[0].X1, segments[1].X2)
+	}
+
+	if segments[0].Size != 3 || segments[0].Color != model.Red {
+		t.Errorf("Expected the trail to use the pen size and turtle color")
+	}
+
+	turtle.PenUp()
+	turtle.Forward(1)
+	if len(m.Drawing().Segments()) != 2 {
+		t.Errorf("Expected the trail to stop when the pen is up")
+	}
+}
+
+func TestPenTrailWraps(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		WrappingX: true,
+		WrappingY: true,
+	})
+
+	m.CreateTurtles(1, nil)
+	turtle := m.Turtle(0)
+	turtle.SetHeading(0)
+	turtle.SetXY(15, 0)
+	turtle.PenDown()
+
+	turtle.Forward(1)
+
+	segments := m.Drawing().Segments()
+	if len(segments) != 2 {
+		t.Fatalf("Expected the trail to be split at the edge into 2 segments, got %d", len(segments))
+	}
+
+	if !closeTo(segments[0].X1, 15) || !closeTo(segments[0].X2, 15.5) {
+		t.Errorf("Expected the first segment to go from 15 to 15.5, got %f to %f", segments[0].X1, segments[0].X2)
+	}
+
+	if !closeTo(segments[1].X1, -15.5) || !closeTo(segments[1].X2, -15) {
+		t.Errorf("Expected the second segment to go from -15.5 to -15, got %f to %f", segments[1].X1, segments[1].X2)
+	}
+
+	// a move further than the world is wide crosses the edge more than once
+	turtle.PenUp()
+	turtle.SetXY(15, 0)
+	turtle.PenDown()
+	m.ClearDrawing()
+	turtle.SetXY(85, 0)
+
+	segments = m.Drawing().Segments()
+	expected := [][2]float64{{15, 15.5}, {-15.5, 15.5}, {-15.5, 15.5}, {-15.5, -8}}
+	if len(segments) != len(expected) {
+		t.Fatalf("Expected the trail to be split into %d segments, got %d", len(expected), len(segments))
+	}
+	for i, segment := range segments {
+		if !closeTo(segment.X1, expected[i][0]) || !closeTo(segment.X2, expected[i][1]) {
+			t.Errorf("Expected segment %d to go from %f to %f, got %f to %f", i, expected[i][0], expected[i][1], segment.X1, segment.X2)
+		}
+	}
+}
+
+func TestDrawingIsBounded(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MaxDrawingSegments: 5,
+	})
+
+	m.CreateTurtles(1, nil)
+	turtle := m.Turtle(0)
+	turtle.SetHeading(0)
+	turtle.PenDown()
+
+	turtle.Forward(8)
+
+	segments := m.Drawing().Segments()
+	if len(segments) != 5 {
+		t.Fatalf("Expected the drawing to hold 5 segments, got %d", len(segments))
+	}
+
+	// the oldest segments should have been dropped
+	if !closeTo(segments[0].X1, 3) || !closeTo(segments[4].X2, 8) {
+		t.Errorf("Expected the newest segments to be kept in order, got %f to %f", segments[0].X1, segments[4].X2)
+	}
+}
+
+func TestStampAndClearDrawing(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(2, nil)
+	m.Turtle(1).SetXY(3, 4)
+	m.Turtle(1).Shape = "triangle"
+	m.Turtle(1).SetHeading(90)
+
+	link, _ := m.Turtle(0).CreateLinkWithTurtle(nil, m.Turtle(1), nil)
+
+	version := m.Drawing().Version()
+
+	m.Turtle(1).Stamp()
+	link.Stamp()
+
+	if m.Drawing().Version() == version {
+		t.Errorf("Expected the drawing version to change")
+	}
+
+	stamps := m.Drawing().Stamps()
+	if len(stamps) != 1 {
+		t.Fatalf("Expected 1 stamp, got %d", len(stamps))
+	}
+	if stamps[0].X != 3 || stamps[0].Y != 4 || stamps[0].Shape != "triangle" || !closeTo(stamps[0].Heading, 90) {
+		t.Errorf("Expected the stamp to match the turtle, got %+v", stamps[0])
+	}
+
+	segments := m.Drawing().Segments()
+	if len(segments) != 1 || segments[0].X2 != 3 || segments[0].Y2 != 4 {
+		t.Errorf("Expected the link stamp to be a segment between the ends")
+	}
+
+	m.ClearDrawing()
+	if len(m.Drawing().Stamps()) != 0 || len(m.Drawing().Segments()) != 0 {
+		t.Errorf("Expected the drawing to be cleared")
+	}
+
+	// during the movement phase stamps are left where the turtle was when the phase began
+	m.BeginMovementPhase()
+	m.Turtle(1).SetXY(5, 5)
+	m.Turtle(1).Stamp()
+	m.CommitMovementPhase()
+	if stamps := m.Drawing().Stamps(); len(stamps) != 1 || stamps[0].X != 3 || stamps[0].Y != 4 {
+		t.Errorf("Expected the stamp to be at the position from before the phase, got %+v", stamps)
+	}
+	m.ClearDrawing()
+
+	// stamping with dead agents does nothing
+	dead := m.Turtle(1)
+	m.KillTurtle(dead)
+	dead.Stamp()
+	link.Stamp()
+	if len(m.Drawing().Stamps()) != 0 || len(m.Drawing().Segments()) != 0 {
+		t.Errorf("Expected dead agents not to stamp")
+	}
+
+	m.Turtle(0).Stamp()
+	m.ClearAll()
+	if len(m.Drawing().Stamps()) != 0 {
+		t.Errorf("Expected ClearAll to clear the drawing")
+	}
+}
+
+func TestDrawingSaveAndLoad(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MaxDrawingSegments: 50,
+	})
+
+	m.CreateTurtles(2, nil)
+	m.Turtle(0).SetHeading(0)
+	m.Turtle(0).PenDown()
+	m.Turtle(0).SetPenSize(2)
+	m.Turtle(0).Forward(3)
+	m.Turtle(1).Stamp()
+
+	bytes, err := json.Marshal(loader.GetModel(m))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	saved := &loader.Model{}
+	if err := json.Unmarshal(bytes, saved); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	loaded := loader.SetModel(saved)
+
+	if len(loaded.Drawing().Segments()) != 3 {
+		t.Errorf("Expected 3 segments after loading, got %d", len(loaded.Drawing().Segments()))
+	}
+
+	if len(loaded.Drawing().Stamps()) != 1 {
+		t.Errorf("Expected 1 stamp after loading, got %d", len(loaded.Drawing().Stamps()))
+	}
+
+	if loaded.Drawing().MaxSegments() != 50 {
+		t.Errorf("Expected the drawing bound to be restored, got %d", loaded.Drawing().MaxSegments())
+	}
+
+	if !loaded.Turtle(0).IsPenDown() || loaded.Turtle(0).GetPenSize() != 2 {
+		t.Errorf("Expected the pen to be restored")
+	}
+}
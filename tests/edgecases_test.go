@@ -17,11 +17,11 @@ func TestTurtlesInRadiusZeroRadius(t *testing.T) {
 	turtle := m.Turtle(0)
 	turtle.SetXY(5, 5)
 
-	// Query with radius 0 - returns empty set (radius 0 means infinitesimally small circle)
+	// Query with radius 0 - like NetLogo's in-radius 0 only the turtles exactly at the point are returned
 	result := m.TurtlesInRadiusXY(5, 5, 0)
 
-	if result.Count() != 0 {
-		t.Errorf("Expected 0 turtles with radius 0, got %d", result.Count())
+	if result.Count() != 1 || !result.Contains(turtle) {
+		t.Errorf("Expected only the turtle at the point with radius 0, got %d", result.Count())
 	}
 }
 
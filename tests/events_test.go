@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/loader"
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func TestScheduleEventFiresOnTick(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	fired := []float64{}
+	record := func(e *model.Event) {
+		fired = append(fired, e.Model().Clock())
+	}
+
+	m.ScheduleEvent(model.EventSettings{Time: 2, Operation: record})
+	m.ScheduleEvent(model.EventSettings{Time: 1.5, Operation: record})
+
+	m.Tick()
+	if len(fired) != 0 {
+		t.Errorf("Expected no events to fire at tick 1, got %d", len(fired))
+	}
+
+	m.Tick()
+	if len(fired) != 2 {
+		t.Fatalf("Expected 2 events to fire by tick 2, got %d", len(fired))
+	}
+
+	if fired[0] != 1.5 || fired[1] != 2 {
+		t.Errorf("Expected events to fire at 1.5 and 2, got %v", fired)
+	}
+
+	if m.Clock() != 2 {
+		t.Errorf("Expected the clock to be 2, got %f", m.Clock())
+	}
+}
+
+func TestScheduleEventSameTimeOrder(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	order := []int{}
+	for i := 0; i < 5; i++ {
+		i := i
+		m.ScheduleEvent(model.EventSettings{
+			Time: 3,
+			Operation: func(e *model.Event) {
+				order = append(order, i)
+			},
+		})
+	}
+
+	m.RunEvents(3)
+
+	for i, v := range order {
+		if v != i {
+			t.Fatalf("Expected events at the same time to fire in the order they were scheduled, got %v", order)
+		}
+	}
+}
+
+func TestScheduleEventErrors(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	_, err := m.ScheduleEvent(model.EventSettings{Time: 1})
+	if err != model.ErrEventNoOperation {
+		t.Errorf("Expected an error for an event with no operation")
+	}
+
+	m.TickAdvance(5)
+
+	_, err = m.ScheduleEvent(model.EventSettings{Time: 1, Name: "test"})
+	if err != model.ErrEventInPast {
+		t.Errorf("Expected an error for an event in the past")
+	}
+
+	_, err = m.ScheduleEvent(model.EventSettings{Time: 6, Interval: -1, Name: "test"})
+	if err != model.ErrEventBadInterval {
+		t.Errorf("Expected an error for a negative interval")
+	}
+}
+
+func TestRepeatingEventAndCancel(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	count := 0
+	e, err := m.ScheduleEvent(model.EventSettings{
+		Time:     0.5,
+		Interval: 0.5,
+		Operation: func(e *model.Event) {
+			count++
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	m.TickAdvance(2)
+	if count != 4 {
+		t.Errorf("Expected repeating event to fire 4 times, got %d", count)
+	}
+
+	e.Cancel()
+	m.TickAdvance(2)
+	if count != 4 {
+		t.Errorf("Expected cancelled event to stop firing, got %d", count)
+	}
+
+	if len(m.Events()) != 0 {
+		t.Errorf("Expected no events on the schedule, got %d", len(m.Events()))
+	}
+}
+
+func TestEventBoundToDeadTurtle(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(2, nil)
+
+	fired := 0
+	m.RegisterEventHandler("gestation", func(e *model.Event) {
+		fired++
+	})
+
+	m.ScheduleEventIn(3, model.EventSettings{Name: "gestation", Turtle: m.Turtle(0)})
+	m.ScheduleEventIn(3, model.EventSettings{Name: "gestation", Turtle: m.Turtle(1)})
+
+	m.Turtle(0).Die()
+
+	m.TickAdvance(3)
+
+	if fired != 1 {
+		t.Errorf("Expected only the event of the living turtle to fire, got %d", fired)
+	}
+}
+
+func TestEventsSurviveSaveAndLoad(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(3, nil)
+
+	m.RegisterEventHandler("regrow", nil)
+	m.Tick()
+	m.ScheduleEvent(model.EventSettings{Name: "regrow", Time: 4, Patch: m.Patch(1, 1)})
+	m.ScheduleEvent(model.EventSettings{Name: "regrow", Time: 2.5, Interval: 1, Turtle: m.Turtle(2)})
+	m.ScheduleEvent(model.EventSettings{Time: 3, Operation: func(e *model.Event) {}})
+
+	// round trip through json
+	bytes, err := json.Marshal(loader.GetModel(m))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	saved := &loader.Model{}
+	if err := json.Unmarshal(bytes, saved); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	loaded := loader.SetModel(saved)
+
+	if loaded.Clock() != 1 {
+		t.Errorf("Expected the clock to be restored, got %f", loaded.Clock())
+	}
+
+	events := loaded.Events()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 named events to be restored, got %d", len(events))
+	}
+
+	if events[0].Time() != 2.5 || events[0].Interval() != 1 || events[0].Turtle() != loaded.Turtle(2) {
+		t.Errorf("Expected the repeating turtle event to be restored first")
+	}
+
+	if events[1].Time() != 4 || events[1].Patch() != loaded.Patch(1, 1) {
+		t.Errorf("Expected the patch event to be restored second")
+	}
+
+	// handlers are registered after loading
+	fired := 0
+	loaded.RegisterEventHandler("regrow", func(e *model.Event) {
+		fired++
+	})
+	loaded.TickAdvance(3)
+	if fired != 3 {
+		t.Errorf("Expected 3 events to fire after loading, got %d", fired)
+	}
+}
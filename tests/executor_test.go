@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/concurrency"
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func TestExecutorAsk(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	m.CreateTurtles(1000, nil)
+	executor := concurrency.NewExecutor(concurrency.ExecutorSettings{Workers: 4})
+	defer executor.Close()
+
+	if executor.Workers() != 4 {
+		t.Errorf("Expected 4 workers, got %d", executor.Workers())
+	}
+
+	// asking many times reuses the same goroutines
+	for i := 0; i < 50; i++ {
+		var count atomic.Int64
+		err := executor.AskTurtles(context.Background(), m.Turtles().List(), func(turtle *model.Turtle) {
+			count.Add(1)
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if count.Load() != 1000 {
+			t.Fatalf("Expected 1000 turtles asked, got %d", count.Load())
+		}
+	}
+
+	var patches atomic.Int64
+	executor.AskPatches(context.Background(), m.Patches.List(), func(patch *model.Patch) {
+		patches.Add(1)
+	})
+	if int(patches.Load()) != m.Patches.Count() {
+		t.Errorf("Expected every patch asked, got %d", patches.Load())
+	}
+
+	if err := executor.AskLinks(context.Background(), nil, func(link *model.Link) {}); err != nil {
+		t.Errorf("Expected no error asking no links, got %v", err)
+	}
+}
+
+func TestExecutorNestedAsk(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	m.CreateTurtles(40, nil)
+	executor := concurrency.NewExecutor(concurrency.ExecutorSettings{Workers: 2, ChunkSize: 1})
+	defer executor.Close()
+
+	// every goroutine is busy with the outer ask so the inner asks have to be run by the goroutine asking
+	var count atomic.Int64
+	err := executor.AskTurtles(context.Background(), m.Turtles().List(), func(turtle *model.Turtle) {
+		executor.AskTurtles(context.Background(), m.Turtles().FirstNOf(5).List(), func(turtle *model.Turtle) {
+			count.Add(1)
+		})
+	})
+	if err != nil || count.Load() != 200 {
+		t.Errorf("Expected 200 nested asks, got %d %v", count.Load(), err)
+	}
+}
+
+func TestExecutorPanic(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	m.CreateTurtles(100, nil)
+	executor := concurrency.NewExecutor(concurrency.ExecutorSettings{Workers: 3, ChunkSize: 10})
+	defer executor.Close()
+
+	boom := errors.New("boom")
+	err := executor.AskTurtles(context.Background(), m.Turtles().List(), func(turtle *model.Turtle) {
+		if turtle.Who() == 42 {
+			panic(boom)
+		}
+	})
+
+	var panicErr *concurrency.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Expected a PanicError, got %v", err)
+	}
+	if panicErr.AgentID != 42 || panicErr.Agent.(*model.Turtle) != m.Turtle(42) {
+		t.Errorf("Expected the panic to be on turtle 42, got %d", panicErr.AgentID)
+	}
+	if !errors.Is(err, boom) || len(panicErr.Stack) == 0 {
+		t.Errorf("Expected the panic value and stack to be kept")
+	}
+
+	// the executor still works after a panic
+	if err := executor.AskTurtles(context.Background(), m.Turtles().List(), func(turtle *model.Turtle) {}); err != nil {
+		t.Errorf("Expected no error after a panic, got %v", err)
+	}
+}
+
+func TestExecutorCancel(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	m.CreateTurtles(100, nil)
+	executor := concurrency.NewExecutor(concurrency.ExecutorSettings{Workers: 1, ChunkSize: 1})
+	defer executor.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var count atomic.Int64
+	err := executor.AskTurtles(ctx, m.Turtles().List(), func(turtle *model.Turtle) {
+		if count.Add(1) == 10 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Errorf("Expected the ask to be cancelled, got %v", err)
+	}
+	if count.Load() >= 100 {
+		t.Errorf("Expected turtles after the cancel to be skipped")
+	}
+}
+
+func TestExecutorClose(t *testing.T) {
+	executor := concurrency.NewExecutor(concurrency.ExecutorSettings{})
+	executor.Close()
+	executor.Close()
+
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	m.CreateTurtles(3, nil)
+	err := executor.AskTurtles(context.Background(), m.Turtles().List(), func(turtle *model.Turtle) {})
+	if err != concurrency.ErrExecutorClosed {
+		t.Errorf("Expected ErrExecutorClosed, got %v", err)
+	}
+}
+
+// a small amount of work on each turtle, asked 50 times like a tick with many stages
+func benchmarkAsks(b *testing.B, ask func(turtles []*model.Turtle, operation model.TurtleOperation)) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	m.CreateTurtles(2000, nil)
+	turtles := m.Turtles().List()
+	var sink atomic.Int64
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 50; j++ {
+			ask(turtles, func(turtle *model.Turtle) {
+				sink.Add(int64(turtle.Who()))
+			})
+		}
+	}
+}
+
+func BenchmarkAskTurtlesPerCall(b *testing.B) {
+	benchmarkAsks(b, func(turtles []*model.Turtle, operation model.TurtleOperation) {
+		concurrency.AskTurtles(turtles, operation, 8)
+	})
+}
+
+func BenchmarkAskTurtlesExecutor(b *testing.B) {
+	executor := concurrency.NewExecutor(concurrency.ExecutorSettings{Workers: 8})
+	defer executor.Close()
+	benchmarkAsks(b, func(turtles []*model.Turtle, operation model.TurtleOperation) {
+		executor.AskTurtles(context.Background(), turtles, operation)
+	})
+}
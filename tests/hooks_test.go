@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func TestHooksTurtleBorn(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{RandomSeed: 1})
+
+	born := []int{}
+	m.Subscribe(model.HookTurtleBorn, func(h *model.Hook) {
+		if h.Kind != model.HookTurtleBorn || h.Model != m {
+			t.Errorf("Expected a birth hook for the model, got kind %v", h.Kind)
+		}
+		born = append(born, h.Turtle.Who())
+	})
+
+	// hooks run after the operation so they see the turtles as the operation set them up
+	m.CreateTurtles(2, func(turtle *model.Turtle) {
+		if slices.Contains(born, turtle.Who()) {
+			t.Errorf("Expected the birth hook to run after the operation for turtle %d", turtle.Who())
+		}
+	})
+	m.Turtle(0).Hatch(2, nil)
+	m.Patch(0, 0).Sprout(1, nil)
+
+	if !slices.Equal(born, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("Expected turtles 0 to 4 to be born in order, got %v", born)
+	}
+}
+
+func TestHooksTurtleBornAfterOperation(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:       1,
+		TurtleProperties: map[string]interface{}{"energy": 0},
+	})
+
+	born := []int{}
+	m.Subscribe(model.HookTurtleBorn, func(h *model.Hook) {
+		if h.Turtle.GetProperty("energy") != 5 {
+			t.Errorf("Expected the birth hook to see the energy the operation set, got %v", h.Turtle.GetProperty("energy"))
+		}
+		born = append(born, h.Turtle.Who())
+	})
+
+	// the operation kills every other turtle, those are never born
+	setUp := func(turtle *model.Turtle) {
+		turtle.SetProperty("energy", 5)
+		if turtle.Who()%2 == 1 {
+			m.KillTurtle(turtle)
+		}
+	}
+	m.CreateTurtles(2, setUp)
+	m.Turtle(0).Hatch(2, setUp)
+	m.Patch(0, 0).Sprout(2, setUp)
+
+	if !slices.Equal(born, []int{0, 2, 4}) {
+		t.Errorf("Expected only the turtles that lived through the operation to be born, got %v", born)
+	}
+}
+
+func TestHooksTurtleDiedKillsLinks(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{RandomSeed: 1})
+	m.CreateTurtles(3, nil)
+
+	created := 0
+	m.Subscribe(model.HookLinkCreated, func(h *model.Hook) {
+		if h.Link == nil || h.Link.End1() != m.Turtle(0) {
+			t.Errorf("Expected the link hook to get the new link")
+		}
+		created++
+	})
+
+	m.Turtle(0).CreateLinkToTurtle(nil, m.Turtle(1), nil)
+	m.Turtle(0).CreateLinkWithTurtle(nil, m.Turtle(2), nil)
+	if created != 2 {
+		t.Errorf("Expected 2 link creations, got %d", created)
+	}
+
+	events := []string{}
+	m.Subscribe(model.HookTurtleDied, func(h *model.Hook) {
+		// the turtle can still be read while the hook runs
+		if h.Turtle.Who() != 0 {
+			t.Errorf("Expected turtle 0 to die, got %d", h.Turtle.Who())
+		}
+		events = append(events, "turtle")
+	})
+	m.Subscribe(model.HookLinkDied, func(h *model.Hook) {
+		if h.Link.End1() == nil {
+			t.Errorf("Expected the link to still have its ends while the hook runs")
+		}
+		events = append(events, "link")
+	})
+
+	m.KillTurtle(m.Turtle(0))
+
+	if !slices.Equal(events, []string{"turtle", "link", "link"}) {
+		t.Errorf("Expected the turtle's death followed by its 2 links, got %v", events)
+	}
+}
+
+func TestHooksBreedChanged(t *testing.T) {
+	wolves := model.NewTurtleBreed("wolves", "", nil)
+	sheep := model.NewTurtleBreed("sheep", "", nil)
+	roads := model.NewLinkBreed("roads")
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:         1,
+		TurtleBreeds:       []*model.TurtleBreed{wolves, sheep},
+		DirectedLinkBreeds: []*model.LinkBreed{roads},
+	})
+
+	var oldBreed, newBreed *model.TurtleBreed
+	calls := 0
+	m.Subscribe(model.HookTurtleBreedChanged, func(h *model.Hook) {
+		oldBreed, newBreed = h.OldTurtleBreed, h.NewTurtleBreed
+		calls++
+	})
+
+	wolves.CreateAgents(1, nil)
+	turtle := wolves.Agent(0)
+	turtle.SetBreed(sheep)
+	if oldBreed != wolves || newBreed != sheep {
+		t.Errorf("Expected the breed to change from wolves to sheep")
+	}
+	if !sheep.Agents().Contains(turtle) || wolves.Agents().Contains(turtle) {
+		t.Errorf("Expected the turtle to be moved to the new breed before the hook runs")
+	}
+
+	// setting the same breed is not a change
+	turtle.SetBreed(sheep)
+	if calls != 1 {
+		t.Errorf("Expected 1 breed change, got %d", calls)
+	}
+
+	m.CreateTurtles(1, nil)
+	link, _ := turtle.CreateLinkToTurtle(nil, m.Turtle(1), nil)
+
+	var oldLinkBreed, newLinkBreed *model.LinkBreed
+	m.Subscribe(model.HookLinkBreedChanged, func(h *model.Hook) {
+		oldLinkBreed, newLinkBreed = h.OldLinkBreed, h.NewLinkBreed
+	})
+	link.SetBreed(roads)
+	if oldLinkBreed == nil || oldLinkBreed.Name() != "" || newLinkBreed != roads {
+		t.Errorf("Expected the link breed to change from the general breed to roads")
+	}
+}
+
+func TestHooksTickAndOrder(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{RandomSeed: 1})
+
+	calls := []string{}
+	first := m.Subscribe(model.HookTick, func(h *model.Hook) {
+		calls = append(calls, "first")
+		if h.Ticks != m.Ticks {
+			t.Errorf("Expected the hook to get the new tick %d, got %d", m.Ticks, h.Ticks)
+		}
+	})
+	m.Subscribe(model.HookTick, func(h *model.Hook) {
+		calls = append(calls, "second")
+	})
+	m.Subscribe(model.HookTurtleBorn, func(h *model.Hook) {
+		calls = append(calls, "born")
+	})
+
+	m.Tick()
+	m.TickAdvance(3)
+
+	if !slices.Equal(calls, []string{"first", "second", "first", "second"}) {
+		t.Errorf("Expected the tick hooks to run in the order they were subscribed, got %v", calls)
+	}
+	if m.Ticks != 4 {
+		t.Errorf("Expected 4 ticks, got %d", m.Ticks)
+	}
+
+	if !m.Unsubscribe(first) {
+		t.Errorf("Expected the first hook to be unsubscribed")
+	}
+	if m.Unsubscribe(first) {
+		t.Errorf("Expected unsubscribing twice to return false")
+	}
+
+	calls = calls[:0]
+	m.Tick()
+	if !slices.Equal(calls, []string{"second"}) {
+		t.Errorf("Expected only the second hook to run, got %v", calls)
+	}
+}
+
+func TestHooksSubscribeFromHook(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{RandomSeed: 1})
+
+	calls := 0
+	var id int
+	id = m.Subscribe(model.HookTick, func(h *model.Hook) {
+		// a hook subscribed while handling a change only runs from the next change
+		h.Model.Subscribe(model.HookTick, func(h *model.Hook) {
+			calls++
+		})
+		h.Model.Unsubscribe(id)
+	})
+
+	m.Tick()
+	if calls != 0 {
+		t.Errorf("Expected the new hook not to run for the tick it was subscribed in, got %d", calls)
+	}
+	m.Tick()
+	if calls != 1 {
+		t.Errorf("Expected the new hook to run once, got %d", calls)
+	}
+}
+
+func TestHooksDuringParallelAsk(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{RandomSeed: 1})
+	m.CreateTurtles(200, nil)
+
+	var mu sync.Mutex
+	ticks := 0
+	m.Turtles().AskParallel(model.ParallelSettings{Workers: 4, ChunkSize: 8}, func(turtle *model.Turtle, r *rand.Rand) {
+		id := m.Subscribe(model.HookTick, func(h *model.Hook) {
+			mu.Lock()
+			ticks++
+			mu.Unlock()
+		})
+		if turtle.Who()%2 == 0 {
+			m.Unsubscribe(id)
+		}
+	})
+
+	m.Tick()
+	if ticks != 100 {
+		t.Errorf("Expected the 100 hooks still subscribed to run, got %d", ticks)
+	}
+}
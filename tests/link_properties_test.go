@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/loader"
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func TestLinkProperties(t *testing.T) {
+
+	roads := model.NewLinkBreedWithProperties("roads", map[string]interface{}{
+		"capacity": 10,
+		"name":     "main",
+	})
+
+	m := model.NewModel(model.ModelSettings{
+		LinkProperties: map[string]interface{}{
+			"weight": 1.5,
+		},
+		UndirectedLinkBreeds: []*model.LinkBreed{roads},
+	})
+
+	m.CreateTurtles(3, nil)
+
+	general, _ := m.Turtle(0).CreateLinkWithTurtle(nil, m.Turtle(1), nil)
+	road, _ := m.Turtle(1).CreateLinkWithTurtle(roads, m.Turtle(2), nil)
+
+	if w, err := general.GetPropF("weight"); err != nil || w != 1.5 {
+		t.Errorf("Expected general link to have weight 1.5, got %v %v", w, err)
+	}
+
+	if _, err := general.GetPropI("capacity"); err == nil {
+		t.Errorf("Expected general link to not have the breed property capacity")
+	}
+
+	if w, err := road.GetPropF("weight"); err != nil || w != 1.5 {
+		t.Errorf("Expected road link to have weight 1.5, got %v %v", w, err)
+	}
+
+	if c, err := road.GetPropI("capacity"); err != nil || c != 10 {
+		t.Errorf("Expected road link to have capacity 10, got %v %v", c, err)
+	}
+
+	if n, err := road.GetPropS("name"); err != nil || n != "main" {
+		t.Errorf("Expected road link to have name main, got %v %v", n, err)
+	}
+
+	road.SetProperty("capacity", 20)
+	if c, _ := road.GetPropI("capacity"); c != 20 {
+		t.Errorf("Expected road link capacity to be 20, got %d", c)
+	}
+
+	// setting a property on one link shouldn't affect the defaults
+	road2, _ := m.Turtle(0).CreateLinkWithTurtle(roads, m.Turtle(2), nil)
+	if c, _ := road2.GetPropI("capacity"); c != 10 {
+		t.Errorf("Expected new road link capacity to be 10, got %d", c)
+	}
+
+	// undeclared properties can't be set
+	road.SetProperty("speed", 5)
+	if road.GetProperty("speed") != nil {
+		t.Errorf("Expected undeclared property to not be set")
+	}
+}
+
+func TestLinkPropertiesSetBreed(t *testing.T) {
+
+	roads := model.NewLinkBreedWithProperties("roads", map[string]interface{}{
+		"capacity": 10,
+	})
+	rails := model.NewLinkBreedWithProperties("rails", map[string]interface{}{
+		"gauge": 1.435,
+	})
+
+	m := model.NewModel(model.ModelSettings{
+		LinkProperties: map[string]interface{}{
+			"weight": 1.0,
+		},
+		UndirectedLinkBreeds: []*model.LinkBreed{roads, rails},
+	})
+
+	m.CreateTurtles(2, nil)
+
+	l, _ := m.Turtle(0).CreateLinkWithTurtle(roads, m.Turtle(1), nil)
+	l.SetProperty("weight", 3.0)
+
+	l.SetBreed(rails)
+
+	if l.GetProperty("capacity") != nil {
+		t.Errorf("Expected the old breed's properties to be removed")
+	}
+
+	if g, err := l.GetPropF("gauge"); err != nil || g != 1.435 {
+		t.Errorf("Expected the new breed's properties to be set, got %v %v", g, err)
+	}
+
+	if w, _ := l.GetPropF("weight"); w != 3.0 {
+		t.Errorf("Expected general properties to be kept when changing breed, got %f", w)
+	}
+}
+
+func TestLinkPropertiesSaveAndLoad(t *testing.T) {
+
+	roads := model.NewLinkBreedWithProperties("roads", map[string]interface{}{
+		"capacity": 10,
+	})
+	follows := model.NewLinkBreed("follows")
+
+	m := model.NewModel(model.ModelSettings{
+		LinkProperties: map[string]interface{}{
+			"weight": 1.0,
+		},
+		UndirectedLinkBreeds: []*model.LinkBreed{roads},
+		DirectedLinkBreeds:   []*model.LinkBreed{follows},
+	})
+
+	m.CreateTurtles(3, nil)
+
+	road, _ := m.Turtle(0).CreateLinkWithTurtle(roads, m.Turtle(1), nil)
+	road.SetProperty("capacity", 25)
+	road.SetProperty("weight", 2.0)
+
+	follow, _ := m.Turtle(1).CreateLinkToTurtle(follows, m.Turtle(2), nil)
+	follow.SetProperty("weight", 0.5)
+
+	// round trip through json
+	bytes, err := json.Marshal(loader.GetModel(m))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	saved := &loader.Model{}
+	if err := json.Unmarshal(bytes, saved); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	loaded := loader.SetModel(saved)
+
+	loadedRoad := loaded.Turtle(0).LinkWith(loaded.UndirectedLinkBreed("roads"), loaded.Turtle(1))
+	if loadedRoad == nil {
+		t.Fatalf("Expected road link to be restored")
+	}
+
+	if c, _ := loadedRoad.GetPropI("capacity"); c != 25 {
+		t.Errorf("Expected capacity to be restored as 25, got %d", c)
+	}
+
+	if w, _ := loadedRoad.GetPropF("weight"); w != 2.0 {
+		t.Errorf("Expected weight to be restored as 2, got %f", w)
+	}
+
+	loadedFollow := loaded.Turtle(1).LinkTo(loaded.DirectedLinkBreed("follows"), loaded.Turtle(2))
+	if loadedFollow == nil {
+		t.Fatalf("Expected follows link to be restored")
+	}
+
+	if w, _ := loadedFollow.GetPropF("weight"); w != 0.5 {
+		t.Errorf("Expected weight to be restored as 0.5, got %f", w)
+	}
+
+	// new links should get the restored breed defaults
+	newRoad, _ := loaded.Turtle(1).CreateLinkWithTurtle(loaded.UndirectedLinkBreed("roads"), loaded.Turtle(2), nil)
+	if c, _ := newRoad.GetPropI("capacity"); c != 10 {
+		t.Errorf("Expected the breed default capacity to be restored as 10, got %d", c)
+	}
+}
@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/concurrency"
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// these tests are meant to be run with -race as well
+
+func TestMovementPhaseSnapshot(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:           5,
+		SpatialIndexCellSize: 2,
+	})
+	m.CreateTurtles(3, func(turtle *model.Turtle) {
+		turtle.SetHeading(0)
+	})
+	turtle := m.Turtle(0)
+	start := m.Patch(0, 0)
+
+	m.BeginMovementPhase()
+	if !m.InMovementPhase() {
+		t.Fatalf("Expected to be in the movement phase")
+	}
+
+	// the turtle's own moves build on each other
+	turtle.Forward(1)
+	turtle.Forward(1)
+
+	if turtle.XCor() != 0 || turtle.PatchHere() != start {
+		t.Errorf("Expected the turtle to read as being at the start until the commit, got %v", turtle.XCor())
+	}
+	if start.TurtlesHere().Count() != 3 {
+		t.Errorf("Expected 3 turtles still on the start patch, got %d", start.TurtlesHere().Count())
+	}
+	if m.TurtlesOnPatch(m.Patch(2, 0)).Count() != 0 {
+		t.Errorf("Expected no turtles on the new patch until the commit")
+	}
+
+	m.CommitMovementPhase()
+	if m.InMovementPhase() {
+		t.Errorf("Expected the movement phase to be over")
+	}
+
+	if turtle.XCor() != 2 || turtle.PatchHere() != m.Patch(2, 0) {
+		t.Errorf("Expected the turtle at 2 0 after the commit, got %v %v", turtle.XCor(), turtle.YCor())
+	}
+	if start.TurtlesHere().Count() != 2 || m.Patch(2, 0).TurtlesHere().Count() != 1 {
+		t.Errorf("Expected the patches to be updated by the commit")
+	}
+	nearest, _ := m.NearestTurtlesXY(2, 0, 1).First()
+	if nearest != turtle {
+		t.Errorf("Expected the spatial index to be updated by the commit")
+	}
+
+	// outside of the phase turtles move right away
+	turtle.Forward(1)
+	if turtle.XCor() != 3 || turtle.PatchHere() != m.Patch(3, 0) {
+		t.Errorf("Expected the turtle to move right away outside of the phase")
+	}
+
+	// committing without a phase does nothing
+	m.CommitMovementPhase()
+}
+
+func TestMovementPhaseParallel(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:           5,
+		SpatialIndexCellSize: 2,
+	})
+	m.CreateTurtles(400, nil)
+
+	// every turtle moves a random distance while reading where the others are
+	var seen atomic.Int64
+	m.Turtles().AskParallel(model.ParallelSettings{Workers: 8, ChunkSize: 10, DeferMovement: true}, func(turtle *model.Turtle, r *rand.Rand) {
+		other := m.Turtle((turtle.Who() + 1) % 400)
+		if other.XCor() != 0 || other.YCor() != 0 {
+			t.Errorf("Expected every turtle to read as being at the start during the phase")
+		}
+		seen.Add(int64(other.PatchHere().TurtlesHere().Count()))
+
+		turtle.SetHeading(float64(r.IntN(360)))
+		turtle.Forward(float64(r.IntN(5)))
+	})
+
+	if seen.Load() != 400*400 {
+		t.Errorf("Expected every turtle to see all turtles on the start patch, got %d", seen.Load())
+	}
+	if m.InMovementPhase() {
+		t.Errorf("Expected the parallel ask to commit the movement")
+	}
+
+	// every turtle is on the patch at its position and counted once
+	total := 0
+	m.Patches.Ask(func(p *model.Patch) {
+		total += p.TurtlesHere().Count()
+	})
+	if total != 400 {
+		t.Errorf("Expected 400 turtles on patches, got %d", total)
+	}
+	m.Turtles().Ask(func(turtle *model.Turtle) {
+		if turtle.PatchHere() != m.Patch(turtle.XCor(), turtle.YCor()) || !turtle.PatchHere().TurtlesHere().Contains(turtle) {
+			t.Errorf("Expected turtle %d to be on the patch at its position", turtle.Who())
+		}
+	})
+}
+
+func TestMovementPhaseQueries(t *testing.T) {
+	// with and without the spatial index since they look for turtles differently
+	for _, cellSize := range []float64{0, 2} {
+		m := model.NewModel(model.ModelSettings{
+			RandomSeed:           5,
+			SpatialIndexCellSize: cellSize,
+		})
+		m.CreateTurtles(200, nil)
+
+		// every turtle moves away while the queries still see them all at the start
+		var wrong atomic.Int64
+		m.Turtles().AskParallel(model.ParallelSettings{Workers: 8, ChunkSize: 10, DeferMovement: true}, func(turtle *model.Turtle, r *rand.Rand) {
+			turtle.SetHeading(float64(r.IntN(360)))
+			turtle.Forward(float64(1 + r.IntN(5)))
+
+			other := m.Turtle((turtle.Who() + 1) % 200)
+			if turtle.DistanceTurtle(other) != 0 || other.DistanceXY(0, 0) != 0 || m.Patch(0, 0).DistanceTurtle(other) != 0 {
+				wrong.Add(1)
+			}
+			if m.TurtlesInRadiusXY(0, 0, 0.5).Count() != 200 || m.Turtles().InRadius(0, 0, 0, 0.5, false).Count() != 200 {
+				wrong.Add(1)
+			}
+			if turtle.NearestTurtles(3).Count() != 3 || turtle.InCone(0.5, 90).Count() != 200 {
+				wrong.Add(1)
+			}
+		})
+
+		if wrong.Load() != 0 {
+			t.Errorf("Expected every query to see the turtles at the start during the phase with cell size %v, %d were wrong", cellSize, wrong.Load())
+		}
+		if m.TurtlesInRadiusXY(0, 0, 0.5).Count() != 0 {
+			t.Errorf("Expected the turtles to have moved after the commit with cell size %v", cellSize)
+		}
+	}
+}
+
+func TestMovementPhaseExecutor(t *testing.T) {
+	positions := func(workers int) []float64 {
+		m := model.NewModel(model.ModelSettings{
+			RandomSeed:           5,
+			SpatialIndexCellSize: 2,
+		})
+		m.CreateTurtles(200, nil)
+		executor := concurrency.NewExecutor(concurrency.ExecutorSettings{Workers: workers, ChunkSize: 7})
+		defer executor.Close()
+
+		m.BeginMovementPhase()
+		err := executor.AskTurtles(context.Background(), m.Turtles().List(), func(turtle *model.Turtle) {
+			turtle.SetHeading(float64(turtle.Who() * 7))
+			turtle.Forward(3)
+			turtle.TurtlesHere().Count()
+		})
+		m.CommitMovementPhase()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		result := []float64{}
+		m.Turtles().Ask(func(turtle *model.Turtle) {
+			result = append(result, turtle.XCor(), turtle.YCor(), float64(turtle.PatchHere().TurtlesHere().Count()))
+		})
+		return result
+	}
+
+	a := positions(1)
+	b := positions(6)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("Expected the same positions and patches at any number of workers")
+		}
+	}
+}
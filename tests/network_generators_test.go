@@ -0,0 +1,301 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+	"github.com/nlatham1999/go-agent/pkg/network"
+)
+
+// returns the number of links each turtle has in the order of the turtles
+func degrees(m *model.Model, turtles *model.TurtleAgentSet) []int {
+	n := network.NewNetwork(m, network.NetworkSettings{Turtles: turtles})
+	result := []int{}
+	for _, turtle := range turtles.List() {
+		result = append(result, n.Neighbors(turtle).Count())
+	}
+	return result
+}
+
+// returns the links as pairs of who numbers so two models can be compared
+func linkPairs(m *model.Model) [][2]int {
+	pairs := [][2]int{}
+	for _, link := range m.Links().List() {
+		pairs = append(pairs, [2]int{link.End1().Who(), link.End2().Who()})
+	}
+	return pairs
+}
+
+func TestGeneratorsDeterministic(t *testing.T) {
+	generators := map[string]func(m *model.Model) (*model.TurtleAgentSet, error){
+		"erdos renyi": func(m *model.Model) (*model.TurtleAgentSet, error) {
+			return network.ErdosRenyi(m, 30, .2, network.GeneratorSettings{})
+		},
+		"watts strogatz": func(m *model.Model) (*model.TurtleAgentSet, error) {
+			return network.WattsStrogatz(m, 30, 2, .3, network.GeneratorSettings{})
+		},
+		"barabasi albert": func(m *model.Model) (*model.TurtleAgentSet, error) {
+			return network.BarabasiAlbert(m, 30, 2, network.GeneratorSettings{})
+		},
+	}
+
+	for name, generator := range generators {
+		m1 := model.NewModel(model.ModelSettings{
+			RandomSeed:  7,
+			RandomSeed2: 7,
+		})
+		m2 := model.NewModel(model.ModelSettings{
+			RandomSeed:  7,
+			RandomSeed2: 7,
+		})
+		if _, err := generator(m1); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		generator(m2)
+
+		pairs1 := linkPairs(m1)
+		pairs2 := linkPairs(m2)
+		if len(pairs1) != len(pairs2) {
+			t.Fatalf("%s: expected the same links for the same seed, got %d and %d", name, len(pairs1), len(pairs2))
+		}
+		for i := range pairs1 {
+			if pairs1[i] != pairs2[i] {
+				t.Errorf("%s: expected the same links for the same seed", name)
+				break
+			}
+		}
+	}
+}
+
+func TestErdosRenyi(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	turtles, err := network.ErdosRenyi(m, 10, 0, network.GeneratorSettings{})
+	if err != nil || turtles.Count() != 10 || m.Links().Count() != 0 {
+		t.Errorf("Expected 10 turtles and no links with a probability of 0")
+	}
+
+	m = model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	network.ErdosRenyi(m, 10, 1, network.GeneratorSettings{})
+	if m.Links().Count() != 45 {
+		t.Errorf("Expected every pair linked with a probability of 1, got %d", m.Links().Count())
+	}
+
+	m = model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	network.ErdosRenyi(m, 100, .1, network.GeneratorSettings{})
+	if m.Links().Count() < 350 || m.Links().Count() > 650 {
+		t.Errorf("Expected about 495 links, got %d", m.Links().Count())
+	}
+
+	if _, err := network.ErdosRenyi(m, 10, 1.5, network.GeneratorSettings{}); err != network.ErrGeneratorProbability {
+		t.Errorf("Expected an error for a probability over 1")
+	}
+}
+
+func TestWattsStrogatz(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	turtles, _ := network.WattsStrogatz(m, 20, 2, 0, network.GeneratorSettings{})
+	for _, degree := range degrees(m, turtles) {
+		if degree != 4 {
+			t.Fatalf("Expected every turtle to have 4 links without rewiring, got %d", degree)
+		}
+	}
+
+	m = model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	turtles, _ = network.WattsStrogatz(m, 20, 2, 1, network.GeneratorSettings{})
+	if m.Links().Count() != 40 {
+		t.Errorf("Expected rewiring to keep the number of links, got %d", m.Links().Count())
+	}
+	for _, link := range m.Links().List() {
+		if link.End1() == link.End2() {
+			t.Errorf("Expected no turtle to be linked to itself")
+		}
+	}
+
+	if _, err := network.WattsStrogatz(m, 4, 2, 0, network.GeneratorSettings{}); err != network.ErrGeneratorNeighbors {
+		t.Errorf("Expected an error when there are too many neighbors for the ring")
+	}
+}
+
+func TestBarabasiAlbert(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	turtles, err := network.BarabasiAlbert(m, 200, 2, network.GeneratorSettings{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 3 links between the first 3 turtles then 2 for every turtle after
+	if m.Links().Count() != 3+2*197 {
+		t.Errorf("Expected %d links, got %d", 3+2*197, m.Links().Count())
+	}
+
+	// the early turtles should have gathered many more links than the late ones
+	d := degrees(m, turtles)
+	if d[0] <= 2*d[199] {
+		t.Errorf("Expected the first turtle to have many more links than the last, got %d and %d", d[0], d[199])
+	}
+
+	if _, err := network.BarabasiAlbert(m, 2, 2, network.GeneratorSettings{}); err != network.ErrGeneratorAmount {
+		t.Errorf("Expected an error when there are too few turtles")
+	}
+}
+
+func TestLattice2D(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	turtles, _ := network.Lattice2D(m, 3, 4, false, network.GeneratorSettings{})
+	if turtles.Count() != 12 || m.Links().Count() != 17 {
+		t.Errorf("Expected 12 turtles and 17 links, got %d and %d", turtles.Count(), m.Links().Count())
+	}
+	d := degrees(m, turtles)
+	if d[0] != 2 || d[1] != 3 || d[5] != 4 {
+		t.Errorf("Expected 2 links on a corner, 3 on an edge and 4 inside, got %v", d)
+	}
+
+	m = model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	turtles, _ = network.Lattice2D(m, 3, 4, true, network.GeneratorSettings{})
+	for _, degree := range degrees(m, turtles) {
+		if degree != 4 {
+			t.Fatalf("Expected every turtle to have 4 links when wrapping, got %d", degree)
+		}
+	}
+}
+
+func TestRingStarComplete(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	turtles, _ := network.Ring(m, 6, network.GeneratorSettings{})
+	for _, degree := range degrees(m, turtles) {
+		if degree != 2 {
+			t.Fatalf("Expected every turtle in a ring to have 2 links")
+		}
+	}
+
+	m = model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	turtles, _ = network.Star(m, 6, network.GeneratorSettings{})
+	d := degrees(m, turtles)
+	if d[0] != 5 || d[1] != 1 {
+		t.Errorf("Expected the center to have 5 links and the others 1, got %v", d)
+	}
+
+	m = model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	network.Complete(m, 6, network.GeneratorSettings{})
+	if m.Links().Count() != 15 {
+		t.Errorf("Expected 15 links, got %d", m.Links().Count())
+	}
+}
+
+func TestGeneratorBreedsAndOperations(t *testing.T) {
+	people := model.NewTurtleBreed("people", "", nil)
+	friends := model.NewLinkBreed("friends")
+	roads := model.NewLinkBreed("roads")
+	m := model.NewModel(model.ModelSettings{
+		TurtleBreeds:         []*model.TurtleBreed{people},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+		DirectedLinkBreeds:   []*model.LinkBreed{roads},
+	})
+
+	turtles, _ := network.Star(m, 4, network.GeneratorSettings{
+		TurtleBreed: people,
+		LinkBreed:   friends,
+		TurtleOperation: func(turtle *model.Turtle) {
+			turtle.Color.SetColor(model.Red)
+		},
+		LinkOperation: func(link *model.Link) {
+			link.Color.SetColor(model.Blue)
+		},
+	})
+
+	if people.Agents().Count() != 4 || friends.Links().Count() != 3 {
+		t.Errorf("Expected the turtles and links to be of the breeds passed in")
+	}
+	for _, turtle := range turtles.List() {
+		if turtle.Color != model.Red {
+			t.Errorf("Expected the turtle operation to run on every turtle")
+		}
+	}
+	for _, link := range friends.Links().List() {
+		if link.Color != model.Blue || link.Directed() {
+			t.Errorf("Expected the link operation to run on every undirected link")
+		}
+	}
+
+	turtles, _ = network.Ring(m, 3, network.GeneratorSettings{LinkBreed: roads})
+	for _, link := range roads.Links().List() {
+		if !link.Directed() {
+			t.Errorf("Expected directed links for a directed breed")
+		}
+	}
+	if roads.Links().Count() != 3 || roads.Link(turtles.List()[0].Who(), turtles.List()[1].Who()) == nil {
+		t.Errorf("Expected directed links going around the ring")
+	}
+}
+
+func TestGeneratorDirectedLinks(t *testing.T) {
+	roads := model.NewLinkBreed("roads")
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:         3,
+		DirectedLinkBreeds: []*model.LinkBreed{roads},
+	})
+
+	// without rewiring the links go forward around the ring, including from the last turtle to the first
+	turtles, _ := network.WattsStrogatz(m, 6, 2, 0, network.GeneratorSettings{LinkBreed: roads})
+	list := turtles.List()
+	for i, turtle := range list {
+		for j := 1; j <= 2; j++ {
+			if roads.Link(turtle.Who(), list[(i+j)%6].Who()) == nil {
+				t.Errorf("Expected a link from turtle %d to turtle %d", i, (i+j)%6)
+			}
+		}
+	}
+
+	// rewired links still go from the turtle they started at
+	turtles, _ = network.WattsStrogatz(m, 8, 2, 1, network.GeneratorSettings{LinkBreed: roads})
+	for i, turtle := range turtles.List() {
+		if out := turtle.OutLinks(roads).Count(); out != 2 {
+			t.Errorf("Expected turtle %d to keep its 2 links going out, got %d", i, out)
+		}
+	}
+
+	turtles, _ = network.Star(m, 4, network.GeneratorSettings{LinkBreed: roads})
+	if turtles.List()[0].OutLinks(roads).Count() != 3 {
+		t.Errorf("Expected the directed links to go out from the center")
+	}
+
+	turtles, _ = network.Complete(m, 4, network.GeneratorSettings{LinkBreed: roads})
+	for i, turtle := range turtles.List() {
+		if out := turtle.OutLinks(roads).Count(); out != 3-i {
+			t.Errorf("Expected turtle %d to link to the %d turtles after it, got %d", i, 3-i, out)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/loader"
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+const graphMLSample = `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="d0" for="node" attr.name="breed" attr.type="string"/>
+  <key id="d1" for="node" attr.name="age" attr.type="int">
+    <default>30</default>
+  </key>
+  <key id="d2" for="node" attr.name="x" attr.type="double"/>
+  <key id="d3" for="node" attr.name="name" attr.type="string"/>
+  <key id="d4" for="edge" attr.name="strength" attr.type="double"/>
+  <key id="d5" for="edge" attr.name="breed" attr.type="string"/>
+  <key id="d6" for="node" attr.name="unknown" attr.type="string"/>
+  <graph id="G" edgedefault="undirected">
+    <node id="alice"><data key="d0">people</data><data key="d1">41</data><data key="d2">3.5</data><data key="d3">Alice</data></node>
+    <node id="bob"><data key="d0">people</data><data key="d6">ignored</data></node>
+    <node id="carol"><data key="d3">Carol</data></node>
+    <edge source="alice" target="bob" directed="true"><data key="d5">follows</data><data key="d4">0.75</data></edge>
+    <edge source="bob" target="carol"><data key="d5">friends</data></edge>
+  </graph>
+</graphml>`
+
+func TestReadGraphML(t *testing.T) {
+	people := model.NewTurtleBreed("people", "", map[string]interface{}{
+		"age": 0,
+	})
+	follows := model.NewLinkBreedWithProperties("follows", map[string]interface{}{
+		"strength": 0.0,
+	})
+	friends := model.NewLinkBreed("friends")
+
+	m := model.NewModel(model.ModelSettings{
+		TurtleBreeds:         []*model.TurtleBreed{people},
+		TurtleProperties:     map[string]interface{}{"name": ""},
+		DirectedLinkBreeds:   []*model.LinkBreed{follows},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+	})
+
+	turtles, err := loader.ReadGraphML(m, strings.NewReader(graphMLSample), loader.NetworkImportSettings{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(turtles) != 3 || m.Turtles().Count() != 3 {
+		t.Fatalf("Expected 3 turtles, got %d", m.Turtles().Count())
+	}
+
+	alice := turtles["alice"]
+	if alice.BreedName() != "people" || alice.XCor() != 3.5 || alice.GetProperty("age") != 41 || alice.GetProperty("name") != "Alice" {
+		t.Errorf("Expected alice to be set from her attributes")
+	}
+	if turtles["bob"].GetProperty("age") != 30 {
+		t.Errorf("Expected the default of the key to be used, got %v", turtles["bob"].GetProperty("age"))
+	}
+	if turtles["carol"].BreedName() != "" {
+		t.Errorf("Expected a node without a breed to be in the general breed")
+	}
+	if alice.Who() != 0 || turtles["carol"].Who() != 2 {
+		t.Errorf("Expected the turtles to be created in the order of the file")
+	}
+
+	follow := m.DirectedLinkBreed("follows").Link(alice.Who(), turtles["bob"].Who())
+	if follow == nil || !follow.Directed() || follow.GetProperty("strength") != 0.75 {
+		t.Errorf("Expected a directed follows link with its strength")
+	}
+	friend := m.UndirectedLinkBreed("friends").Link(turtles["bob"].Who(), turtles["carol"].Who())
+	if friend == nil || friend.Directed() {
+		t.Errorf("Expected an undirected friends link from the default edge type")
+	}
+}
+
+func TestReadGraphMLErrors(t *testing.T) {
+	people := model.NewTurtleBreed("people", "", nil)
+	follows := model.NewLinkBreed("follows")
+
+	m := model.NewModel(model.ModelSettings{
+		TurtleBreeds:       []*model.TurtleBreed{people},
+		DirectedLinkBreeds: []*model.LinkBreed{follows},
+	})
+
+	_, err := loader.ReadGraphML(m, strings.NewReader(strings.Replace(graphMLSample, ">people<", ">robots<", 1)), loader.NetworkImportSettings{})
+	if err != loader.ErrUnknownTurtleBreed {
+		t.Errorf("Expected an error for a breed that isn't in the model, got %v", err)
+	}
+
+	_, err = loader.ReadGraphML(m, strings.NewReader(strings.Replace(graphMLSample, `target="carol"`, `target="dave"`, 1)), loader.NetworkImportSettings{})
+	if err != loader.ErrUnknownNode {
+		t.Errorf("Expected an error for an edge to a missing node, got %v", err)
+	}
+
+	_, err = loader.ReadGraphML(m, strings.NewReader(strings.Replace(graphMLSample, `directed="true"`, `directed="sometimes"`, 1)), loader.NetworkImportSettings{})
+	if err != loader.ErrInvalidDirected {
+		t.Errorf("Expected an error for a directed value that isn't true or false, got %v", err)
+	}
+}
+
+func TestReadGraphMLSkipsRepeatedEdges(t *testing.T) {
+	people := model.NewTurtleBreed("people", "", map[string]interface{}{
+		"age": 0,
+	})
+	follows := model.NewLinkBreedWithProperties("follows", map[string]interface{}{
+		"strength": 0.0,
+	})
+	friends := model.NewLinkBreed("friends")
+
+	m := model.NewModel(model.ModelSettings{
+		TurtleBreeds:         []*model.TurtleBreed{people},
+		TurtleProperties:     map[string]interface{}{"name": ""},
+		DirectedLinkBreeds:   []*model.LinkBreed{follows},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+	})
+
+	// the same friends edge a second time can't be made into another link
+	duplicate := `<edge source="carol" target="bob"><data key="d5">friends</data></edge>`
+	turtles, err := loader.ReadGraphML(m, strings.NewReader(strings.Replace(graphMLSample, "</graph>", duplicate+"</graph>", 1)), loader.NetworkImportSettings{})
+	var skipped *loader.SkippedEdgesError
+	if !errors.As(err, &skipped) || skipped.Skipped != 1 {
+		t.Fatalf("Expected the duplicate edge to be skipped, got %v", err)
+	}
+	if len(turtles) != 3 || m.Links().Count() != 2 {
+		t.Errorf("Expected the rest of the network to still be read")
+	}
+}
+
+func TestGraphMLRoundTrip(t *testing.T) {
+	people := model.NewTurtleBreed("people", "", map[string]interface{}{
+		"age": 0,
+	})
+	follows := model.NewLinkBreedWithProperties("follows", map[string]interface{}{
+		"strength": 0.0,
+	})
+	friends := model.NewLinkBreed("friends")
+
+	m := model.NewModel(model.ModelSettings{
+		TurtleBreeds:         []*model.TurtleBreed{people},
+		TurtleProperties:     map[string]interface{}{"name": ""},
+		DirectedLinkBreeds:   []*model.LinkBreed{follows},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+	})
+
+	turtles, _ := people.CreateAgents(3, nil)
+	list := turtles.List()
+	for i, turtle := range list {
+		turtle.SetXY(float64(i), float64(-i))
+		turtle.SetProperty("age", 20+i)
+		turtle.SetProperty("name", string(rune('a'+i)))
+	}
+	list[0].SetLabel("first")
+	list[0].CreateLinkToTurtle(follows, list[1], func(l *model.Link) {
+		l.SetProperty("strength", 2.5)
+	})
+	list[1].CreateLinkWithTurtle(friends, list[2], nil)
+
+	var buffer bytes.Buffer
+	if err := loader.WriteGraphML(m, &buffer, loader.NetworkExportSettings{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// a second model with the same breeds to read into
+	copied := model.NewModel(model.ModelSettings{
+		TurtleBreeds: []*model.TurtleBreed{model.NewTurtleBreed("people", "", map[string]interface{}{
+			"age": 0,
+		})},
+		TurtleProperties: map[string]interface{}{"name": ""},
+		DirectedLinkBreeds: []*model.LinkBreed{model.NewLinkBreedWithProperties("follows", map[string]interface{}{
+			"strength": 0.0,
+		})},
+		UndirectedLinkBreeds: []*model.LinkBreed{model.NewLinkBreed("friends")},
+	})
+	read, err := loader.ReadGraphML(copied, &buffer, loader.NetworkImportSettings{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i, original := range list {
+		readTurtle := read[strconv.Itoa(original.Who())]
+		if readTurtle.BreedName() != "people" || readTurtle.XCor() != original.XCor() || readTurtle.YCor() != original.YCor() {
+			t.Errorf("Expected turtle %d to keep its breed and position", i)
+		}
+		if readTurtle.GetProperty("age") != original.GetProperty("age") || readTurtle.GetProperty("name") != original.GetProperty("name") {
+			t.Errorf("Expected turtle %d to keep its properties", i)
+		}
+	}
+	if copied.Turtle(0).GetLabel() != "first" {
+		t.Errorf("Expected the label to be kept")
+	}
+
+	follow := copied.DirectedLinkBreed("follows").Link(0, 1)
+	if follow == nil || follow.GetProperty("strength") != 2.5 {
+		t.Errorf("Expected the directed link and its strength to be kept")
+	}
+	if copied.UndirectedLinkBreed("friends").Link(1, 2) == nil || copied.Links().Count() != 2 {
+		t.Errorf("Expected the undirected link to be kept")
+	}
+}
+
+func TestEdgeListCSV(t *testing.T) {
+	people := model.NewTurtleBreed("people", "", nil)
+	follows := model.NewLinkBreedWithProperties("follows", map[string]interface{}{
+		"strength": 0.0,
+	})
+
+	m := model.NewModel(model.ModelSettings{
+		TurtleBreeds:       []*model.TurtleBreed{people},
+		DirectedLinkBreeds: []*model.LinkBreed{follows},
+	})
+
+	edges := "source,target,breed,strength\n" +
+		"a,b,follows,1.5\n" +
+		"b,c,follows,2\n" +
+		"c,a,,\n"
+
+	turtles, err := loader.ReadEdgeListCSV(m, strings.NewReader(edges), loader.NetworkImportSettings{
+		TurtleBreed: "people",
+		Directed:    true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(turtles) != 3 || m.TurtleBreed("people").Agents().Count() != 3 {
+		t.Fatalf("Expected a people turtle for each node")
+	}
+
+	if follows.Links().Count() != 2 {
+		t.Errorf("Expected 2 follows links, got %d", follows.Links().Count())
+	}
+	if follows.Link(turtles["a"].Who(), turtles["b"].Who()).GetProperty("strength") != 1.5 {
+		t.Errorf("Expected the strength to be read as a number")
+	}
+	if m.Link(turtles["c"].Who(), turtles["a"].Who()) == nil {
+		t.Errorf("Expected a general directed link when there is no breed")
+	}
+
+	var buffer bytes.Buffer
+	if err := loader.WriteEdgeListCSV(m, &buffer, loader.NetworkExportSettings{Links: follows.Links()}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "source,target,directed,breed,strength\n0,1,true,follows,1.5\n1,2,true,follows,2\n"
+	if buffer.String() != expected {
+		t.Errorf("Expected the edge list\n%s\ngot\n%s", expected, buffer.String())
+	}
+
+	copied := model.NewModel(model.ModelSettings{
+		DirectedLinkBreeds: []*model.LinkBreed{model.NewLinkBreed("follows")},
+	})
+	loader.ReadEdgeListCSV(copied, &buffer, loader.NetworkImportSettings{})
+	if copied.DirectedLinkBreed("follows").Links().Count() != 2 {
+		t.Errorf("Expected the directed column to be read back")
+	}
+
+	if _, err := loader.ReadEdgeListCSV(m, strings.NewReader("from,to\n1,2\n"), loader.NetworkImportSettings{}); err != loader.ErrMissingColumn {
+		t.Errorf("Expected an error without source and target columns")
+	}
+	if _, err := loader.ReadEdgeListCSV(m, strings.NewReader("source,target,directed\n1,2,maybe\n"), loader.NetworkImportSettings{}); err != loader.ErrInvalidDirected {
+		t.Errorf("Expected an error for a directed value that isn't true or false, got %v", err)
+	}
+
+	// an empty directed value falls back to the settings, the repeated edges are skipped
+	repeated := model.NewModel(model.ModelSettings{})
+	_, err = loader.ReadEdgeListCSV(repeated, strings.NewReader("source,target,directed\na,b,\na,b,true\nb,a,false\na,b,false\n"), loader.NetworkImportSettings{Directed: true})
+	var skipped *loader.SkippedEdgesError
+	if !errors.As(err, &skipped) || skipped.Skipped != 2 {
+		t.Fatalf("Expected the 2 repeated edges to be skipped, got %v", err)
+	}
+	if repeated.Links().Count() != 2 {
+		t.Errorf("Expected a directed and an undirected link, got %d", repeated.Links().Count())
+	}
+}
+
+func TestWriteGEXF(t *testing.T) {
+	people := model.NewTurtleBreed("people", "", map[string]interface{}{
+		"age": 0,
+	})
+	friends := model.NewLinkBreed("friends")
+
+	m := model.NewModel(model.ModelSettings{
+		TurtleBreeds:         []*model.TurtleBreed{people},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+	})
+	turtles, _ := people.CreateAgents(2, nil)
+	list := turtles.List()
+	list[0].CreateLinkWithTurtle(friends, list[1], nil)
+
+	var buffer bytes.Buffer
+	if err := loader.WriteGEXF(m, &buffer, loader.NetworkExportSettings{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buffer.String()
+
+	for _, expected := range []string{
+		`<gexf xmlns="http://gexf.net/1.3"`,
+		`mode="static"`,
+		`<attribute id="0" title="breed" type="string">`,
+		`title="age" type="integer"`,
+		`<node id="0" label="0">`,
+		`<viz:position x=`,
+		`<edge id="0" source="0" target="1" type="undirected">`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected the gexf to contain %s\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "<spells>") {
+		t.Errorf("Expected no spells in a static network")
+	}
+}
+
+func TestGEXFRecorder(t *testing.T) {
+	people := model.NewTurtleBreed("people", "", map[string]interface{}{
+		"age": 0,
+	})
+	friends := model.NewLinkBreed("friends")
+
+	m := model.NewModel(model.ModelSettings{
+		TurtleBreeds:         []*model.TurtleBreed{people},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+	})
+	recorder := loader.NewGEXFRecorder(loader.NetworkExportSettings{})
+
+	turtles, _ := people.CreateAgents(2, nil)
+	list := turtles.List()
+	m.ResetTicks()
+
+	// tick 0 and 1 with an age of 1, tick 2 with an age of 2 and a new link, the second turtle dies after tick 2
+	list[0].SetProperty("age", 1)
+	recorder.Record(m)
+	m.Tick()
+	recorder.Record(m)
+	m.Tick()
+	list[0].SetProperty("age", 2)
+	list[0].CreateLinkWithTurtle(friends, list[1], nil)
+	recorder.Record(m)
+	m.Tick()
+	m.KillTurtle(list[1])
+	recorder.Record(m)
+
+	var buffer bytes.Buffer
+	if err := recorder.Write(&buffer); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buffer.String()
+
+	for _, expected := range []string{
+		`mode="dynamic"`,
+		`timeformat="double"`,
+		`<attributes class="node" mode="dynamic">`,
+		// the age of the first turtle changes at tick 2 and stays
+		`value="1" start="0" end="1"`,
+		`value="2" start="2"></attvalue>`,
+		// the second turtle was there from tick 0 to 2
+		`<spell start="0" end="2"></spell>`,
+		// the link was made at tick 2 and went with the turtle
+		`<edge id="0" source="0" target="1" type="undirected">`,
+		`<spell start="2" end="2"></spell>`,
+		// the first turtle is still there
+		`<spell start="0"></spell>`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected the gexf to contain %s\n%s", expected, out)
+		}
+	}
+}
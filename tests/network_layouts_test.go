@@ -0,0 +1,298 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+	"github.com/nlatham1999/go-agent/pkg/network"
+)
+
+func distanceBetween(a *model.Turtle, b *model.Turtle) float64 {
+	return math.Hypot(a.XCor()-b.XCor(), a.YCor()-b.YCor())
+}
+
+func inWorld(m *model.Model, turtle *model.Turtle) bool {
+	return turtle.XCor() >= m.MinXCor() && turtle.XCor() < m.MaxXCor() &&
+		turtle.YCor() >= m.MinYCor() && turtle.YCor() < m.MaxYCor()
+}
+
+func TestSpringLayout(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  3,
+		RandomSeed2: 3,
+	})
+	turtles, _ := network.Ring(m, 12, network.GeneratorSettings{})
+
+	// start everyone bunched up near the middle
+	for i, turtle := range turtles.List() {
+		turtle.SetXY(float64(i%3)*.1, float64(i/3)*.1)
+	}
+
+	pinned := turtles.List()[0]
+	pinned.SetXY(5, 5)
+
+	n := network.NewNetwork(m, network.NetworkSettings{})
+	layout := network.NewSpringLayout(m, n, network.SpringLayoutSettings{
+		Pinned:       model.NewTurtleAgentSet([]*model.Turtle{pinned}),
+		SpringLength: 4,
+	})
+
+	steps := network.RunLayout(layout, 500)
+	if steps >= 500 {
+		t.Errorf("Expected the layout to settle")
+	}
+
+	if pinned.XCor() != 5 || pinned.YCor() != 5 {
+		t.Errorf("Expected the pinned turtle not to move")
+	}
+
+	list := turtles.List()
+	for i, turtle := range list {
+		if !inWorld(m, turtle) {
+			t.Errorf("Expected turtle %d to stay in the world", i)
+		}
+		next := list[(i+1)%len(list)]
+		if d := distanceBetween(turtle, next); d < .5 {
+			t.Errorf("Expected linked turtles to be spread apart, got %f", d)
+		}
+	}
+
+	// linked turtles should end up closer than turtles on the other side of the ring
+	if distanceBetween(list[1], list[2]) >= distanceBetween(list[1], list[7]) {
+		t.Errorf("Expected linked turtles to be closer than far away turtles")
+	}
+}
+
+func TestSpringLayoutStaysInWorld(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  3,
+		RandomSeed2: 3,
+	})
+	turtles, _ := network.ErdosRenyi(m, 40, .05, network.GeneratorSettings{})
+
+	n := network.NewNetwork(m, network.NetworkSettings{})
+	layout := network.NewSpringLayout(m, n, network.SpringLayoutSettings{SpringLength: 20})
+	for i := 0; i < 50; i++ {
+		layout.Step()
+	}
+
+	for _, turtle := range turtles.List() {
+		if !inWorld(m, turtle) {
+			t.Fatalf("Expected strong pushes to stop at the edge of the world, got %f %f", turtle.XCor(), turtle.YCor())
+		}
+	}
+}
+
+func TestSpringLayout3D(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed: 2,
+		MinPzCor:   -5,
+		MaxPzCor:   5,
+	})
+	turtles, _ := network.Complete(m, 4, network.GeneratorSettings{})
+	for _, turtle := range turtles.List() {
+		turtle.SetXYZ(0, 0, 0)
+	}
+
+	n := network.NewNetwork(m, network.NetworkSettings{})
+	network.RunLayout(network.NewSpringLayout(m, n, network.SpringLayoutSettings{Use3D: true, SpringLength: 3}), 300)
+
+	list := turtles.List()
+	for i := range list {
+		for j := i + 1; j < len(list); j++ {
+			if list[i].DistanceTurtle(list[j]) < 1 {
+				t.Errorf("Expected turtles %d and %d to be pushed apart", i, j)
+			}
+		}
+		if list[i].ZCor() < m.MinZCor() || list[i].ZCor() >= m.MaxZCor() {
+			t.Errorf("Expected turtle %d to stay in the world", i)
+		}
+	}
+}
+
+func TestRadialLayout(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  3,
+		RandomSeed2: 3,
+	})
+	turtles, _ := network.Star(m, 5, network.GeneratorSettings{})
+	list := turtles.List()
+
+	// hang another turtle off of the first leaf
+	extra, _ := m.CreateTurtles(1, nil)
+	list[1].CreateLinkWithTurtle(nil, extra.List()[0], nil)
+
+	n := network.NewNetwork(m, network.NetworkSettings{})
+	layout := network.NewRadialLayout(m, n, network.RadialLayoutSettings{
+		Root:        list[0],
+		RingSpacing: 4,
+		Speed:       .5,
+	})
+
+	// half the way each step so it takes a few steps
+	if layout.Step() {
+		t.Errorf("Expected the layout not to settle in one step when moving half the way")
+	}
+	steps := network.RunLayout(layout, 100)
+	if steps >= 100 {
+		t.Errorf("Expected the layout to settle")
+	}
+
+	if list[0].XCor() != 0 || list[0].YCor() != 0 {
+		t.Errorf("Expected the root at the center, got %f %f", list[0].XCor(), list[0].YCor())
+	}
+	for i := 1; i < 5; i++ {
+		if d := distanceBetween(list[0], list[i]); math.Abs(d-4) > .01 {
+			t.Errorf("Expected the leaves on the first ring, got %f", d)
+		}
+	}
+	if d := distanceBetween(list[0], extra.List()[0]); math.Abs(d-8) > .01 {
+		t.Errorf("Expected the extra turtle on the second ring, got %f", d)
+	}
+}
+
+func TestRadialLayoutFitsWorld(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  3,
+		RandomSeed2: 3,
+	})
+	turtles, _ := network.Ring(m, 30, network.GeneratorSettings{})
+
+	n := network.NewNetwork(m, network.NetworkSettings{})
+	network.RunLayout(network.NewRadialLayout(m, n, network.RadialLayoutSettings{}), 10)
+
+	for _, turtle := range turtles.List() {
+		if !inWorld(m, turtle) {
+			t.Fatalf("Expected the rings to fit in the world")
+		}
+	}
+	if d := distanceBetween(turtles.List()[0], turtles.List()[15]); math.Abs(d-15) > .01 {
+		t.Errorf("Expected the furthest turtle at the edge of the world, got %f", d)
+	}
+}
+
+func TestTutteLayout(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  3,
+		RandomSeed2: 3,
+	})
+
+	// a square of pinned turtles with one free turtle linked to all of them
+	turtles, _ := network.Star(m, 5, network.GeneratorSettings{})
+	list := turtles.List()
+	pinned := model.NewTurtleAgentSet(list[1:])
+
+	n := network.NewNetwork(m, network.NetworkSettings{})
+	layout := network.NewTutteLayout(m, n, network.TutteLayoutSettings{
+		Pinned:        pinned,
+		ArrangePinned: true,
+		Radius:        10,
+	})
+
+	for _, turtle := range list[1:] {
+		if math.Abs(math.Hypot(turtle.XCor(), turtle.YCor())-10) > .01 {
+			t.Errorf("Expected the pinned turtles on the circle")
+		}
+	}
+
+	network.RunLayout(layout, 100)
+	if math.Abs(list[0].XCor()) > .01 || math.Abs(list[0].YCor()) > .01 {
+		t.Errorf("Expected the free turtle in the middle of the pinned turtles, got %f %f", list[0].XCor(), list[0].YCor())
+	}
+}
+
+func TestTutteLayoutKeepsPinned(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  3,
+		RandomSeed2: 3,
+	})
+	turtles, _ := network.Star(m, 3, network.GeneratorSettings{})
+	list := turtles.List()
+	list[1].SetXY(-4, 2)
+	list[2].SetXY(4, 2)
+
+	// the radius is only used when arranging the pinned turtles
+	n := network.NewNetwork(m, network.NetworkSettings{})
+	network.RunLayout(network.NewTutteLayout(m, n, network.TutteLayoutSettings{
+		Pinned: model.NewTurtleAgentSet(list[1:]),
+		Radius: 10,
+	}), 100)
+
+	if list[1].XCor() != -4 || list[1].YCor() != 2 || list[2].XCor() != 4 || list[2].YCor() != 2 {
+		t.Errorf("Expected the pinned turtles not to move")
+	}
+	if math.Abs(list[0].XCor()) > .01 || math.Abs(list[0].YCor()-2) > .01 {
+		t.Errorf("Expected the free turtle between the pinned turtles, got %f %f", list[0].XCor(), list[0].YCor())
+	}
+}
+
+func TestRadialLayout3D(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPzCor: -15,
+		MaxPzCor: 15,
+	})
+	turtles, _ := network.Star(m, 4, network.GeneratorSettings{})
+	list := turtles.List()
+	for i, turtle := range list {
+		turtle.SetXYZ(0, 0, float64(i))
+	}
+
+	n := network.NewNetwork(m, network.NetworkSettings{})
+	network.RunLayout(network.NewRadialLayout(m, n, network.RadialLayoutSettings{
+		Root:        list[0],
+		CenterZ:     5,
+		RingSpacing: 4,
+		Use3D:       true,
+	}), 10)
+
+	for i, turtle := range list {
+		if turtle.ZCor() != 5 {
+			t.Errorf("Expected turtle %d to be moved to the center's z, got %f", i, turtle.ZCor())
+		}
+		if i > 0 && math.Abs(distanceBetween(list[0], turtle)-4) > .01 {
+			t.Errorf("Expected turtle %d on the first ring", i)
+		}
+	}
+}
+
+func TestLayoutReachesWorldEdge(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+	turtles, _ := network.Star(m, 3, network.GeneratorSettings{})
+	list := turtles.List()
+
+	// the rings are wider than the world so the leaves stop at its edges
+	n := network.NewNetwork(m, network.NetworkSettings{})
+	network.RunLayout(network.NewRadialLayout(m, n, network.RadialLayoutSettings{
+		Root:        list[0],
+		RingSpacing: 20,
+	}), 10)
+
+	for _, turtle := range list[1:] {
+		if !inWorld(m, turtle) {
+			t.Fatalf("Expected the leaves to stay in the world")
+		}
+		if math.Abs(turtle.YCor()) < 15.49 {
+			t.Errorf("Expected the leaf at the edge of the world past the last patch center, got %f", turtle.YCor())
+		}
+	}
+}
+
+func TestLayoutSphere(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPzCor: -15,
+		MaxPzCor: 15,
+	})
+	turtles, _ := m.CreateTurtles(50, nil)
+	m.LayoutSphere(turtles.List(), 10)
+
+	for _, turtle := range turtles.List() {
+		r := math.Sqrt(turtle.XCor()*turtle.XCor() + turtle.YCor()*turtle.YCor() + turtle.ZCor()*turtle.ZCor())
+		if math.Abs(r-10) > .0001 {
+			t.Fatalf("Expected every turtle on the sphere, got a radius of %f", r)
+		}
+	}
+	if turtles.List()[0].ZCor() != 10 || turtles.List()[49].ZCor() != -10 {
+		t.Errorf("Expected the turtles to go from the top of the sphere to the bottom")
+	}
+}
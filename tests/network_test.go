@@ -0,0 +1,348 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+	"github.com/nlatham1999/go-agent/pkg/network"
+)
+
+func road(m *model.Model, roads *model.LinkBreed, from int, to int, length float64) {
+	link, _ := m.Turtle(from).CreateLinkToTurtle(roads, m.Turtle(to), nil)
+	link.SetProperty("length", length)
+}
+
+func friend(m *model.Model, friends *model.LinkBreed, a int, b int) {
+	m.Turtle(a).CreateLinkWithTurtle(friends, m.Turtle(b), nil)
+}
+
+func TestNetworkShortestPaths(t *testing.T) {
+	roads := model.NewLinkBreedWithProperties("roads", map[string]interface{}{
+		"length": 1.0,
+	})
+
+	m := model.NewModel(model.ModelSettings{
+		DirectedLinkBreeds: []*model.LinkBreed{roads},
+	})
+	m.CreateTurtles(5, nil)
+
+	// 0 -> 1 -> 2 -> 3 is long in links but short in length, 0 -> 3 is one link but long
+	road(m, roads, 0, 1, 1)
+	road(m, roads, 1, 2, 1)
+	road(m, roads, 2, 3, 1)
+	road(m, roads, 0, 3, 10)
+
+	n := network.NewNetwork(m, network.NetworkSettings{
+		LinkBreeds:     []*model.LinkBreed{roads},
+		WeightProperty: "length",
+	})
+
+	path, err := n.ShortestPath(m.Turtle(0), m.Turtle(3))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(path) != 2 || path[0] != m.Turtle(0) || path[1] != m.Turtle(3) {
+		t.Errorf("Expected the path with the fewest links to be the direct road")
+	}
+
+	path, cost, err := n.WeightedShortestPath(m.Turtle(0), m.Turtle(3))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(path) != 4 || cost != 3 {
+		t.Errorf("Expected the lightest path to go through 1 and 2 for a cost of 3, got %d turtles and %f", len(path), cost)
+	}
+
+	links, _ := n.WeightedShortestPathLinks(m.Turtle(0), m.Turtle(3))
+	if len(links) != 3 || links[0].End1() != m.Turtle(0) || links[2].End2() != m.Turtle(3) {
+		t.Errorf("Expected the links of the lightest path in order")
+	}
+
+	links, _ = n.ShortestPathLinks(m.Turtle(0), m.Turtle(3))
+	if len(links) != 1 {
+		t.Errorf("Expected the direct road to be the only link")
+	}
+
+	if d, _ := n.DistanceTo(m.Turtle(0), m.Turtle(2)); d != 2 {
+		t.Errorf("Expected a distance of 2 links, got %d", d)
+	}
+	if d, _ := n.WeightedDistanceTo(m.Turtle(0), m.Turtle(3)); d != 3 {
+		t.Errorf("Expected a weighted distance of 3, got %f", d)
+	}
+
+	// the roads are one way
+	if _, err := n.DistanceTo(m.Turtle(3), m.Turtle(0)); err != network.ErrNoPath {
+		t.Errorf("Expected no path against the direction of the roads")
+	}
+
+	// unless the direction is ignored
+	undirected := network.NewNetwork(m, network.NetworkSettings{
+		LinkBreeds: []*model.LinkBreed{roads},
+		Undirected: true,
+	})
+	if d, _ := undirected.DistanceTo(m.Turtle(3), m.Turtle(0)); d != 1 {
+		t.Errorf("Expected to go back along the road when undirected, got %d", d)
+	}
+
+	if _, err := n.DistanceTo(m.Turtle(0), m.Turtle(4)); err != network.ErrNoPath {
+		t.Errorf("Expected no path to a turtle without links")
+	}
+
+	if n.TurtlesInRadius(m.Turtle(0), 1).Count() != 3 {
+		t.Errorf("Expected the turtle and its 2 neighbors within 1 link")
+	}
+	if n.TurtlesInWeightedRadius(m.Turtle(0), 2).Count() != 3 {
+		t.Errorf("Expected 3 turtles within a weight of 2")
+	}
+}
+
+func TestNetworkBreedsAndTurtles(t *testing.T) {
+	roads := model.NewLinkBreedWithProperties("roads", map[string]interface{}{
+		"length": 1.0,
+	})
+	friends := model.NewLinkBreed("friends")
+
+	m := model.NewModel(model.ModelSettings{
+		DirectedLinkBreeds:   []*model.LinkBreed{roads},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+	})
+	m.CreateTurtles(4, nil)
+
+	road(m, roads, 0, 1, 1)
+	friend(m, friends, 1, 2)
+	friend(m, friends, 2, 3)
+
+	onlyFriends := network.NewNetwork(m, network.NetworkSettings{
+		LinkBreeds: []*model.LinkBreed{friends},
+	})
+	if _, err := onlyFriends.DistanceTo(m.Turtle(0), m.Turtle(1)); err != network.ErrNoPath {
+		t.Errorf("Expected roads to be left out of the friends network")
+	}
+	if d, _ := onlyFriends.DistanceTo(m.Turtle(3), m.Turtle(1)); d != 2 {
+		t.Errorf("Expected friends links to go both ways")
+	}
+
+	everything := network.NewNetwork(m, network.NetworkSettings{})
+	if d, _ := everything.DistanceTo(m.Turtle(0), m.Turtle(3)); d != 3 {
+		t.Errorf("Expected every link to be used when no breeds are given, got %d", d)
+	}
+
+	// leaving out a turtle leaves out its links
+	some := network.NewNetwork(m, network.NetworkSettings{
+		Turtles: m.Turtles().With(func(turtle *model.Turtle) bool { return turtle.Who() != 2 }),
+	})
+	if _, err := some.DistanceTo(m.Turtle(0), m.Turtle(3)); err != network.ErrNoPath {
+		t.Errorf("Expected no path when the turtle in the middle is left out")
+	}
+	if _, err := some.DistanceTo(m.Turtle(0), m.Turtle(2)); err != network.ErrTurtleNotInNetwork {
+		t.Errorf("Expected an error for a turtle that isn't in the network")
+	}
+
+	if some.Count() != 3 || !everything.Neighbors(m.Turtle(2)).Contains(m.Turtle(1)) {
+		t.Errorf("Expected the network to hold the turtles and their neighbors")
+	}
+}
+
+func TestNetworkComponents(t *testing.T) {
+	roads := model.NewLinkBreedWithProperties("roads", map[string]interface{}{
+		"length": 1.0,
+	})
+
+	m := model.NewModel(model.ModelSettings{
+		DirectedLinkBreeds: []*model.LinkBreed{roads},
+	})
+	m.CreateTurtles(7, nil)
+
+	// a cycle 0 -> 1 -> 2 -> 0, a tail 2 -> 3, a separate pair 4 -> 5 -> 4, and 6 alone
+	road(m, roads, 0, 1, 1)
+	road(m, roads, 1, 2, 1)
+	road(m, roads, 2, 0, 1)
+	road(m, roads, 2, 3, 1)
+	road(m, roads, 4, 5, 1)
+	road(m, roads, 5, 4, 1)
+
+	n := network.NewNetwork(m, network.NetworkSettings{})
+
+	components := n.Components()
+	if len(components) != 3 {
+		t.Fatalf("Expected 3 weak components, got %d", len(components))
+	}
+	if components[0].Count() != 4 || components[1].Count() != 2 || components[2].Count() != 1 {
+		t.Errorf("Expected components of 4, 2 and 1 turtles")
+	}
+
+	strong := n.StronglyConnectedComponents()
+	if len(strong) != 4 {
+		t.Fatalf("Expected 4 strong components, got %d", len(strong))
+	}
+
+	sizes := map[int]int{}
+	for _, component := range strong {
+		sizes[component.Count()]++
+		if component.Contains(m.Turtle(0)) && (!component.Contains(m.Turtle(2)) || component.Contains(m.Turtle(3))) {
+			t.Errorf("Expected the cycle to be its own strong component")
+		}
+	}
+	if sizes[3] != 1 || sizes[2] != 1 || sizes[1] != 2 {
+		t.Errorf("Expected strong components of 3, 2, 1 and 1 turtles, got %v", sizes)
+	}
+}
+
+func TestNetworkCentrality(t *testing.T) {
+	friends := model.NewLinkBreed("friends")
+
+	m := model.NewModel(model.ModelSettings{
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+	})
+	m.CreateTurtles(5, nil)
+
+	// a star with 0 in the middle
+	for i := 1; i < 5; i++ {
+		friend(m, friends, 0, i)
+	}
+
+	n := network.NewNetwork(m, network.NetworkSettings{})
+
+	betweenness := n.BetweennessCentrality()
+	if betweenness[m.Turtle(0)] != 6 || betweenness[m.Turtle(1)] != 0 {
+		t.Errorf("Expected the center to be on all 6 paths between the leaves, got %f", betweenness[m.Turtle(0)])
+	}
+
+	closeness := n.ClosenessCentrality()
+	if closeness[m.Turtle(0)] != 1 || !closeTo(closeness[m.Turtle(1)], 4.0/7) {
+		t.Errorf("Expected closeness of 1 for the center and 4/7 for a leaf, got %f and %f", closeness[m.Turtle(0)], closeness[m.Turtle(1)])
+	}
+
+	eigenvector := n.EigenvectorCentrality()
+	if !closeTo(eigenvector[m.Turtle(0)], 1) || !closeTo(eigenvector[m.Turtle(1)], .5) {
+		t.Errorf("Expected eigenvector centrality of 1 for the center and .5 for a leaf, got %f and %f", eigenvector[m.Turtle(0)], eigenvector[m.Turtle(1)])
+	}
+
+	pageRank := n.PageRank(.85)
+	total := 0.0
+	for _, rank := range pageRank {
+		total += rank
+	}
+	if !closeTo(total, 1) {
+		t.Errorf("Expected the page ranks to add up to 1, got %f", total)
+	}
+	if pageRank[m.Turtle(0)] <= pageRank[m.Turtle(1)] || !closeTo(pageRank[m.Turtle(1)], pageRank[m.Turtle(4)]) {
+		t.Errorf("Expected the center to have the highest page rank and the leaves to be equal")
+	}
+}
+
+func TestNetworkDirectedCentrality(t *testing.T) {
+	roads := model.NewLinkBreedWithProperties("roads", map[string]interface{}{
+		"length": 1.0,
+	})
+
+	m := model.NewModel(model.ModelSettings{
+		DirectedLinkBreeds: []*model.LinkBreed{roads},
+	})
+	m.CreateTurtles(3, nil)
+
+	// a chain 0 -> 1 -> 2
+	road(m, roads, 0, 1, 1)
+	road(m, roads, 1, 2, 1)
+
+	n := network.NewNetwork(m, network.NetworkSettings{})
+
+	betweenness := n.BetweennessCentrality()
+	if betweenness[m.Turtle(1)] != 1 || betweenness[m.Turtle(0)] != 0 {
+		t.Errorf("Expected the middle of the chain to be on the one path, got %f", betweenness[m.Turtle(1)])
+	}
+
+	pageRank := n.PageRank(.85)
+	if !(pageRank[m.Turtle(2)] > pageRank[m.Turtle(1)] && pageRank[m.Turtle(1)] > pageRank[m.Turtle(0)]) {
+		t.Errorf("Expected the page rank to build up along the chain")
+	}
+
+	closeness := n.ClosenessCentrality()
+	if closeness[m.Turtle(2)] != 0 || !closeTo(closeness[m.Turtle(0)], 2.0/3) {
+		t.Errorf("Expected the end of the chain to reach nothing, got %f and %f", closeness[m.Turtle(2)], closeness[m.Turtle(0)])
+	}
+}
+
+func TestNetworkWeightedBetweenness(t *testing.T) {
+	roads := model.NewLinkBreedWithProperties("roads", map[string]interface{}{
+		"length": 1.0,
+	})
+
+	m := model.NewModel(model.ModelSettings{
+		DirectedLinkBreeds: []*model.LinkBreed{roads},
+	})
+	m.CreateTurtles(4, nil)
+
+	// two ways from 0 to 3, through 1 is lighter
+	road(m, roads, 0, 1, 1)
+	road(m, roads, 1, 3, 1)
+	road(m, roads, 0, 2, 2)
+	road(m, roads, 2, 3, 2)
+
+	unweighted := network.NewNetwork(m, network.NetworkSettings{})
+	if b := unweighted.BetweennessCentrality(); b[m.Turtle(1)] != .5 || b[m.Turtle(2)] != .5 {
+		t.Errorf("Expected both ways to share the path without weights, got %f and %f", b[m.Turtle(1)], b[m.Turtle(2)])
+	}
+
+	weighted := network.NewNetwork(m, network.NetworkSettings{WeightProperty: "length"})
+	if b := weighted.BetweennessCentrality(); b[m.Turtle(1)] != 1 || b[m.Turtle(2)] != 0 {
+		t.Errorf("Expected only the lighter way to be used with weights, got %f and %f", b[m.Turtle(1)], b[m.Turtle(2)])
+	}
+}
+
+func TestNetworkNegativeWeights(t *testing.T) {
+	roads := model.NewLinkBreedWithProperties("roads", map[string]interface{}{
+		"length": 1.0,
+	})
+
+	m := model.NewModel(model.ModelSettings{
+		DirectedLinkBreeds: []*model.LinkBreed{roads},
+	})
+	m.CreateTurtles(4, nil)
+
+	// through 1 would be lighter if the negative weight counted
+	road(m, roads, 0, 1, -5)
+	road(m, roads, 1, 3, 6)
+	road(m, roads, 0, 2, 1)
+	road(m, roads, 2, 3, .5)
+
+	n := network.NewNetwork(m, network.NetworkSettings{WeightProperty: "length"})
+
+	if distance, err := n.WeightedDistanceTo(m.Turtle(0), m.Turtle(1)); err != nil || distance != 0 {
+		t.Errorf("Expected the negative weight to count as 0, got %f", distance)
+	}
+	if distance, err := n.WeightedDistanceTo(m.Turtle(0), m.Turtle(3)); err != nil || distance != 1.5 {
+		t.Errorf("Expected the lightest path to weigh 1.5, got %f", distance)
+	}
+	if b := n.BetweennessCentrality(); b[m.Turtle(1)] != 0 || b[m.Turtle(2)] != 1 {
+		t.Errorf("Expected only the way through 2 to be used, got %f and %f", b[m.Turtle(1)], b[m.Turtle(2)])
+	}
+}
+
+func TestNetworkZeroWeightBetweenness(t *testing.T) {
+	roads := model.NewLinkBreedWithProperties("roads", map[string]interface{}{
+		"length": 1.0,
+	})
+
+	m := model.NewModel(model.ModelSettings{
+		DirectedLinkBreeds: []*model.LinkBreed{roads},
+	})
+	m.CreateTurtles(4, nil)
+
+	// 0 reaches 1 directly or through 2 over a link that weighs nothing, both just as light
+	road(m, roads, 0, 1, 1)
+	road(m, roads, 0, 2, 1)
+	road(m, roads, 2, 1, 0)
+	road(m, roads, 1, 3, 1)
+
+	n := network.NewNetwork(m, network.NetworkSettings{WeightProperty: "length"})
+
+	// 2 is on one of the two ways from 0 to 1 and from 0 to 3, 1 is on every way to 3
+	b := n.BetweennessCentrality()
+	if b[m.Turtle(2)] != 1 {
+		t.Errorf("Expected 2 to be on half of the paths from 0 to 1 and 3, got %f", b[m.Turtle(2)])
+	}
+	if b[m.Turtle(1)] != 2 {
+		t.Errorf("Expected 1 to be on every path to 3, got %f", b[m.Turtle(1)])
+	}
+}
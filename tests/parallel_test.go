@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/concurrency"
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// draws a number for every turtle in parallel and returns them by who number
+func parallelDraws(seed uint64, workers int, chunkSize int) []float64 {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:       seed,
+		RandomSeed2:      seed,
+		TurtleProperties: map[string]interface{}{"draw": 0.0},
+	})
+	m.CreateTurtles(500, nil)
+	m.Turtles().AskParallel(model.ParallelSettings{Workers: workers, ChunkSize: chunkSize}, func(turtle *model.Turtle, r *rand.Rand) {
+		turtle.SetProperty("draw", r.Float64()+r.NormFloat64())
+	})
+	draws := make([]float64, 500)
+	m.Turtles().Ask(func(turtle *model.Turtle) {
+		draws[turtle.Who()], _ = turtle.GetPropF("draw")
+	})
+	return draws
+}
+
+func TestAskParallelReproducible(t *testing.T) {
+	expected := parallelDraws(7, 1, 16)
+	for _, workers := range []int{2, 3, 8, 64, 0} {
+		draws := parallelDraws(7, workers, 16)
+		for i := range draws {
+			if draws[i] != expected[i] {
+				t.Fatalf("Expected the same draws with %d workers, turtle %d got %v instead of %v", workers, i, draws[i], expected[i])
+			}
+		}
+	}
+
+	other := parallelDraws(8, 4, 16)
+	same := 0
+	for i := range other {
+		if other[i] == expected[i] {
+			same++
+		}
+	}
+	if same > 0 {
+		t.Errorf("Expected a different seed to give different draws, %d were the same", same)
+	}
+}
+
+func TestAskParallelCallsDiffer(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  3,
+		RandomSeed2: 3,
+	})
+	m.CreateTurtles(10, nil)
+	first := make([]float64, 10)
+	second := make([]float64, 10)
+	m.Turtles().AskParallel(model.ParallelSettings{Workers: 2, ChunkSize: 3}, func(turtle *model.Turtle, r *rand.Rand) {
+		first[turtle.Who()] = r.Float64()
+	})
+	m.Turtles().AskParallel(model.ParallelSettings{Workers: 2, ChunkSize: 3}, func(turtle *model.Turtle, r *rand.Rand) {
+		second[turtle.Who()] = r.Float64()
+	})
+	for i := range first {
+		if first[i] == second[i] {
+			t.Errorf("Expected asking again to give new random numbers for turtle %d", i)
+		}
+	}
+
+	// the turtles in a chunk share a generator so they get different numbers
+	if first[0] == first[1] || first[1] == first[2] {
+		t.Errorf("Expected turtles in the same chunk to get different numbers")
+	}
+}
+
+func TestAskParallelVisitsEveryAgent(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	m.CreateTurtles(257, nil)
+
+	var count atomic.Int64
+	m.Turtles().AskParallel(model.ParallelSettings{}, func(turtle *model.Turtle, r *rand.Rand) {
+		count.Add(1)
+	})
+	if count.Load() != 257 {
+		t.Errorf("Expected 257 turtles asked, got %d", count.Load())
+	}
+
+	var patches atomic.Int64
+	m.Patches.AskParallel(model.ParallelSettings{Workers: 4, ChunkSize: 7}, func(patch *model.Patch, r *rand.Rand) {
+		patches.Add(1)
+	})
+	if int(patches.Load()) != m.Patches.Count() {
+		t.Errorf("Expected every patch asked, got %d", patches.Load())
+	}
+
+	model.NewLinkAgentSet(nil).AskParallel(model.ParallelSettings{}, func(link *model.Link, r *rand.Rand) {
+		t.Errorf("Expected no links asked")
+	})
+}
+
+func TestAskParallelPanic(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 1,
+	})
+	m.CreateTurtles(100, nil)
+
+	boom := errors.New("boom")
+	var panicErr *model.PanicError
+	func() {
+		defer func() {
+			panicErr, _ = recover().(*model.PanicError)
+		}()
+		m.Turtles().AskParallel(model.ParallelSettings{Workers: 3, ChunkSize: 10}, func(turtle *model.Turtle, r *rand.Rand) {
+			if turtle.Who() == 42 {
+				panic(boom)
+			}
+		})
+	}()
+
+	// the panic comes back on this goroutine with the turtle it happened on
+	if panicErr == nil {
+		t.Fatalf("Expected AskParallel to panic with a PanicError")
+	}
+	if panicErr.AgentID != 42 || panicErr.Agent.(*model.Turtle) != m.Turtle(42) {
+		t.Errorf("Expected the panic to be on turtle 42, got %d", panicErr.AgentID)
+	}
+	if !errors.Is(panicErr, boom) || len(panicErr.Stack) == 0 {
+		t.Errorf("Expected the panic value and stack to be kept")
+	}
+
+	// the movement phase is still committed
+	func() {
+		defer func() { recover() }()
+		m.Turtles().AskParallel(model.ParallelSettings{DeferMovement: true}, func(turtle *model.Turtle, r *rand.Rand) {
+			panic("boom")
+		})
+	}()
+	if m.InMovementPhase() {
+		t.Errorf("Expected the movement phase to be committed after a panic")
+	}
+}
+
+func TestConcurrencyAskDeterministic(t *testing.T) {
+	draws := func(workers int) []float64 {
+		m := model.NewModel(model.ModelSettings{
+			RandomSeed:  11,
+			RandomSeed2: 11,
+		})
+		m.CreateTurtles(100, nil)
+		values := make([]float64, 100)
+		concurrency.AskTurtlesDeterministic(m.Turtles().List(), func(turtle *model.Turtle, r *rand.Rand) {
+			values[turtle.Who()] = r.Float64()
+		}, workers)
+		return values
+	}
+
+	a := draws(1)
+	b := draws(5)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("Expected the same draws at any number of goroutines, turtle %d differs", i)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/loader"
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func TestPatchColumnsDeclared(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor: -2,
+		MaxPxCor: 2,
+		MinPyCor: -2,
+		MaxPyCor: 2,
+		PatchProperties: map[string]interface{}{
+			"untyped": 1.0,
+		},
+		PatchColumns: map[string]interface{}{
+			"heat":   1.5,
+			"count":  2,
+			"wet":    false,
+			"ignore": "strings are not supported",
+		},
+	})
+
+	heat := m.FloatColumn("heat")
+	count := m.IntColumn("count")
+	wet := m.BoolColumn("wet")
+	if heat == nil || count == nil || wet == nil {
+		t.Fatalf("Expected the columns to be declared")
+	}
+
+	if m.FloatColumn("count") != nil || m.FloatColumn("ignore") != nil || m.IntColumn("heat") != nil {
+		t.Errorf("Expected columns to only be found by their type")
+	}
+
+	if len(heat.Values()) != m.Patches.Count() {
+		t.Errorf("Expected a value for each patch, got %d", len(heat.Values()))
+	}
+
+	p := m.Patch(1, 1)
+	if heat.Get(p) != 1.5 || count.Get(p) != 2 || wet.Get(p) {
+		t.Errorf("Expected the patches to start with the default values")
+	}
+
+	heat.Set(p, 4)
+	if heat.Get(p) != 4 || heat.Get(m.Patch(0, 0)) != 1.5 {
+		t.Errorf("Expected only the patch that was set to change")
+	}
+}
+
+func TestPatchColumnsGetProperty(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor: -2,
+		MaxPxCor: 2,
+		MinPyCor: -2,
+		MaxPyCor: 2,
+		PatchProperties: map[string]interface{}{
+			"untyped": 1.0,
+		},
+		PatchColumns: map[string]interface{}{
+			"heat":   1.5,
+			"count":  2,
+			"wet":    false,
+			"ignore": "strings are not supported",
+		},
+	})
+	p := m.Patch(0, 0)
+
+	if p.GetPropF("heat") != 1.5 || p.GetPropI("count") != 2 || p.GetPropB("wet") {
+		t.Errorf("Expected GetProperty to read the columns")
+	}
+
+	p.SetProperty("heat", 3)
+	p.SetProperty("count", 5.7)
+	p.SetProperty("wet", true)
+	p.SetProperty("wet", "not a bool")
+
+	if m.FloatColumn("heat").Get(p) != 3 || m.IntColumn("count").Get(p) != 5 || !m.BoolColumn("wet").Get(p) {
+		t.Errorf("Expected SetProperty to write to the columns")
+	}
+
+	// untyped variables keep working as before
+	p.SetProperty("untyped", 2.5)
+	if p.GetPropF("untyped") != 2.5 {
+		t.Errorf("Expected untyped variables to still be stored on the patch")
+	}
+
+	// numbers are converted the same way for columns and untyped variables, anything else gives 0
+	if p.GetPropI("heat") != 3 || p.GetPropF("count") != 5 || p.GetPropI("untyped") != 2 {
+		t.Errorf("Expected numbers to be converted between ints and floats, got %d %f %d", p.GetPropI("heat"), p.GetPropF("count"), p.GetPropI("untyped"))
+	}
+	if p.GetPropF("wet") != 0 || p.GetPropI("wet") != 0 {
+		t.Errorf("Expected a bool column to read as 0 as a number")
+	}
+
+	// properties include every column
+	properties := p.Properties()
+	if properties["heat"] != 3.0 || properties["count"] != 5 || properties["wet"] != true || properties["untyped"] != 2.5 {
+		t.Errorf("Expected the properties to include the columns, got %v", properties)
+	}
+
+	m.ClearPatches()
+	if m.FloatColumn("heat").Get(p) != 1.5 || m.BoolColumn("wet").Get(p) {
+		t.Errorf("Expected clearing the patches to reset the columns")
+	}
+}
+
+func TestPatchColumnOperations(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor: -2,
+		MaxPxCor: 2,
+		MinPyCor: -2,
+		MaxPyCor: 2,
+		PatchProperties: map[string]interface{}{
+			"untyped": 1.0,
+		},
+		PatchColumns: map[string]interface{}{
+			"heat":   1.5,
+			"count":  2,
+			"wet":    false,
+			"ignore": "strings are not supported",
+		},
+	})
+
+	heat := m.FloatColumn("heat")
+	heat.Fill(2)
+	heat.Set(m.Patch(-2, -2), -1)
+	heat.Set(m.Patch(2, 2), 10)
+
+	if heat.Min() != -1 || heat.Max() != 10 {
+		t.Errorf("Expected min -1 and max 10, got %f and %f", heat.Min(), heat.Max())
+	}
+	if heat.Sum() != 2*23-1+10 {
+		t.Errorf("Expected the sum to be 55, got %f", heat.Sum())
+	}
+
+	heat.Scale(.5)
+	if heat.Get(m.Patch(0, 0)) != 1 || heat.Max() != 5 {
+		t.Errorf("Expected the column to be scaled")
+	}
+
+	heat.Add(heat)
+	if heat.Get(m.Patch(0, 0)) != 2 {
+		t.Errorf("Expected the column to be added to itself")
+	}
+
+	heat.AddScaled(heat, -.5)
+	if heat.Get(m.Patch(0, 0)) != 1 {
+		t.Errorf("Expected half the column to be subtracted")
+	}
+
+	count := m.IntColumn("count")
+	count.Scale(3)
+	count.Set(m.Patch(0, 0), -4)
+	if count.Sum() != 6*24-4 || count.Min() != -4 || count.Max() != 6 {
+		t.Errorf("Expected int column operations to work, got sum %d", count.Sum())
+	}
+
+	wet := m.BoolColumn("wet")
+	wet.Set(m.Patch(1, 0), true)
+	wet.Set(m.Patch(0, 1), true)
+	if wet.Count() != 2 {
+		t.Errorf("Expected 2 wet patches, got %d", wet.Count())
+	}
+	wet.Fill(true)
+	if wet.Count() != m.Patches.Count() {
+		t.Errorf("Expected every patch to be wet")
+	}
+}
+
+func TestPatchColumnDiffuse(t *testing.T) {
+	settings := model.ModelSettings{
+		PatchProperties: map[string]interface{}{
+			"heat": 0.0,
+		},
+	}
+	untyped := model.NewModel(settings)
+	typed := model.NewModel(model.ModelSettings{
+		PatchColumns: map[string]interface{}{
+			"heat": 0.0,
+		},
+	})
+
+	untyped.Patch(0, 0).SetProperty("heat", 100.0)
+	typed.Patch(0, 0).SetProperty("heat", 100.0)
+
+	for i := 0; i < 3; i++ {
+		untyped.Diffuse("heat", .5)
+		typed.Diffuse("heat", .5)
+		untyped.Diffuse4("heat", .3)
+		typed.Diffuse4("heat", .3)
+	}
+
+	untyped.Patches.Ask(func(p *model.Patch) {
+		if !closeTo(p.GetPropF("heat"), typed.FloatColumn("heat").Get(typed.Patch(float64(p.XCor()), float64(p.YCor())))) {
+			t.Errorf("Expected diffusing a column to match diffusing a property at %d %d", p.XCor(), p.YCor())
+		}
+	})
+
+	if !closeTo(typed.FloatColumn("heat").Sum(), 100) {
+		t.Errorf("Expected diffusion to keep the total, got %f", typed.FloatColumn("heat").Sum())
+	}
+
+	if typed.Diffuse("missing", .5) != model.ErrPatchVariableNotFloat {
+		t.Errorf("Expected an error when diffusing a variable that isn't a float")
+	}
+
+	// turtles can walk uphill on a column
+	typed.CreateTurtles(1, func(turtle *model.Turtle) {
+		turtle.SetXY(1, 1)
+	})
+	typed.Turtle(0).Uphill("heat")
+	if typed.Turtle(0).PatchHere() != typed.Patch(0, 0) {
+		t.Errorf("Expected the turtle to move uphill to the hottest patch")
+	}
+}
+
+func TestPatchColumnsSaveAndLoad(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor: -2,
+		MaxPxCor: 2,
+		MinPyCor: -2,
+		MaxPyCor: 2,
+		PatchProperties: map[string]interface{}{
+			"untyped": 1.0,
+		},
+		PatchColumns: map[string]interface{}{
+			"heat":   1.5,
+			"count":  2,
+			"wet":    false,
+			"ignore": "strings are not supported",
+		},
+	})
+	m.FloatColumn("heat").Set(m.Patch(1, 2), 7.5)
+	m.IntColumn("count").Set(m.Patch(-1, 0), 9)
+	m.BoolColumn("wet").Set(m.Patch(2, 2), true)
+
+	bytes, err := json.Marshal(loader.GetModel(m))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	saved := &loader.Model{}
+	if err := json.Unmarshal(bytes, saved); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	loaded := loader.SetModel(saved)
+
+	if loaded.IntColumn("count") == nil || loaded.IntColumn("count").Default() != 2 {
+		t.Fatalf("Expected the int column to keep its type after loading")
+	}
+	if loaded.FloatColumn("heat").Get(loaded.Patch(1, 2)) != 7.5 {
+		t.Errorf("Expected the float column values to be restored")
+	}
+	if loaded.IntColumn("count").Get(loaded.Patch(-1, 0)) != 9 {
+		t.Errorf("Expected the int column values to be restored")
+	}
+	if !loaded.BoolColumn("wet").Get(loaded.Patch(2, 2)) || loaded.BoolColumn("wet").Count() != 1 {
+		t.Errorf("Expected the bool column values to be restored")
+	}
+}
+
+func BenchmarkDiffuseProperty(b *testing.B) {
+	m := model.NewModel(model.ModelSettings{
+		PatchProperties: map[string]interface{}{
+			"heat": 1.0,
+		},
+	})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Diffuse("heat", .5)
+	}
+}
+
+func BenchmarkDiffuseColumn(b *testing.B) {
+	m := model.NewModel(model.ModelSettings{
+		PatchColumns: map[string]interface{}{
+			"heat": 1.0,
+		},
+	})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Diffuse("heat", .5)
+	}
+}
+
+func BenchmarkScaleProperty(b *testing.B) {
+	m := model.NewModel(model.ModelSettings{
+		PatchProperties: map[string]interface{}{
+			"heat": 1.0,
+		},
+	})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Patches.Ask(func(p *model.Patch) {
+			p.SetProperty("heat", p.GetPropF("heat")*.99)
+		})
+	}
+}
+
+func BenchmarkScaleColumn(b *testing.B) {
+	m := model.NewModel(model.ModelSettings{
+		PatchColumns: map[string]interface{}{
+			"heat": 1.0,
+		},
+	})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.FloatColumn("heat").Scale(.99)
+	}
+}
@@ -0,0 +1,287 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func notWall(p *model.Patch) bool {
+	return !p.GetPropB("wall")
+}
+
+// checks that every step of the path moves to a neighboring patch
+func checkPathSteps(t *testing.T, path *model.Path, neighbors func(p *model.Patch) *model.PatchAgentSet) {
+	for i := 1; i < len(path.Patches); i++ {
+		if !neighbors(path.Patches[i-1]).Contains(path.Patches[i]) {
+			t.Errorf("Expected step %d of the path to move to a neighboring patch", i)
+		}
+	}
+}
+
+func TestAStarOpenGrid(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor: -5,
+		MaxPxCor: 5,
+		MinPyCor: -5,
+		MaxPyCor: 5,
+	})
+
+	start := m.Patch(-3, -3)
+	goal := m.Patch(3, 0)
+
+	path, err := m.AStar(start, goal, model.PathSettings{Connectivity: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if path.Patches[0] != start || path.Patches[len(path.Patches)-1] != goal {
+		t.Errorf("Expected the path to go from the start to the goal")
+	}
+	if len(path.Patches) != 10 || path.Cost != 9 {
+		t.Errorf("Expected a 4 connected path of 9 steps, got %d patches costing %f", len(path.Patches), path.Cost)
+	}
+	checkPathSteps(t, path, func(p *model.Patch) *model.PatchAgentSet { return p.Neighbors4() })
+
+	path, err = m.AStar(start, goal, model.PathSettings{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(path.Patches) != 7 || !closeTo(path.Cost, 3+3*math.Sqrt(2)) {
+		t.Errorf("Expected an 8 connected path of 6 steps, got %d patches costing %f", len(path.Patches), path.Cost)
+	}
+	checkPathSteps(t, path, func(p *model.Patch) *model.PatchAgentSet { return p.Neighbors() })
+
+	path, _ = m.AStar(start, start, model.PathSettings{})
+	if len(path.Patches) != 1 || path.Cost != 0 {
+		t.Errorf("Expected a path to the same patch to be just the patch")
+	}
+}
+
+func TestPathAroundWall(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor:        -5,
+		MaxPxCor:        5,
+		MinPyCor:        -5,
+		MaxPyCor:        5,
+		PatchProperties: map[string]interface{}{"wall": false},
+	})
+
+	// a wall across the middle with a gap at the top
+	for y := -5; y <= 3; y++ {
+		m.Patch(0, float64(y)).SetProperty("wall", true)
+	}
+
+	start := m.Patch(-2, -4)
+	goal := m.Patch(2, -4)
+
+	for _, connectivity := range []int{4, 8} {
+		settings := model.PathSettings{Walkable: notWall, Connectivity: connectivity}
+
+		aStar, err := m.AStar(start, goal, settings)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		dijkstra, err := m.Dijkstra(start, goal, settings)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !closeTo(aStar.Cost, dijkstra.Cost) {
+			t.Errorf("Expected A* and Dijkstra to find paths of the same cost, got %f and %f", aStar.Cost, dijkstra.Cost)
+		}
+
+		for _, patch := range aStar.Patches {
+			if patch.GetPropB("wall") {
+				t.Errorf("Expected the path to avoid the wall")
+			}
+		}
+
+		if !containsPatch(aStar.Patches, m.Patch(0, 4)) && !containsPatch(aStar.Patches, m.Patch(0, 5)) {
+			t.Errorf("Expected the path to go through the gap")
+		}
+	}
+
+	// close the gap
+	m.Patch(0, 4).SetProperty("wall", true)
+	m.Patch(0, 5).SetProperty("wall", true)
+	if _, err := m.AStar(start, goal, model.PathSettings{Walkable: notWall}); err != model.ErrNoPath {
+		t.Errorf("Expected no path when the wall is closed, got %v", err)
+	}
+}
+
+func containsPatch(patches []*model.Patch, patch *model.Patch) bool {
+	for _, p := range patches {
+		if p == patch {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPathCornerCutting(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor:        -5,
+		MaxPxCor:        5,
+		MinPyCor:        -5,
+		MaxPyCor:        5,
+		PatchProperties: map[string]interface{}{"wall": false},
+	})
+
+	m.Patch(1, 0).SetProperty("wall", true)
+	m.Patch(0, 1).SetProperty("wall", true)
+
+	path, err := m.AStar(m.Patch(0, 0), m.Patch(1, 1), model.PathSettings{Walkable: notWall})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(path.Patches) == 2 {
+		t.Errorf("Expected the path to not squeeze between the walls")
+	}
+
+	path, _ = m.AStar(m.Patch(0, 0), m.Patch(1, 1), model.PathSettings{Walkable: notWall, AllowCornerCutting: true})
+	if len(path.Patches) != 2 {
+		t.Errorf("Expected the path to cut the corner when allowed")
+	}
+}
+
+func TestPathCost(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor: -5,
+		MaxPxCor: 5,
+		MinPyCor: -5,
+		MaxPyCor: 5,
+	})
+
+	// a swamp in the middle that costs a lot to cross
+	swampCost := func(p *model.Patch) float64 {
+		if p.XCor() == 0 && p.YCor() > -4 && p.YCor() < 4 {
+			return 20
+		}
+		return 1
+	}
+
+	path, err := m.Dijkstra(m.Patch(-2, 0), m.Patch(2, 0), model.PathSettings{Cost: swampCost, Connectivity: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path.Cost != 12 {
+		t.Errorf("Expected the path to go around the swamp for a cost of 12, got %f", path.Cost)
+	}
+
+	aStar, _ := m.AStar(m.Patch(-2, 0), m.Patch(2, 0), model.PathSettings{Cost: swampCost, Connectivity: 4})
+	if aStar.Cost != path.Cost {
+		t.Errorf("Expected A* to find the same cost as Dijkstra, got %f", aStar.Cost)
+	}
+
+	costs, err := m.PathCostsFrom(m.Patch(-2, 0), model.PathSettings{Cost: swampCost, Connectivity: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(costs) != m.Patches.Count() || costs[m.Patch(2, 0)] != 12 || costs[m.Patch(-2, 0)] != 0 {
+		t.Errorf("Expected the cost to every patch")
+	}
+
+	if _, err := m.AStar(m.Patch(0, 0), m.Patch(1, 0), model.PathSettings{Connectivity: 6}); err != model.ErrPathConnectivity {
+		t.Errorf("Expected an error for a bad connectivity")
+	}
+}
+
+func TestPathWrapping(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor:  -5,
+		MaxPxCor:  5,
+		MinPyCor:  -5,
+		MaxPyCor:  5,
+		WrappingX: true,
+	})
+
+	path, err := m.AStar(m.Patch(-5, 0), m.Patch(5, 0), model.PathSettings{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(path.Patches) != 2 {
+		t.Errorf("Expected the path to wrap around the edge, got %d patches", len(path.Patches))
+	}
+}
+
+func TestPath3D(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor:        -2,
+		MaxPxCor:        2,
+		MinPyCor:        -2,
+		MaxPyCor:        2,
+		MinPzCor:        -2,
+		MaxPzCor:        2,
+		PatchProperties: map[string]interface{}{"wall": false},
+	})
+
+	// block the layer in the middle except for one hole
+	m.Patches.Ask(func(p *model.Patch) {
+		if p.ZCor() == 0 && !(p.XCor() == 2 && p.YCor() == 2) {
+			p.SetProperty("wall", true)
+		}
+	})
+
+	start := m.Patch3D(0, 0, -2)
+	goal := m.Patch3D(0, 0, 2)
+
+	path, err := m.AStar(start, goal, model.PathSettings{Walkable: notWall, Connectivity: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !containsPatch(path.Patches, m.Patch3D(2, 2, 0)) {
+		t.Errorf("Expected the path to go through the hole in the layer")
+	}
+	if path.Cost != 12 {
+		t.Errorf("Expected a cost of 12 going along the axes, got %f", path.Cost)
+	}
+
+	dijkstra, _ := m.Dijkstra(start, goal, model.PathSettings{Walkable: notWall})
+	aStar, _ := m.AStar(start, goal, model.PathSettings{Walkable: notWall})
+	if !closeTo(dijkstra.Cost, aStar.Cost) || aStar.Cost >= 12 {
+		t.Errorf("Expected diagonal moves to make the path cheaper, got %f and %f", aStar.Cost, dijkstra.Cost)
+	}
+}
+
+func TestFollowPath(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor: -5,
+		MaxPxCor: 5,
+		MinPyCor: -5,
+		MaxPyCor: 5,
+	})
+
+	path, _ := m.AStar(m.Patch(0, 0), m.Patch(3, 0), model.PathSettings{Connectivity: 4})
+
+	m.CreateTurtles(1, nil)
+	turtle := m.Turtle(0)
+	turtle.SetXY(0, 0)
+
+	steps := 0
+	for !turtle.FollowPath(path) {
+		steps++
+		if steps > 10 {
+			t.Fatalf("Expected the turtle to reach the end of the path")
+		}
+		if turtle.PatchHere() != path.Patches[steps] {
+			t.Errorf("Expected the turtle to be on patch %d of the path", steps)
+		}
+	}
+
+	if turtle.PatchHere() != m.Patch(3, 0) || steps != 2 {
+		t.Errorf("Expected the turtle to reach the goal in 3 steps, took %d", steps+1)
+	}
+
+	if !closeTo(turtle.GetHeading(), 0) {
+		t.Errorf("Expected the turtle to face along the path, got %f", turtle.GetHeading())
+	}
+
+	// a turtle off the path goes to the start first
+	turtle.SetXY(-4, 4)
+	turtle.FollowPath(path)
+	if turtle.PatchHere() != m.Patch(0, 0) {
+		t.Errorf("Expected the turtle to go to the start of the path")
+	}
+}
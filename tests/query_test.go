@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func whos(turtles []*model.Turtle) []int {
+	result := []int{}
+	for _, turtle := range turtles {
+		result = append(result, turtle.Who())
+	}
+	return result
+}
+
+func sameInts(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAgentSetUnionIntersectDifference(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+	m.CreateTurtles(6, nil)
+
+	a := model.NewTurtleAgentSet([]*model.Turtle{m.Turtle(0), m.Turtle(1), m.Turtle(2), m.Turtle(3)})
+	b := model.NewTurtleAgentSet([]*model.Turtle{m.Turtle(5), m.Turtle(3), m.Turtle(1)})
+
+	if got := whos(a.Union(b).List()); !sameInts(got, []int{0, 1, 2, 3, 5}) {
+		t.Errorf("Expected union 0 1 2 3 5, got %v", got)
+	}
+	if got := whos(a.Intersect(b).List()); !sameInts(got, []int{1, 3}) {
+		t.Errorf("Expected intersection 1 3, got %v", got)
+	}
+	if got := whos(a.Difference(b).List()); !sameInts(got, []int{0, 2}) {
+		t.Errorf("Expected difference 0 2, got %v", got)
+	}
+
+	// the agent sets themselves are left alone
+	if a.Count() != 4 || b.Count() != 3 {
+		t.Errorf("Expected the agent sets to be unchanged")
+	}
+
+	links := model.NewLinkAgentSet(nil)
+	if links.Union(links).Count() != 0 {
+		t.Errorf("Expected an empty union of empty link sets")
+	}
+
+	left := model.NewPatchAgentSet([]*model.Patch{m.Patch(0, 0), m.Patch(1, 0)})
+	right := model.NewPatchAgentSet([]*model.Patch{m.Patch(1, 0), m.Patch(2, 0)})
+	if left.Union(right).Count() != 3 || left.Intersect(right).Count() != 1 || left.Difference(right).Count() != 1 {
+		t.Errorf("Expected patch set algebra to work")
+	}
+}
+
+func TestAgentSetIter(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+	m.CreateTurtles(6, nil)
+
+	seen := []int{}
+	for turtle := range m.Turtles().Iter() {
+		seen = append(seen, turtle.Who())
+		if turtle.Who() == 2 {
+			break
+		}
+	}
+	if !sameInts(seen, []int{0, 1, 2}) {
+		t.Errorf("Expected ranging to stop after turtle 2, got %v", seen)
+	}
+
+	count := 0
+	for range m.Patches.Iter() {
+		count++
+	}
+	if count != m.Patches.Count() {
+		t.Errorf("Expected to range over %d patches, got %d", m.Patches.Count(), count)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:       1,
+		RandomSeed2:      1,
+		TurtleProperties: map[string]interface{}{"energy": 0.0, "team": ""},
+	})
+	m.CreateTurtles(6, func(turtle *model.Turtle) {
+		turtle.SetProperty("energy", float64(turtle.Who()%3))
+		if turtle.Who() < 2 {
+			turtle.SetProperty("team", "red")
+		} else {
+			turtle.SetProperty("team", "blue")
+		}
+	})
+
+	blue := func(turtle *model.Turtle) bool {
+		return turtle.GetProperty("team") == "blue"
+	}
+
+	// energies are 0 1 2 0 1 2 and turtles 2 to 5 are blue
+	query := m.Turtles().Query().Where(blue).SortDesc(energy)
+	if got := whos(query.List()); !sameInts(got, []int{2, 5, 4, 3}) {
+		t.Errorf("Expected blue turtles by energy 2 5 4 3, got %v", got)
+	}
+	if got := whos(query.Limit(2).List()); !sameInts(got, []int{2, 5}) {
+		t.Errorf("Expected the top two 2 5, got %v", got)
+	}
+	if got := whos(m.Turtles().Query().SortAsc(energy).Limit(3).List()); !sameInts(got, []int{0, 3, 1}) {
+		t.Errorf("Expected the lowest three 0 3 1, got %v", got)
+	}
+
+	if query.Count() != 4 {
+		t.Errorf("Expected 4 blue turtles, got %d", query.Count())
+	}
+	if sum := query.Sum(energy); sum != 5 {
+		t.Errorf("Expected a sum of 5, got %v", sum)
+	}
+	if mean, err := query.Mean(energy); err != nil || mean != 1.25 {
+		t.Errorf("Expected a mean of 1.25, got %v %v", mean, err)
+	}
+
+	values := []float64{}
+	for value := range query.Floats(energy) {
+		values = append(values, value)
+	}
+	if len(values) != 4 || values[0] != 2 || values[3] != 0 {
+		t.Errorf("Expected sorted energies, got %v", values)
+	}
+
+	first, err := query.First()
+	if err != nil || first.Who() != 2 {
+		t.Errorf("Expected turtle 2 first, got %v", err)
+	}
+
+	asked := 0
+	query.Ask(func(turtle *model.Turtle) {
+		asked++
+	})
+	if asked != 4 {
+		t.Errorf("Expected 4 turtles asked, got %d", asked)
+	}
+}
+
+func TestQueryIsLazy(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+	m.CreateTurtles(6, nil)
+
+	checked := 0
+	query := m.Turtles().Query().Where(func(turtle *model.Turtle) bool {
+		checked++
+		return true
+	}).Limit(2)
+	if checked != 0 {
+		t.Errorf("Expected nothing to run until the query is used, got %d checks", checked)
+	}
+
+	query.List()
+	if checked != 2 {
+		t.Errorf("Expected the limit to stop after 2 checks, got %d", checked)
+	}
+
+	// the query runs against the agent set as it is when used
+	m.CreateTurtles(1, nil)
+	if m.Turtles().Query().Count() != 7 {
+		t.Errorf("Expected a new query to see the new turtle")
+	}
+
+	empty := model.NewTurtleAgentSet(nil).Query()
+	if _, err := empty.First(); err != model.ErrNoAgentsInQuery {
+		t.Errorf("Expected an error from an empty query")
+	}
+	if _, err := empty.Mean(energy); err != model.ErrNoAgentsInQuery {
+		t.Errorf("Expected an error from the mean of an empty query")
+	}
+	if empty.Limit(0).Count() != 0 {
+		t.Errorf("Expected no agents")
+	}
+}
@@ -0,0 +1,318 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// draws from every distribution and sampling function so two runs can be compared
+func randomSequence(m *model.Model) []float64 {
+	sequence := []float64{
+		m.RandomNormal(0, 1),
+		m.RandomLogNormal(0, 1),
+		m.RandomExponential(2),
+		float64(m.RandomPoisson(3)),
+		float64(m.RandomPoisson(50)),
+		m.RandomGamma(2, 1),
+		m.RandomGamma(.5, 2),
+		m.RandomBeta(2, 3),
+		float64(m.RandomBinomial(10, .3)),
+		float64(m.RandomBinomial(1000, .3)),
+		float64(m.RandomWeighted([]float64{1, 2, 3})),
+		float64(m.RandomZipf(1.2, 100)),
+	}
+
+	turtles := m.Turtles()
+	one, _ := turtles.OneOf()
+	sequence = append(sequence, float64(one.Who()))
+	for _, turtle := range turtles.NOf(3).List() {
+		sequence = append(sequence, float64(turtle.Who()))
+	}
+	weighted, _ := turtles.WeightedOneOf(func(t *model.Turtle) float64 { return float64(t.Who()) })
+	sequence = append(sequence, float64(weighted.Who()))
+	for _, turtle := range turtles.WeightedNOf(2, func(t *model.Turtle) float64 { return float64(t.Who()) }).List() {
+		sequence = append(sequence, float64(turtle.Who()))
+	}
+	return sequence
+}
+
+func sameSequence(a []float64, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRandomReproducibleWithSeed(t *testing.T) {
+	m1 := model.NewModel(model.ModelSettings{
+		RandomSeed:  42,
+		RandomSeed2: 43,
+	})
+	m2 := model.NewModel(model.ModelSettings{
+		RandomSeed:  42,
+		RandomSeed2: 43,
+	})
+	m1.CreateTurtles(10, nil)
+	m2.CreateTurtles(10, nil)
+
+	for i := 0; i < 20; i++ {
+		if !sameSequence(randomSequence(m1), randomSequence(m2)) {
+			t.Fatalf("Expected the same draws with the same seed")
+		}
+	}
+
+	m3 := model.NewModel(model.ModelSettings{
+		RandomSeed:  43,
+		RandomSeed2: 44,
+	})
+	m3.CreateTurtles(10, nil)
+	if sameSequence(randomSequence(m1), randomSequence(m3)) {
+		t.Errorf("Expected different draws with a different seed")
+	}
+}
+
+func TestRandomReproducibleWithRandomState(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  7,
+		RandomSeed2: 8,
+	})
+	m.CreateTurtles(10, nil)
+	randomSequence(m)
+
+	seed1, seed2, state := m.GetRandomState()
+	first := randomSequence(m)
+
+	if err := m.SetRandomState(seed1, seed2, state); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !sameSequence(first, randomSequence(m)) {
+		t.Errorf("Expected the same draws after restoring the random state")
+	}
+
+	// a second model picks up where the first left off
+	other := model.NewModel(model.ModelSettings{
+		RandomSeed:  99,
+		RandomSeed2: 100,
+	})
+	other.CreateTurtles(10, nil)
+	other.SetRandomState(seed1, seed2, state)
+	if !sameSequence(first, randomSequence(other)) {
+		t.Errorf("Expected the same draws in another model with the restored random state")
+	}
+}
+
+// returns the mean and variance of the draws
+func moments(n int, draw func() float64) (float64, float64) {
+	sum, sumSquares := 0.0, 0.0
+	for i := 0; i < n; i++ {
+		x := draw()
+		sum += x
+		sumSquares += x * x
+	}
+	mean := sum / float64(n)
+	return mean, sumSquares/float64(n) - mean*mean
+}
+
+func TestRandomDistributions(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 2,
+	})
+	n := 50000
+
+	tests := []struct {
+		name     string
+		draw     func() float64
+		mean     float64
+		variance float64
+	}{
+		{"normal", func() float64 { return m.RandomNormal(5, 2) }, 5, 4},
+		{"log normal", func() float64 { return m.RandomLogNormal(0, .5) }, math.Exp(.125), (math.Exp(.25) - 1) * math.Exp(.25)},
+		{"exponential", func() float64 { return m.RandomExponential(3) }, 3, 9},
+		{"poisson small", func() float64 { return float64(m.RandomPoisson(4)) }, 4, 4},
+		{"poisson large", func() float64 { return float64(m.RandomPoisson(200)) }, 200, 200},
+		{"gamma", func() float64 { return m.RandomGamma(3, 2) }, 1.5, .75},
+		{"gamma small shape", func() float64 { return m.RandomGamma(.5, 1) }, .5, .5},
+		{"beta", func() float64 { return m.RandomBeta(2, 5) }, 2.0 / 7, 10.0 / (49 * 8)},
+		{"binomial small", func() float64 { return float64(m.RandomBinomial(10, .4)) }, 4, 2.4},
+		{"binomial large", func() float64 { return float64(m.RandomBinomial(5000, .1)) }, 500, 450},
+	}
+
+	for _, test := range tests {
+		mean, variance := moments(n, test.draw)
+		if math.Abs(mean-test.mean) > .03*math.Max(1, math.Abs(test.mean)) {
+			t.Errorf("%s: expected a mean of about %f, got %f", test.name, test.mean, mean)
+		}
+		if math.Abs(variance-test.variance) > .08*math.Max(1, test.variance) {
+			t.Errorf("%s: expected a variance of about %f, got %f", test.name, test.variance, variance)
+		}
+	}
+
+	if m.RandomPoisson(0) != 0 || m.RandomBinomial(0, .5) != 0 || m.RandomBinomial(10, 1) != 10 {
+		t.Errorf("Expected the edge cases to give exact answers")
+	}
+}
+
+func TestRandomWeightedAndZipf(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 2,
+	})
+
+	counts := make([]int, 4)
+	for i := 0; i < 40000; i++ {
+		counts[m.RandomWeighted([]float64{1, 0, 3, -2})]++
+	}
+	if counts[1] != 0 || counts[3] != 0 {
+		t.Errorf("Expected weights that aren't positive never to be picked")
+	}
+	if ratio := float64(counts[2]) / float64(counts[0]); ratio < 2.8 || ratio > 3.2 {
+		t.Errorf("Expected the third weight to be picked 3 times as often, got %f", ratio)
+	}
+	if m.RandomWeighted([]float64{0, 0}) != -1 {
+		t.Errorf("Expected -1 when no weight is positive")
+	}
+
+	ranks := make([]int, 11)
+	for i := 0; i < 40000; i++ {
+		ranks[m.RandomZipf(1, 10)]++
+	}
+	if ranks[0] != 0 {
+		t.Errorf("Expected the ranks to start at 1")
+	}
+	if ratio := float64(ranks[1]) / float64(ranks[2]); ratio < 1.8 || ratio > 2.2 {
+		t.Errorf("Expected rank 1 to be picked twice as often as rank 2, got %f", ratio)
+	}
+}
+
+func TestAgentSetSampling(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 2,
+	})
+	m.CreateTurtles(20, nil)
+	turtles := m.Turtles()
+
+	sample := turtles.NOf(5)
+	if sample.Count() != 5 {
+		t.Errorf("Expected 5 turtles, got %d", sample.Count())
+	}
+	previous := -1
+	for _, turtle := range sample.List() {
+		if turtle.Who() <= previous {
+			t.Errorf("Expected the sample to keep the order of the agent set")
+		}
+		previous = turtle.Who()
+	}
+	if turtles.NOf(50).Count() != 20 {
+		t.Errorf("Expected every turtle when asking for more than there are")
+	}
+
+	empty := model.NewTurtleAgentSet(nil)
+	if _, err := empty.OneOf(); err != model.ErrNoTurtlesInAgentSet {
+		t.Errorf("Expected an error from an empty agent set")
+	}
+	if empty.NOf(3).Count() != 0 {
+		t.Errorf("Expected no turtles from an empty agent set")
+	}
+
+	// only even turtles have weight
+	even := func(turtle *model.Turtle) float64 {
+		if turtle.Who()%2 == 0 {
+			return 1
+		}
+		return 0
+	}
+	for i := 0; i < 100; i++ {
+		turtle, _ := turtles.WeightedOneOf(even)
+		if turtle.Who()%2 != 0 {
+			t.Fatalf("Expected only turtles with weight to be picked")
+		}
+	}
+	if picked := turtles.WeightedNOf(15, even); picked.Count() != 10 {
+		t.Errorf("Expected only the 10 turtles with weight, got %d", picked.Count())
+	}
+
+	// heavier turtles are picked more often
+	heavy := 0
+	for i := 0; i < 2000; i++ {
+		turtle, _ := turtles.WeightedOneOf(func(turtle *model.Turtle) float64 {
+			if turtle.Who() == 0 {
+				return 19
+			}
+			return 1
+		})
+		if turtle.Who() == 0 {
+			heavy++
+		}
+	}
+	if heavy < 900 || heavy > 1100 {
+		t.Errorf("Expected the heavy turtle about half the time, got %d of 2000", heavy)
+	}
+
+	shuffled := m.Turtles().NOf(20)
+	shuffled.Shuffle()
+	if shuffled.Count() != 20 {
+		t.Errorf("Expected shuffling to keep every turtle")
+	}
+	inOrder := true
+	for i, turtle := range shuffled.List() {
+		if turtle.Who() != i {
+			inOrder = false
+		}
+	}
+	if inOrder {
+		t.Errorf("Expected the turtles to be shuffled")
+	}
+}
+
+func TestLinkAndPatchSampling(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:  1,
+		RandomSeed2: 2,
+	})
+	m.CreateTurtles(5, nil)
+	for i := 1; i < 5; i++ {
+		m.Turtle(0).CreateLinkWithTurtle(nil, m.Turtle(i), nil)
+	}
+
+	links := m.Links()
+	if links.NOf(2).Count() != 2 {
+		t.Errorf("Expected 2 links")
+	}
+	link, err := links.WeightedOneOf(func(l *model.Link) float64 {
+		if l.End2() == m.Turtle(3) {
+			return 1
+		}
+		return 0
+	})
+	if err != nil || link.End2() != m.Turtle(3) {
+		t.Errorf("Expected the only link with weight")
+	}
+	links.Shuffle()
+	if links.Count() != 4 {
+		t.Errorf("Expected shuffling to keep every link")
+	}
+
+	patches := m.Patches
+	patch, err := patches.OneOf()
+	if err != nil || patch == nil {
+		t.Errorf("Expected a random patch")
+	}
+	picked := patches.WeightedNOf(3, func(p *model.Patch) float64 {
+		if p.XCor() == 0 {
+			return 1
+		}
+		return 0
+	})
+	if picked.Count() != 3 || !picked.All(func(p *model.Patch) bool { return p.XCor() == 0 }) {
+		t.Errorf("Expected 3 patches from the column with weight")
+	}
+}
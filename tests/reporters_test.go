@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func energy(t *model.Turtle) float64 {
+	value, _ := t.GetPropF("energy")
+	return value
+}
+
+func TestMaxMinOneOf(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:       1,
+		RandomSeed2:      1,
+		TurtleProperties: map[string]interface{}{"energy": 0.0},
+	})
+	m.CreateTurtles(6, func(turtle *model.Turtle) {
+		turtle.SetProperty("energy", float64(turtle.Who()%3))
+	})
+	turtles := m.Turtles()
+
+	// energies are 0 1 2 0 1 2, so the max is turtle 2 or 5 and the min is turtle 0 or 3
+	maxSeen := map[int]bool{}
+	minSeen := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		max, err := turtles.MaxOneOf(energy)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		maxSeen[max.Who()] = true
+		min, _ := turtles.MinOneOf(energy)
+		minSeen[min.Who()] = true
+	}
+	if len(maxSeen) != 2 || !maxSeen[2] || !maxSeen[5] {
+		t.Errorf("Expected ties for the max to be broken at random between 2 and 5, got %v", maxSeen)
+	}
+	if len(minSeen) != 2 || !minSeen[0] || !minSeen[3] {
+		t.Errorf("Expected ties for the min to be broken at random between 0 and 3, got %v", minSeen)
+	}
+
+	if _, err := model.NewTurtleAgentSet(nil).MaxOneOf(energy); err != model.ErrNoTurtlesInAgentSet {
+		t.Errorf("Expected an error from an empty agent set")
+	}
+
+	// the same seed breaks ties the same way
+	setEnergy := func(turtle *model.Turtle) {
+		turtle.SetProperty("energy", float64(turtle.Who()%3))
+	}
+	a := model.NewModel(model.ModelSettings{
+		RandomSeed:       9,
+		RandomSeed2:      9,
+		TurtleProperties: map[string]interface{}{"energy": 0.0},
+	})
+	a.CreateTurtles(6, setEnergy)
+	b := model.NewModel(model.ModelSettings{
+		RandomSeed:       9,
+		RandomSeed2:      9,
+		TurtleProperties: map[string]interface{}{"energy": 0.0},
+	})
+	b.CreateTurtles(6, setEnergy)
+	for i := 0; i < 20; i++ {
+		x, _ := a.Turtles().MaxOneOf(energy)
+		y, _ := b.Turtles().MaxOneOf(energy)
+		if x.Who() != y.Who() {
+			t.Fatalf("Expected ties to be broken the same way with the same seed")
+		}
+	}
+}
+
+func TestMaxMinNOf(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:       1,
+		RandomSeed2:      1,
+		TurtleProperties: map[string]interface{}{"energy": 0.0},
+	})
+	// energies are 0 1 2 0 1 2
+	m.CreateTurtles(6, func(turtle *model.Turtle) {
+		turtle.SetProperty("energy", float64(turtle.Who()%3))
+	})
+	turtles := m.Turtles()
+
+	top := turtles.MaxNOf(2, energy)
+	if top.Count() != 2 || !top.Contains(m.Turtle(2)) || !top.Contains(m.Turtle(5)) {
+		t.Errorf("Expected the two turtles with the most energy")
+	}
+
+	// one of the two turtles with 1 energy makes the cut
+	seen := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		top = turtles.MaxNOf(3, energy)
+		if top.Count() != 3 || !top.Contains(m.Turtle(2)) || !top.Contains(m.Turtle(5)) {
+			t.Fatalf("Expected the turtles with the most energy to always make the cut")
+		}
+		for _, turtle := range top.List() {
+			seen[turtle.Who()] = true
+		}
+	}
+	if !seen[1] || !seen[4] {
+		t.Errorf("Expected the tie at the cut off to be broken at random")
+	}
+
+	bottom := turtles.MinNOf(2, energy)
+	list := bottom.List()
+	if len(list) != 2 || list[0] != m.Turtle(0) || list[1] != m.Turtle(3) {
+		t.Errorf("Expected the two turtles with the least energy in agent set order")
+	}
+
+	if turtles.MaxNOf(10, energy).Count() != 6 {
+		t.Errorf("Expected every turtle when asking for more than there are")
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:       1,
+		RandomSeed2:      1,
+		TurtleProperties: map[string]interface{}{"energy": 0.0},
+	})
+	// energies are 0 1 2 0 1 2
+	m.CreateTurtles(6, func(turtle *model.Turtle) {
+		turtle.SetProperty("energy", float64(turtle.Who()%3))
+	})
+	turtles := m.Turtles()
+
+	if sum := turtles.Sum(energy); sum != 6 {
+		t.Errorf("Expected a sum of 6, got %f", sum)
+	}
+	if mean, _ := turtles.Mean(energy); mean != 1 {
+		t.Errorf("Expected a mean of 1, got %f", mean)
+	}
+	// squared differences are 1 0 1 1 0 1, over 5
+	if variance, _ := turtles.Variance(energy); math.Abs(variance-.8) > 1e-9 {
+		t.Errorf("Expected a variance of .8, got %f", variance)
+	}
+
+	empty := model.NewTurtleAgentSet(nil)
+	if empty.Sum(energy) != 0 {
+		t.Errorf("Expected a sum of 0 for no turtles")
+	}
+	if _, err := empty.Mean(energy); err != model.ErrNoTurtlesInAgentSet {
+		t.Errorf("Expected an error for the mean of no turtles")
+	}
+	if _, err := turtles.FirstNOf(1).Variance(energy); err != model.ErrVarianceTooFewAgents {
+		t.Errorf("Expected an error for the variance of one turtle")
+	}
+
+	histogram := turtles.Histogram(energy, 0, 2, 2)
+	if len(histogram) != 2 || histogram[0] != 2 || histogram[1] != 4 {
+		t.Errorf("Expected 2 turtles in the first bin and 4 in the last, got %v", histogram)
+	}
+	histogram = turtles.Histogram(energy, .5, 1.5, 1)
+	if histogram[0] != 2 {
+		t.Errorf("Expected values outside of the range to be left out, got %v", histogram)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:       1,
+		RandomSeed2:      1,
+		TurtleProperties: map[string]interface{}{"energy": 0.0, "team": ""},
+	})
+	m.CreateTurtles(6, func(turtle *model.Turtle) {
+		turtle.SetProperty("energy", float64(turtle.Who()%3))
+		if turtle.Who() < 2 {
+			turtle.SetProperty("team", "red")
+		} else {
+			turtle.SetProperty("team", "blue")
+		}
+	})
+
+	teams := m.Turtles().GroupByProperty("team")
+	if len(teams) != 2 || teams["red"].Count() != 2 || teams["blue"].Count() != 4 {
+		t.Errorf("Expected 2 red turtles and 4 blue turtles")
+	}
+
+	byEnergy := m.Turtles().GroupBy(func(t *model.Turtle) interface{} {
+		return energy(t) > 0
+	})
+	if byEnergy[true].Count() != 4 || byEnergy[false].Count() != 2 {
+		t.Errorf("Expected 4 turtles with energy and 2 without")
+	}
+	list := byEnergy[true].List()
+	if list[0] != m.Turtle(1) || list[3] != m.Turtle(5) {
+		t.Errorf("Expected each group to keep the order of the agent set")
+	}
+}
+
+func TestPatchAndLinkReporters(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:      1,
+		PatchProperties: map[string]interface{}{"food": 0.0},
+		MinPxCor:        0,
+		MaxPxCor:        4,
+		MinPyCor:        0,
+		MaxPyCor:        1,
+	})
+	m.Patches.Ask(func(p *model.Patch) {
+		p.SetProperty("food", float64(p.XCor()))
+	})
+	food := func(p *model.Patch) float64 {
+		return p.GetPropF("food")
+	}
+
+	richest, _ := m.Patches.MaxOneOf(food)
+	if richest.XCor() != 4 {
+		t.Errorf("Expected the patch with the most food")
+	}
+	if m.Patches.Sum(food) != 20 || m.Patches.MinNOf(2, food).Count() != 2 {
+		t.Errorf("Expected the patch reporters to work like the turtle reporters")
+	}
+	if groups := m.Patches.GroupByProperty("food"); len(groups) != 5 {
+		t.Errorf("Expected a group for each amount of food")
+	}
+
+	m.CreateTurtles(4, nil)
+	for i := 1; i < 4; i++ {
+		m.Turtle(0).CreateLinkWithTurtle(nil, m.Turtle(i), func(l *model.Link) {
+			l.Size = i
+		})
+	}
+	size := func(l *model.Link) float64 { return float64(l.Size) }
+	if thickest, _ := m.Links().MaxOneOf(size); thickest.End2() != m.Turtle(3) {
+		t.Errorf("Expected the thickest link")
+	}
+	if mean, _ := m.Links().Mean(size); mean != 2 {
+		t.Errorf("Expected a mean link size of 2, got %f", mean)
+	}
+}
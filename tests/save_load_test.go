@@ -0,0 +1,397 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/loader"
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+// saves the model to json and loads it back
+func saveAndLoad(t *testing.T, m *model.Model) *model.Model {
+	bytes, err := json.Marshal(loader.GetModel(m))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	saved := &loader.Model{}
+	if err := json.Unmarshal(bytes, saved); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return loader.SetModel(saved)
+}
+
+// builds a model with random agents and state, the seed decides everything about it
+func randomWorld(seed uint64) *model.Model {
+	r := rand.New(rand.NewPCG(seed, 1))
+
+	wolves := model.NewTurtleBreed("wolves", "wolf", map[string]interface{}{"hunger": 0.0})
+	roads := model.NewLinkBreedWithProperties("roads", map[string]interface{}{"traffic": 0.0})
+	friends := model.NewLinkBreed("friends")
+
+	settings := model.ModelSettings{
+		RandomSeed:           seed,
+		TurtleBreeds:         []*model.TurtleBreed{wolves},
+		DirectedLinkBreeds:   []*model.LinkBreed{roads},
+		UndirectedLinkBreeds: []*model.LinkBreed{friends},
+		TurtleProperties:     map[string]interface{}{"energy": 0.0, "name": ""},
+		PatchProperties:      map[string]interface{}{"food": 0.0},
+		PatchColumns:         map[string]interface{}{"grass": 0.0, "visits": 0, "wet": false},
+		LinkProperties:       map[string]interface{}{"weight": 0.0},
+		WrappingX:            r.IntN(2) == 0,
+		WrappingY:            r.IntN(2) == 0,
+		MinPxCor:             -r.IntN(5) - 1,
+		MaxPxCor:             r.IntN(5) + 1,
+		MinPyCor:             -r.IntN(5) - 1,
+		MaxPyCor:             r.IntN(5) + 1,
+	}
+	if r.IntN(3) == 0 {
+		settings.MinPzCor = -1
+		settings.MaxPzCor = 1
+	}
+	if r.IntN(2) == 0 {
+		settings.SpatialIndexCellSize = 2
+	}
+	m := model.NewModel(settings)
+	m.DefaultShapeTurtles = "arrow"
+	m.DefaultShapeLinks = "curve"
+
+	m.Patches.Ask(func(p *model.Patch) {
+		p.SetProperty("food", r.Float64())
+		p.SetProperty("grass", r.Float64())
+		p.SetProperty("visits", r.IntN(10))
+		p.SetProperty("wet", r.IntN(2) == 0)
+		p.Color.SetColorRGBA(r.IntN(256), r.IntN(256), r.IntN(256), 255)
+		if r.IntN(4) == 0 {
+			p.Label = "patch"
+			p.PlabelColor.SetColorRGBA(1, 2, 3, 4)
+		}
+	})
+
+	m.CreateTurtles(r.IntN(10)+5, nil)
+	wolves.CreateAgents(r.IntN(5)+1, nil)
+	m.Turtles().Ask(func(turtle *model.Turtle) {
+		if m.Is3D() {
+			turtle.SetXYZ(m.RandomXCor(), m.RandomYCor(), m.RandomZCor())
+			turtle.SetPitch(r.Float64()*180 - 90)
+		} else {
+			turtle.SetXY(m.RandomXCor(), m.RandomYCor())
+		}
+		turtle.SetSize(r.Float64() + 0.5)
+		turtle.Hidden = r.IntN(3) == 0
+		turtle.SetLabel("turtle")
+		turtle.SetProperty("energy", r.Float64()*10)
+		turtle.SetProperty("name", "t")
+		turtle.SetProperty("hunger", r.Float64())
+		if r.IntN(3) == 0 {
+			turtle.SetPenSize(2)
+			turtle.PenDown()
+		}
+	})
+
+	// killing turtles leaves gaps in the who numbers
+	for _, turtle := range m.Turtles().List() {
+		if r.IntN(4) == 0 {
+			m.KillTurtle(turtle)
+		}
+	}
+
+	turtles := m.Turtles().List()
+	for i := 0; i < len(turtles); i++ {
+		end1, end2 := turtles[r.IntN(len(turtles))], turtles[r.IntN(len(turtles))]
+		if end1 == end2 {
+			continue
+		}
+		var link *model.Link
+		switch r.IntN(3) {
+		case 0:
+			link, _ = end1.CreateLinkToTurtle(roads, end2, nil)
+		case 1:
+			link, _ = end1.CreateLinkWithTurtle(friends, end2, nil)
+		case 2:
+			link, _ = end1.CreateLinkWithTurtle(nil, end2, nil)
+		}
+		if link == nil {
+			continue
+		}
+		link.Shape = "dashed"
+		link.Thickness = r.Float64()
+		link.Size = r.IntN(3) + 1
+		link.SetProperty("weight", r.Float64())
+		link.SetProperty("traffic", r.Float64())
+		if r.IntN(3) == 0 {
+			link.Hide()
+		}
+		if r.IntN(4) == 0 {
+			link.Tie()
+		}
+	}
+
+	// killing a link leaves a gap in the link ids
+	if m.Links().Count() > 0 {
+		first, _ := m.Links().First()
+		m.KillLink(first)
+	}
+
+	// moving with the pen down draws on the drawing layer
+	m.Turtles().Ask(func(turtle *model.Turtle) {
+		turtle.Forward(r.Float64())
+	})
+
+	m.RegisterEventHandler("grow", func(e *model.Event) {})
+	m.ScheduleEvent(model.EventSettings{Name: "grow", Time: 3, Interval: 2, Turtle: turtles[0]})
+	m.ScheduleEvent(model.EventSettings{Name: "grow", Time: 2, Patch: m.Patch(0, 0)})
+	m.TickAdvance(r.IntN(2))
+
+	return m
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	for seed := uint64(0); seed < 30; seed++ {
+		m := randomWorld(seed)
+		loaded := saveAndLoad(t, m)
+
+		expectSameModel(t, seed, m, loaded)
+	}
+}
+
+// checks that the loaded model is the same as the model and carries on the same way
+// positions, headings and other numbers can be a rounding error off after loading
+func expectSameModel(t *testing.T, seed uint64, m *model.Model, loaded *model.Model) {
+	t.Helper()
+
+	if loaded.MinPxCor() != m.MinPxCor() || loaded.MaxPxCor() != m.MaxPxCor() || loaded.MinPyCor() != m.MinPyCor() || loaded.MaxPyCor() != m.MaxPyCor() ||
+		loaded.MinPzCor() != m.MinPzCor() || loaded.MaxPzCor() != m.MaxPzCor() || loaded.WrappingX() != m.WrappingX() || loaded.WrappingY() != m.WrappingY() {
+		t.Fatalf("Seed %d: expected the same world", seed)
+	}
+	if loaded.Ticks != m.Ticks || loaded.Clock() != m.Clock() || loaded.DefaultShapeTurtles != m.DefaultShapeTurtles || loaded.DefaultShapeLinks != m.DefaultShapeLinks {
+		t.Errorf("Seed %d: expected the same ticks, clock and default shapes", seed)
+	}
+	if loaded.SpatialIndexEnabled() != m.SpatialIndexEnabled() || loaded.SpatialIndexCellSize() != m.SpatialIndexCellSize() {
+		t.Errorf("Seed %d: expected the same spatial index", seed)
+	}
+
+	if loaded.Turtles().Count() != m.Turtles().Count() {
+		t.Fatalf("Seed %d: expected %d turtles, got %d", seed, m.Turtles().Count(), loaded.Turtles().Count())
+	}
+	for _, turtle := range m.Turtles().List() {
+		other := loaded.Turtle(turtle.Who())
+		if other == nil {
+			t.Fatalf("Seed %d: expected turtle %d to be loaded", seed, turtle.Who())
+		}
+		if other.BreedName() != turtle.BreedName() || other.Shape != turtle.Shape || other.GetColor() != turtle.GetColor() || other.IsHidden() != turtle.IsHidden() || other.GetLabel() != turtle.GetLabel() {
+			t.Errorf("Seed %d: expected turtle %d to look the same", seed, turtle.Who())
+		}
+		if !closeTo(other.XCor(), turtle.XCor()) || !closeTo(other.YCor(), turtle.YCor()) || !closeTo(other.ZCor(), turtle.ZCor()) {
+			t.Errorf("Seed %d: expected turtle %d at %f %f %f, got %f %f %f", seed, turtle.Who(), turtle.XCor(), turtle.YCor(), turtle.ZCor(), other.XCor(), other.YCor(), other.ZCor())
+		}
+		if !closeTo(math.Mod(other.GetHeading()-turtle.GetHeading()+540, 360), 180) || !closeTo(other.GetPitch(), turtle.GetPitch()) {
+			t.Errorf("Seed %d: expected turtle %d to face %f %f, got %f %f", seed, turtle.Who(), turtle.GetHeading(), turtle.GetPitch(), other.GetHeading(), other.GetPitch())
+		}
+		if !closeTo(other.GetSize(), turtle.GetSize()) || other.IsPenDown() != turtle.IsPenDown() || !closeTo(other.GetPenSize(), turtle.GetPenSize()) {
+			t.Errorf("Seed %d: expected turtle %d to have the same size and pen", seed, turtle.Who())
+		}
+		if !sameProperties(other.Properties(), turtle.Properties()) {
+			t.Errorf("Seed %d: expected turtle %d to have the properties %v, got %v", seed, turtle.Who(), turtle.Properties(), other.Properties())
+		}
+		if other.PatchHere().ID() != turtle.PatchHere().ID() || other.TurtlesHere().Count() != turtle.TurtlesHere().Count() {
+			t.Errorf("Seed %d: expected turtle %d to be on the same patch with the same turtles", seed, turtle.Who())
+		}
+		if other.TiedTurtles().Count() != turtle.TiedTurtles().Count() {
+			t.Errorf("Seed %d: expected turtle %d to have the same tied turtles", seed, turtle.Who())
+		}
+	}
+
+	links, loadedLinks := m.Links().List(), loaded.Links().List()
+	if len(loadedLinks) != len(links) {
+		t.Fatalf("Seed %d: expected %d links, got %d", seed, len(links), len(loadedLinks))
+	}
+	for i, link := range links {
+		other := loadedLinks[i]
+		if other.ID() != link.ID() || other.End1().Who() != link.End1().Who() || other.End2().Who() != link.End2().Who() || other.Directed() != link.Directed() || other.BreedName() != link.BreedName() {
+			t.Errorf("Seed %d: expected link %d between the same turtles", seed, link.ID())
+			continue
+		}
+		if other.Shape != link.Shape || other.Color != link.Color || !closeTo(other.Thickness, link.Thickness) || other.Size != link.Size || other.IsHidden() != link.IsHidden() || other.TieMode() != link.TieMode() {
+			t.Errorf("Seed %d: expected link %d to look the same", seed, link.ID())
+		}
+		if !sameProperties(other.Properties(), link.Properties()) {
+			t.Errorf("Seed %d: expected link %d to have the properties %v, got %v", seed, link.ID(), link.Properties(), other.Properties())
+		}
+	}
+	if m.ShownLinks.Count() != loaded.ShownLinks.Count() {
+		t.Errorf("Seed %d: expected %d shown links, got %d", seed, m.ShownLinks.Count(), loaded.ShownLinks.Count())
+	}
+
+	m.Patches.Ask(func(p *model.Patch) {
+		other := loaded.Patch3D(float64(p.XCor()), float64(p.YCor()), float64(p.ZCor()))
+		if other == nil {
+			t.Fatalf("Seed %d: expected patch %d %d %d to be loaded", seed, p.XCor(), p.YCor(), p.ZCor())
+		}
+		if other.Color != p.Color || other.Label != p.Label || other.PlabelColor != p.PlabelColor {
+			t.Errorf("Seed %d: expected patch %d %d to look the same", seed, p.XCor(), p.YCor())
+		}
+		if !sameProperties(other.Properties(), p.Properties()) {
+			t.Errorf("Seed %d: expected patch %d %d to have the values %v, got %v", seed, p.XCor(), p.YCor(), p.Properties(), other.Properties())
+		}
+	})
+
+	segments, loadedSegments := m.Drawing().Segments(), loaded.Drawing().Segments()
+	if len(loadedSegments) != len(segments) {
+		t.Errorf("Seed %d: expected %d drawn segments, got %d", seed, len(segments), len(loadedSegments))
+	}
+	for i := 0; i < len(segments) && i < len(loadedSegments); i++ {
+		a, b := segments[i], loadedSegments[i]
+		if !closeTo(a.X1, b.X1) || !closeTo(a.Y1, b.Y1) || !closeTo(a.X2, b.X2) || !closeTo(a.Y2, b.Y2) || a.Color != b.Color || !closeTo(a.Size, b.Size) {
+			t.Errorf("Seed %d: expected drawn segment %d to be the same", seed, i)
+		}
+	}
+
+	events, loadedEvents := m.Events(), loaded.Events()
+	if len(loadedEvents) != len(events) {
+		t.Fatalf("Seed %d: expected %d events, got %d", seed, len(events), len(loadedEvents))
+	}
+	for i, event := range events {
+		other := loadedEvents[i]
+		if other.Name() != event.Name() || other.Time() != event.Time() || other.Interval() != event.Interval() || (other.Turtle() == nil) != (event.Turtle() == nil) || (other.Patch() == nil) != (event.Patch() == nil) {
+			t.Errorf("Seed %d: expected event %d to be the same", seed, i)
+		}
+	}
+
+	// the loaded model carries on the same way
+	if m.NextWho() != loaded.NextWho() || m.NextLinkID() != loaded.NextLinkID() {
+		t.Errorf("Seed %d: expected the next who number and link id to be restored", seed)
+	}
+	seed1, seed2, state := m.GetRandomState()
+	loadedSeed1, loadedSeed2, loadedState := loaded.GetRandomState()
+	if seed1 != loadedSeed1 || seed2 != loadedSeed2 || !slices.Equal(state, loadedState) {
+		t.Errorf("Seed %d: expected the random generator to continue from the same state", seed)
+	}
+}
+
+// checks that two agents have the same properties, numbers only have to be close since ints and floats aren't always kept apart
+func sameProperties(a map[string]interface{}, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		other, ok := b[name]
+		if !ok {
+			return false
+		}
+		x, xNumber := propertyNumber(value)
+		y, yNumber := propertyNumber(other)
+		if xNumber && yNumber {
+			if !closeTo(x, y) {
+				return false
+			}
+		} else if value != other {
+			return false
+		}
+	}
+	return true
+}
+
+func propertyNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func TestSaveLoadKeepsWhoNumbers(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+	m.CreateTurtles(4, nil)
+	m.KillTurtle(m.Turtle(1))
+	m.KillTurtle(m.Turtle(3))
+
+	loaded := saveAndLoad(t, m)
+
+	if loaded.Turtle(1) != nil || loaded.Turtle(3) != nil {
+		t.Errorf("Expected the dead turtles to stay dead")
+	}
+	if loaded.Turtle(0) == nil || loaded.Turtle(2) == nil {
+		t.Fatalf("Expected turtles 0 and 2 to be loaded")
+	}
+
+	created, _ := loaded.CreateTurtles(1, nil)
+	if turtle, _ := created.First(); turtle.Who() != 4 {
+		t.Errorf("Expected the next turtle to get who number 4, got %d", turtle.Who())
+	}
+}
+
+func TestSaveLoadMigratesVersion1(t *testing.T) {
+	// a save from before the format had a version
+	version1 := `{
+		"turtleBreeds": [],
+		"directedLinkBreeds": [],
+		"undirectedLinkBreeds": [],
+		"patchProperties": {},
+		"turtleProperties": {},
+		"linkProperties": {},
+		"minPxCor": -2, "maxPxCor": 2, "minPyCor": -2, "maxPyCor": 2,
+		"randomSeed1": 3, "randomSeed2": 0, "randomState": "",
+		"patches": [],
+		"turtles": [
+			{"x": 1, "y": 1, "who": 0, "heading": 90, "size": 1, "shape": "circle"},
+			{"x": -1, "y": 0, "who": 1, "heading": 0, "size": 1, "shape": "circle"},
+			{"x": 0, "y": -1, "who": 2, "heading": 0, "size": 1, "shape": "circle"}
+		],
+		"links": [
+			{"end1": 0, "end2": 1, "directed": true, "size": 1},
+			{"end1": 1, "end2": 2, "directed": false, "size": 1}
+		],
+		"ticks": 7
+	}`
+
+	saved := &loader.Model{}
+	if err := json.Unmarshal([]byte(version1), saved); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	loaded := loader.SetModel(saved)
+	if saved.Version != 0 {
+		t.Errorf("Expected the saved model passed in to be left alone, got version %d", saved.Version)
+	}
+	if loaded.Ticks != 7 || loaded.Turtles().Count() != 3 || loaded.Links().Count() != 2 {
+		t.Fatalf("Expected the version 1 save to load")
+	}
+	if loaded.Turtle(0).XCor() != 1 || loaded.Turtle(0).GetHeading() != 90 {
+		t.Errorf("Expected turtle 0 to be loaded where it was saved")
+	}
+	if loaded.NextWho() != 3 || loaded.NextLinkID() != 2 {
+		t.Errorf("Expected the next who number and link id to follow the saved agents, got %d and %d", loaded.NextWho(), loaded.NextLinkID())
+	}
+	if link := loaded.Turtle(1).LinkWith(nil, loaded.Turtle(2)); link == nil || link.ID() != 1 {
+		t.Errorf("Expected the links to get ids in the order they were saved")
+	}
+
+	if err := loader.Migrate(saved); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if saved.Version != loader.CurrentVersion || saved.NextWho != 3 || saved.Links[1].ID != 1 {
+		t.Errorf("Expected the save to be migrated to the current version")
+	}
+}
+
+func TestSaveLoadNewerVersion(t *testing.T) {
+	saved := loader.GetModel(model.NewModel(model.ModelSettings{}))
+	if saved.Version != loader.CurrentVersion {
+		t.Errorf("Expected the model to be saved in the current version, got %d", saved.Version)
+	}
+
+	saved.Version = loader.CurrentVersion + 1
+	if err := loader.Migrate(saved); !errors.Is(err, loader.ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+	if saved.Version != loader.CurrentVersion+1 {
+		t.Errorf("Expected a newer save to be left alone")
+	}
+}
@@ -0,0 +1,248 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func TestAskScheduledSequential(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(10, nil)
+
+	order := []int{}
+	m.Turtles().AskScheduled(model.NewSequentialScheduler(), func(turtle *model.Turtle) {
+		order = append(order, turtle.Who())
+	})
+
+	for i, who := range order {
+		if who != i {
+			t.Errorf("Expected turtle %d to be activated at position %d, got %d", i, i, who)
+		}
+	}
+}
+
+func TestAskScheduledRandom(t *testing.T) {
+	settings := model.ModelSettings{
+		RandomSeed: 10,
+	}
+
+	getOrder := func() []int {
+		m := model.NewModel(settings)
+		m.CreateTurtles(50, nil)
+
+		order := []int{}
+		m.Turtles().AskScheduled(model.NewRandomScheduler(), func(turtle *model.Turtle) {
+			order = append(order, turtle.Who())
+		})
+		return order
+	}
+
+	order1 := getOrder()
+	order2 := getOrder()
+
+	if len(order1) != 50 {
+		t.Fatalf("Expected 50 turtles to be activated, got %d", len(order1))
+	}
+
+	// each turtle should be activated exactly once
+	seen := map[int]bool{}
+	for _, who := range order1 {
+		if seen[who] {
+			t.Errorf("Expected turtle %d to be activated once", who)
+		}
+		seen[who] = true
+	}
+
+	// the order should be shuffled
+	sequential := true
+	for i, who := range order1 {
+		if who != i {
+			sequential = false
+		}
+	}
+	if sequential {
+		t.Errorf("Expected random scheduler to shuffle the turtles")
+	}
+
+	// the same seed should give the same order
+	for i := range order1 {
+		if order1[i] != order2[i] {
+			t.Fatalf("Expected the same order with the same seed, got %v and %v", order1, order2)
+		}
+	}
+}
+
+func TestAskScheduledUsesModelScheduler(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed: 3,
+		Scheduler:  model.NewRandomScheduler(),
+	})
+
+	m.CreateTurtles(50, nil)
+
+	order := []int{}
+	m.Turtles().AskScheduled(nil, func(turtle *model.Turtle) {
+		order = append(order, turtle.Who())
+	})
+
+	sequential := true
+	for i, who := range order {
+		if who != i {
+			sequential = false
+		}
+	}
+	if sequential {
+		t.Errorf("Expected the model's random scheduler to be used")
+	}
+
+	m.SetScheduler(nil)
+	order = []int{}
+	m.Turtles().AskScheduled(nil, func(turtle *model.Turtle) {
+		order = append(order, turtle.Who())
+	})
+	for i, who := range order {
+		if who != i {
+			t.Errorf("Expected the model to fall back to a sequential scheduler")
+			break
+		}
+	}
+}
+
+func TestAskScheduledSkipsDeadTurtles(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(4, nil)
+
+	activated := 0
+	m.Turtles().AskScheduled(model.NewSequentialScheduler(), func(turtle *model.Turtle) {
+		activated++
+		if turtle.Who() == 0 {
+			m.Turtle(1).Die()
+		}
+	})
+
+	if activated != 3 {
+		t.Errorf("Expected 3 turtles to be activated, got %d", activated)
+	}
+}
+
+func TestAskStagedSimultaneous(t *testing.T) {
+	// a row of patches where each patch takes the value of its left neighbor
+	m := model.NewModel(model.ModelSettings{
+		PatchProperties: map[string]interface{}{
+			"value": 0.0,
+		},
+		MinPxCor: 0,
+		MaxPxCor: 4,
+		MinPyCor: -1,
+		MaxPyCor: 1,
+	})
+
+	m.Patch(0, 0).SetProperty("value", 1.0)
+
+	shiftRight := []model.PatchStage{
+		{
+			Name: "shift",
+			Operation: func(p *model.Patch) func() {
+				left := m.Patch(float64(p.XCor()-1), 0)
+				if left == nil {
+					return nil
+				}
+				newValue := left.GetPropF("value")
+				return func() {
+					p.SetProperty("value", newValue)
+				}
+			},
+		},
+	}
+
+	// sequential updates should smear the value all the way across the row
+	m.Patches.AskStaged(model.NewSequentialScheduler(), shiftRight)
+	if m.Patch(4, 0).GetPropF("value") != 1 {
+		t.Errorf("Expected sequential updates to reach the end of the row")
+	}
+
+	m.ClearPatches()
+	m.Patch(0, 0).SetProperty("value", 1.0)
+
+	// simultaneous updates should only move the value over by one
+	m.Patches.AskStaged(model.NewSimultaneousScheduler(nil), shiftRight)
+	if m.Patch(1, 0).GetPropF("value") != 1 {
+		t.Errorf("Expected the value to move one patch over")
+	}
+	if m.Patch(2, 0).GetPropF("value") != 0 {
+		t.Errorf("Expected simultaneous updates to read the old state")
+	}
+}
+
+func TestAskStagedRunsStagesInOrder(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(3, nil)
+
+	stages := []string{}
+	m.Turtles().AskStaged(model.NewRandomScheduler(), []model.TurtleStage{
+		{
+			Name: "sense",
+			Operation: func(turtle *model.Turtle) func() {
+				stages = append(stages, "sense")
+				return nil
+			},
+		},
+		{
+			Name: "act",
+			Operation: func(turtle *model.Turtle) func() {
+				stages = append(stages, "act")
+				return nil
+			},
+		},
+	})
+
+	expected := []string{"sense", "sense", "sense", "act", "act", "act"}
+	if len(stages) != len(expected) {
+		t.Fatalf("Expected %d activations, got %d", len(expected), len(stages))
+	}
+	for i := range expected {
+		if stages[i] != expected[i] {
+			t.Errorf("Expected every turtle to finish a stage before the next one starts, got %v", stages)
+			break
+		}
+	}
+}
+
+func TestTickStages(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		TurtleProperties: map[string]interface{}{
+			"age": 0,
+		},
+	})
+
+	m.CreateTurtles(5, nil)
+
+	m.SetTickStages([]model.TickStage{
+		{
+			Name: "age",
+			Turtles: func(turtle *model.Turtle) func() {
+				age, _ := turtle.GetPropI("age")
+				turtle.SetProperty("age", age+1)
+				return nil
+			},
+		},
+	})
+
+	m.Tick()
+	m.Tick()
+
+	if m.Ticks != 2 {
+		t.Errorf("Expected ticks to be 2, got %d", m.Ticks)
+	}
+
+	if !m.Turtles().All(func(turtle *model.Turtle) bool {
+		age, _ := turtle.GetPropI("age")
+		return age == 2
+	}) {
+		t.Errorf("Expected every turtle to be aged twice")
+	}
+}
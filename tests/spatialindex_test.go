@@ -0,0 +1,295 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func sameTurtles(a *model.TurtleAgentSet, b *model.TurtleAgentSet) bool {
+	if a.Count() != b.Count() {
+		return false
+	}
+	return a.All(b.Contains)
+}
+
+func TestSpatialIndexMatchesPatchQueries(t *testing.T) {
+	// the same turtles scattered around a model with the index and one without
+	models := []*model.Model{}
+	for _, cellSize := range []float64{3, 0} {
+		m := model.NewModel(model.ModelSettings{
+			RandomSeed:           7,
+			WrappingX:            true,
+			WrappingY:            true,
+			SpatialIndexCellSize: cellSize,
+		})
+		m.CreateTurtles(300, func(turtle *model.Turtle) {
+			turtle.SetXY(m.RandomXCor(), m.RandomYCor())
+		})
+		models = append(models, m)
+	}
+	indexed, plain := models[0], models[1]
+
+	if !indexed.SpatialIndexEnabled() || plain.SpatialIndexEnabled() {
+		t.Fatalf("Expected only the first model to have a spatial index")
+	}
+
+	// move the turtles around so the index has to keep up
+	for i := 0; i < 5; i++ {
+		indexed.Turtles().Ask(func(turtle *model.Turtle) {
+			turtle.SetHeading(float64(turtle.Who() * 37))
+			turtle.Forward(2.5)
+		})
+		plain.Turtles().Ask(func(turtle *model.Turtle) {
+			turtle.SetHeading(float64(turtle.Who() * 37))
+			turtle.Forward(2.5)
+		})
+	}
+
+	points := [][]float64{{0, 0}, {15, 15}, {-15.4, 3}, {7.2, -15.5}}
+	for _, point := range points {
+		for _, radius := range []float64{.5, 2, 4.5, 20} {
+			a := indexed.TurtlesInRadiusXY(point[0], point[1], radius)
+			b := plain.TurtlesInRadiusXY(point[0], point[1], radius)
+			if a.Count() != b.Count() {
+				t.Errorf("Expected %d turtles within %f of %v, got %d", b.Count(), radius, point, a.Count())
+			}
+			a.Ask(func(turtle *model.Turtle) {
+				if !b.Contains(plain.Turtle(turtle.Who())) {
+					t.Errorf("Expected turtle %d to not be within %f of %v", turtle.Who(), radius, point)
+				}
+			})
+		}
+	}
+}
+
+func TestSpatialIndexFollowsCreateAndKill(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		SpatialIndexCellSize: 4,
+	})
+
+	m.CreateTurtles(3, nil)
+	m.Turtle(1).SetXY(10, 10)
+
+	if m.TurtlesInRadiusXY(0, 0, 1).Count() != 2 {
+		t.Errorf("Expected 2 turtles at the origin")
+	}
+
+	m.KillTurtle(m.Turtle(0))
+	if m.TurtlesInRadiusXY(0, 0, 1).Count() != 1 {
+		t.Errorf("Expected the dead turtle to be removed from the index")
+	}
+
+	m.Turtle(1).Hatch(2, nil)
+	if m.TurtlesInRadiusXY(10, 10, 1).Count() != 3 {
+		t.Errorf("Expected hatched turtles to be added to the index")
+	}
+
+	m.ClearTurtles()
+	if m.TurtlesInRadiusXY(0, 0, 100).Count() != 0 {
+		t.Errorf("Expected the index to be empty after clearing the turtles")
+	}
+
+	m.CreateTurtles(1, nil)
+	if m.TurtlesInRadiusXY(0, 0, 1).Count() != 1 {
+		t.Errorf("Expected the index to work after clearing the turtles")
+	}
+
+	// turning the index off and back on should give the same answers
+	m.DisableSpatialIndex()
+	if m.SpatialIndexEnabled() || m.TurtlesInRadiusXY(0, 0, 1).Count() != 1 {
+		t.Errorf("Expected the turtle to be found without the index")
+	}
+	m.EnableSpatialIndex(2)
+	if m.SpatialIndexCellSize() != 2 || m.TurtlesInRadiusXY(0, 0, 1).Count() != 1 {
+		t.Errorf("Expected the turtle to be found after enabling the index")
+	}
+}
+
+func TestNearestTurtles(t *testing.T) {
+	for _, cellSize := range []float64{0, 2} {
+		m := model.NewModel(model.ModelSettings{
+			WrappingX:            true,
+			SpatialIndexCellSize: cellSize,
+		})
+
+		m.CreateTurtles(5, nil)
+		m.Turtle(0).SetXY(0, 0)
+		m.Turtle(1).SetXY(3, 0)
+		m.Turtle(2).SetXY(1, 0)
+		m.Turtle(3).SetXY(-15, 0) // close to turtle 4 across the wrapped edge
+		m.Turtle(4).SetXY(15, 0)
+
+		nearest := m.NearestTurtlesXY(0.1, 0, 2).List()
+		if len(nearest) != 2 || nearest[0] != m.Turtle(0) || nearest[1] != m.Turtle(2) {
+			t.Errorf("Expected turtles 0 and 2 to be the nearest with cell size %f", cellSize)
+		}
+
+		others := m.Turtle(4).NearestTurtles(1)
+		if others.Count() != 1 || !others.Contains(m.Turtle(3)) {
+			t.Errorf("Expected turtle 3 to be nearest to turtle 4 across the edge with cell size %f", cellSize)
+		}
+
+		if m.NearestTurtlesXY(0, 0, 10).Count() != 5 {
+			t.Errorf("Expected all turtles when asking for more than there are")
+		}
+
+		if m.NearestTurtlesXY(0, 0, 0).Count() != 0 {
+			t.Errorf("Expected no turtles when asking for none")
+		}
+	}
+}
+
+func TestSpatialIndexCollisions(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		SpatialIndexCellSize: 2,
+	})
+
+	m.CreateTurtles(3, nil)
+	m.Turtle(0).SetXY(0, 0)
+	m.Turtle(1).SetXY(.5, 0)
+	m.Turtle(2).SetXY(5, 0)
+	m.Turtle(1).SetHeading(0)
+
+	colliding := m.TurtlesCollidingWith(m.Turtle(0), .8)
+	if colliding.Count() != 1 || !colliding.Contains(m.Turtle(1)) {
+		t.Errorf("Expected turtle 0 to collide only with turtle 1")
+	}
+
+	if !m.TurtleWillCollide(m.Turtle(1), 4, .8) {
+		t.Errorf("Expected turtle 1 to collide when moving to turtle 2")
+	}
+}
+
+func TestSpatialIndex3D(t *testing.T) {
+	settings := model.ModelSettings{
+		MinPxCor:             -5,
+		MaxPxCor:             5,
+		MinPyCor:             -5,
+		MaxPyCor:             5,
+		MinPzCor:             -5,
+		MaxPzCor:             5,
+		SpatialIndexCellSize: 2,
+	}
+	m := model.NewModel(settings)
+
+	m.CreateTurtles(2, nil)
+	m.Turtle(0).SetXYZ(0, 0, 0)
+	m.Turtle(1).SetXYZ(0, 0, 4)
+
+	if m.TurtlesInRadiusXYZ(0, 0, 0, 2).Count() != 1 {
+		t.Errorf("Expected only the turtle at the origin within 2 in 3D")
+	}
+
+	if m.TurtlesInRadiusXYZ(0, 0, 3, 2).Count() != 1 || !m.TurtlesInRadiusXYZ(0, 0, 3, 2).Contains(m.Turtle(1)) {
+		t.Errorf("Expected the raised turtle to be found in 3D")
+	}
+
+	if !sameTurtles(m.NearestTurtlesXYZ(0, 0, 5, 1), model.NewTurtleAgentSet([]*model.Turtle{m.Turtle(1)})) {
+		t.Errorf("Expected the raised turtle to be nearest to the top of the world")
+	}
+}
+
+// the benchmark worlds by cell size, building 100k turtles takes a while so each world is built once
+var spatialBenchmarkModels = map[float64]*model.Model{}
+
+// returns a wrapping world with 100k turtles scattered over about 100k patches
+// the turtles are sprouted where they go so they don't all start on the same patch
+func spatialBenchmarkModel(b *testing.B, cellSize float64) *model.Model {
+	if m, ok := spatialBenchmarkModels[cellSize]; ok {
+		return m
+	}
+
+	m := model.NewModel(model.ModelSettings{
+		RandomSeed:           7,
+		MinPxCor:             -160,
+		MaxPxCor:             160,
+		MinPyCor:             -160,
+		MaxPyCor:             160,
+		WrappingX:            true,
+		WrappingY:            true,
+		SpatialIndexCellSize: cellSize,
+	})
+	for i := 0; i < 100000; i++ {
+		x, y := m.RandomXCor(), m.RandomYCor()
+		m.Patch(x, y).Sprout(1, func(t *model.Turtle) {
+			t.SetXY(x, y)
+		})
+	}
+
+	spatialBenchmarkModels[cellSize] = m
+	b.ResetTimer()
+	return m
+}
+
+func benchmarkTurtlesInRadius(b *testing.B, cellSize float64) {
+	m := spatialBenchmarkModel(b, cellSize)
+
+	for i := 0; i < b.N; i++ {
+		m.TurtlesInRadiusXY(float64(i%300-150), float64(i%270-135), 3)
+	}
+}
+
+func BenchmarkTurtlesInRadiusPatches(b *testing.B) {
+	benchmarkTurtlesInRadius(b, 0)
+}
+
+func BenchmarkTurtlesInRadiusSpatialIndex(b *testing.B) {
+	benchmarkTurtlesInRadius(b, 2)
+}
+
+func benchmarkNearestTurtles(b *testing.B, cellSize float64) {
+	m := spatialBenchmarkModel(b, cellSize)
+
+	for i := 0; i < b.N; i++ {
+		m.NearestTurtlesXY(float64(i%300-150), float64(i%270-135), 5)
+	}
+}
+
+func BenchmarkNearestTurtlesScan(b *testing.B) {
+	benchmarkNearestTurtles(b, 0)
+}
+
+func BenchmarkNearestTurtlesSpatialIndex(b *testing.B) {
+	benchmarkNearestTurtles(b, 2)
+}
+
+func benchmarkCollisions(b *testing.B, cellSize float64) {
+	m := spatialBenchmarkModel(b, cellSize)
+	count := m.Turtles().Count()
+
+	for i := 0; i < b.N; i++ {
+		turtle := m.Turtle(i % count)
+		m.TurtlesCollidingWith(turtle, 1)
+		m.TurtleWillCollide(turtle, 1, 1)
+	}
+}
+
+func BenchmarkCollisionsPatches(b *testing.B) {
+	benchmarkCollisions(b, 0)
+}
+
+func BenchmarkCollisionsSpatialIndex(b *testing.B) {
+	benchmarkCollisions(b, 2)
+}
+
+// moving has to keep the index up to date on top of the patches, this shows what that costs
+// with few turtles per cell it can be slower than without the index, the queries above are where the index pays off
+func benchmarkMoveTurtles(b *testing.B, cellSize float64) {
+	m := spatialBenchmarkModel(b, cellSize)
+
+	for i := 0; i < b.N; i++ {
+		m.Turtles().Ask(func(t *model.Turtle) {
+			t.Right(10)
+			t.Forward(.5)
+		})
+	}
+}
+
+func BenchmarkMoveTurtlesPatches(b *testing.B) {
+	benchmarkMoveTurtles(b, 0)
+}
+
+func BenchmarkMoveTurtlesSpatialIndex(b *testing.B) {
+	benchmarkMoveTurtles(b, 2)
+}
@@ -0,0 +1,310 @@
+package tests
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func closeTo(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestTieFixed(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(2, nil)
+
+	root := m.Turtle(0)
+	leaf := m.Turtle(1)
+	root.SetHeading(0)
+	leaf.SetHeading(0)
+	leaf.SetXY(2, 0)
+
+	l, _ := root.CreateLinkToTurtle(nil, leaf, nil)
+	l.Tie()
+
+	if !l.Tied() || l.TieMode() != model.TieFixed {
+		t.Fatalf("Expected link to be tied in fixed mode")
+	}
+
+	root.Forward(1)
+	if !closeTo(leaf.XCor(), 3) || !closeTo(leaf.YCor(), 0) {
+		t.Errorf("Expected leaf to move to (3, 0), got (%f, %f)", leaf.XCor(), leaf.YCor())
+	}
+
+	// turning the root should swing the leaf around it and turn the leaf
+	root.Right(90)
+	if !closeTo(leaf.XCor(), 1) || !closeTo(leaf.YCor(), 2) {
+		t.Errorf("Expected leaf to swing to (1, 2), got (%f, %f)", leaf.XCor(), leaf.YCor())
+	}
+	if !closeTo(leaf.GetHeading(), root.GetHeading()) {
+		t.Errorf("Expected leaf to turn with the root, got %f and %f", leaf.GetHeading(), root.GetHeading())
+	}
+
+	// the leaf moving should not move the root of a directed tie
+	leaf.SetXY(5, 5)
+	if !closeTo(root.XCor(), 1) || !closeTo(root.YCor(), 0) {
+		t.Errorf("Expected root to stay at (1, 0), got (%f, %f)", root.XCor(), root.YCor())
+	}
+
+	l.Untie()
+	root.SetXY(0, 0)
+	if !closeTo(leaf.XCor(), 5) || !closeTo(leaf.YCor(), 5) {
+		t.Errorf("Expected untied leaf to stay at (5, 5), got (%f, %f)", leaf.XCor(), leaf.YCor())
+	}
+}
+
+func TestTieFree(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(3, nil)
+
+	root := m.Turtle(0)
+	leaf := m.Turtle(1)
+	grandLeaf := m.Turtle(2)
+	root.SetHeading(0)
+	leaf.SetHeading(0)
+	leaf.SetXY(2, 0)
+	grandLeaf.SetXY(4, 0)
+
+	l1, _ := root.CreateLinkToTurtle(nil, leaf, nil)
+	l1.SetTieMode(model.TieFree)
+	l2, _ := leaf.CreateLinkToTurtle(nil, grandLeaf, nil)
+	l2.Tie()
+
+	root.Forward(1)
+	if !closeTo(leaf.XCor(), 3) || !closeTo(grandLeaf.XCor(), 5) {
+		t.Errorf("Expected the tied turtles to move with the root, got %f and %f", leaf.XCor(), grandLeaf.XCor())
+	}
+
+	// a free tie means turning the root doesn't affect anything tied below it
+	root.Right(90)
+	if !closeTo(leaf.XCor(), 3) || !closeTo(leaf.YCor(), 0) || !closeTo(leaf.GetHeading(), 0) {
+		t.Errorf("Expected free tied leaf to not swing, got (%f, %f) heading %f", leaf.XCor(), leaf.YCor(), leaf.GetHeading())
+	}
+	if !closeTo(grandLeaf.XCor(), 5) || !closeTo(grandLeaf.YCor(), 0) {
+		t.Errorf("Expected grand leaf to not swing, got (%f, %f)", grandLeaf.XCor(), grandLeaf.YCor())
+	}
+
+	// the leaf turning should still swing its own fixed leaf
+	leaf.Left(90)
+	if !closeTo(grandLeaf.XCor(), 3) || !closeTo(grandLeaf.YCor(), -2) {
+		t.Errorf("Expected grand leaf to swing to (3, -2), got (%f, %f)", grandLeaf.XCor(), grandLeaf.YCor())
+	}
+}
+
+func TestTieCycle(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(3, nil)
+
+	a := m.Turtle(0)
+	b := m.Turtle(1)
+	c := m.Turtle(2)
+	a.SetXY(0, 0)
+	b.SetXY(1, 0)
+	c.SetXY(0, 1)
+
+	l1, _ := a.CreateLinkWithTurtle(nil, b, nil)
+	l2, _ := b.CreateLinkWithTurtle(nil, c, nil)
+	l3, _ := c.CreateLinkWithTurtle(nil, a, nil)
+	l1.Tie()
+	l2.Tie()
+	l3.Tie()
+
+	if a.TiedTurtles().Count() != 2 {
+		t.Errorf("Expected 2 tied turtles, got %d", a.TiedTurtles().Count())
+	}
+
+	a.SetXY(2, 2)
+	if !closeTo(b.XCor(), 3) || !closeTo(b.YCor(), 2) {
+		t.Errorf("Expected b to move once to (3, 2), got (%f, %f)", b.XCor(), b.YCor())
+	}
+	if !closeTo(c.XCor(), 2) || !closeTo(c.YCor(), 3) {
+		t.Errorf("Expected c to move once to (2, 3), got (%f, %f)", c.XCor(), c.YCor())
+	}
+
+	// undirected ties work both ways
+	c.SetXY(2, 4)
+	if !closeTo(a.YCor(), 3) || !closeTo(b.YCor(), 3) {
+		t.Errorf("Expected a and b to move with c, got %f and %f", a.YCor(), b.YCor())
+	}
+
+	// killing a tied link unties it
+	l1.Die()
+	l3.Die()
+	a.SetXY(0, 0)
+	if !closeTo(b.XCor(), 3) || !closeTo(c.XCor(), 2) {
+		t.Errorf("Expected b and c to no longer follow a")
+	}
+}
+
+func TestTieWrapping(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		WrappingX: true,
+		WrappingY: true,
+	})
+
+	m.CreateTurtles(2, nil)
+
+	root := m.Turtle(0)
+	leaf := m.Turtle(1)
+	root.SetHeading(0)
+	root.SetXY(15, 0)
+	leaf.SetXY(-15, 0)
+
+	l, _ := root.CreateLinkWithTurtle(nil, leaf, nil)
+	l.Tie()
+
+	// moving across the edge should move the leaf by the same amount
+	root.Forward(1)
+	if !closeTo(root.XCor(), -15) || !closeTo(leaf.XCor(), -14) {
+		t.Errorf("Expected root at -15 and leaf at -14, got %f and %f", root.XCor(), leaf.XCor())
+	}
+
+	// swinging should go the short way across the edge
+	root.Right(180)
+	if !closeTo(leaf.XCor(), 15) || !closeTo(leaf.YCor(), 0) {
+		t.Errorf("Expected leaf to swing across the edge to (15, 0), got (%f, %f)", leaf.XCor(), leaf.YCor())
+	}
+}
+
+func TestTieAtEdge(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor: -5,
+		MaxPxCor: 5,
+		MinPyCor: -5,
+		MaxPyCor: 5,
+	})
+
+	m.CreateTurtles(2, nil)
+
+	root := m.Turtle(0)
+	leaf := m.Turtle(1)
+	root.SetHeading(0)
+	leaf.SetHeading(0)
+	root.SetXY(4, 4)
+	leaf.SetXY(5, 4)
+
+	l, _ := root.CreateLinkToTurtle(nil, leaf, nil)
+	l.Tie()
+
+	// the leaf stops at the edge while the root still moves
+	root.Forward(1)
+	if !closeTo(root.XCor(), 5) || !closeTo(leaf.XCor(), 5.5) || !closeTo(leaf.YCor(), 4) {
+		t.Errorf("Expected root at 5 and leaf stopped at the edge at 5.5, got %f and (%f, %f)", root.XCor(), leaf.XCor(), leaf.YCor())
+	}
+	if leaf.PatchHere() != m.Patch(5, 4) {
+		t.Errorf("Expected the leaf to be on the edge patch")
+	}
+
+	// stopping at the edge doesn't keep the distance to the root
+	if !closeTo(root.DistanceTurtle(leaf), .5) {
+		t.Errorf("Expected the leaf to end up closer to the root, got %f", root.DistanceTurtle(leaf))
+	}
+
+	// swinging past the edge stops at the edge and still turns the leaf
+	root.SetXY(4, 4)
+	leaf.SetXY(4, 0)
+	root.Right(90)
+	if !closeTo(leaf.XCor(), 5.5) || !closeTo(leaf.YCor(), 4) {
+		t.Errorf("Expected leaf to swing to the edge at (5.5, 4), got (%f, %f)", leaf.XCor(), leaf.YCor())
+	}
+	if !closeTo(leaf.GetHeading(), root.GetHeading()) {
+		t.Errorf("Expected leaf to turn with the root, got %f and %f", leaf.GetHeading(), root.GetHeading())
+	}
+}
+
+func TestTie3D(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor: -5,
+		MaxPxCor: 5,
+		MinPyCor: -5,
+		MaxPyCor: 5,
+		MinPzCor: -5,
+		MaxPzCor: 5,
+	})
+
+	m.CreateTurtles(2, nil)
+
+	root := m.Turtle(0)
+	leaf := m.Turtle(1)
+	root.SetXYZ(0, 0, 0)
+	leaf.SetXYZ(1, 0, 1)
+
+	l, _ := root.CreateLinkToTurtle(nil, leaf, nil)
+	l.Tie()
+
+	root.SetXYZ(1, 1, 2)
+	if !closeTo(leaf.XCor(), 2) || !closeTo(leaf.YCor(), 1) || !closeTo(leaf.ZCor(), 3) {
+		t.Errorf("Expected leaf to move to (2, 1, 3), got (%f, %f, %f)", leaf.XCor(), leaf.YCor(), leaf.ZCor())
+	}
+}
+
+func TestTie3DPitch(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor: -5,
+		MaxPxCor: 5,
+		MinPyCor: -5,
+		MaxPyCor: 5,
+		MinPzCor: -5,
+		MaxPzCor: 5,
+	})
+
+	m.CreateTurtles(2, nil)
+
+	root := m.Turtle(0)
+	leaf := m.Turtle(1)
+	root.SetHeading(0)
+	leaf.SetHeading(0)
+	root.SetXYZ(0, 0, 0)
+	leaf.SetXYZ(2, 0, 0)
+
+	l, _ := root.CreateLinkToTurtle(nil, leaf, nil)
+	l.Tie()
+
+	// pitching the root up swings the leaf in front of it up over the root and pitches the leaf
+	root.SetPitch(90)
+	if !closeTo(leaf.XCor(), 0) || !closeTo(leaf.YCor(), 0) || !closeTo(leaf.ZCor(), 2) {
+		t.Errorf("Expected leaf to swing up to (0, 0, 2), got (%f, %f, %f)", leaf.XCor(), leaf.YCor(), leaf.ZCor())
+	}
+	if !closeTo(leaf.GetPitch(), 90) {
+		t.Errorf("Expected leaf to pitch with the root, got %f", leaf.GetPitch())
+	}
+}
+
+func TestTieConcurrent(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		WrappingX: true,
+		WrappingY: true,
+	})
+
+	// pairs tied both ways so either end moving moves the other
+	m.CreateTurtles(20, nil)
+	for i := 0; i < 20; i += 2 {
+		l, _ := m.Turtle(i).CreateLinkWithTurtle(nil, m.Turtle(i+1), nil)
+		l.Tie()
+	}
+
+	// both ends of every pair move at the same time from different goroutines
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 200; i++ {
+			m.Turtles().AskParallel(model.ParallelSettings{Workers: 20, ChunkSize: 1, DeferMovement: true}, func(turtle *model.Turtle, r *rand.Rand) {
+				turtle.Right(float64(r.IntN(90)))
+				turtle.Forward(.5)
+			})
+		}
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected moving turtles tied both ways from several goroutines not to deadlock")
+	}
+}
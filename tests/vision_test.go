@@ -0,0 +1,244 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nlatham1999/go-agent/pkg/model"
+)
+
+func TestPatchesInRadius(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	patches := m.PatchesInRadiusXY(0, 0, 1)
+	if patches.Count() != 5 {
+		t.Errorf("Expected 5 patches within a radius of 1, got %d", patches.Count())
+	}
+
+	patches = m.PatchesInRadiusXY(0, 0, 1.5)
+	if patches.Count() != 9 {
+		t.Errorf("Expected 9 patches within a radius of 1.5, got %d", patches.Count())
+	}
+
+	// a small radius should still find the patch the point is on
+	patches = m.PatchesInRadiusXY(0, 0, .3)
+	if patches.Count() != 1 || !patches.Contains(m.Patch(0, 0)) {
+		t.Errorf("Expected only the patch at the point, got %d patches", patches.Count())
+	}
+
+	// no wrapping so the patches past the edge are left out
+	patches = m.PatchesInRadiusXY(15, 15, 1)
+	if patches.Count() != 3 {
+		t.Errorf("Expected 3 patches in the corner, got %d", patches.Count())
+	}
+}
+
+func TestPatchesInRadiusWrapping(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		WrappingX: true,
+		WrappingY: true,
+	})
+
+	patches := m.PatchesInRadiusXY(15, 15, 1)
+	if patches.Count() != 5 {
+		t.Errorf("Expected 5 patches in the corner with wrapping, got %d", patches.Count())
+	}
+
+	if !patches.Contains(m.Patch(-15, 15)) || !patches.Contains(m.Patch(15, -15)) {
+		t.Errorf("Expected the patches across the edges to be included")
+	}
+
+	// a radius bigger than the world should include every patch once
+	patches = m.PatchesInRadiusXY(0, 0, 100)
+	if patches.Count() != m.Patches.Count() {
+		t.Errorf("Expected every patch to be within a huge radius, got %d", patches.Count())
+	}
+}
+
+func TestTurtlesInRadiusSmallRadius(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	// enough turtles that the patches get used instead of checking every turtle
+	m.CreateTurtles(20, func(turtle *model.Turtle) {
+		turtle.SetXY(5, 5)
+	})
+	m.Turtle(0).SetXY(0.1, 0)
+
+	turtles := m.TurtlesInRadiusXY(0, 0, .3)
+	if turtles.Count() != 1 || !turtles.Contains(m.Turtle(0)) {
+		t.Errorf("Expected the turtle close to the point to be found, got %d turtles", turtles.Count())
+	}
+}
+
+func TestInRadiusZero(t *testing.T) {
+	// with and without the spatial index since they look for turtles differently
+	for _, cellSize := range []float64{0, 2} {
+		m := model.NewModel(model.ModelSettings{
+			SpatialIndexCellSize: cellSize,
+		})
+
+		m.CreateTurtles(20, func(turtle *model.Turtle) {
+			turtle.SetXY(5, 5)
+		})
+		m.Turtle(0).SetXY(0, 0)
+		m.Turtle(1).SetXY(0.1, 0)
+
+		// like netlogo's in-radius 0 only the turtles right at the point are found
+		turtles := m.TurtlesInRadiusXY(0, 0, 0)
+		if turtles.Count() != 1 || !turtles.Contains(m.Turtle(0)) {
+			t.Errorf("Expected only the turtle at the point with a radius of 0 and cell size %v, got %d turtles", cellSize, turtles.Count())
+		}
+		if m.Turtles().InRadiusXY(5, 5, 0).Count() != 18 {
+			t.Errorf("Expected the 18 turtles at 5 5 with a radius of 0 and cell size %v", cellSize)
+		}
+
+		// a negative radius finds nothing
+		if m.TurtlesInRadiusXY(0, 0, -1).Count() != 0 || m.Turtles().InRadiusXY(0, 0, -1).Count() != 0 {
+			t.Errorf("Expected no turtles with a negative radius and cell size %v", cellSize)
+		}
+	}
+
+	m := model.NewModel(model.ModelSettings{})
+	patches := m.PatchesInRadiusXY(0, 0, 0)
+	if patches.Count() != 1 || !patches.Contains(m.Patch(0, 0)) {
+		t.Errorf("Expected only the patch centered on the point with a radius of 0, got %d", patches.Count())
+	}
+	if m.PatchesInRadiusXY(0.2, 0, 0).Count() != 0 || m.Patches.InRadiusXY(0, 0, -1).Count() != 0 {
+		t.Errorf("Expected no patches off center with a radius of 0 or with a negative radius")
+	}
+	if m.Patches.InRadiusXY(0, 0, 0).Count() != 1 {
+		t.Errorf("Expected the agentset to find the patch centered on the point with a radius of 0")
+	}
+}
+
+func TestAgentSetInRadius(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(10, func(turtle *model.Turtle) {
+		turtle.SetXY(float64(turtle.Who()), 0)
+	})
+
+	even := m.Turtles().With(func(turtle *model.Turtle) bool {
+		return turtle.Who()%2 == 0
+	})
+
+	turtles := even.InRadiusXY(0, 0, 4)
+	if turtles.Count() != 3 {
+		t.Errorf("Expected turtles 0, 2 and 4 to be in the radius, got %d", turtles.Count())
+	}
+
+	if turtles.Contains(m.Turtle(1)) {
+		t.Errorf("Expected only turtles from the agentset")
+	}
+
+	// the whole set should give the same answer as the model
+	if m.Turtles().InRadiusXY(0, 0, 4).Count() != m.TurtlesInRadiusXY(0, 0, 4).Count() {
+		t.Errorf("Expected the agentset to match the model")
+	}
+
+	row := m.Patches.With(func(p *model.Patch) bool {
+		return p.YCor() == 0
+	})
+	patches := row.InRadiusXY(0, 0, 2)
+	if patches.Count() != 5 {
+		t.Errorf("Expected 5 patches of the row in the radius, got %d", patches.Count())
+	}
+}
+
+func TestInCone(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{})
+
+	m.CreateTurtles(5, nil)
+
+	viewer := m.Turtle(0)
+	viewer.SetXY(0, 0)
+	viewer.SetHeading(0)
+
+	m.Turtle(1).SetXY(3, 0)  // straight ahead
+	m.Turtle(2).SetXY(3, 1)  // ahead and a little off to the side
+	m.Turtle(3).SetXY(0, 3)  // off to the side
+	m.Turtle(4).SetXY(-3, 0) // behind
+
+	inCone := viewer.InCone(5, 60)
+	if inCone.Count() != 3 {
+		t.Errorf("Expected the viewer and 2 turtles in the cone, got %d", inCone.Count())
+	}
+	if !inCone.Contains(m.Turtle(1)) || !inCone.Contains(m.Turtle(2)) {
+		t.Errorf("Expected the turtles ahead to be in the cone")
+	}
+	if inCone.Contains(m.Turtle(3)) || inCone.Contains(m.Turtle(4)) {
+		t.Errorf("Expected the turtles to the side and behind to not be in the cone")
+	}
+
+	// the cone should be limited by distance
+	if viewer.InCone(2, 60).Contains(m.Turtle(1)) {
+		t.Errorf("Expected the turtle out of reach to not be in the cone")
+	}
+
+	// a cone that goes all the way around is the same as a radius
+	if viewer.InCone(5, 360).Count() != 5 {
+		t.Errorf("Expected all turtles in a full cone")
+	}
+
+	others := m.Turtles().WhoAreNotTurtle(viewer)
+	if others.InCone(viewer, 5, 60).Count() != 2 {
+		t.Errorf("Expected 2 other turtles in the cone")
+	}
+
+	patches := viewer.PatchesInCone(2, 90)
+	if !patches.Contains(m.Patch(1, 0)) || !patches.Contains(m.Patch(2, 0)) || !patches.Contains(m.Patch(1, 1)) {
+		t.Errorf("Expected the patches ahead to be in the cone")
+	}
+	if patches.Contains(m.Patch(-1, 0)) || patches.Contains(m.Patch(0, 2)) {
+		t.Errorf("Expected the patches behind and to the side to not be in the cone")
+	}
+}
+
+func TestInConeWrapping(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		WrappingX: true,
+	})
+
+	m.CreateTurtles(2, nil)
+
+	viewer := m.Turtle(0)
+	viewer.SetXY(14, 0)
+	viewer.SetHeading(0)
+	m.Turtle(1).SetXY(-14, 0)
+
+	if !viewer.InCone(5, 30).Contains(m.Turtle(1)) {
+		t.Errorf("Expected the cone to see across the wrapped edge")
+	}
+}
+
+func TestInCone3D(t *testing.T) {
+	m := model.NewModel(model.ModelSettings{
+		MinPxCor: -5,
+		MaxPxCor: 5,
+		MinPyCor: -5,
+		MaxPyCor: 5,
+		MinPzCor: -5,
+		MaxPzCor: 5,
+	})
+
+	m.CreateTurtles(3, nil)
+
+	viewer := m.Turtle(0)
+	viewer.SetXYZ(0, 0, 0)
+	viewer.SetHeading(0)
+	viewer.FaceXYZ(1, 0, 1) // pitched up 45 degrees
+
+	m.Turtle(1).SetXYZ(2, 0, 2) // along the pitch
+	m.Turtle(2).SetXYZ(3, 0, 0) // ahead but level
+
+	inCone := viewer.InCone(4, 60)
+	if !inCone.Contains(m.Turtle(1)) {
+		t.Errorf("Expected the turtle along the pitch to be in the cone")
+	}
+	if inCone.Contains(m.Turtle(2)) {
+		t.Errorf("Expected the level turtle to be outside the pitched cone")
+	}
+
+	if m.PatchesInRadiusXYZ(0, 0, 0, 1).Count() != 7 {
+		t.Errorf("Expected 7 patches within a radius of 1 in 3D, got %d", m.PatchesInRadiusXYZ(0, 0, 0, 1).Count())
+	}
+}